@@ -3,8 +3,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -12,6 +16,8 @@ import (
 	"github.com/pavlegich/gophkeeper/internal/client"
 	"github.com/pavlegich/gophkeeper/internal/client/controllers"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	"github.com/pavlegich/gophkeeper/internal/client/domains/user"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 	"github.com/pavlegich/gophkeeper/internal/client/utils"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
@@ -28,18 +34,6 @@ func main() {
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 
-	// Manager for read and write
-	rw := rwmanager.NewRWManager(ctx, os.Stdin, os.Stdout)
-
-	// Versions
-	rw.Writeln(ctx, "Build version: "+buildVersion)
-	rw.Writeln(ctx, "Build date: "+buildDate)
-
-	// Greeting
-	rw.Writeln(ctx, utils.Greet)
-	// WaitGroup
-	wg := &sync.WaitGroup{}
-
 	// Logger
 	err := logger.Init(ctx, "Panic")
 	if err != nil {
@@ -54,8 +48,60 @@ func main() {
 		logger.Log.Error("main: parse flags failed", zap.Error(err))
 	}
 
+	// TLS with the custom CA bundle and the client certificate
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig, err = cfg.TLSConfig()
+	if err != nil {
+		logger.Log.Error("main: load TLS configuration failed", zap.Error(err))
+		os.Stderr.WriteString("couldn't load TLS configuration: " + err.Error() + "\n")
+		stop()
+		os.Exit(utils.ExitUsage)
+	}
+
+	// Expired access tokens are refreshed automatically
+	utils.SetTransport(user.NewRefreshTransport(ctx, cfg, transport))
+
+	// Stored login session
+	sessionErr := user.LoadSession(ctx, cfg)
+	if sessionErr != nil {
+		logger.Log.Error("main: load session failed", zap.Error(sessionErr))
+	}
+
+	// Non-interactive mode, if the command is specified in the arguments
+	if flag.NArg() > 0 {
+		code := runCommand(ctx, cfg, flag.Args())
+		stop()
+		os.Exit(code)
+	}
+
+	// Manager for read and write
+	// In JSON mode only the results are written into the standard output
+	rwOpts := []rwmanager.Option{}
+	if cfg.Output == config.OutputJSON {
+		rwOpts = append(rwOpts, rwmanager.WithJSON(true), rwmanager.WithErrOutput(os.Stderr))
+	}
+	rw := rwmanager.NewRWManager(ctx, os.Stdin, os.Stdout, rwOpts...)
+
+	// Versions
+	rw.Writeln(ctx, "Build version: "+buildVersion)
+	rw.Writeln(ctx, "Build date: "+buildDate)
+
+	// Greeting
+	rw.Writeln(ctx, utils.Greet)
+	// WaitGroup
+	wg := &sync.WaitGroup{}
+
 	// Client
 	ctrl := controllers.NewController(ctx, rw, cfg)
+	if errors.Is(sessionErr, errs.ErrSessionExpired) {
+		rw.Error(ctx, errs.ErrSessionExpired)
+	}
+	if cfg.Cookie != nil {
+		err = ctrl.ResumeSession(ctx)
+		if known := utils.GetKnownErr(err); known != nil {
+			rw.Error(ctx, known)
+		}
+	}
 	client, err := client.NewClient(ctx, ctrl, rw, cfg)
 	if err != nil {
 		logger.Log.Error("main: create new client failed", zap.Error(err))
@@ -94,3 +140,43 @@ func main() {
 
 	rw.Writeln(ctx, utils.Quit)
 }
+
+// runCommand does the command from the arguments in the non-interactive mode
+// and returns the exit code for the result.
+func runCommand(ctx context.Context, cfg *config.ClientConfig, args []string) int {
+	jsonOutput := cfg.Output == config.OutputJSON
+	errRW := rwmanager.NewQuietRWManager(ctx, strings.NewReader(""), os.Stdout, os.Stderr)
+
+	cmd, err := controllers.ParseArgs(ctx, args)
+	if err != nil {
+		return writeCommandResult(ctx, errRW, jsonOutput, nil, err)
+	}
+
+	rw := rwmanager.NewQuietRWManager(ctx, strings.NewReader(cmd.Input), os.Stdout, os.Stderr,
+		rwmanager.WithJSON(jsonOutput))
+	ctrl := controllers.NewController(ctx, rw, cfg)
+	err = ctrl.HandleArgs(ctx, cmd)
+
+	return writeCommandResult(ctx, errRW, jsonOutput, rw.PopResult(ctx), err)
+}
+
+// writeCommandResult writes the error of the command into the error output,
+// or the JSON document with the result of the command into the output
+// in JSON mode, and returns the exit code for the result.
+func writeCommandResult(ctx context.Context, rw rwmanager.RWService, jsonOutput bool, result any, err error) int {
+	if err != nil {
+		logger.Log.Error("main: command failed", zap.Error(err))
+	}
+	if jsonOutput {
+		rw.WriteDocument(ctx, utils.FormatResult(result, err))
+		return utils.GetExitCode(err)
+	}
+	if err != nil {
+		known := utils.GetKnownErr(err)
+		if known == nil {
+			known = err
+		}
+		rw.Error(ctx, known)
+	}
+	return utils.GetExitCode(err)
+}
@@ -5,14 +5,20 @@ go 1.21.5
 require (
 	github.com/golang/mock v1.6.0
 	github.com/jackc/pgx/v5 v5.5.2
+	github.com/mattn/go-sqlite3 v1.14.22
 	go.uber.org/automaxprocs v1.5.3
 	go.uber.org/zap v1.26.0
+	google.golang.org/grpc v1.61.0
+	google.golang.org/protobuf v1.31.0
 )
 
 require (
+	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/sethvargo/go-retry v0.2.4 // indirect
+	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/tools v0.17.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231106174013-bbf56f31fb17 // indirect
 )
 
 require (
@@ -26,7 +32,7 @@ require (
 	github.com/pressly/goose/v3 v3.17.0
 	github.com/stretchr/testify v1.8.4 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/crypto v0.17.0
+	golang.org/x/crypto v0.18.0
 	golang.org/x/sync v0.6.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
@@ -35,11 +35,15 @@ func (c *Client) Serve(ctx context.Context) error {
 			return nil
 		default:
 			err := c.controller.HandleCommand(ctx)
-			if err != nil {
-				got := utils.GetKnownErr(err)
-				if got == nil {
-					return fmt.Errorf("Serve: handle command failed %w", err)
-				}
+			got := utils.GetKnownErr(err)
+			if err != nil && got == nil {
+				return fmt.Errorf("Serve: handle command failed %w", err)
+			}
+			if c.config.Output == config.OutputJSON {
+				c.rw.WriteDocument(ctx, utils.FormatResult(c.rw.PopResult(ctx), err))
+				continue
+			}
+			if got != nil {
 				c.rw.Error(ctx, got)
 			}
 		}
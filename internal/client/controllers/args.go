@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	"github.com/pavlegich/gophkeeper/internal/client/domains/user"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+)
+
+// Environment variables with the user's credentials for the non-interactive mode.
+const (
+	EnvLogin          = "GOPHKEEPER_LOGIN"
+	EnvPassword       = "GOPHKEEPER_PASSWORD"
+	EnvMasterPassword = "GOPHKEEPER_MASTER_PASSWORD"
+	EnvCode           = "GOPHKEEPER_OTP"
+	EnvNewPassword    = "GOPHKEEPER_NEW_PASSWORD"
+)
+
+// Command contains the command of the non-interactive mode. Input contains
+// the answers for the prompts of the command, one per line. Content contains
+// the text data read from the file, it is stored verbatim.
+type Command struct {
+	Action         string
+	Field          string
+	Input          string
+	Content        []byte
+	Login          string
+	Password       string
+	MasterPassword string
+	Code           string
+}
+
+// metaFlag contains metadata pairs from the repeated command line flag.
+type metaFlag []string
+
+// String returns metadata pairs separated by comma.
+func (m *metaFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+// Set adds the metadata pair in 'key=value' format.
+func (m *metaFlag) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("Set: metadata must be in 'key=value' format")
+	}
+	*m = append(*m, v)
+	return nil
+}
+
+// ParseArgs parses the command line arguments of the non-interactive mode,
+// e.g. 'get --type credentials --name github --field password',
+// and returns the command with the prepared answers for its prompts.
+func ParseArgs(ctx context.Context, args []string) (*Command, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("ParseArgs: %w", errs.ErrUnknownCommand)
+	}
+
+	cmd := &Command{
+		Action:         strings.ToLower(args[0]),
+		Login:          os.Getenv(EnvLogin),
+		Password:       os.Getenv(EnvPassword),
+		MasterPassword: os.Getenv(EnvMasterPassword),
+		Code:           os.Getenv(EnvCode),
+	}
+
+	fs := flag.NewFlagSet(cmd.Action, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&cmd.Login, "user", cmd.Login, "Login of the user")
+	fs.StringVar(&cmd.Code, "code", cmd.Code, "One-time code or recovery code for two-factor authentication")
+	dType := fs.String("type", "", "Data type (credentials/card/text/binary)")
+	name := fs.String("name", "", "Data name")
+	file := fs.String("file", "", "Path to file with data, '-' for standard input")
+	out := fs.String("out", "", "Path for saving binary data")
+	version := fs.String("version", "", "Data version")
+	session := fs.String("id", "", "Session identifier")
+	folder := fs.String("folder", "", "Folder path, e.g. work/servers")
+	tag := fs.String("tag", "", "Tag of data")
+	keep := fs.String("keep", "", "Value kept for resolving conflict (local/server/show)")
+	query := fs.String("query", "", "Search query, e.g. 'git* type:credentials url=github.com'")
+	fs.StringVar(&cmd.Field, "field", "", "Field of data for output, e.g. password")
+	var meta metaFlag
+	fs.Var(&meta, "meta", "Metadata in 'key=value' format, can be repeated")
+
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("ParseArgs: parse flags failed %s %w", err.Error(), errs.ErrInvalidArgs)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("ParseArgs: unexpected arguments %v %w", fs.Args(), errs.ErrInvalidArgs)
+	}
+
+	var lines []string
+	switch cmd.Action {
+	case "register", "login", "logout", "sync", "sessions", "folders", "conflicts":
+	case "list":
+		lines = []string{*dType, *folder, *tag}
+	case "get":
+		lines = []string{*dType, *name}
+		if *dType == "binary" {
+			lines = append(lines, *out)
+		}
+	case "delete", "history":
+		lines = []string{*dType, *name}
+	case "restore":
+		lines = []string{*dType, *name, *version}
+	case "show-version":
+		lines = []string{*dType, *name, *version}
+		if *dType == "binary" {
+			lines = append(lines, *out)
+		}
+	case "move":
+		lines = []string{*dType, *name, *folder}
+	case "tag", "untag":
+		lines = []string{*dType, *name, *tag}
+	case "search":
+		lines = []string{*query}
+	case "resolve":
+		lines = []string{*dType, *name, *keep}
+	case "revoke":
+		lines = []string{*session}
+	case "disable-2fa":
+		lines = []string{cmd.Code}
+	case "passwd":
+		newPassword := os.Getenv(EnvNewPassword)
+		if cmd.Password == "" || newPassword == "" {
+			return nil, fmt.Errorf("ParseArgs: current and new passwords are required %w", errs.ErrInvalidArgs)
+		}
+		lines = []string{cmd.Password, newPassword, newPassword}
+	case "unregister":
+		if cmd.Password == "" {
+			return nil, fmt.Errorf("ParseArgs: password is required %w", errs.ErrInvalidArgs)
+		}
+		lines = []string{cmd.Password, cmd.Code}
+	case "create", "update":
+		var data []string
+		if *dType == "text" {
+			cmd.Content, err = readContent(*file)
+		} else {
+			data, err = readDataLines(*dType, *file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseArgs: read data failed %w", err)
+		}
+		lines = append([]string{*dType, *name}, data...)
+		for _, m := range meta {
+			k, v, _ := strings.Cut(m, "=")
+			if strings.Contains(k, " : ") || strings.Contains(v, " : ") {
+				return nil, fmt.Errorf("ParseArgs: metadata %s contains ' : ' %w", k, errs.ErrInvalidArgs)
+			}
+			lines = append(lines, k+" : "+v)
+		}
+		lines = append(lines, utils.Close)
+	default:
+		return nil, fmt.Errorf("ParseArgs: %w", errs.ErrUnknownCommand)
+	}
+
+	err = checkInput(lines)
+	if err != nil {
+		return nil, fmt.Errorf("ParseArgs: %w", err)
+	}
+	if len(lines) > 0 {
+		cmd.Input = strings.Join(lines, "\n") + "\n"
+	}
+
+	return cmd, nil
+}
+
+// HandleArgs logs the user in with the credentials of the command
+// and does the requested action in the non-interactive mode.
+func (c *Controller) HandleArgs(ctx context.Context, cmd *Command) error {
+	ctx = context.WithValue(ctx, utils.ContextActionKey, cmd.Action)
+	ctx = context.WithValue(ctx, utils.ContextFieldKey, cmd.Field)
+	if cmd.Content != nil {
+		ctx = context.WithValue(ctx, utils.ContextContentKey, cmd.Content)
+	}
+
+	if cmd.Action == "logout" {
+		err := c.user.Logout(ctx)
+		if err != nil {
+			return fmt.Errorf("HandleArgs: logout user failed %w", err)
+		}
+		return nil
+	}
+
+	// The stored session is used, if the password is not specified. The password
+	// of passwd and unregister is their input, so the stored session is preferred
+	reauth := cmd.Action == "passwd" || cmd.Action == "unregister"
+	resume := (cmd.Password == "" || reauth) && c.cfg.Cookie != nil &&
+		(cmd.Login == "" || cmd.Login == c.cfg.Login) && cmd.Action != "register"
+	if cmd.MasterPassword == "" || (!resume && (cmd.Login == "" || cmd.Password == "")) {
+		return fmt.Errorf("HandleArgs: login, password and master password are required %w", errs.ErrInvalidArgs)
+	}
+
+	// Prompts and messages of authentication are not written in the non-interactive mode
+	authInput := []string{cmd.Login, cmd.Password, cmd.MasterPassword}
+	if resume {
+		authInput = []string{cmd.MasterPassword}
+	}
+	if cmd.Action == "register" {
+		authInput = append(authInput, cmd.MasterPassword)
+	}
+	err := checkInput(authInput)
+	if err != nil {
+		return fmt.Errorf("HandleArgs: %w", err)
+	}
+	authRW := rwmanager.NewQuietRWManager(ctx, strings.NewReader(strings.Join(authInput, "\n")+"\n"),
+		io.Discard, io.Discard)
+	auth := user.NewUserService(ctx, authRW, c.cfg)
+	auth.SetCode(cmd.Code)
+
+	if cmd.Action == "register" {
+		err := auth.Register(ctx)
+		if err != nil {
+			return fmt.Errorf("HandleArgs: register user failed %w", err)
+		}
+		return c.openReplica(ctx)
+	}
+
+	if resume {
+		err = auth.Resume(ctx)
+	} else {
+		err = auth.Login(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("HandleArgs: login user failed %w", err)
+	}
+	err = c.openReplica(ctx)
+	if err != nil {
+		return fmt.Errorf("HandleArgs: %w", err)
+	}
+	if cmd.Action == "login" {
+		return nil
+	}
+
+	act := c.action(cmd.Action)
+	if act == nil {
+		return fmt.Errorf("HandleArgs: %w", errs.ErrUnknownCommand)
+	}
+	err = act(ctx)
+	if err != nil {
+		return fmt.Errorf("HandleArgs: %s data failed %w", cmd.Action, err)
+	}
+
+	return nil
+}
+
+// checkInput checks that the answers for the prompts contain no line breaks,
+// since every answer is one line of the input and the answer with the line break
+// would be taken for the next answers.
+func checkInput(lines []string) error {
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\r\n") {
+			return fmt.Errorf("checkInput: value contains line break %w", errs.ErrInvalidArgs)
+		}
+	}
+	return nil
+}
+
+// readDataLines reads data of the requested type from the file
+// and returns the answers for the prompts of the data reader.
+func readDataLines(dType string, path string) ([]string, error) {
+	if dType == "binary" {
+		if path == "" || path == "-" {
+			return nil, fmt.Errorf("readDataLines: binary data is read only from file %w", errs.ErrInvalidArgs)
+		}
+		return []string{path}, nil
+	}
+
+	content, err := readContent(path)
+	if err != nil {
+		return nil, fmt.Errorf("readDataLines: %w", err)
+	}
+
+	switch dType {
+	case "credentials":
+		return fieldLines(content, "login", "password")
+	case "card":
+		return fieldLines(content, "number", "expires", "owner", "cv")
+	}
+	return nil, fmt.Errorf("readDataLines: %w", errs.ErrInvalidDataType)
+}
+
+// readContent reads the content of the file, '-' is the standard input.
+func readContent(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("readContent: file is required %w", errs.ErrInvalidArgs)
+	}
+
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readContent: %w", errs.ErrInvalidFilePath)
+	}
+	return content, nil
+}
+
+// fieldLines returns values of the requested fields of JSON object in order.
+func fieldLines(content []byte, fields ...string) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var values map[string]any
+	err := dec.Decode(&values)
+	if err != nil {
+		return nil, fmt.Errorf("fieldLines: decode data failed %s %w", err.Error(), errs.ErrInvalidArgs)
+	}
+
+	lines := make([]string, 0, len(fields))
+	for _, f := range fields {
+		v, ok := values[f]
+		if !ok {
+			return nil, fmt.Errorf("fieldLines: field %s is required %w", f, errs.ErrInvalidArgs)
+		}
+		lines = append(lines, fmt.Sprint(v))
+	}
+	return lines, nil
+}
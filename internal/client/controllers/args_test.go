@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+)
+
+func TestParseArgs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "note.txt")
+	os.WriteFile(textPath, []byte("first line\nclose\nsecond line\n"), 0600)
+	credsPath := filepath.Join(dir, "creds.json")
+	os.WriteFile(credsPath, []byte(`{"login": "gopher", "password": "secret"}`), 0600)
+	cardPath := filepath.Join(dir, "card.json")
+	os.WriteFile(cardPath, []byte(`{"number": 4111111111111111, "expires": "10/30", "owner": "GOPHER", "cv": 123}`), 0600)
+	t.Setenv(EnvPassword, "old")
+	t.Setenv(EnvNewPassword, "new")
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantInput   string
+		wantField   string
+		wantContent string
+		wantErr     error
+	}{
+		{
+			name:      "get_field",
+			args:      []string{"get", "--type", "credentials", "--name", "github", "--field", "password"},
+			wantInput: "credentials\ngithub\n",
+			wantField: "password",
+			wantErr:   nil,
+		},
+		{
+			name:      "get_binary",
+			args:      []string{"get", "--type", "binary", "--name", "doc", "--out", "./doc.pdf"},
+			wantInput: "binary\ndoc\n./doc.pdf\n",
+			wantErr:   nil,
+		},
+		{
+			name:        "create_text",
+			args:        []string{"create", "--type", "text", "--name", "note", "--file", textPath, "--meta", "site=github.com"},
+			wantInput:   "text\nnote\nsite : github.com\nclose\n",
+			wantContent: "first line\nclose\nsecond line\n",
+			wantErr:     nil,
+		},
+		{
+			name:      "update_credentials",
+			args:      []string{"update", "--type", "credentials", "--name", "github", "--file", credsPath},
+			wantInput: "credentials\ngithub\ngopher\nsecret\nclose\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "create_card",
+			args:      []string{"create", "--type", "card", "--name", "visa", "--file", cardPath},
+			wantInput: "card\nvisa\n4111111111111111\n10/30\nGOPHER\n123\nclose\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "list_all",
+			args:      []string{"list"},
+			wantInput: "\n\n\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "list_by_folder_and_tag",
+			args:      []string{"list", "--folder", "work/servers", "--tag", "prod"},
+			wantInput: "\nwork/servers\nprod\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "show_version",
+			args:      []string{"show-version", "--type", "binary", "--name", "doc", "--version", "2", "--out", "./old.pdf"},
+			wantInput: "binary\ndoc\n2\n./old.pdf\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "resolve",
+			args:      []string{"resolve", "--type", "text", "--name", "note", "--keep", "local"},
+			wantInput: "text\nnote\nlocal\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "search",
+			args:      []string{"search", "--query", "git* url=github.com"},
+			wantInput: "git* url=github.com\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "move",
+			args:      []string{"move", "--type", "credentials", "--name", "github", "--folder", "work"},
+			wantInput: "credentials\ngithub\nwork\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "passwd",
+			args:      []string{"passwd"},
+			wantInput: "old\nnew\nnew\n",
+			wantErr:   nil,
+		},
+		{
+			name:      "unregister",
+			args:      []string{"unregister", "--code", "123456"},
+			wantInput: "old\n123456\n",
+			wantErr:   nil,
+		},
+		{
+			name:    "create_without_file",
+			args:    []string{"create", "--type", "text", "--name", "note"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "name_with_line_break",
+			args:    []string{"delete", "--type", "text", "--name", "note\ncredentials"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "query_with_line_break",
+			args:    []string{"search", "--query", "git*\nother"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "meta_with_line_break",
+			args:    []string{"create", "--type", "text", "--name", "note", "--file", textPath, "--meta", "site=a\nclose"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "meta_with_separator",
+			args:    []string{"create", "--type", "text", "--name", "note", "--file", textPath, "--meta", "a : b=c"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "unknown_flag",
+			args:    []string{"get", "--unknown"},
+			wantErr: errs.ErrInvalidArgs,
+		},
+		{
+			name:    "unknown_command",
+			args:    []string{"fly"},
+			wantErr: errs.ErrUnknownCommand,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArgs(ctx, tt.args)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Input != tt.wantInput {
+				t.Errorf("ParseArgs() input = %q, want %q", got.Input, tt.wantInput)
+			}
+			if got.Field != tt.wantField {
+				t.Errorf("ParseArgs() field = %q, want %q", got.Field, tt.wantField)
+			}
+			if string(got.Content) != tt.wantContent {
+				t.Errorf("ParseArgs() content = %q, want %q", got.Content, tt.wantContent)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/pavlegich/gophkeeper/internal/client/domains/data"
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/user"
 	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
@@ -18,22 +19,25 @@ import (
 
 // Controller contains configuration for building the client app.
 type Controller struct {
-	rw   rwmanager.RWService
-	cfg  *config.ClientConfig
-	user user.Service
-	data data.Service
+	rw      rwmanager.RWService
+	cfg     *config.ClientConfig
+	user    user.Service
+	data    data.Service
+	replica *replica.Replica
 }
 
 // NewController creates and returns new client controller.
 func NewController(ctx context.Context, rw rwmanager.RWService, cfg *config.ClientConfig) *Controller {
+	rep := replica.NewReplica(ctx, cfg)
 	userService := user.NewUserService(ctx, rw, cfg)
-	dataService := data.NewDataService(ctx, rw, cfg)
+	dataService := data.NewDataService(ctx, rw, cfg, rep)
 
 	return &Controller{
-		rw:   rw,
-		cfg:  cfg,
-		user: userService,
-		data: dataService,
+		rw:      rw,
+		cfg:     cfg,
+		user:    userService,
+		data:    dataService,
+		replica: rep,
 	}
 }
 
@@ -55,21 +59,32 @@ func (c *Controller) HandleCommand(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("HandleCommand: register user failed %w", err)
 		}
+		return c.openReplica(ctx)
 	case "login":
 		err := c.user.Login(ctx)
 		if err != nil {
 			return fmt.Errorf("HandleCommand: login user failed %w", err)
 		}
-	case "create", "update":
-		clientAct = c.data.CreateOrUpdate
-	case "get":
-		clientAct = c.data.GetValue
-	case "delete":
-		clientAct = c.data.Delete
+		return c.openReplica(ctx)
+	case "logout":
+		err := c.user.Logout(ctx)
+		if err != nil {
+			return fmt.Errorf("HandleCommand: logout user failed %w", err)
+		}
+		return c.openReplica(ctx)
+	case "unregister":
+		err := c.user.Unregister(ctx)
+		if err != nil {
+			return fmt.Errorf("HandleCommand: unregister user failed %w", err)
+		}
+		return c.openReplica(ctx)
 	case "exit":
 		return fmt.Errorf("HandleCommand: %w", errs.ErrExit)
 	default:
-		return fmt.Errorf("HandleCommand: %w", errs.ErrUnknownCommand)
+		clientAct = c.action(act)
+		if clientAct == nil {
+			return fmt.Errorf("HandleCommand: %w", errs.ErrUnknownCommand)
+		}
 	}
 
 	err = utils.DoWithRetryIfEmpty(ctx, c.rw, clientAct)
@@ -78,3 +93,74 @@ func (c *Controller) HandleCommand(ctx context.Context) error {
 	}
 	return nil
 }
+
+// ResumeSession continues the session loaded from the session file
+// and loads the local replica of the user.
+func (c *Controller) ResumeSession(ctx context.Context) error {
+	err := c.user.Resume(ctx)
+	if err != nil {
+		return fmt.Errorf("ResumeSession: resume session failed %w", err)
+	}
+	return c.openReplica(ctx)
+}
+
+// action returns the service method for the action,
+// nil is returned if the action is unknown.
+func (c *Controller) action(act string) func(ctx context.Context) error {
+	switch act {
+	case "sessions":
+		return c.user.Sessions
+	case "revoke":
+		return c.user.RevokeSession
+	case "passwd":
+		return c.user.ChangePassword
+	case "unregister":
+		return c.user.Unregister
+	case "enable-2fa":
+		return c.user.EnableTwoFactor
+	case "disable-2fa":
+		return c.user.DisableTwoFactor
+	case "create", "update":
+		return c.data.CreateOrUpdate
+	case "get":
+		return c.data.GetValue
+	case "delete":
+		return c.data.Delete
+	case "list":
+		return c.data.List
+	case "history":
+		return c.data.History
+	case "show-version":
+		return c.data.GetVersion
+	case "restore":
+		return c.data.Restore
+	case "sync":
+		return c.data.Sync
+	case "folders":
+		return c.data.Folders
+	case "move":
+		return c.data.Move
+	case "tag":
+		return c.data.Tag
+	case "untag":
+		return c.data.Untag
+	case "search":
+		return c.data.Search
+	case "conflicts":
+		return c.data.Conflicts
+	case "resolve":
+		return c.data.Resolve
+	}
+	return nil
+}
+
+// openReplica loads the local replica of the logged in user. If the replica
+// couldn't be decrypted, the master password is wrong and the key is dropped.
+func (c *Controller) openReplica(ctx context.Context) error {
+	err := c.replica.Open(ctx)
+	if err != nil {
+		c.cfg.Cipher = nil
+		return fmt.Errorf("openReplica: open local replica failed %w", err)
+	}
+	return nil
+}
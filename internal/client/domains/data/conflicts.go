@@ -0,0 +1,181 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+)
+
+// conflictValues contains the local and the server values of data object
+// in conflict, the missing value means that data object is deleted.
+type conflictValues struct {
+	Conflict
+	Local         any `json:"local,omitempty"`
+	Server        any `json:"server,omitempty"`
+	ServerVersion int `json:"server_version,omitempty"`
+}
+
+// Conflicts writes the local changes rejected because of conflict into the output as a table.
+func (s *DataService) Conflicts(ctx context.Context) error {
+	changes := s.replica.ConflictList()
+	conflicts := make([]*Conflict, 0, len(changes))
+	for _, ch := range changes {
+		conflicts = append(conflicts, &Conflict{
+			Type:    ch.Type,
+			Name:    ch.Name,
+			Op:      ch.Op,
+			Version: ch.Version,
+		})
+	}
+
+	s.rw.WriteResult(ctx, conflicts, formatConflictsTable(conflicts))
+
+	return nil
+}
+
+// Resolve reads information about data in conflict and the decision from the input:
+// 'local' sends the local change again over the change of another client, 'server'
+// drops the local change and 'show' writes both values and keeps the conflict.
+func (s *DataService) Resolve(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("Resolve: %w", errs.ErrUnauthorized)
+	}
+
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Resolve: couldn't read data type and name %w", err)
+	}
+	ch, ok := s.replica.Conflict(d.Type, d.Name)
+	if !ok {
+		return fmt.Errorf("Resolve: conflict of %s/%s %w", d.Type, d.Name, errs.ErrNotExist)
+	}
+
+	s.rw.Write(ctx, "Keep local or server value, or show both? (local/server/show): ")
+	answer, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("Resolve: read answer failed %w", err)
+	}
+
+	// The local change is based on the version of data object received from the server
+	_, err = s.replica.Pull(ctx)
+	if err != nil && !errors.Is(err, errs.ErrOffline) {
+		return fmt.Errorf("Resolve: pull changes failed %w", err)
+	}
+
+	switch strings.ToLower(answer) {
+	case "show":
+		err = s.showConflict(ctx, ch)
+		if err != nil {
+			return fmt.Errorf("Resolve: %w", err)
+		}
+		return nil
+	case "local":
+		err = s.replica.Resolve(d.Type, d.Name, true)
+		if err != nil {
+			return fmt.Errorf("Resolve: %w", err)
+		}
+		err = s.push(ctx)
+		if errors.Is(err, errs.ErrOffline) {
+			s.rw.Error(ctx, fmt.Errorf("%w, the change is saved locally and will be sent later", errs.ErrOffline))
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("Resolve: push changes failed %w", err)
+		}
+	case "server":
+		err = s.replica.Resolve(d.Type, d.Name, false)
+		if err != nil {
+			return fmt.Errorf("Resolve: %w", err)
+		}
+	default:
+		return fmt.Errorf("Resolve: unknown answer %s %w", answer, errs.ErrInvalidArgs)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// showConflict writes the local value of data object, which was rejected
+// by the server, and the current value stored on the server.
+func (s *DataService) showConflict(ctx context.Context, ch *replica.Change) error {
+	result := &conflictValues{
+		Conflict: Conflict{Type: ch.Type, Name: ch.Name, Op: ch.Op, Version: ch.Version},
+	}
+
+	// The binary values are not read, only their sizes are written
+	var local *Data
+	if ch.Op != replica.OpDelete {
+		var err error
+		if ch.Blob != nil {
+			local, err = s.openEntry(&ch.Entry)
+		} else {
+			local, err = decryptEntry(s.cfg.Cipher, &ch.Entry, s.cfg.Legacy)
+		}
+		if err != nil {
+			return fmt.Errorf("showConflict: %w", err)
+		}
+		if local.Content != nil {
+			defer local.Content.Close()
+		}
+		result.Local = conflictValue(local)
+	}
+
+	remote, err := s.fetchValue(ctx, &Data{Type: ch.Type, Name: ch.Name}, 0)
+	if err != nil && !errors.Is(err, errs.ErrNotExist) {
+		return fmt.Errorf("showConflict: get server value failed %w", err)
+	}
+	if remote != nil {
+		if remote.Content != nil {
+			defer remote.Content.Close()
+		}
+		result.Server = conflictValue(remote)
+		result.ServerVersion = remote.Version
+	}
+
+	s.rw.WriteResult(ctx, result, formatConflict(local, remote))
+
+	return nil
+}
+
+// conflictValue returns the value for the output of conflict,
+// only the size is returned for the binary value.
+func conflictValue(d *Data) any {
+	if d.Type == "binary" {
+		return map[string]int64{"size": valueSize(d)}
+	}
+	return valueResult(d.Data)
+}
+
+// valueSize returns the size of the value of data object,
+// the streamed value is not read.
+func valueSize(d *Data) int64 {
+	if d.Content != nil {
+		return d.Size
+	}
+	return int64(len(d.Data))
+}
+
+// formatConflictsTable returns information about conflicts formatted as a table.
+func formatConflictsTable(conflicts []*Conflict) string {
+	if len(conflicts) == 0 {
+		return "no conflicts"
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tNAME\tCHANGE\tBASE VERSION")
+	for _, c := range conflicts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", c.Type, c.Name, c.Op, c.Version)
+	}
+	tw.Flush()
+
+	return strings.TrimRight(buf.String(), "\n")
+}
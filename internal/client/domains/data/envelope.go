@@ -0,0 +1,175 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+)
+
+// envelope contains metadata of data object and information about the file
+// of binary data, they are encrypted together, so the server stores neither
+// the metadata nor the name, the type, the size and the checksum of the file.
+type envelope struct {
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	File     *fileInfo       `json:"file,omitempty"`
+}
+
+// fileInfo contains information about the original file of binary data:
+// the name, the content type, the size and SHA-256 checksum in hex of the file.
+type fileInfo struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`
+}
+
+// encryptFile encrypts the file at the path as a stream into the writer,
+// the value is encrypted by chunks, so it is decrypted as a stream when
+// it is received. Returns information about the file.
+func encryptFile(c *encryption.Cipher, d *Data, path string, w io.Writer) (*fileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("encryptFile: %w", errs.ErrInvalidFilePath)
+	}
+	defer file.Close()
+
+	// The content type is detected by the beginning of the file
+	r := bufio.NewReaderSize(file, 512)
+	head, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("encryptFile: %w", errs.ErrInvalidFilePath)
+	}
+	info := &fileInfo{
+		Name:        filepath.Base(path),
+		ContentType: contentType(filepath.Base(path), head),
+	}
+
+	ew, err := c.NewEncryptWriter(w, additionalData(d, "data"))
+	if err != nil {
+		return nil, fmt.Errorf("encryptFile: %w", err)
+	}
+	sum := sha256.New()
+	info.Size, err = io.Copy(io.MultiWriter(ew, sum), r)
+	if err != nil {
+		return nil, fmt.Errorf("encryptFile: encrypt file failed %w", err)
+	}
+	err = ew.Close()
+	if err != nil {
+		return nil, fmt.Errorf("encryptFile: encrypt file failed %w", err)
+	}
+	info.Checksum = hex.EncodeToString(sum.Sum(nil))
+
+	return info, nil
+}
+
+// verify compares the size and the checksum of the decrypted content
+// with the ones of the original file.
+func (f *fileInfo) verify(content []byte) error {
+	sum := sha256.Sum256(content)
+	if int64(len(content)) != f.Size || hex.EncodeToString(sum[:]) != f.Checksum {
+		return fmt.Errorf("verify: %w", errs.ErrDigestMismatch)
+	}
+	return nil
+}
+
+// newReader returns the reader of the decrypted content, which compares the size
+// and the checksum of the content with the ones of the original file, when
+// the content is read to the end. ErrDigestMismatch is returned instead
+// of io.EOF, if they differ.
+func (f *fileInfo) newReader(content io.Reader) io.Reader {
+	return &fileReader{
+		r:    content,
+		file: f,
+		hash: sha256.New(),
+	}
+}
+
+// fileReader calculates the size and the checksum of the content while it is read.
+type fileReader struct {
+	r    io.Reader
+	file *fileInfo
+	hash hash.Hash
+	size int64
+}
+
+// Read reads the content and verifies it at the end of the content.
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	r.size += int64(n)
+	if r.size > r.file.Size {
+		return n, fmt.Errorf("Read: %w", errs.ErrDigestMismatch)
+	}
+	if errors.Is(err, io.EOF) && (r.size != r.file.Size || hex.EncodeToString(r.hash.Sum(nil)) != r.file.Checksum) {
+		return n, fmt.Errorf("Read: %w", errs.ErrDigestMismatch)
+	}
+	return n, err
+}
+
+// encryptEnvelope encrypts the envelope of data object, the result
+// is JSON string, since the server stores metadata in JSON format.
+func encryptEnvelope(c *encryption.Cipher, d *Data, env *envelope) (json.RawMessage, error) {
+	plain, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("encryptEnvelope: marshal envelope failed %w", err)
+	}
+	encrypted, err := c.Encrypt(plain, additionalData(d, "envelope"))
+	if err != nil {
+		return nil, fmt.Errorf("encryptEnvelope: encrypt envelope failed %w", err)
+	}
+	metadata, err := json.Marshal(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("encryptEnvelope: marshal metadata failed %w", err)
+	}
+	return metadata, nil
+}
+
+// decryptEnvelope decrypts the envelope of data object from metadata stored
+// on the server. Metadata encrypted without envelope by the previous versions
+// of the client is returned as the envelope without file information, as well as
+// plain metadata saved before the client-side encryption, if legacy is true.
+// Otherwise plain metadata is rejected, so the server can't substitute it.
+func decryptEnvelope(c *encryption.Cipher, d *Data, metadata json.RawMessage, legacy bool) (*envelope, error) {
+	if len(metadata) == 0 {
+		return &envelope{}, nil
+	}
+	// Metadata saved before the client-side encryption is plain JSON object
+	if legacy && bytes.HasPrefix(bytes.TrimSpace(metadata), []byte("{")) {
+		return &envelope{Metadata: metadata}, nil
+	}
+	var encrypted []byte
+	err := json.Unmarshal(metadata, &encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decryptEnvelope: unmarshal metadata failed %w", err)
+	}
+	if len(encrypted) == 0 {
+		return &envelope{}, nil
+	}
+
+	plain, err := c.Decrypt(encrypted, additionalData(d, "envelope"))
+	if err == nil {
+		var env envelope
+		err = json.Unmarshal(plain, &env)
+		if err != nil {
+			return nil, fmt.Errorf("decryptEnvelope: unmarshal envelope failed %w", err)
+		}
+		return &env, nil
+	}
+
+	plain, err = c.Decrypt(encrypted, additionalData(d, "metadata"))
+	if err != nil {
+		return nil, fmt.Errorf("decryptEnvelope: %w", errs.ErrDecryptFailed)
+	}
+	return &envelope{Metadata: plain}, nil
+}
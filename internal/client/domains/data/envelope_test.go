@@ -0,0 +1,297 @@
+package data
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+)
+
+// newFileInfo returns information about the file with the content.
+func newFileInfo(name string, content []byte) *fileInfo {
+	sum := sha256.Sum256(content)
+	return &fileInfo{
+		Name:        name,
+		ContentType: contentType(name, content),
+		Size:        int64(len(content)),
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+}
+
+func Test_encryptFile(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	d := &Data{Type: "binary", Name: "scan"}
+	dir := t.TempDir()
+	value := bytes.Repeat([]byte("binary value"), encryption.ChunkSize/6)
+	path := filepath.Join(dir, "scan.pdf")
+	err = os.WriteFile(path, value, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantFile *fileInfo
+		wantErr  error
+	}{
+		{
+			name:     "ok",
+			path:     path,
+			wantFile: newFileInfo("scan.pdf", value),
+			wantErr:  nil,
+		},
+		{
+			name:    "not_existing",
+			path:    filepath.Join(dir, "missing.pdf"),
+			wantErr: errs.ErrInvalidFilePath,
+		},
+		{
+			name:    "directory",
+			path:    dir,
+			wantErr: errs.ErrInvalidFilePath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			got, err := encryptFile(c, d, tt.path, &buf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("encryptFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if *got != *tt.wantFile {
+				t.Errorf("encryptFile() file = %v, want %v", got, tt.wantFile)
+			}
+			r, err := c.NewDecryptReader(&buf, additionalData(d, "data"))
+			if err != nil {
+				t.Fatalf("NewDecryptReader() error = %v", err)
+			}
+			plain, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(plain, value) {
+				t.Errorf("encryptFile() decrypted %d bytes, %v, want %d bytes", len(plain), err, len(value))
+			}
+		})
+	}
+}
+
+func Test_decryptEnvelope(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	d := &Data{Type: "binary", Name: "scan"}
+
+	envelopeMetadata, err := encryptEnvelope(c, d, &envelope{
+		Metadata: json.RawMessage(`{"site":"github.com"}`),
+		File:     newFileInfo("scan.pdf", []byte("binary value")),
+	})
+	if err != nil {
+		t.Fatalf("encryptEnvelope() error = %v", err)
+	}
+	encrypted, err := c.Encrypt([]byte(`{"site":"github.com"}`), additionalData(d, "metadata"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	legacyMetadata, _ := json.Marshal(encrypted)
+
+	tests := []struct {
+		name         string
+		d            *Data
+		metadata     json.RawMessage
+		legacy       bool
+		wantMetadata string
+		wantFile     bool
+		wantErr      bool
+	}{
+		{
+			name:         "envelope",
+			d:            d,
+			metadata:     envelopeMetadata,
+			wantMetadata: `{"site":"github.com"}`,
+			wantFile:     true,
+		},
+		{
+			name:         "metadata_without_envelope",
+			d:            d,
+			metadata:     legacyMetadata,
+			wantMetadata: `{"site":"github.com"}`,
+		},
+		{
+			name:         "plain_metadata_legacy",
+			d:            d,
+			metadata:     json.RawMessage(`{"site":"github.com"}`),
+			legacy:       true,
+			wantMetadata: `{"site":"github.com"}`,
+		},
+		{
+			name:     "plain_metadata_rejected",
+			d:        d,
+			metadata: json.RawMessage(`{"site":"github.com"}`),
+			wantErr:  true,
+		},
+		{
+			name:     "empty",
+			d:        d,
+			metadata: nil,
+		},
+		{
+			name:     "null",
+			d:        d,
+			metadata: json.RawMessage(`null`),
+		},
+		{
+			name:     "wrong_data_object",
+			d:        &Data{Type: "binary", Name: "other"},
+			metadata: envelopeMetadata,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptEnvelope(c, tt.d, tt.metadata, tt.legacy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptEnvelope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got.Metadata) != tt.wantMetadata {
+				t.Errorf("decryptEnvelope() metadata = %s, want %s", got.Metadata, tt.wantMetadata)
+			}
+			if (got.File != nil) != tt.wantFile {
+				t.Errorf("decryptEnvelope() file = %v, want file %v", got.File, tt.wantFile)
+			}
+		})
+	}
+}
+
+func Test_openFile(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	metadata, err := encryptEnvelope(c, &Data{Type: "binary", Name: "scan"}, &envelope{
+		File: newFileInfo("scan.pdf", []byte("binary value")),
+	})
+	if err != nil {
+		t.Fatalf("encryptEnvelope() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		value        []byte
+		wantFileName string
+		wantErr      error
+	}{
+		{
+			name:         "ok",
+			value:        []byte("binary value"),
+			wantFileName: "scan.pdf",
+		},
+		{
+			name:    "corrupted",
+			value:   []byte("binary valuE"),
+			wantErr: errs.ErrDigestMismatch,
+		},
+		{
+			name:    "truncated",
+			value:   []byte("binary"),
+			wantErr: errs.ErrDigestMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{Type: "binary", Name: "scan", Data: tt.value}
+			err := openFile(c, d, metadata, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("openFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if d.FileName != tt.wantFileName {
+				t.Errorf("openFile() file name = %s, want %s", d.FileName, tt.wantFileName)
+			}
+		})
+	}
+}
+
+func Test_openContent(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	metadata, err := encryptEnvelope(c, &Data{Type: "binary", Name: "scan"}, &envelope{
+		File: newFileInfo("scan.pdf", []byte("binary value")),
+	})
+	if err != nil {
+		t.Fatalf("encryptEnvelope() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		value        []byte
+		metadata     json.RawMessage
+		wantFileName string
+		wantErr      error
+	}{
+		{
+			name:         "ok",
+			value:        []byte("binary value"),
+			metadata:     metadata,
+			wantFileName: "scan.pdf",
+		},
+		{
+			name:         "corrupted",
+			value:        []byte("binary valuE"),
+			metadata:     metadata,
+			wantFileName: "scan.pdf",
+			wantErr:      errs.ErrDigestMismatch,
+		},
+		{
+			name:         "truncated",
+			value:        []byte("binary"),
+			metadata:     metadata,
+			wantFileName: "scan.pdf",
+			wantErr:      errs.ErrDigestMismatch,
+		},
+		{
+			name:         "extended",
+			value:        []byte("binary value and more"),
+			metadata:     metadata,
+			wantFileName: "scan.pdf",
+			wantErr:      errs.ErrDigestMismatch,
+		},
+		{
+			name:  "without_file_information",
+			value: []byte("binary value"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{Type: "binary", Name: "scan"}
+			r, err := openContent(c, d, tt.metadata, bytes.NewReader(tt.value), false)
+			if err != nil {
+				t.Fatalf("openContent() error = %v", err)
+			}
+			_, err = io.ReadAll(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("openContent() read error = %v, want %v", err, tt.wantErr)
+			}
+			if d.FileName != tt.wantFileName {
+				t.Errorf("openContent() file name = %s, want %s", d.FileName, tt.wantFileName)
+			}
+		})
+	}
+}
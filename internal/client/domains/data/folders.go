@@ -0,0 +1,281 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+)
+
+// Folders requests the server for all the user's folders
+// and writes their paths into the output.
+func (s *DataService) Folders(ctx context.Context) error {
+	folders, err := s.fetchFolders(ctx)
+	if err != nil {
+		return fmt.Errorf("Folders: get folders failed %w", err)
+	}
+
+	paths := make([]string, 0, len(folders))
+	for _, f := range folders {
+		paths = append(paths, f.Path)
+	}
+	text := strings.Join(paths, "\n")
+	if len(paths) == 0 {
+		text = "no folders"
+	}
+
+	s.rw.WriteResult(ctx, folders, text)
+
+	return nil
+}
+
+// Move reads information about data and the path of the folder from the input,
+// requests the server to move data object into the folder. The missing folders
+// of the path are created, the empty path moves data object to the root.
+func (s *DataService) Move(ctx context.Context) error {
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Move: couldn't read data type and name %w", err)
+	}
+
+	s.rw.Write(ctx, "Folder path, e.g. work/servers, or empty for root: ")
+	path, err := s.rw.Read(ctx)
+	if err != nil && !errors.Is(err, errs.ErrEmptyInput) {
+		return fmt.Errorf("Move: couldn't read folder path %w", err)
+	}
+
+	folderID, err := s.resolveFolder(ctx, path)
+	if err != nil {
+		return fmt.Errorf("Move: get folder failed %w", err)
+	}
+
+	err = s.sendJSON(ctx, http.MethodPut, "/api/user/data/"+d.Type+"/"+d.Name+"/folder",
+		map[string]int{"folder_id": folderID}, nil)
+	if err != nil {
+		return fmt.Errorf("Move: move data failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Tag reads information about data and the tag from the input,
+// requests the server to add the tag to data object.
+func (s *DataService) Tag(ctx context.Context) error {
+	d, tag, err := readDataTag(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Tag: couldn't read data and tag %w", err)
+	}
+
+	err = s.sendJSON(ctx, http.MethodPut, "/api/user/data/"+d.Type+"/"+d.Name+"/tags/"+url.PathEscape(tag), nil, nil)
+	if err != nil {
+		return fmt.Errorf("Tag: tag data failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Untag reads information about data and the tag from the input,
+// requests the server to remove the tag from data object.
+func (s *DataService) Untag(ctx context.Context) error {
+	d, tag, err := readDataTag(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Untag: couldn't read data and tag %w", err)
+	}
+
+	err = s.sendJSON(ctx, http.MethodDelete, "/api/user/data/"+d.Type+"/"+d.Name+"/tags/"+url.PathEscape(tag), nil, nil)
+	if err != nil {
+		return fmt.Errorf("Untag: untag data failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// fetchFolders requests the server for all the user's folders,
+// returns them with paths sorted by path.
+func (s *DataService) fetchFolders(ctx context.Context) ([]*Folder, error) {
+	var folders []*Folder
+	err := s.sendJSON(ctx, http.MethodGet, "/api/user/folders", nil, &folders)
+	if err != nil {
+		return nil, fmt.Errorf("fetchFolders: %w", err)
+	}
+
+	setFolderPaths(folders)
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].Path < folders[j].Path
+	})
+
+	return folders, nil
+}
+
+// resolveFolder returns the identifier of the folder with the path, the missing
+// folders of the path are created. Zero is returned for the empty path, which
+// means the root.
+func (s *DataService) resolveFolder(ctx context.Context, path string) (int, error) {
+	names := splitFolderPath(path)
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	folders, err := s.fetchFolders(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("resolveFolder: %w", err)
+	}
+
+	parentID := 0
+	for _, name := range names {
+		id := 0
+		for _, f := range folders {
+			if f.ParentID == parentID && f.Name == name {
+				id = f.ID
+				break
+			}
+		}
+		if id == 0 {
+			created := &Folder{Name: name, ParentID: parentID}
+			err = s.sendJSON(ctx, http.MethodPost, "/api/user/folders", created, created)
+			if err != nil {
+				return 0, fmt.Errorf("resolveFolder: create folder failed %w", err)
+			}
+			id = created.ID
+		}
+		parentID = id
+	}
+
+	return parentID, nil
+}
+
+// sendJSON sends the request of the logged in user with the value in JSON format
+// and decodes the response body into the result, if it is not nil.
+func (s *DataService) sendJSON(ctx context.Context, method string, path string, v any, result any) error {
+	var body bytes.Buffer
+	if v != nil {
+		err := json.NewEncoder(&body).Encode(v)
+		if err != nil {
+			return fmt.Errorf("sendJSON: marshal request failed %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Address+path, &body)
+	if err != nil {
+		return fmt.Errorf("sendJSON: new request failed %w", err)
+	}
+	if s.cfg.Cookie != nil {
+		req.AddCookie(s.cfg.Cookie)
+	}
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			return fmt.Errorf("sendJSON: %w", errs.ErrOffline)
+		}
+		return fmt.Errorf("sendJSON: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("sendJSON: %w", err)
+	}
+
+	if result != nil {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return fmt.Errorf("sendJSON: decode response failed %w", err)
+		}
+	}
+	return nil
+}
+
+// readDataTag reads from the input data type, data name and the tag.
+func readDataTag(ctx context.Context, rw rwmanager.RWService) (*Data, string, error) {
+	d, err := readDataTypeAndName(ctx, rw)
+	if err != nil {
+		return nil, "", fmt.Errorf("readDataTag: couldn't read data type and name %w", err)
+	}
+
+	rw.Write(ctx, "Tag: ")
+	tag, err := rw.Read(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("readDataTag: couldn't read tag %w", err)
+	}
+
+	return d, tag, nil
+}
+
+// setFolderPaths sets the paths of the folders by the names of their parents.
+func setFolderPaths(folders []*Folder) {
+	byID := make(map[int]*Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	for _, f := range folders {
+		names := []string{f.Name}
+		// The depth is limited by the number of folders in case of broken parents
+		for p := byID[f.ParentID]; p != nil && len(names) <= len(folders); p = byID[p.ParentID] {
+			names = append([]string{p.Name}, names...)
+		}
+		f.Path = strings.Join(names, "/")
+	}
+}
+
+// findFolder returns the identifier of the folder with the path,
+// zero is returned if there is no such folder.
+func findFolder(folders []*Folder, path string) int {
+	path = strings.Join(splitFolderPath(path), "/")
+	for _, f := range folders {
+		if f.Path == path {
+			return f.ID
+		}
+	}
+	return 0
+}
+
+// setItemFolders sets the paths of the folders which contain data objects.
+func setItemFolders(items []*Item, folders []*Folder) {
+	paths := make(map[int]string, len(folders))
+	for _, f := range folders {
+		paths[f.ID] = f.Path
+	}
+	for _, item := range items {
+		item.Folder = paths[item.FolderID]
+	}
+}
+
+// splitFolderPath returns the names of the folders of the path,
+// the empty names are skipped, e.g. for leading and trailing slashes.
+func splitFolderPath(path string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(path, "/") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// folderLabel returns the path of the folder for the table, the root is shown as dash.
+func folderLabel(path string) string {
+	if path == "" {
+		return "-"
+	}
+	return path
+}
@@ -2,7 +2,12 @@
 // interacting with data on the client side.
 package data
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
 
 // Data contains information about data object.
 type Data struct {
@@ -10,6 +15,52 @@ type Data struct {
 	Type     string `json:"type"`
 	Data     []byte `json:"data"`
 	Metadata []byte `json:"metadata"`
+	Version  int    `json:"version,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	// Content streams the binary value instead of Data, it is decrypted
+	// and verified while it is read and it must be closed. Size is the size
+	// of the streamed value, if it is known.
+	Content io.ReadCloser `json:"-"`
+	Size    int64         `json:"-"`
+}
+
+// Item contains information about data object stored on the server.
+type Item struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	FileName  string          `json:"file_name,omitempty"`
+	Size      int64           `json:"size,omitempty"`
+	FolderID  int             `json:"folder_id,omitempty"`
+	Folder    string          `json:"folder,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+}
+
+// Folder contains information about the folder for organizing data objects,
+// the path of the folder contains the names of its parents separated by slash.
+type Folder struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Path     string `json:"path"`
+}
+
+// Revision contains information about one of the versions of data object.
+type Revision struct {
+	Version   int       `json:"version"`
+	Device    string    `json:"device"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Conflict contains the local change of data object rejected by the server,
+// because data object was changed by another client. Op is the operation of
+// the local change, Version is the version it is based on.
+type Conflict struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Op      string `json:"op"`
+	Version int    `json:"version"`
 }
 
 // Service describes methods related with data object.
@@ -17,6 +68,18 @@ type Service interface {
 	CreateOrUpdate(ctx context.Context) error
 	GetValue(ctx context.Context) error
 	Delete(ctx context.Context) error
+	List(ctx context.Context) error
+	History(ctx context.Context) error
+	Restore(ctx context.Context) error
+	Sync(ctx context.Context) error
+	Folders(ctx context.Context) error
+	Move(ctx context.Context) error
+	Tag(ctx context.Context) error
+	Untag(ctx context.Context) error
+	Search(ctx context.Context) error
+	GetVersion(ctx context.Context) error
+	Conflicts(ctx context.Context) error
+	Resolve(ctx context.Context) error
 }
 
 // DataReader describes methods related with object,
@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+)
+
+// MaxQueryTerms is the maximum number of terms in the search query accepted by the server.
+const MaxQueryTerms = 16
+
+// searchQuery contains the terms of the search query: substrings and prefixes
+// of the name, data types and metadata pairs.
+type searchQuery struct {
+	names    []string
+	prefixes []string
+	types    []string
+	metadata map[string]string
+}
+
+// Search reads the search query from the input, requests the server for data
+// objects matching the name and type terms and writes them into the output
+// as a table. Metadata is encrypted, so metadata pairs are matched by the client
+// after decrypting. If the server is unavailable, the local replica is searched.
+func (s *DataService) Search(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("Search: %w", errs.ErrUnauthorized)
+	}
+
+	s.rw.Write(ctx, "Search query, e.g. git* type:credentials url=github.com: ")
+	q, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("Search: couldn't read search query %w", err)
+	}
+	query, err := parseSearchQuery(q)
+	if err != nil {
+		return fmt.Errorf("Search: %w", err)
+	}
+
+	var items []*Item
+	err = s.pushBeforeRead(ctx)
+	if err == nil {
+		items, err = s.fetchSearch(ctx, query)
+	}
+	if errors.Is(err, errs.ErrOffline) {
+		s.rw.Error(ctx, fmt.Errorf("%w, the local copy is used", errs.ErrOffline))
+		items, err = s.localItems(""), nil
+	}
+	if err != nil {
+		return fmt.Errorf("Search: search data failed %w", err)
+	}
+
+	found := make([]*Item, 0, len(items))
+	for _, item := range items {
+		err = decryptItem(s.cfg.Cipher, item, s.cfg.Legacy)
+		if err != nil {
+			return fmt.Errorf("Search: decrypt metadata failed %w", err)
+		}
+		if query.match(item) {
+			found = append(found, item)
+		}
+	}
+
+	s.rw.WriteResult(ctx, found, formatItemsTable(found))
+
+	return nil
+}
+
+// fetchSearch requests the server for data objects matching the name and type
+// terms of the query, all data objects are requested if there are no such terms.
+func (s *DataService) fetchSearch(ctx context.Context, query *searchQuery) ([]*Item, error) {
+	q := query.serverQuery()
+	if q == "" {
+		items, err := s.fetchItems(ctx, "", "", "")
+		if err != nil {
+			return nil, fmt.Errorf("fetchSearch: %w", err)
+		}
+		return items, nil
+	}
+
+	folders, err := s.fetchFolders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetchSearch: get folders failed %w", err)
+	}
+
+	var items []*Item
+	err = s.sendJSON(ctx, http.MethodGet, "/api/user/data/search?q="+url.QueryEscape(q), nil, &items)
+	if errors.Is(err, errs.ErrBadRequest) {
+		return nil, fmt.Errorf("fetchSearch: query rejected by the server %w", errs.ErrInvalidQuery)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetchSearch: %w", err)
+	}
+	setItemFolders(items, folders)
+
+	return items, nil
+}
+
+// parseSearchQuery parses the search query of the terms separated by spaces:
+// 'type:{dataType}' is data type, 'key=value' is metadata pair, the term
+// ending with '*' is the prefix of the name and the other terms are
+// the substrings of the name.
+func parseSearchQuery(q string) (*searchQuery, error) {
+	terms := strings.Fields(q)
+	if len(terms) == 0 || len(terms) > MaxQueryTerms {
+		return nil, fmt.Errorf("parseSearchQuery: number of terms %d %w", len(terms), errs.ErrInvalidQuery)
+	}
+
+	query := &searchQuery{
+		metadata: make(map[string]string),
+	}
+	for _, term := range terms {
+		if t, ok := strings.CutPrefix(term, "type:"); ok {
+			t = strings.ToLower(t)
+			if !utils.IsValidDataType(t) {
+				return nil, fmt.Errorf("parseSearchQuery: %w", errs.ErrInvalidDataType)
+			}
+			query.types = append(query.types, t)
+			continue
+		}
+		if key, value, ok := strings.Cut(term, "="); ok {
+			if key == "" {
+				return nil, fmt.Errorf("parseSearchQuery: empty metadata key %w", errs.ErrInvalidQuery)
+			}
+			query.metadata[key] = value
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(term, "*"); ok {
+			if prefix == "" {
+				return nil, fmt.Errorf("parseSearchQuery: empty name prefix %w", errs.ErrInvalidQuery)
+			}
+			query.prefixes = append(query.prefixes, strings.ToLower(prefix))
+			continue
+		}
+		query.names = append(query.names, strings.ToLower(term))
+	}
+
+	return query, nil
+}
+
+// serverQuery returns the name and type terms of the query for the server,
+// metadata pairs are not sent, since the server stores encrypted metadata.
+func (q *searchQuery) serverQuery() string {
+	terms := make([]string, 0, len(q.names)+len(q.prefixes)+len(q.types))
+	terms = append(terms, q.names...)
+	for _, p := range q.prefixes {
+		terms = append(terms, p+"*")
+	}
+	for _, t := range q.types {
+		terms = append(terms, "type:"+t)
+	}
+	return strings.Join(terms, " ")
+}
+
+// match checks whether data object with decrypted metadata matches all the terms of the query.
+func (q *searchQuery) match(item *Item) bool {
+	if len(q.types) > 0 && !slices.Contains(q.types, item.Type) {
+		return false
+	}
+	name := strings.ToLower(item.Name)
+	for _, n := range q.names {
+		if !strings.Contains(name, n) {
+			return false
+		}
+	}
+	for _, p := range q.prefixes {
+		if !strings.HasPrefix(name, p) {
+			return false
+		}
+	}
+	if len(q.metadata) == 0 {
+		return true
+	}
+	metadata := make(map[string]string)
+	if json.Unmarshal(item.Metadata, &metadata) != nil {
+		return false
+	}
+	for k, v := range q.metadata {
+		if value, ok := metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+)
+
+func Test_parseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    *searchQuery
+		wantErr error
+	}{
+		{
+			name: "all_terms",
+			arg:  "Git* hub type:Credentials url=github.com",
+			want: &searchQuery{
+				names:    []string{"hub"},
+				prefixes: []string{"git"},
+				types:    []string{"credentials"},
+				metadata: map[string]string{"url": "github.com"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "empty",
+			arg:     " ",
+			want:    nil,
+			wantErr: errs.ErrInvalidQuery,
+		},
+		{
+			name:    "too_many_terms",
+			arg:     strings.Repeat("a ", MaxQueryTerms+1),
+			want:    nil,
+			wantErr: errs.ErrInvalidQuery,
+		},
+		{
+			name:    "invalid_type",
+			arg:     "type:photo",
+			want:    nil,
+			wantErr: errs.ErrInvalidDataType,
+		},
+		{
+			name:    "empty_metadata_key",
+			arg:     "=github.com",
+			want:    nil,
+			wantErr: errs.ErrInvalidQuery,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSearchQuery(tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseSearchQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSearchQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_searchQuery_match(t *testing.T) {
+	item := &Item{
+		Name:     "GitHub",
+		Type:     "credentials",
+		Metadata: []byte(`{"url":"github.com","app":"git"}`),
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    bool
+		wantSrv string
+	}{
+		{
+			name:    "name_and_metadata",
+			query:   "git* hub url=github.com",
+			want:    true,
+			wantSrv: "hub git*",
+		},
+		{
+			name:    "other_metadata_value",
+			query:   "url=gitlab.com",
+			want:    false,
+			wantSrv: "",
+		},
+		{
+			name:    "other_type",
+			query:   "type:card",
+			want:    false,
+			wantSrv: "type:card",
+		},
+		{
+			name:    "not_prefix",
+			query:   "hub*",
+			want:    false,
+			wantSrv: "hub*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery() error = %v", err)
+			}
+			if got := q.match(item); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+			if got := q.serverQuery(); got != tt.wantSrv {
+				t.Errorf("serverQuery() = %q, want %q", got, tt.wantSrv)
+			}
+		})
+	}
+}
@@ -1,181 +1,528 @@
 package data
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/pavlegich/gophkeeper/internal/client/domains/data/readers"
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
 	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 	"github.com/pavlegich/gophkeeper/internal/client/utils"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
 )
 
 // DataService contains objects for data service.
 type DataService struct {
-	rw  rwmanager.RWService
-	cfg *config.ClientConfig
+	rw      rwmanager.RWService
+	cfg     *config.ClientConfig
+	replica *replica.Replica
 }
 
 // NewDataService creates and returns new data service.
-func NewDataService(ctx context.Context, rw rwmanager.RWService, cfg *config.ClientConfig) *DataService {
+func NewDataService(ctx context.Context, rw rwmanager.RWService, cfg *config.ClientConfig,
+	rep *replica.Replica) *DataService {
 	return &DataService{
-		rw:  rw,
-		cfg: cfg,
+		rw:      rw,
+		cfg:     cfg,
+		replica: rep,
 	}
 }
 
 // CreateOrUpdate reads information about data from the input,
 // sends request to the server with requested action.
 func (s *DataService) CreateOrUpdate(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("CreateOrUpdate: %w", errs.ErrUnauthorized)
+	}
+
 	d, err := readDataTypeAndName(ctx, s.rw)
 	if err != nil {
 		return fmt.Errorf("CreateOrUpdate: couldn't read data type and name %w", err)
 	}
 
-	// Read data and put it into multipart
-	var buf bytes.Buffer
-	multipartWriter := multipart.NewWriter(&buf)
-	defer multipartWriter.Close()
-	err = createMultipartData(ctx, s.rw, multipartWriter, d)
+	// Read and encrypt data
+	e, err := readData(ctx, s.rw, s.cfg.Cipher, s.replica, d)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: create multipart data failed %w", err)
+		return fmt.Errorf("CreateOrUpdate: read data failed %w", err)
 	}
 
-	// Prepare request
-	target := s.cfg.Address + "/api/user/data/" + d.Type + "/" + d.Name
-
 	act, err := utils.GetActionFromContext(ctx)
 	if err != nil {
 		return fmt.Errorf("CreateOrUpdate: get action from context failed %w", err)
 	}
 
-	var method string
-	switch act {
-	case "create":
-		method = http.MethodPost
-	case "update":
-		method = http.MethodPut
+	op := replica.OpCreate
+	if act == "update" {
+		op = replica.OpUpdate
+		e.Version = s.currentVersion(ctx, d)
+	}
+
+	err = s.commit(ctx, &replica.Change{
+		Op:    op,
+		Entry: *e,
+	})
+	if err != nil {
+		return fmt.Errorf("CreateOrUpdate: %s data failed %w", op, err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// GetValue sends request to the server to get value that was requested by the client.
+// If the server is unavailable, the value is taken from the local replica.
+func (s *DataService) GetValue(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("GetValue: %w", errs.ErrUnauthorized)
+	}
+
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("GetValue: couldn't read data type and name %w", err)
+	}
+
+	var value *Data
+	err = s.pushBeforeRead(ctx)
+	if err == nil {
+		value, err = s.fetchValue(ctx, d, 0)
+	}
+	if errors.Is(err, errs.ErrOffline) {
+		s.rw.Error(ctx, fmt.Errorf("%w, the local copy is used", errs.ErrOffline))
+		value, err = s.localValue(d)
 	}
+	if err != nil {
+		return fmt.Errorf("GetValue: get data value failed %w", err)
+	}
+
+	err = s.writeValue(ctx, value)
+	if err != nil {
+		return fmt.Errorf("GetValue: %w", err)
+	}
+
+	return nil
+}
+
+// GetVersion reads information about data and its version from the input,
+// requests the server for the value of this version and writes it like GetValue.
+func (s *DataService) GetVersion(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("GetVersion: %w", errs.ErrUnauthorized)
+	}
+
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("GetVersion: couldn't read data type and name %w", err)
+	}
+	version, err := readVersion(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("GetVersion: %w", err)
+	}
+
+	value, err := s.fetchValue(ctx, d, version)
+	if err != nil {
+		return fmt.Errorf("GetVersion: get data version failed %w", err)
+	}
+
+	err = s.writeValue(ctx, value)
+	if err != nil {
+		return fmt.Errorf("GetVersion: %w", err)
+	}
+
+	return nil
+}
+
+// writeValue writes the decrypted value into the output, the binary value
+// is saved into the file, by default with its original name. The streamed
+// value is written into the file while it is received.
+func (s *DataService) writeValue(ctx context.Context, value *Data) error {
+	if value.Content != nil {
+		defer value.Content.Close()
+	}
+	if value.Type == "binary" {
+		fileName := utils.CleanFileName(value.FileName)
+		if fileName == "" {
+			fileName = utils.CleanFileName(value.Name)
+		}
+		s.rw.Write(ctx, "Type path for save file (empty for "+fileName+"): ")
+		path, err := s.rw.Read(ctx)
+		if err != nil && !errors.Is(err, errs.ErrEmptyInput) {
+			return fmt.Errorf("writeValue: read file path failed %w", err)
+		}
+		if path == "" {
+			path = fileName
+		}
+		if path == "" {
+			return fmt.Errorf("writeValue: %w", errs.ErrInvalidFilePath)
+		}
+		var content io.Reader = bytes.NewReader(value.Data)
+		if value.Content != nil {
+			content = value.Content
+		}
+		err = saveFile(path, content)
+		if err != nil {
+			return fmt.Errorf("writeValue: write to file failed %w", err)
+		}
+
+		s.rw.WriteResult(ctx, map[string]string{"path": path}, utils.Success)
+
+		return nil
+	}
+
+	if field := utils.GetFieldFromContext(ctx); field != "" {
+		var err error
+		value.Data, err = selectField(value.Data, field)
+		if err != nil {
+			return fmt.Errorf("writeValue: select field failed %w", err)
+		}
+	}
+
+	s.rw.WriteResult(ctx, valueResult(value.Data), string(value.Data))
+
+	return nil
+}
+
+// Delete reads information about data from the input,
+// sends request to the server to delete requested data.
+func (s *DataService) Delete(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("Delete: %w", errs.ErrUnauthorized)
+	}
+
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Delete: couldn't read data type and name %w", err)
+	}
+
+	err = s.commit(ctx, &replica.Change{
+		Op: replica.OpDelete,
+		Entry: replica.Entry{
+			Type:    d.Type,
+			Name:    d.Name,
+			Version: s.currentVersion(ctx, d),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Delete: delete data failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Sync sends the queued local changes to the server and updates
+// the local replica with the changes made by other clients.
+func (s *DataService) Sync(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("Sync: %w", errs.ErrUnauthorized)
+	}
+
+	// Receive the changes even if some local changes were rejected because of conflict
+	pushErr := s.push(ctx)
+	if errors.Is(pushErr, errs.ErrOffline) {
+		return fmt.Errorf("Sync: push local changes failed %w", pushErr)
+	}
+
+	applied, err := s.replica.Pull(ctx)
+	if pushErr != nil {
+		return fmt.Errorf("Sync: push local changes failed %w", pushErr)
+	}
+	if err != nil {
+		return fmt.Errorf("Sync: pull changes failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, map[string]int{"received": applied}, fmt.Sprintf("%d changes received", applied))
+
+	return nil
+}
+
+// List requests the server for information about all the stored data,
+// optionally filtered by type, folder and tag, and writes it into the output
+// as a table. If the server is unavailable, the information is taken from
+// the local replica, which doesn't keep folders and tags.
+func (s *DataService) List(ctx context.Context) error {
+	if s.cfg.Cipher == nil {
+		return fmt.Errorf("List: %w", errs.ErrUnauthorized)
+	}
+
+	s.rw.Write(ctx, "Data type (credentials/card/text/binary), or empty for all: ")
+	dType, err := s.rw.Read(ctx)
+	if err != nil && !errors.Is(err, errs.ErrEmptyInput) {
+		return fmt.Errorf("List: couldn't read data type %w", err)
+	}
+	dType = strings.ToLower(dType)
+	if dType != "" && !utils.IsValidDataType(dType) {
+		return fmt.Errorf("List: %w", errs.ErrInvalidDataType)
+	}
+
+	s.rw.Write(ctx, "Folder path, or empty for all: ")
+	folder, err := s.rw.Read(ctx)
+	if err != nil && !errors.Is(err, errs.ErrEmptyInput) {
+		return fmt.Errorf("List: couldn't read folder path %w", err)
+	}
+
+	s.rw.Write(ctx, "Tag, or empty for all: ")
+	tag, err := s.rw.Read(ctx)
+	if err != nil && !errors.Is(err, errs.ErrEmptyInput) {
+		return fmt.Errorf("List: couldn't read tag %w", err)
+	}
+
+	var items []*Item
+	err = s.pushBeforeRead(ctx)
+	if err == nil {
+		items, err = s.fetchItems(ctx, dType, folder, tag)
+	}
+	if errors.Is(err, errs.ErrOffline) && folder == "" && tag == "" {
+		s.rw.Error(ctx, fmt.Errorf("%w, the local copy is used", errs.ErrOffline))
+		items, err = s.localItems(dType), nil
+	}
+	if err != nil {
+		return fmt.Errorf("List: get data list failed %w", err)
+	}
+
+	for _, item := range items {
+		err = decryptItem(s.cfg.Cipher, item, s.cfg.Legacy)
+		if err != nil {
+			return fmt.Errorf("List: decrypt metadata failed %w", err)
+		}
+	}
+
+	s.rw.WriteResult(ctx, items, formatItemsTable(items))
+
+	return nil
+}
+
+// History reads information about data from the input, requests the server
+// for all the versions of requested data and writes them into the output as a table.
+func (s *DataService) History(ctx context.Context) error {
+	d, err := readDataTypeAndName(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("History: couldn't read data type and name %w", err)
+	}
+
+	// Prepare request
+	target := s.cfg.Address + "/api/user/data/" + d.Type + "/" + d.Name + "/versions"
 
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, method, target, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: new request failed %w", err)
+		return fmt.Errorf("History: new request failed %w", err)
 	}
 
-	// Add values into the header
 	if s.cfg.Cookie != nil {
 		req.AddCookie(s.cfg.Cookie)
 	}
-	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
 
 	// Send request
 	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: send request failed %w", err)
+		return fmt.Errorf("History: send request failed %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check response
-	err = utils.CheckStatusCode(resp.StatusCode)
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("History: get data versions failed %w", err)
+	}
+
+	var revisions []*Revision
+	err = json.NewDecoder(resp.Body).Decode(&revisions)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: create data failed %w", err)
+		return fmt.Errorf("History: decode data versions failed %w", err)
 	}
 
-	s.rw.Writeln(ctx, utils.Success)
+	s.rw.WriteResult(ctx, revisions, formatRevisionsTable(revisions))
 
 	return nil
 }
 
-// GetValue sends request to the server to get value that was requested by the client.
-func (s *DataService) GetValue(ctx context.Context) error {
+// Restore reads information about data and its version from the input,
+// requests the server to roll the data back to this version.
+func (s *DataService) Restore(ctx context.Context) error {
 	d, err := readDataTypeAndName(ctx, s.rw)
 	if err != nil {
-		return fmt.Errorf("GetValue: couldn't read data type and name %w", err)
+		return fmt.Errorf("Restore: couldn't read data type and name %w", err)
+	}
+
+	version, err := readVersion(ctx, s.rw)
+	if err != nil {
+		return fmt.Errorf("Restore: %w", err)
 	}
+	// The value changed by other device since the last synchronization is not overwritten
+	current := s.currentVersion(ctx, d)
 
 	// Prepare request
-	target := s.cfg.Address + "/api/user/data/" + d.Type + "/" + d.Name
+	target := s.cfg.Address + "/api/user/data/" + d.Type + "/" + d.Name +
+		"/versions/" + strconv.Itoa(version) + "/restore"
 
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
 	if err != nil {
-		return fmt.Errorf("GetValue: new request failed %w", err)
+		return fmt.Errorf("Restore: new request failed %w", err)
 	}
 
 	if s.cfg.Cookie != nil {
 		req.AddCookie(s.cfg.Cookie)
 	}
+	req.Header.Set(utils.DeviceHeader, s.cfg.Device)
+	if current > 0 {
+		req.Header.Set("If-Match", utils.ETag(current))
+	}
 
 	// Send request
 	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("GetValue: send request failed %w", err)
+		return fmt.Errorf("Restore: send request failed %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check response
-	err = utils.CheckStatusCode(resp.StatusCode)
+	err = utils.CheckStatusCode(resp)
 	if err != nil {
-		return fmt.Errorf("GetValue: create data failed %w", err)
+		return fmt.Errorf("Restore: restore data version failed %w", err)
 	}
 
-	if d.Type == "binary" {
-		s.rw.Write(ctx, "Type path for save file: ")
-		path, err := s.rw.Read(ctx)
-		if err != nil {
-			return fmt.Errorf("GetValue: read file path failed %w", err)
-		}
-		err = utils.SaveFromMultipartToFile(ctx, resp, path)
-		if err != nil {
-			return fmt.Errorf("GetValue: save file failed %w", err)
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// fetchItems requests the server for information about all the stored data,
+// optionally filtered by type, folder path and tag, and sets the folder paths
+// of the items.
+func (s *DataService) fetchItems(ctx context.Context, dType string, folder string, tag string) ([]*Item, error) {
+	folders, err := s.fetchFolders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetchItems: get folders failed %w", err)
+	}
+
+	// Prepare request
+	query := url.Values{}
+	if dType != "" {
+		query.Set("type", dType)
+	}
+	if folder != "" {
+		folderID := findFolder(folders, folder)
+		if folderID == 0 {
+			return nil, fmt.Errorf("fetchItems: folder %s %w", folder, errs.ErrNotExist)
 		}
+		query.Set("folder", strconv.Itoa(folderID))
+	}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	target := s.cfg.Address + "/api/user/data"
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
 
-		s.rw.Writeln(ctx, utils.Success)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
 
-		return nil
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetchItems: new request failed %w", err)
 	}
 
-	var buf bytes.Buffer
+	if s.cfg.Cookie != nil {
+		req.AddCookie(s.cfg.Cookie)
+	}
 
-	_, err = buf.ReadFrom(resp.Body)
+	// Send request
+	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("GetValue: read data from body failed %w", err)
+		if utils.IsConnectionError(err) {
+			return nil, fmt.Errorf("fetchItems: %w", errs.ErrOffline)
+		}
+		return nil, fmt.Errorf("fetchItems: send request failed %w", err)
 	}
-	s.rw.Writeln(ctx, buf.String())
+	defer resp.Body.Close()
 
-	return nil
-}
+	// Check response
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("fetchItems: get data list failed %w", err)
+	}
 
-// Delete reads information about data from the input,
-// sends request to the server to delete requested data.
-func (s *DataService) Delete(ctx context.Context) error {
-	d, err := readDataTypeAndName(ctx, s.rw)
+	var items []*Item
+	err = json.NewDecoder(resp.Body).Decode(&items)
 	if err != nil {
-		return fmt.Errorf("Delete: couldn't read data type and name %w", err)
+		return nil, fmt.Errorf("fetchItems: decode data list failed %w", err)
 	}
 
+	setItemFolders(items, folders)
+
+	return items, nil
+}
+
+// localItems returns information about the data stored in the local replica,
+// optionally filtered by type.
+func (s *DataService) localItems(dType string) []*Item {
+	entries := s.replica.List(dType)
+	items := make([]*Item, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, &Item{
+			Name:      e.Name,
+			Type:      e.Type,
+			Metadata:  e.Metadata,
+			CreatedAt: e.CreatedAt,
+			FileName:  e.FileName,
+		})
+	}
+	return items
+}
+
+// fetchValue requests the server for the value of data object, the current one
+// or of the version, if it is not zero. Returns the decrypted value and its version.
+// The binary value is not read, it is decrypted and verified as a stream while
+// the content of the result is read, the content must be closed. The current
+// binary value missing in the local replica is kept in it, when it is read
+// to the end, so it is available without the server.
+func (s *DataService) fetchValue(ctx context.Context, d *Data, version int) (*Data, error) {
 	// Prepare request
 	target := s.cfg.Address + "/api/user/data/" + d.Type + "/" + d.Name
+	if version != 0 {
+		target += "/versions/" + strconv.Itoa(version)
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
+	timeout := 15 * time.Second
+	if d.Type == "binary" {
+		timeout = utils.TransferTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	streamed := false
+	defer func() {
+		if !streamed {
+			cancel()
+		}
+	}()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return fmt.Errorf("Delete: new request failed %w", err)
+		return nil, fmt.Errorf("fetchValue: new request failed %w", err)
 	}
 
 	if s.cfg.Cookie != nil {
@@ -185,17 +532,218 @@ func (s *DataService) Delete(ctx context.Context) error {
 	// Send request
 	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: send request failed %w", err)
+		if utils.IsConnectionError(err) {
+			return nil, fmt.Errorf("fetchValue: %w", errs.ErrOffline)
+		}
+		return nil, fmt.Errorf("fetchValue: send request failed %w", err)
 	}
-	defer resp.Body.Close()
+	defer func() {
+		if !streamed {
+			resp.Body.Close()
+		}
+	}()
 
 	// Check response
-	err = utils.CheckStatusCode(resp.StatusCode)
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("fetchValue: get data failed %w", err)
+	}
+
+	result := &Data{
+		Name:    d.Name,
+		Type:    d.Type,
+		Version: utils.GetVersionFromETag(resp.Header.Get("ETag")),
+	}
+
+	if d.Type == "binary" && !strings.HasPrefix(resp.Header.Get("Content-Type"), "multipart/") {
+		body, err := utils.NewDigestReader(resp)
+		if err != nil {
+			return nil, fmt.Errorf("fetchValue: %w", err)
+		}
+		metadata, err := utils.GetMetadata(resp)
+		if err != nil {
+			return nil, fmt.Errorf("fetchValue: %w", err)
+		}
+		var spool *spoolWriter
+		if version == 0 {
+			spool = s.newSpool(result)
+		}
+		if spool != nil {
+			body = io.TeeReader(body, spool)
+		}
+		content, err := decryptContent(s.cfg.Cipher, d, body, s.cfg.Legacy)
+		if err != nil {
+			spool.discard()
+			return nil, fmt.Errorf("fetchValue: decrypt data failed %w", err)
+		}
+		result.FileName = utils.GetFileName(resp)
+		content, err = openContent(s.cfg.Cipher, result, metadata, content, s.cfg.Legacy)
+		if err != nil {
+			spool.discard()
+			return nil, fmt.Errorf("fetchValue: %w", err)
+		}
+		if spool != nil {
+			content = &completeReader{Reader: content, complete: &spool.complete}
+		}
+		result.Content = &contentCloser{
+			Reader: content,
+			close: func() error {
+				defer cancel()
+				s.keepSpool(spool, result)
+				return resp.Body.Close()
+			},
+		}
+		streamed = true
+		return result, nil
+	}
+
+	var encrypted []byte
+	var metadata json.RawMessage
+	if d.Type == "binary" {
+		// The servers of previous versions send binary data as multipart file field
+		encrypted, err = utils.GetFileFromMultipart(ctx, resp)
+		if err != nil {
+			return nil, fmt.Errorf("fetchValue: get file failed %w", err)
+		}
+	} else {
+		encrypted, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("fetchValue: read data from body failed %w", err)
+		}
+		err = utils.VerifyDigest(resp, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("fetchValue: %w", err)
+		}
+	}
+
+	result.Data, err = decryptValue(s.cfg.Cipher, d, encrypted, s.cfg.Legacy)
 	if err != nil {
-		return fmt.Errorf("CreateOrUpdate: create data failed %w", err)
+		return nil, fmt.Errorf("fetchValue: decrypt data failed %w", err)
+	}
+	err = openFile(s.cfg.Cipher, result, metadata, s.cfg.Legacy)
+	if err != nil {
+		return nil, fmt.Errorf("fetchValue: %w", err)
 	}
 
-	s.rw.Writeln(ctx, utils.Success)
+	return result, nil
+}
+
+// localValue returns the decrypted value of data object from the local replica.
+func (s *DataService) localValue(d *Data) (*Data, error) {
+	e, ok := s.replica.Get(d.Type, d.Name)
+	if !ok {
+		return nil, fmt.Errorf("localValue: %w", errs.ErrNotExist)
+	}
+	if e.Blob != nil {
+		value, err := s.openEntry(e)
+		if err != nil {
+			return nil, fmt.Errorf("localValue: %w", err)
+		}
+		return value, nil
+	}
+	if e.Data == nil {
+		// The binary value is not received from the server yet
+		return nil, fmt.Errorf("localValue: %w", errs.ErrOffline)
+	}
+	value, err := decryptEntry(s.cfg.Cipher, e, s.cfg.Legacy)
+	if err != nil {
+		return nil, fmt.Errorf("localValue: decrypt data failed %w", err)
+	}
+	return value, nil
+}
+
+// openEntry returns data object from the local replica with the content,
+// which streams the binary value decrypted from the file of the replica.
+// The content must be closed.
+func (s *DataService) openEntry(e *replica.Entry) (*Data, error) {
+	f, err := s.replica.OpenBlob(e)
+	if err != nil {
+		return nil, fmt.Errorf("openEntry: %w", err)
+	}
+	d := &Data{
+		Name:     e.Name,
+		Type:     e.Type,
+		Version:  e.Version,
+		FileName: e.FileName,
+	}
+	content, err := decryptContent(s.cfg.Cipher, d, f, s.cfg.Legacy)
+	if err == nil {
+		content, err = openContent(s.cfg.Cipher, d, e.Metadata, content, s.cfg.Legacy)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("openEntry: %w", err)
+	}
+	d.Content = &contentCloser{Reader: content, close: f.Close}
+	return d, nil
+}
+
+// currentVersion returns the version of data object known by the client,
+// zero is returned if data object is unknown.
+func (s *DataService) currentVersion(ctx context.Context, d *Data) int {
+	e, ok := s.replica.Get(d.Type, d.Name)
+	if !ok {
+		// The replica could be not synchronized yet
+		_, err := s.replica.Pull(ctx)
+		if err != nil {
+			return 0
+		}
+		e, ok = s.replica.Get(d.Type, d.Name)
+		if !ok {
+			return 0
+		}
+	}
+	return e.Version
+}
+
+// push sends the queued local changes to the server. The changes rejected because
+// of conflict are kept in the replica until the user resolves them.
+func (s *DataService) push(ctx context.Context) error {
+	_, err := s.replica.Push(ctx)
+	if err != nil {
+		return fmt.Errorf("push: %w", err)
+	}
+	return nil
+}
+
+// pushBeforeRead sends the queued changes before reading data from the server.
+// Rejected changes are reported, but they don't prevent reading, only ErrOffline
+// is returned, so the data is read from the local replica.
+func (s *DataService) pushBeforeRead(ctx context.Context) error {
+	err := s.push(ctx)
+	if errors.Is(err, errs.ErrOffline) {
+		return fmt.Errorf("pushBeforeRead: %w", err)
+	}
+	if err != nil {
+		s.rw.Error(ctx, utils.GetKnownErr(err))
+	}
+	return nil
+}
+
+// commit queues the local change, sends the queued changes to the server
+// and updates the local replica. If the server is unavailable, the change
+// stays in the queue and is sent later.
+func (s *DataService) commit(ctx context.Context, ch *replica.Change) error {
+	err := s.replica.Queue(ch)
+	if err != nil {
+		return fmt.Errorf("commit: queue change failed %w", err)
+	}
+
+	pushErr := s.push(ctx)
+	if errors.Is(pushErr, errs.ErrOffline) {
+		s.rw.Error(ctx, fmt.Errorf("%w, the change is saved locally and will be sent later", errs.ErrOffline))
+		return nil
+	}
+
+	// Receive the changes even if the local change was rejected
+	// because of conflict, so the client knows the current version
+	_, err = s.replica.Pull(ctx)
+	if pushErr != nil {
+		return fmt.Errorf("commit: push changes failed %w", pushErr)
+	}
+	if err != nil {
+		return fmt.Errorf("commit: pull changes failed %w", err)
+	}
 
 	return nil
 }
@@ -226,10 +774,27 @@ func readDataTypeAndName(ctx context.Context, rw rwmanager.RWService) (*Data, er
 	return d, nil
 }
 
-// createMultipartData put data parts into multipart fields.
-func createMultipartData(ctx context.Context, rw rwmanager.RWService, mpwriter *multipart.Writer, d *Data) error {
+// readVersion reads from the input and returns the version of data object.
+func readVersion(ctx context.Context, rw rwmanager.RWService) (int, error) {
+	rw.Write(ctx, "Version: ")
+	versionString, err := rw.Read(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("readVersion: couldn't read version %w", err)
+	}
+	version, err := strconv.Atoi(versionString)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("readVersion: %w", errs.ErrInvalidVersion)
+	}
+	return version, nil
+}
+
+// readData reads data and metadata from the input into the data object,
+// returns them encrypted in the form stored by the server. The binary value
+// is not read into memory, the file is encrypted as a stream into the file
+// of the replica, which the value is sent from.
+func readData(ctx context.Context, rw rwmanager.RWService, c *encryption.Cipher, rep *replica.Replica,
+	d *Data) (*replica.Entry, error) {
 	// Data
-	var part []byte
 	var err error
 	var dataReader DataReader
 	switch d.Type {
@@ -240,52 +805,380 @@ func createMultipartData(ctx context.Context, rw rwmanager.RWService, mpwriter *
 	case "text":
 		dataReader = readers.NewTextReader(ctx, rw)
 	}
+	content, fromFile := utils.GetContentFromContext(ctx)
 
-	part, err = dataReader.Read(ctx)
-	if err != nil {
-		return fmt.Errorf("createMultipartData: couldn't read %s %w", d.Type, err)
+	e := &replica.Entry{
+		Name:      d.Name,
+		Type:      d.Type,
+		CreatedAt: time.Now(),
 	}
+	env := &envelope{}
 
-	// Put data into the dataPart
-	var dataPart io.Writer
+	var blob *replica.BlobWriter
 	if d.Type == "binary" {
 		rw.Write(ctx, "Type absolute path to file: ")
 		path, err := rw.Read(ctx)
 		if err != nil {
-			return fmt.Errorf("createMultipartData: couldn't read file path %w", err)
+			return nil, fmt.Errorf("readData: couldn't read file path %w", err)
 		}
-		dataPart, err = mpwriter.CreateFormFile("file", path)
+		blob, err = rep.NewBlobWriter()
 		if err != nil {
-			return fmt.Errorf("createMultipartData: couldn't create form file %w", err)
+			return nil, fmt.Errorf("readData: %w", err)
 		}
-		file, err := os.Open(path)
+		defer blob.Close()
+		env.File, err = encryptFile(c, d, path, blob)
 		if err != nil {
-			return fmt.Errorf("createMultipartData: %w", errs.ErrInvalidFilePath)
+			return nil, fmt.Errorf("readData: %w", err)
 		}
-		defer file.Close()
-		_, err = io.Copy(dataPart, file)
+		d.FileName = env.File.Name
+	} else if d.Type == "text" && fromFile {
+		// The text from the file is kept verbatim with its line breaks
+		d.Data = content
+	} else {
+		d.Data, err = dataReader.Read(ctx)
 		if err != nil {
-			return fmt.Errorf("createMultipartData: copy file failed %w", err)
+			return nil, fmt.Errorf("readData: couldn't read %s %w", d.Type, err)
 		}
-	} else {
-		dataPart, err = mpwriter.CreateFormField("data")
+	}
+
+	if d.Type != "binary" {
+		e.Data, err = encryptValue(c, d)
 		if err != nil {
-			return fmt.Errorf("createMultipartData: create multipart data form failed %w", err)
+			return nil, fmt.Errorf("readData: encrypt data failed %w", err)
 		}
-		dataPart.Write(part)
 	}
 
 	// Metadata
-	metaPart, err := mpwriter.CreateFormField("metadata")
+	metaReader := readers.NewMetadataReader(ctx, rw)
+	d.Metadata, err = metaReader.Read(ctx)
 	if err != nil {
-		return fmt.Errorf("createMultipartData: create multipart metadata form failed %w", err)
+		return nil, fmt.Errorf("readData: read metadata failed %w", err)
 	}
-	metaReader := readers.NewMetadataReader(ctx, rw)
-	metaBytes, err := metaReader.Read(ctx)
+	env.Metadata = d.Metadata
+	e.Metadata, err = encryptEnvelope(c, d, env)
 	if err != nil {
-		return fmt.Errorf("createMultipartData: read metadata failed %w", err)
+		return nil, fmt.Errorf("readData: %w", err)
 	}
-	metaPart.Write(metaBytes)
 
+	if blob != nil {
+		e.Blob, err = blob.Commit()
+		if err != nil {
+			return nil, fmt.Errorf("readData: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+// selectField returns the value of the requested field of data
+// stored in JSON format, e.g. password of credentials.
+func selectField(value []byte, field string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(value, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("selectField: %w", errs.ErrInvalidField)
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("selectField: %w", errs.ErrInvalidField)
+	}
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return []byte(str), nil
+	}
+	return raw, nil
+}
+
+// valueResult returns the value of data for JSON output: JSON objects,
+// e.g. credentials and cards, are kept as is, other values are returned as strings.
+func valueResult(value []byte) any {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		return json.RawMessage(trimmed)
+	}
+	return string(value)
+}
+
+// additionalData returns additional data for authenticating the encrypted
+// part of the data object, it binds the encrypted part to the data object.
+func additionalData(d *Data, part string) []byte {
+	return []byte(d.Type + "/" + d.Name + "/" + part)
+}
+
+// decryptValue decrypts the value of data object. The value saved before
+// the client-side encryption has no format version byte, it is returned as is
+// only if legacy is true, since the plain value can't be told from the encrypted one.
+func decryptValue(c *encryption.Cipher, d *Data, value []byte, legacy bool) ([]byte, error) {
+	if legacy && !encryption.IsEncrypted(value) {
+		return value, nil
+	}
+	plain, err := c.Decrypt(value, additionalData(d, "data"))
+	if err != nil {
+		return nil, fmt.Errorf("decryptValue: %w", errs.ErrDecryptFailed)
+	}
+	return plain, nil
+}
+
+// encryptValue encrypts the value of data object, the binary value
+// is encrypted by encryptFile as a stream.
+func encryptValue(c *encryption.Cipher, d *Data) ([]byte, error) {
+	encrypted, err := c.Encrypt(d.Data, additionalData(d, "data"))
+	if err != nil {
+		return nil, fmt.Errorf("encryptValue: %w", err)
+	}
+	return encrypted, nil
+}
+
+// decryptContent returns the reader of the value of data object decrypted
+// from the encrypted stream. The value saved before the client-side encryption
+// has no format version byte, it is returned as is only if legacy is true.
+func decryptContent(c *encryption.Cipher, d *Data, encrypted io.Reader, legacy bool) (io.Reader, error) {
+	r := bufio.NewReader(encrypted)
+	head, err := r.Peek(1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decryptContent: read data failed %w", err)
+	}
+	if legacy && !encryption.IsEncrypted(head) {
+		return r, nil
+	}
+	plain, err := c.NewDecryptReader(r, additionalData(d, "data"))
+	if err != nil {
+		return nil, fmt.Errorf("decryptContent: %w", decryptError(encrypted, err))
+	}
+	return &decryptedReader{r: plain, encrypted: encrypted}, nil
+}
+
+// decryptedReader reads the decrypted value and replaces the errors of decryption.
+type decryptedReader struct {
+	r         io.Reader
+	encrypted io.Reader
+}
+
+// Read reads the decrypted value.
+func (r *decryptedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("Read: %w", decryptError(r.encrypted, err))
+	}
+	return n, err
+}
+
+// decryptError returns ErrDecryptFailed for the value, which is not decrypted.
+// The value corrupted while receiving is not decrypted as well, so the rest
+// of the value is read to check its digest and ErrDigestMismatch is returned
+// in this case.
+func decryptError(encrypted io.Reader, err error) error {
+	if !errors.Is(err, encryption.ErrDecryptFailed) && !errors.Is(err, encryption.ErrMalformedData) &&
+		!errors.Is(err, encryption.ErrUnsupportedVersion) {
+		return err
+	}
+	_, readErr := io.Copy(io.Discard, encrypted)
+	if errors.Is(readErr, errs.ErrDigestMismatch) {
+		return readErr
+	}
+	return errs.ErrDecryptFailed
+}
+
+// contentCloser closes the streamed content of data object.
+type contentCloser struct {
+	io.Reader
+	close func() error
+}
+
+// Close closes the content.
+func (c *contentCloser) Close() error {
+	return c.close()
+}
+
+// decryptEntry decrypts the value of data object stored in the local replica.
+func decryptEntry(c *encryption.Cipher, e *replica.Entry, legacy bool) (*Data, error) {
+	d := &Data{
+		Name:     e.Name,
+		Type:     e.Type,
+		Version:  e.Version,
+		FileName: e.FileName,
+	}
+	var err error
+	d.Data, err = decryptValue(c, d, e.Data, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("decryptEntry: %w", err)
+	}
+	err = openFile(c, d, e.Metadata, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("decryptEntry: %w", err)
+	}
+	return d, nil
+}
+
+// contentType returns the content type of the file by its extension,
+// if the extension is unknown, the type is detected by the content.
+func contentType(fileName string, content []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(fileName)); t != "" {
+		return t
+	}
+	return http.DetectContentType(content)
+}
+
+// decryptItem decrypts metadata of data object stored on the server, metadata
+// is set in JSON format, the file name and the size are set for binary data.
+func decryptItem(c *encryption.Cipher, item *Item, legacy bool) error {
+	env, err := decryptEnvelope(c, &Data{Type: item.Type, Name: item.Name}, item.Metadata, legacy)
+	if err != nil {
+		return fmt.Errorf("decryptItem: %w", err)
+	}
+	item.Metadata = env.Metadata
+	if env.File != nil {
+		item.FileName = env.File.Name
+		item.Size = env.File.Size
+	}
+	return nil
+}
+
+// openFile verifies the decrypted binary value by the file information from
+// the envelope of data object and sets the original file name. Binary data
+// saved by the previous versions of the client has no file information.
+func openFile(c *encryption.Cipher, d *Data, metadata json.RawMessage, legacy bool) error {
+	if d.Type != "binary" {
+		return nil
+	}
+	env, err := decryptEnvelope(c, d, metadata, legacy)
+	if err != nil {
+		return fmt.Errorf("openFile: %w", err)
+	}
+	if env.File == nil {
+		return nil
+	}
+	err = env.File.verify(d.Data)
+	if err != nil {
+		return fmt.Errorf("openFile: %w", err)
+	}
+	d.FileName = env.File.Name
 	return nil
 }
+
+// openContent returns the reader of the decrypted binary value, which verifies
+// the value by the file information from the envelope of data object, and sets
+// the original file name like openFile.
+func openContent(c *encryption.Cipher, d *Data, metadata json.RawMessage, content io.Reader,
+	legacy bool) (io.Reader, error) {
+	env, err := decryptEnvelope(c, d, metadata, legacy)
+	if err != nil {
+		return nil, fmt.Errorf("openContent: %w", err)
+	}
+	if env.File == nil {
+		return content, nil
+	}
+	d.FileName = env.File.Name
+	d.Size = env.File.Size
+	return env.File.newReader(content), nil
+}
+
+// saveFile writes the content into the temporary file next to the path and
+// renames it, so the file is not left partially written, if the content
+// is not received or verified.
+func saveFile(path string, content io.Reader) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("saveFile: create file failed %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, content)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("saveFile: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("saveFile: close file failed %w", err)
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("saveFile: rename file failed %w", err)
+	}
+	return nil
+}
+
+// formatItemsTable returns information about data objects formatted as a table.
+func formatItemsTable(items []*Item) string {
+	if len(items) == 0 {
+		return "no data stored"
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tNAME\tCREATED\tFOLDER\tTAGS\tMETADATA")
+	for _, item := range items {
+		tags := strings.Join(item.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Type, item.Name,
+			item.CreatedAt.Format("02/01/2006 15:04"), folderLabel(item.Folder), tags,
+			formatMetadata(item.Metadata))
+	}
+	tw.Flush()
+
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// formatMetadata returns metadata in 'key=value' format sorted by keys.
+func formatMetadata(metadata json.RawMessage) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	m := make(map[string]string)
+	err := json.Unmarshal(metadata, &m)
+	if err != nil {
+		return string(metadata)
+	}
+
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+// formatRevisionsTable returns information about data versions formatted as a table.
+func formatRevisionsTable(revisions []*Revision) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDEVICE\tCREATED")
+	for _, rev := range revisions {
+		device := rev.Device
+		if device == "" {
+			device = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", rev.Version, device, rev.CreatedAt.Format("02/01/2006 15:04:05"))
+	}
+	tw.Flush()
+
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// formatConflict returns the local and the server values of data object
+// for comparing them, the missing value means that data object is deleted.
+func formatConflict(local *Data, remote *Data) string {
+	var b strings.Builder
+	switch {
+	case local == nil:
+		b.WriteString("Local change: deleted\n")
+	case local.Type == "binary":
+		fmt.Fprintf(&b, "Local file: %d bytes\n", valueSize(local))
+	default:
+		fmt.Fprintf(&b, "Local version:\n%s\n", local.Data)
+	}
+	switch {
+	case remote == nil:
+		b.WriteString("Server: deleted")
+	case remote.Type == "binary":
+		fmt.Fprintf(&b, "Server file (version %d): %d bytes", remote.Version, valueSize(remote))
+	default:
+		fmt.Fprintf(&b, "Server version %d:\n%s", remote.Version, remote.Data)
+	}
+	return b.String()
+}
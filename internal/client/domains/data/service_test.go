@@ -3,13 +3,27 @@ package data
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"mime/multipart"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
 )
 
 func TestNewDataService(t *testing.T) {
@@ -17,6 +31,7 @@ func TestNewDataService(t *testing.T) {
 	type args struct {
 		rw  rwmanager.RWService
 		cfg *config.ClientConfig
+		rep *replica.Replica
 	}
 	tests := []struct {
 		name string
@@ -28,16 +43,18 @@ func TestNewDataService(t *testing.T) {
 			args: args{
 				rw:  nil,
 				cfg: nil,
+				rep: nil,
 			},
 			want: &DataService{
-				rw:  nil,
-				cfg: nil,
+				rw:      nil,
+				cfg:     nil,
+				replica: nil,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewDataService(ctx, tt.args.rw, tt.args.cfg); !reflect.DeepEqual(got, tt.want) {
+			if got := NewDataService(ctx, tt.args.rw, tt.args.cfg, tt.args.rep); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewDataService() = %v, want %v", got, tt.want)
 			}
 		})
@@ -123,32 +140,82 @@ func Test_readDataTypeAndName(t *testing.T) {
 	}
 }
 
-func Test_createMultipartData(t *testing.T) {
+func Test_readData(t *testing.T) {
 	ctx := context.Background()
-	var buf bytes.Buffer
-	mw := multipart.NewWriter(&buf)
-	defer mw.Close()
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	filePath := filepath.Join(t.TempDir(), "scan.pdf")
+	err = os.WriteFile(filePath, []byte("binary value"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
 
 	type args struct {
-		mpwriter *multipart.Writer
-		d        *Data
+		d       *Data
+		input   string
+		content []byte
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name         string
+		args         args
+		wantData     []byte
+		wantMetadata []byte
+		wantFileName string
+		wantErr      bool
 	}{
 		{
 			name: "text_ok",
 			args: args{
-				mpwriter: mw,
 				d: &Data{
-					Name:     "myText",
-					Type:     "text",
-					Data:     []byte(`text data`),
-					Metadata: []byte(`{"meta": "data"}`),
+					Name: "myText",
+					Type: "text",
+				},
+				input: "text data\nclose\nmeta : data\nclose\n",
+			},
+			wantData:     []byte(`text data`),
+			wantMetadata: []byte(`{"meta":"data"}`),
+			wantErr:      false,
+		},
+		{
+			name: "text_from_file",
+			args: args{
+				d: &Data{
+					Name: "myText",
+					Type: "text",
+				},
+				input:   "meta : data\nclose\n",
+				content: []byte("first line\nclose\nsecond line\n"),
+			},
+			wantData:     []byte("first line\nclose\nsecond line\n"),
+			wantMetadata: []byte(`{"meta":"data"}`),
+			wantErr:      false,
+		},
+		{
+			name: "binary_ok",
+			args: args{
+				d: &Data{
+					Name: "myFile",
+					Type: "binary",
+				},
+				input: filePath + "\nclose\n",
+			},
+			wantData:     []byte("binary value"),
+			wantMetadata: []byte(`{}`),
+			wantFileName: "scan.pdf",
+			wantErr:      false,
+		},
+		{
+			name: "invalid_file_path",
+			args: args{
+				d: &Data{
+					Name: "myFile",
+					Type: "binary",
 				},
+				input: "/not/existing/file\n",
 			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
@@ -156,11 +223,579 @@ func Test_createMultipartData(t *testing.T) {
 			var in bytes.Buffer
 			var out bytes.Buffer
 			rw := rwmanager.NewRWManager(context.Background(), &in, &out)
+			in.Write([]byte(tt.args.input))
+
+			ctx := ctx
+			if tt.args.content != nil {
+				ctx = context.WithValue(ctx, utils.ContextContentKey, tt.args.content)
+			}
+			rep := replica.NewReplica(ctx, &config.ClientConfig{CacheDir: t.TempDir(), Login: "user"})
+			e, err := readData(ctx, rw, c, rep, tt.args.d)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// The binary value is kept in the file of the replica
+			encrypted := e.Data
+			if e.Type == "binary" {
+				f, err := rep.OpenBlob(e)
+				if err != nil || e.Data != nil {
+					t.Fatalf("readData() binary value is not spooled, error = %v", err)
+				}
+				encrypted, err = io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					t.Fatalf("read spooled value error = %v", err)
+				}
+			}
+			gotData, err := c.Decrypt(encrypted, additionalData(tt.args.d, "data"))
+			if err != nil {
+				t.Fatalf("decrypt data error = %v", err)
+			}
+			if !bytes.Equal(gotData, tt.wantData) {
+				t.Errorf("readData() data = %s, want %s", gotData, tt.wantData)
+			}
+
+			item := &Item{Name: e.Name, Type: e.Type, Metadata: e.Metadata}
+			err = decryptItem(c, item, false)
+			if err != nil {
+				t.Fatalf("decryptItem() error = %v", err)
+			}
+			if !bytes.Equal(item.Metadata, tt.wantMetadata) {
+				t.Errorf("readData() metadata = %s, want %s", item.Metadata, tt.wantMetadata)
+			}
+			if e.FileName != "" || e.ContentType != "" {
+				t.Errorf("readData() file is not encrypted, name = %s, content type = %s", e.FileName, e.ContentType)
+			}
+			if item.FileName != tt.wantFileName {
+				t.Errorf("readData() file name = %s, want %s", item.FileName, tt.wantFileName)
+			}
+		})
+	}
+}
+
+func TestDataService_fetchValue_spooled(t *testing.T) {
+	ctx := context.Background()
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	d := &Data{Type: "binary", Name: "scan"}
+	value := bytes.Repeat([]byte("binary value"), 10000)
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	err = os.WriteFile(path, value, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var encrypted bytes.Buffer
+	file, err := encryptFile(c, d, path, &encrypted)
+	if err != nil {
+		t.Fatalf("encryptFile() error = %v", err)
+	}
+	metadata, err := encryptEnvelope(c, d, &envelope{File: file})
+	if err != nil {
+		t.Fatalf("encryptEnvelope() error = %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", utils.ETag(1))
+		w.Header().Set(utils.MetadataHeader, base64.StdEncoding.EncodeToString(metadata))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(encrypted.Bytes())
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		version  int
+		readAll  bool
+		wantKept bool
+	}{
+		{
+			name:     "read",
+			version:  1,
+			readAll:  true,
+			wantKept: true,
+		},
+		{
+			name:     "not_read_to_the_end",
+			version:  1,
+			readAll:  false,
+			wantKept: false,
+		},
+		{
+			name:     "another_version",
+			version:  2,
+			readAll:  true,
+			wantKept: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClientConfig{Address: srv.URL, CacheDir: t.TempDir(), Login: "user", Cipher: c}
+			rep := replica.NewReplica(ctx, cfg)
+			rep.Entries["binary/scan"] = &replica.Entry{Type: "binary", Name: "scan", Version: tt.version, Metadata: metadata}
+			s := NewDataService(ctx, nil, cfg, rep)
 
-			in.Write([]byte(fmt.Sprintf("%s\n%s\n%s\nclose\n", tt.args.d.Type, tt.args.d.Name, tt.args.d.Data)))
-			if err := createMultipartData(ctx, rw, tt.args.mpwriter, tt.args.d); (err != nil) != tt.wantErr {
-				t.Errorf("createMultipartData() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := s.fetchValue(ctx, d, 0)
+			if err != nil {
+				t.Fatalf("DataService.fetchValue() error = %v", err)
+			}
+			if tt.readAll {
+				_, err = io.Copy(io.Discard, got.Content)
+			} else {
+				_, err = got.Content.Read(make([]byte, 10))
+			}
+			if err != nil {
+				t.Fatalf("read content error = %v", err)
+			}
+			got.Content.Close()
+
+			local, err := s.localValue(d)
+			if !tt.wantKept {
+				if !errors.Is(err, errs.ErrOffline) {
+					t.Errorf("DataService.localValue() error = %v, want %v", err, errs.ErrOffline)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DataService.localValue() error = %v", err)
+			}
+			defer local.Content.Close()
+			content, err := io.ReadAll(local.Content)
+			if err != nil || !bytes.Equal(content, value) {
+				t.Errorf("DataService.localValue() = %d bytes, %v, want %d bytes", len(content), err, len(value))
+			}
+			if local.FileName != "scan.pdf" || local.Size != int64(len(value)) {
+				t.Errorf("DataService.localValue() file = %s, %d, want %s, %d",
+					local.FileName, local.Size, "scan.pdf", len(value))
+			}
+		})
+	}
+}
+
+func Test_formatItemsTable(t *testing.T) {
+	created := time.Date(2024, time.January, 25, 14, 30, 0, 0, time.UTC)
+	type args struct {
+		items []*Item
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "ok",
+			args: args{
+				items: []*Item{
+					{
+						Name:      "myCreds",
+						Type:      "credentials",
+						Metadata:  []byte(`{"url": "github.com", "app": "git"}`),
+						CreatedAt: created,
+						Folder:    "work/servers",
+						Tags:      []string{"dev", "prod"},
+					},
+					{
+						Name:      "note",
+						Type:      "text",
+						CreatedAt: created,
+					},
+				},
+			},
+			want: "TYPE         NAME     CREATED           FOLDER        TAGS      METADATA\n" +
+				"credentials  myCreds  25/01/2024 14:30  work/servers  dev,prod  app=git, url=github.com\n" +
+				"text         note     25/01/2024 14:30  -             -         ",
+		},
+		{
+			name: "empty_list",
+			args: args{
+				items: []*Item{},
+			},
+			want: "no data stored",
+		},
+		{
+			name: "not_map_metadata",
+			args: args{
+				items: []*Item{
+					{
+						Name:      "note",
+						Type:      "text",
+						Metadata:  []byte(`["meta"]`),
+						CreatedAt: created,
+					},
+				},
+			},
+			want: "TYPE  NAME  CREATED           FOLDER  TAGS  METADATA\n" +
+				"text  note  25/01/2024 14:30  -       -     [\"meta\"]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatItemsTable(tt.args.items); got != tt.want {
+				t.Errorf("formatItemsTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatRevisionsTable(t *testing.T) {
+	created := time.Date(2024, time.January, 28, 9, 15, 30, 0, time.UTC)
+	tests := []struct {
+		name      string
+		revisions []*Revision
+		want      string
+	}{
+		{
+			name: "ok",
+			revisions: []*Revision{
+				{
+					Version:   2,
+					Device:    "laptop",
+					CreatedAt: created,
+				},
+				{
+					Version:   1,
+					CreatedAt: created,
+				},
+			},
+			want: "VERSION  DEVICE  CREATED\n" +
+				"2        laptop  28/01/2024 09:15:30\n" +
+				"1        -       28/01/2024 09:15:30",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatRevisionsTable(tt.revisions); got != tt.want {
+				t.Errorf("formatRevisionsTable() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_formatConflict(t *testing.T) {
+	tests := []struct {
+		name   string
+		local  *Data
+		remote *Data
+		want   string
+	}{
+		{
+			name:   "text",
+			local:  &Data{Type: "text", Name: "note", Data: []byte("local text")},
+			remote: &Data{Type: "text", Name: "note", Data: []byte("server text"), Version: 3},
+			want:   "Local version:\nlocal text\nServer version 3:\nserver text",
+		},
+		{
+			name:   "binary",
+			local:  &Data{Type: "binary", Name: "file", Data: []byte("abc")},
+			remote: &Data{Type: "binary", Name: "file", Data: []byte("abcde"), Version: 2},
+			want:   "Local file: 3 bytes\nServer file (version 2): 5 bytes",
+		},
+		{
+			name:   "binary_streamed",
+			local:  &Data{Type: "binary", Name: "file", Content: io.NopCloser(nil), Size: 3},
+			remote: &Data{Type: "binary", Name: "file", Content: io.NopCloser(nil), Size: 5, Version: 2},
+			want:   "Local file: 3 bytes\nServer file (version 2): 5 bytes",
+		},
+		{
+			name:   "deleted",
+			local:  nil,
+			remote: &Data{Type: "text", Name: "note", Data: []byte("server text"), Version: 3},
+			want:   "Local change: deleted\nServer version 3:\nserver text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatConflict(tt.local, tt.remote); got != tt.want {
+				t.Errorf("formatConflict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectField(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   []byte
+		field   string
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "string_field",
+			value:   []byte(`{"login": "gopher", "password": "secret"}`),
+			field:   "password",
+			want:    []byte("secret"),
+			wantErr: false,
+		},
+		{
+			name:    "number_field",
+			value:   []byte(`{"number": 4111111111111111, "cv": 123}`),
+			field:   "cv",
+			want:    []byte("123"),
+			wantErr: false,
+		},
+		{
+			name:    "no_field",
+			value:   []byte(`{"login": "gopher"}`),
+			field:   "password",
+			wantErr: true,
+		},
+		{
+			name:    "not_json",
+			value:   []byte("plain text"),
+			field:   "password",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectField(tt.value, tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("selectField() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_contentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  []byte
+		want     string
+	}{
+		{
+			name:     "by_extension",
+			fileName: "report.pdf",
+			content:  []byte("content"),
+			want:     "application/pdf",
+		},
+		{
+			name:     "by_content",
+			fileName: "image",
+			content:  []byte("\x89PNG\r\n\x1a\n"),
+			want:     "image/png",
+		},
+		{
+			name:     "unknown",
+			fileName: "file.unknown-extension",
+			content:  []byte{0x00, 0x01, 0x02},
+			want:     "application/octet-stream",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contentType(tt.fileName, tt.content); got != tt.want {
+				t.Errorf("contentType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decryptValue(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	d := &Data{Type: "credentials", Name: "github"}
+	encrypted, err := c.Encrypt([]byte(`{"login":"user"}`), additionalData(d, "data"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		value   []byte
+		legacy  bool
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:  "encrypted",
+			value: encrypted,
+			want:  []byte(`{"login":"user"}`),
+		},
+		{
+			name:   "saved_before_encryption_legacy",
+			value:  []byte(`{"login":"user"}`),
+			legacy: true,
+			want:   []byte(`{"login":"user"}`),
+		},
+		{
+			name:    "saved_before_encryption_rejected",
+			value:   []byte(`{"login":"user"}`),
+			wantErr: true,
+		},
+		{
+			name:    "plain_value_like_encrypted",
+			value:   append([]byte{encryption.Version1}, []byte("plain value")...),
+			legacy:  true,
+			wantErr: true,
+		},
+		{
+			name:   "empty_legacy",
+			value:  []byte{},
+			legacy: true,
+			want:   []byte{},
+		},
+		{
+			name:    "corrupted",
+			value:   append([]byte{encryption.Version1}, bytes.Repeat([]byte{0}, 64)...),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptValue(c, d, tt.value, tt.legacy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decryptValue() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decryptContent(t *testing.T) {
+	c, err := encryption.NewCipher("master", bytes.Repeat([]byte{1}, encryption.SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	d := &Data{Type: "binary", Name: "scan", Data: bytes.Repeat([]byte("binary value"), 10000)}
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	err = os.WriteFile(path, d.Data, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var buf bytes.Buffer
+	_, err = encryptFile(c, d, path, &buf)
+	if err != nil {
+		t.Fatalf("encryptFile() error = %v", err)
+	}
+	encrypted := buf.Bytes()
+	if encrypted[0] != encryption.Version2 {
+		t.Fatalf("encryptFile() version = %d, want %d", encrypted[0], encryption.Version2)
+	}
+	corrupted := append([]byte(nil), encrypted...)
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	tests := []struct {
+		name    string
+		d       *Data
+		value   []byte
+		digest  []byte
+		legacy  bool
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:  "encrypted",
+			d:     d,
+			value: encrypted,
+			want:  d.Data,
+		},
+		{
+			name:   "saved_before_encryption_legacy",
+			d:      d,
+			value:  []byte("binary value"),
+			legacy: true,
+			want:   []byte("binary value"),
+		},
+		{
+			name:    "saved_before_encryption_rejected",
+			d:       d,
+			value:   []byte("binary value"),
+			wantErr: errs.ErrDecryptFailed,
+		},
+		{
+			name:   "empty_legacy",
+			d:      d,
+			value:  []byte{},
+			legacy: true,
+			want:   []byte{},
+		},
+		{
+			name:    "wrong_data_object",
+			d:       &Data{Type: "binary", Name: "other"},
+			value:   encrypted,
+			wantErr: errs.ErrDecryptFailed,
+		},
+		{
+			name:    "corrupted_while_receiving",
+			d:       d,
+			value:   corrupted,
+			digest:  encrypted,
+			wantErr: errs.ErrDigestMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: make(http.Header),
+				Body:   io.NopCloser(bytes.NewReader(tt.value)),
+			}
+			if tt.digest != nil {
+				sum := sha256.Sum256(tt.digest)
+				resp.Header.Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sum[:]))
+			}
+			body, err := utils.NewDigestReader(resp)
+			if err != nil {
+				t.Fatalf("NewDigestReader() error = %v", err)
+			}
+
+			var got []byte
+			r, err := decryptContent(c, tt.d, body, tt.legacy)
+			if err == nil {
+				got, err = io.ReadAll(r)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decryptContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decryptContent() = %d bytes, want %d bytes", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func Test_saveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scan.pdf")
+	err := os.WriteFile(path, []byte("previous value"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// The file is kept, if the content is not received
+	err = saveFile(path, io.MultiReader(strings.NewReader("binary"), iotest.ErrReader(errs.ErrDigestMismatch)))
+	if !errors.Is(err, errs.ErrDigestMismatch) {
+		t.Fatalf("saveFile() error = %v, want %v", err, errs.ErrDigestMismatch)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "previous value" {
+		t.Errorf("saveFile() file = %s, want previous value", got)
+	}
+
+	err = saveFile(path, strings.NewReader("binary value"))
+	if err != nil {
+		t.Fatalf("saveFile() error = %v", err)
+	}
+	got, _ = os.ReadFile(path)
+	if string(got) != "binary value" {
+		t.Errorf("saveFile() file = %s, want binary value", got)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("saveFile() left %d files, want 1", len(entries))
+	}
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
+)
+
+// spoolWriter writes the encrypted binary value received from the server into
+// the file of the local replica. The error of writing doesn't break receiving
+// the value, the value is not kept in the replica in this case.
+type spoolWriter struct {
+	w        *replica.BlobWriter
+	err      error
+	complete bool
+}
+
+// Write writes the part of the value into the file.
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		_, w.err = w.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// discard removes the file with the value.
+func (w *spoolWriter) discard() {
+	if w != nil {
+		w.w.Close()
+	}
+}
+
+// completeReader marks the content as complete, when it is read to the end.
+// The decrypted content ends only after the value is received and verified.
+type completeReader struct {
+	io.Reader
+	complete *bool
+}
+
+// Read reads the content.
+func (r *completeReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		*r.complete = true
+	}
+	return n, err
+}
+
+// newSpool returns the writer of the binary value of data object into the local
+// replica, if the replica has the version of data object without the value.
+// Nil is returned, if the value is not needed or can't be written.
+func (s *DataService) newSpool(d *Data) *spoolWriter {
+	e, ok := s.replica.Get(d.Type, d.Name)
+	if !ok || e.Version != d.Version || e.Data != nil || e.Blob != nil {
+		return nil
+	}
+	w, err := s.replica.NewBlobWriter()
+	if err != nil {
+		return nil
+	}
+	return &spoolWriter{w: w}
+}
+
+// keepSpool keeps the binary value written into the file in the local replica,
+// if the value was received completely, otherwise the file is removed.
+func (s *DataService) keepSpool(w *spoolWriter, d *Data) {
+	if w == nil {
+		return
+	}
+	if !w.complete || w.err != nil {
+		w.discard()
+		return
+	}
+	blob, err := w.w.Commit()
+	if err != nil {
+		w.discard()
+		return
+	}
+	// The value is already received by the user, so the error of keeping it
+	// is not returned, the value is requested from the server next time
+	s.replica.SetBlob(d.Type, d.Name, d.Version, blob)
+}
@@ -0,0 +1,284 @@
+package replica
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+)
+
+// blobExt is the extension of the files with spooled values.
+const blobExt = ".blob"
+
+// blobGrace is the time the unused spooled value is kept after its last change,
+// so the value being spooled by another client process is not removed.
+const blobGrace = 10 * time.Minute
+
+// Blob describes the encrypted binary value spooled into the file of the blob
+// directory next to the cache, so the replica keeps only the name of the file,
+// the size and SHA-256 checksum in hex of the encrypted value.
+type Blob struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// BlobWriter writes the encrypted value into the new file of the blob directory
+// and calculates its size and checksum.
+type BlobWriter struct {
+	file      *os.File
+	hash      hash.Hash
+	size      int64
+	committed bool
+}
+
+// NewBlobWriter creates the new file in the blob directory and returns the writer
+// of the value into it. The file is not removed as unused until the process exits,
+// so the value is added to the replica after it is written.
+func (r *Replica) NewBlobWriter() (*BlobWriter, error) {
+	dir, err := r.blobDir()
+	if err != nil {
+		return nil, fmt.Errorf("NewBlobWriter: %w", err)
+	}
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, fmt.Errorf("NewBlobWriter: generate file name failed %w", err)
+	}
+	name := hex.EncodeToString(id) + blobExt
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("NewBlobWriter: create file failed %w", err)
+	}
+
+	r.mu.Lock()
+	r.spooled[name] = true
+	r.mu.Unlock()
+
+	return &BlobWriter{
+		file: file,
+		hash: sha256.New(),
+	}, nil
+}
+
+// Write writes the part of the value into the file.
+func (w *BlobWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// Commit flushes and closes the file, returns the description of the written value.
+func (w *BlobWriter) Commit() (*Blob, error) {
+	err := w.file.Sync()
+	if err != nil {
+		return nil, fmt.Errorf("Commit: sync file failed %w", err)
+	}
+	err = w.file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Commit: close file failed %w", err)
+	}
+	w.committed = true
+	return &Blob{
+		Path:     filepath.Base(w.file.Name()),
+		Size:     w.size,
+		Checksum: hex.EncodeToString(w.hash.Sum(nil)),
+	}, nil
+}
+
+// Close removes the file, if the value is not committed.
+func (w *BlobWriter) Close() error {
+	if w.committed {
+		return nil
+	}
+	w.committed = true
+	w.file.Close()
+	err := os.Remove(w.file.Name())
+	if err != nil {
+		return fmt.Errorf("Close: remove file failed %w", err)
+	}
+	return nil
+}
+
+// SetBlob sets the value spooled after receiving it from the server to the data
+// object of the version, so the value is available without the server. The value
+// is removed, if the replica has another version of data object or its value.
+func (r *Replica) SetBlob(dType string, name string, version int, blob *Blob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key(dType, name)
+	e, ok := r.Entries[k]
+	if !ok || e.Version != version || e.Data != nil || e.Blob != nil {
+		delete(r.spooled, blob.Path)
+		dir, err := r.blobDir()
+		if err != nil {
+			return fmt.Errorf("SetBlob: %w", err)
+		}
+		err = os.Remove(filepath.Join(dir, blob.Path))
+		if err != nil {
+			return fmt.Errorf("SetBlob: remove unused value failed %w", err)
+		}
+		return nil
+	}
+	entry := *e
+	entry.Blob = blob
+	r.Entries[k] = &entry
+
+	err := r.save()
+	if err != nil {
+		return fmt.Errorf("SetBlob: save replica failed %w", err)
+	}
+	return nil
+}
+
+// OpenBlob opens the file with the encrypted value of data object.
+// ErrOffline is returned, if the value is not received from the server yet.
+func (r *Replica) OpenBlob(e *Entry) (io.ReadCloser, error) {
+	if e.Blob == nil {
+		return nil, fmt.Errorf("OpenBlob: %w", errs.ErrOffline)
+	}
+	file, err := r.openValue(e)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("OpenBlob: %w", errs.ErrOffline)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OpenBlob: open file failed %w", err)
+	}
+	return file, nil
+}
+
+// valueReader contains methods for reading the value of data object
+// as a stream and by the parts at the offsets.
+type valueReader interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+}
+
+// memoryValue is the value of data object stored in the replica.
+type memoryValue struct {
+	*bytes.Reader
+}
+
+// Close does nothing.
+func (memoryValue) Close() error {
+	return nil
+}
+
+// openValue returns the reader of the encrypted value of the entry,
+// the spooled value is read from its file.
+func (r *Replica) openValue(e *Entry) (valueReader, error) {
+	if e.Blob == nil {
+		return memoryValue{bytes.NewReader(e.Data)}, nil
+	}
+	dir, err := r.blobDir()
+	if err != nil {
+		return nil, fmt.Errorf("openValue: %w", err)
+	}
+	file, err := os.Open(filepath.Join(dir, e.Blob.Path))
+	if err != nil {
+		return nil, fmt.Errorf("openValue: open file failed %w", err)
+	}
+	return file, nil
+}
+
+// size returns the size of the encrypted value of the entry.
+func (e *Entry) size() int64 {
+	if e.Blob != nil {
+		return e.Blob.Size
+	}
+	return int64(len(e.Data))
+}
+
+// checksum returns SHA-256 checksum in hex of the encrypted value of the entry.
+func (e *Entry) checksum() string {
+	if e.Blob != nil {
+		return e.Blob.Checksum
+	}
+	sum := sha256.Sum256(e.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// collectBlobs removes the files of the values, which are not used by the entries,
+// the queued changes and the conflicts of the replica, except the values spooled
+// by the process or recently, which could be not added to the replica yet.
+func (r *Replica) collectBlobs() error {
+	dir, err := r.blobDir()
+	if err != nil {
+		return fmt.Errorf("collectBlobs: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("collectBlobs: read blob directory failed %w", err)
+	}
+
+	used := make(map[string]bool)
+	for _, e := range r.Entries {
+		if e.Blob != nil {
+			used[e.Blob.Path] = true
+		}
+	}
+	for _, changes := range [][]*Change{r.Pending, r.Conflicts} {
+		for _, ch := range changes {
+			if ch.Blob != nil {
+				used[ch.Blob.Path] = true
+			}
+		}
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, blobExt) || used[name] || r.spooled[name] {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil || time.Since(info.ModTime()) < blobGrace {
+			continue
+		}
+		err = os.Remove(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("collectBlobs: remove file failed %w", err)
+		}
+	}
+	return nil
+}
+
+// blobDir returns the directory of the user's spooled values next to the cache file
+// and creates it, if it doesn't exist. If the cache is disabled, the values are spooled
+// into the temporary directory of the process, since the queued changes are sent
+// from the files as well.
+func (r *Replica) blobDir() (string, error) {
+	if r.tmpDir != "" {
+		return r.tmpDir, nil
+	}
+	dir := blobPath(r.cfg, r.cfg.Login)
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "gophkeeper-")
+		if err != nil {
+			return "", fmt.Errorf("blobDir: create temporary directory failed %w", err)
+		}
+		r.tmpDir = tmp
+		return tmp, nil
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("blobDir: create blob directory failed %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,140 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+)
+
+// spool writes the value into the blob directory of the replica.
+func spool(t *testing.T, r *Replica, value []byte) *Blob {
+	t.Helper()
+	w, err := r.NewBlobWriter()
+	if err != nil {
+		t.Fatalf("Replica.NewBlobWriter() error = %v", err)
+	}
+	defer w.Close()
+	_, err = w.Write(value)
+	if err != nil {
+		t.Fatalf("BlobWriter.Write() error = %v", err)
+	}
+	blob, err := w.Commit()
+	if err != nil {
+		t.Fatalf("BlobWriter.Commit() error = %v", err)
+	}
+	return blob
+}
+
+func TestReplica_SetBlob(t *testing.T) {
+	ctx := context.Background()
+	salt := bytes.Repeat([]byte{1}, encryption.SaltSize)
+	c, err := encryption.NewCipher("master", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		entry    *Entry
+		version  int
+		wantBlob bool
+	}{
+		{
+			name:     "same_version",
+			entry:    &Entry{Type: "binary", Name: "file", Version: 2},
+			version:  2,
+			wantBlob: true,
+		},
+		{
+			name:     "another_version",
+			entry:    &Entry{Type: "binary", Name: "file", Version: 3},
+			version:  2,
+			wantBlob: false,
+		},
+		{
+			name:     "value_exists",
+			entry:    &Entry{Type: "binary", Name: "file", Version: 2, Data: []byte("value")},
+			version:  2,
+			wantBlob: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClientConfig{CacheDir: t.TempDir(), Login: "user", Cipher: c, Salt: salt}
+			r := NewReplica(ctx, cfg)
+			r.Entries["binary/file"] = tt.entry
+			err := r.save()
+			if err != nil {
+				t.Fatalf("Replica.save() error = %v", err)
+			}
+
+			blob := spool(t, r, []byte("encrypted"))
+			err = r.SetBlob("binary", "file", tt.version, blob)
+			if err != nil {
+				t.Fatalf("Replica.SetBlob() error = %v", err)
+			}
+
+			// The replica is saved with the spooled value
+			saved := NewReplica(ctx, cfg)
+			err = saved.Open(ctx)
+			if err != nil {
+				t.Fatalf("Replica.Open() error = %v", err)
+			}
+			e, _ := saved.Get("binary", "file")
+			f, err := saved.OpenBlob(e)
+			if !tt.wantBlob {
+				if !errors.Is(err, errs.ErrOffline) {
+					t.Errorf("Replica.OpenBlob() error = %v, want %v", err, errs.ErrOffline)
+				}
+				_, err = os.Stat(filepath.Join(blobPath(cfg, cfg.Login), blob.Path))
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("unused value is not removed, error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replica.OpenBlob() error = %v", err)
+			}
+			defer f.Close()
+			value, err := io.ReadAll(f)
+			if err != nil || string(value) != "encrypted" {
+				t.Errorf("Replica.OpenBlob() value = %s, %v, want %s", value, err, "encrypted")
+			}
+		})
+	}
+}
+
+func TestRemoveCache(t *testing.T) {
+	ctx := context.Background()
+	salt := bytes.Repeat([]byte{1}, encryption.SaltSize)
+	c, err := encryption.NewCipher("master", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	cfg := &config.ClientConfig{CacheDir: t.TempDir(), Login: "user", Cipher: c, Salt: salt}
+	r := NewReplica(ctx, cfg)
+	blob := spool(t, r, []byte("encrypted"))
+	err = r.Queue(&Change{Op: OpCreate, Entry: Entry{Type: "binary", Name: "file", Blob: blob}})
+	if err != nil {
+		t.Fatalf("Replica.Queue() error = %v", err)
+	}
+
+	err = RemoveCache(cfg, cfg.Login)
+	if err != nil {
+		t.Fatalf("RemoveCache() error = %v", err)
+	}
+	for _, path := range []string{cachePath(cfg, cfg.Login), blobPath(cfg, cfg.Login)} {
+		_, err = os.Stat(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("RemoveCache() %s is not removed, error = %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package replica
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// cacheFile contains the replica stored on the disk. The salt and the key
+// check value are stored in plain form for unlocking the replica without
+// the server, the replica is encrypted with the key derived from the master password.
+type cacheFile struct {
+	Salt     []byte `json:"salt"`
+	KeyCheck []byte `json:"key_check,omitempty"`
+	Replica  []byte `json:"replica"`
+}
+
+// Open loads the replica of the logged in user from the cache directory.
+// If the replica is not stored yet, the replica becomes empty.
+func (r *Replica) Open(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Cursor = 0
+	r.Entries = make(map[string]*Entry)
+	r.Pending = make([]*Change, 0)
+	r.Conflicts = make([]*Change, 0)
+
+	path := cachePath(r.cfg, r.cfg.Login)
+	if path == "" || r.cfg.Cipher == nil {
+		return nil
+	}
+
+	f, err := readCacheFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Open: read cache file failed %w", err)
+	}
+
+	plain, err := r.cfg.Cipher.Decrypt(f.Replica, []byte("replica/"+r.cfg.Login))
+	if err != nil {
+		return fmt.Errorf("Open: decrypt replica failed %w", errs.ErrDecryptFailed)
+	}
+	err = json.Unmarshal(plain, r)
+	if err != nil {
+		return fmt.Errorf("Open: unmarshal replica failed %w", err)
+	}
+	if r.Entries == nil {
+		r.Entries = make(map[string]*Entry)
+	}
+
+	return nil
+}
+
+// CachedSalt returns the salt and the key check value of the user stored together
+// with the user's replica, so the replica can be unlocked when the server is unavailable.
+func CachedSalt(cfg *config.ClientConfig, login string) ([]byte, []byte, error) {
+	path := cachePath(cfg, login)
+	if path == "" {
+		return nil, nil, fmt.Errorf("CachedSalt: cache is disabled")
+	}
+	f, err := readCacheFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CachedSalt: read cache file failed %w", err)
+	}
+	return f.Salt, f.KeyCheck, nil
+}
+
+// RemoveCache removes the stored replica of the user.
+func RemoveCache(cfg *config.ClientConfig, login string) error {
+	path := cachePath(cfg, login)
+	if path == "" {
+		return nil
+	}
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("RemoveCache: remove cache file failed %w", err)
+	}
+	err = os.RemoveAll(blobPath(cfg, login))
+	if err != nil {
+		return fmt.Errorf("RemoveCache: remove spooled values failed %w", err)
+	}
+	return nil
+}
+
+// save encrypts the replica and writes it into the cache directory, then removes
+// the spooled values, which are not used by the replica anymore.
+func (r *Replica) save() error {
+	path := cachePath(r.cfg, r.cfg.Login)
+	if r.cfg.Cipher == nil {
+		return nil
+	}
+	if path == "" {
+		return r.collectBlobs()
+	}
+
+	plain, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("save: marshal replica failed %w", err)
+	}
+	encrypted, err := r.cfg.Cipher.Encrypt(plain, []byte("replica/"+r.cfg.Login))
+	if err != nil {
+		return fmt.Errorf("save: encrypt replica failed %w", err)
+	}
+	body, err := json.Marshal(&cacheFile{
+		Salt:     r.cfg.Salt,
+		KeyCheck: r.cfg.KeyCheck,
+		Replica:  encrypted,
+	})
+	if err != nil {
+		return fmt.Errorf("save: marshal cache file failed %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("save: create cache directory failed %w", err)
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, body, 0600)
+	if err != nil {
+		return fmt.Errorf("save: write cache file failed %w", err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("save: rename cache file failed %w", err)
+	}
+
+	err = r.collectBlobs()
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	return nil
+}
+
+// readCacheFile reads the cache file.
+func readCacheFile(path string) (*cacheFile, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("readCacheFile: %w", err)
+	}
+	var f cacheFile
+	err = json.Unmarshal(body, &f)
+	if err != nil {
+		return nil, fmt.Errorf("readCacheFile: unmarshal cache file failed %w", err)
+	}
+	return &f, nil
+}
+
+// cachePath returns the path of the user's cache file, which depends
+// on the server address and the user login. Empty path is returned
+// if the cache is disabled or the user is not logged in.
+func cachePath(cfg *config.ClientConfig, login string) string {
+	if cfg == nil || cfg.CacheDir == "" || login == "" {
+		return ""
+	}
+	return filepath.Join(cfg.CacheDir, cacheName(cfg.Address, login)+".cache")
+}
+
+// blobPath returns the path of the directory with the user's spooled binary values
+// next to the cache file. Empty path is returned if the cache is disabled.
+func blobPath(cfg *config.ClientConfig, login string) string {
+	if cfg == nil || cfg.CacheDir == "" || login == "" {
+		return ""
+	}
+	return filepath.Join(cfg.CacheDir, cacheName(cfg.Address, login)+".files")
+}
+
+// cacheName returns the name of the user's cache files.
+func cacheName(address string, login string) string {
+	sum := sha256.Sum256([]byte(address + "\n" + login))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,101 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+)
+
+func TestReplica_Open(t *testing.T) {
+	ctx := context.Background()
+	salt := bytes.Repeat([]byte{1}, encryption.SaltSize)
+	c, err := encryption.NewCipher("master", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	wrong, err := encryption.NewCipher("wrong", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg := &config.ClientConfig{
+		Address:  "http://localhost:8080",
+		CacheDir: dir,
+		Login:    "user",
+		Cipher:   c,
+		Salt:     salt,
+	}
+	saved := NewReplica(ctx, cfg)
+	saved.Cursor = 5
+	saved.Entries["text/note"] = &Entry{Type: "text", Name: "note", Version: 2, Seq: 5, Data: []byte("encrypted")}
+	err = saved.Queue(&Change{Op: OpDelete, Entry: Entry{Type: "text", Name: "note", Version: 2}})
+	if err != nil {
+		t.Fatalf("Replica.Queue() error = %v", err)
+	}
+
+	info, err := os.Stat(cachePath(cfg, cfg.Login))
+	if err != nil {
+		t.Fatalf("cache file is not saved: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cache file permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.ClientConfig
+		wantCursor  int64
+		wantPending int
+		wantErr     error
+	}{
+		{
+			name:        "ok",
+			cfg:         cfg,
+			wantCursor:  5,
+			wantPending: 1,
+			wantErr:     nil,
+		},
+		{
+			name:        "wrong_master_password",
+			cfg:         &config.ClientConfig{Address: cfg.Address, CacheDir: dir, Login: "user", Cipher: wrong},
+			wantCursor:  0,
+			wantPending: 0,
+			wantErr:     errs.ErrDecryptFailed,
+		},
+		{
+			name:        "another_user",
+			cfg:         &config.ClientConfig{Address: cfg.Address, CacheDir: dir, Login: "another", Cipher: c},
+			wantCursor:  0,
+			wantPending: 0,
+			wantErr:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplica(ctx, tt.cfg)
+			err := r.Open(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Replica.Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if r.Cursor != tt.wantCursor || len(r.Pending) != tt.wantPending {
+				t.Errorf("Replica.Open() cursor = %v, pending = %v, want %v, %v",
+					r.Cursor, len(r.Pending), tt.wantCursor, tt.wantPending)
+			}
+		})
+	}
+
+	got, _, err := CachedSalt(cfg, "user")
+	if err != nil || !bytes.Equal(got, salt) {
+		t.Errorf("CachedSalt() = %v, %v, want %v", got, err, salt)
+	}
+}
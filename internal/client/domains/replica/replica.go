@@ -0,0 +1,579 @@
+// Package replica contains the local replica of the user's data
+// and methods for its synchronization with the server.
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// Operations on data objects which can be pushed to the server.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Entry contains the encrypted data object as it is stored on the server.
+type Entry struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	Seq       int64           `json:"seq"`
+	Data      []byte          `json:"data"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	// FileName and ContentType describe the original file of the binary value
+	// saved by the previous versions of the client, now they are encrypted
+	// together with metadata.
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// Blob describes the binary value spooled into the file instead of Data.
+	Blob *Blob `json:"blob,omitempty"`
+}
+
+// Change contains the local change of data object waiting for sending to the server.
+// Version of the entry contains the version of data object the change is based on,
+// the server rejects the change if data object was changed after this version.
+// Large binary value is sent by chunks, the identifier of its started upload
+// is kept until the upload is committed.
+type Change struct {
+	Op string `json:"op"`
+	Entry
+	UploadID string `json:"upload_id,omitempty"`
+}
+
+// feedChange contains the change of data object received from the server.
+type feedChange struct {
+	Entry
+	Deleted bool `json:"deleted"`
+}
+
+// changeFeed contains the changes received from the server and the cursor
+// for requesting the next changes.
+type changeFeed struct {
+	Cursor  int64         `json:"cursor"`
+	More    bool          `json:"more"`
+	Changes []*feedChange `json:"changes"`
+}
+
+// Replica contains the local copy of the user's data, the cursor of the last
+// received change, the queue of local changes for sending to the server and
+// the local changes rejected because of conflict, which wait for resolving.
+type Replica struct {
+	mu        sync.Mutex
+	cfg       *config.ClientConfig
+	spooled   map[string]bool
+	tmpDir    string
+	Cursor    int64             `json:"cursor"`
+	Entries   map[string]*Entry `json:"entries"`
+	Pending   []*Change         `json:"pending"`
+	Conflicts []*Change         `json:"conflicts,omitempty"`
+}
+
+// NewReplica creates and returns new empty replica.
+func NewReplica(ctx context.Context, cfg *config.ClientConfig) *Replica {
+	return &Replica{
+		cfg:       cfg,
+		spooled:   make(map[string]bool),
+		Entries:   make(map[string]*Entry),
+		Pending:   make([]*Change, 0),
+		Conflicts: make([]*Change, 0),
+	}
+}
+
+// Queue adds the local change into the queue for sending to the server.
+func (r *Replica) Queue(ch *Change) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Pending = append(r.Pending, ch)
+
+	err := r.save()
+	if err != nil {
+		return fmt.Errorf("Queue: save replica failed %w", err)
+	}
+	return nil
+}
+
+// Push sends the queued changes to the server in order. If the server
+// is unavailable, the changes stay in the queue and ErrOffline is returned.
+// The changes rejected because data object was changed by another client
+// are moved into the conflicts, the other changes are sent, and the rejected
+// ones are returned with ErrConflict. The change rejected for another reason
+// is removed from the queue and its error is returned.
+func (r *Replica) Push(ctx context.Context) ([]*Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conflicts := make([]*Change, 0)
+	for len(r.Pending) > 0 {
+		ch := r.Pending[0]
+
+		version, err := r.send(ctx, ch)
+		if errors.Is(err, errs.ErrOffline) {
+			return conflicts, fmt.Errorf("Push: %w", err)
+		}
+
+		r.Pending = r.Pending[1:]
+		switch {
+		case err == nil:
+			r.rebase(ch, version)
+			r.keepBlob(ch, version)
+		case errors.Is(err, errs.ErrConflict) || errors.Is(err, errs.ErrAlreadyExists):
+			r.addConflict(ch)
+			conflicts = append(conflicts, ch)
+			err = nil
+		}
+		saveErr := r.save()
+		if err != nil {
+			return conflicts, fmt.Errorf("Push: %s %s/%s failed %w", ch.Op, ch.Type, ch.Name, err)
+		}
+		if saveErr != nil {
+			return conflicts, fmt.Errorf("Push: save replica failed %w", saveErr)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return conflicts, fmt.Errorf("Push: %d changes rejected %w", len(conflicts), errs.ErrConflict)
+	}
+	return conflicts, nil
+}
+
+// ConflictList returns the local changes rejected because of conflict
+// ordered by type and name.
+func (r *Replica) ConflictList() []*Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conflicts := make([]*Change, len(r.Conflicts))
+	copy(conflicts, r.Conflicts)
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Type != conflicts[j].Type {
+			return conflicts[i].Type < conflicts[j].Type
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
+
+// Conflict returns the local change of data object rejected because of conflict.
+func (r *Replica) Conflict(dType string, name string) (*Change, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.conflictIndex(dType, name)
+	if i < 0 {
+		return nil, false
+	}
+	return r.Conflicts[i], true
+}
+
+// Resolve removes the conflict of data object. If keepLocal is true, the local
+// change is queued again based on the version of data object known by the replica,
+// so it overwrites the change of another client, otherwise the local change is dropped.
+func (r *Replica) Resolve(dType string, name string, keepLocal bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.conflictIndex(dType, name)
+	if i < 0 {
+		return fmt.Errorf("Resolve: conflict of %s/%s %w", dType, name, errs.ErrNotExist)
+	}
+	ch := r.Conflicts[i]
+	r.Conflicts = append(r.Conflicts[:i], r.Conflicts[i+1:]...)
+
+	if keepLocal {
+		e, ok := r.Entries[key(dType, name)]
+		switch {
+		case ok:
+			ch.Version = e.Version
+			if ch.Op == OpCreate {
+				ch.Op = OpUpdate
+			}
+			r.Pending = append(r.Pending, ch)
+		case ch.Op != OpDelete:
+			// Data object was deleted by another client
+			ch.Version = 0
+			ch.Op = OpCreate
+			r.Pending = append(r.Pending, ch)
+		}
+	}
+
+	err := r.save()
+	if err != nil {
+		return fmt.Errorf("Resolve: save replica failed %w", err)
+	}
+	return nil
+}
+
+// Pull requests the server for the changes since the replica cursor,
+// applies them to the replica and returns the number of applied changes.
+// The values of binary data objects are not sent with the changes,
+// they are received from the server when data object is requested.
+func (r *Replica) Pull(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	applied := 0
+	for {
+		feed, err := r.fetch(ctx)
+		if err != nil {
+			return applied, fmt.Errorf("Pull: get changes failed %w", err)
+		}
+		applied += r.apply(feed)
+		if !feed.More || len(feed.Changes) == 0 {
+			break
+		}
+	}
+
+	err := r.save()
+	if err != nil {
+		return applied, fmt.Errorf("Pull: save replica failed %w", err)
+	}
+
+	return applied, nil
+}
+
+// Get returns the data object from the replica with the queued changes applied.
+func (r *Replica) Get(dType string, name string) (*Entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.view()[key(dType, name)]
+	return e, ok
+}
+
+// List returns the data objects from the replica with the queued changes applied,
+// optionally filtered by type, ordered by type and name.
+func (r *Replica) List(dType string) []*Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	view := r.view()
+	entries := make([]*Entry, 0, len(view))
+	for _, e := range view {
+		if dType == "" || e.Type == dType {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// send sends the change to the server, returns the new version of data object.
+func (r *Replica) send(ctx context.Context, ch *Change) (int, error) {
+	target := r.cfg.Address + "/api/user/data/" + ch.Type + "/" + ch.Name
+
+	var method string
+	switch ch.Op {
+	case OpCreate:
+		method = http.MethodPost
+	case OpUpdate:
+		method = http.MethodPut
+	case OpDelete:
+		method = http.MethodDelete
+	default:
+		return 0, fmt.Errorf("send: unknown operation %s", ch.Op)
+	}
+
+	if ch.Op != OpDelete && ch.Type == "binary" && ch.size() > ChunkSize {
+		version, err := r.upload(ctx, ch)
+		if err != nil {
+			return 0, fmt.Errorf("send: %w", err)
+		}
+		return version, nil
+	}
+
+	timeout := 15 * time.Second
+	if ch.Type == "binary" {
+		timeout = utils.TransferTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, fmt.Errorf("send: new request failed %w", err)
+	}
+
+	// Data is streamed into multipart body while the request is sent
+	if ch.Op != OpDelete {
+		var size byteCounter
+		boundary := multipart.NewWriter(nil).Boundary()
+		err = writeMultipart(newMultipartWriter(&size, boundary), ch, http.NoBody)
+		if err != nil {
+			return 0, fmt.Errorf("send: count multipart data size failed %w", err)
+		}
+		req.ContentLength = int64(size) + ch.size()
+		req.GetBody = func() (io.ReadCloser, error) {
+			return r.multipartBody(ch, boundary)
+		}
+		req.Body, err = r.multipartBody(ch, boundary)
+		if err != nil {
+			return 0, fmt.Errorf("send: %w", err)
+		}
+		req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	}
+
+	if r.cfg.Cookie != nil {
+		req.AddCookie(r.cfg.Cookie)
+	}
+	if ch.Version > 0 {
+		req.Header.Set("If-Match", utils.ETag(ch.Version))
+	}
+	req.Header.Set(utils.DeviceHeader, r.cfg.Device)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			return 0, fmt.Errorf("send: %w", errs.ErrOffline)
+		}
+		return 0, fmt.Errorf("send: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return 0, fmt.Errorf("send: %w", err)
+	}
+
+	return utils.GetVersionFromETag(resp.Header.Get("ETag")), nil
+}
+
+// fetch requests the server for the changes since the replica cursor.
+func (r *Replica) fetch(ctx context.Context) (*changeFeed, error) {
+	target := r.cfg.Address + "/api/user/sync?since=" + strconv.FormatInt(r.Cursor, 10)
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: new request failed %w", err)
+	}
+
+	if r.cfg.Cookie != nil {
+		req.AddCookie(r.cfg.Cookie)
+	}
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			return nil, fmt.Errorf("fetch: %w", errs.ErrOffline)
+		}
+		return nil, fmt.Errorf("fetch: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+
+	var feed changeFeed
+	err = json.NewDecoder(resp.Body).Decode(&feed)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: decode changes failed %w", err)
+	}
+
+	return &feed, nil
+}
+
+// apply applies the changes from the feed to the replica, moves the cursor
+// and returns the number of applied changes.
+func (r *Replica) apply(feed *changeFeed) int {
+	applied := 0
+	for _, ch := range feed.Changes {
+		if ch.Seq <= r.Cursor {
+			continue
+		}
+		k := key(ch.Type, ch.Name)
+		if ch.Deleted {
+			delete(r.Entries, k)
+		} else {
+			e := ch.Entry
+			// The changes of binary data come without value,
+			// the value of the same version is kept
+			if old, ok := r.Entries[k]; ok && e.Data == nil && old.Version == e.Version {
+				e.Data = old.Data
+				e.Blob = old.Blob
+			}
+			r.Entries[k] = &e
+		}
+		r.Cursor = ch.Seq
+		applied++
+	}
+	if feed.Cursor > r.Cursor {
+		r.Cursor = feed.Cursor
+	}
+	return applied
+}
+
+// rebase sets the new version of data object, received from the server
+// after sending the change, as the base version of the next queued changes
+// of the same data object.
+func (r *Replica) rebase(sent *Change, version int) {
+	if version == 0 {
+		return
+	}
+	for _, ch := range r.Pending {
+		if ch.Type == sent.Type && ch.Name == sent.Name {
+			ch.Version = version
+		}
+	}
+}
+
+// keepBlob keeps the spooled binary value sent to the server as the value
+// of the new version of data object, so it is not requested from the server again.
+func (r *Replica) keepBlob(sent *Change, version int) {
+	if sent.Blob == nil || version == 0 {
+		return
+	}
+	e := sent.Entry
+	e.Version = version
+	r.Entries[key(e.Type, e.Name)] = &e
+}
+
+// addConflict keeps the rejected change in the conflicts, the previous
+// conflict of the same data object is replaced, since the change is newer.
+func (r *Replica) addConflict(ch *Change) {
+	ch.UploadID = ""
+	if i := r.conflictIndex(ch.Type, ch.Name); i >= 0 {
+		r.Conflicts[i] = ch
+		return
+	}
+	r.Conflicts = append(r.Conflicts, ch)
+}
+
+// conflictIndex returns the index of the conflict of data object, -1 is returned
+// if there is no conflict.
+func (r *Replica) conflictIndex(dType string, name string) int {
+	for i, ch := range r.Conflicts {
+		if ch.Type == dType && ch.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// view returns the data objects from the replica with the queued changes applied.
+func (r *Replica) view() map[string]*Entry {
+	view := make(map[string]*Entry, len(r.Entries))
+	for k, e := range r.Entries {
+		view[k] = e
+	}
+	for _, ch := range r.Pending {
+		k := key(ch.Type, ch.Name)
+		if ch.Op == OpDelete {
+			delete(view, k)
+			continue
+		}
+		e := ch.Entry
+		view[k] = &e
+	}
+	return view
+}
+
+// byteCounter counts the bytes written into it.
+type byteCounter int64
+
+// Write counts the bytes and discards them.
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// newMultipartWriter returns multipart writer with the boundary,
+// which is generated by multipart writer, so it is always valid.
+func newMultipartWriter(w io.Writer, boundary string) *multipart.Writer {
+	mpwriter := multipart.NewWriter(w)
+	mpwriter.SetBoundary(boundary)
+	return mpwriter
+}
+
+// multipartBody returns the body with encrypted data and metadata of the change
+// in multipart fields, which is written by the separate goroutine while it is read.
+// The spooled binary value is streamed from its file.
+func (r *Replica) multipartBody(ch *Change, boundary string) (io.ReadCloser, error) {
+	value, err := r.openValue(&ch.Entry)
+	if err != nil {
+		return nil, fmt.Errorf("multipartBody: %w", err)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer value.Close()
+		// The reader gets the error of writing, if any
+		pw.CloseWithError(writeMultipart(newMultipartWriter(pw, boundary), ch, value))
+	}()
+	return pr, nil
+}
+
+// writeMultipart puts encrypted metadata and data of the change into multipart fields,
+// the binary value is put into the file field with the original file name and content type.
+// Metadata goes first, so the server streams the value into the storage.
+func writeMultipart(mpwriter *multipart.Writer, ch *Change, value io.Reader) error {
+	metaPart, err := mpwriter.CreateFormField("metadata")
+	if err != nil {
+		return fmt.Errorf("writeMultipart: create multipart metadata form failed %w", err)
+	}
+	_, err = metaPart.Write(ch.Metadata)
+	if err != nil {
+		return fmt.Errorf("writeMultipart: write metadata failed %w", err)
+	}
+
+	var dataPart io.Writer
+	if ch.Type == "binary" {
+		params := map[string]string{"name": "file"}
+		if ch.FileName != "" {
+			params["filename"] = ch.FileName
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", params))
+		if ch.ContentType != "" {
+			h.Set("Content-Type", ch.ContentType)
+		}
+		dataPart, err = mpwriter.CreatePart(h)
+	} else {
+		dataPart, err = mpwriter.CreateFormField("data")
+	}
+	if err != nil {
+		return fmt.Errorf("writeMultipart: create multipart data form failed %w", err)
+	}
+	_, err = io.Copy(dataPart, value)
+	if err != nil {
+		return fmt.Errorf("writeMultipart: write data failed %w", err)
+	}
+
+	err = mpwriter.Close()
+	if err != nil {
+		return fmt.Errorf("writeMultipart: close multipart writer failed %w", err)
+	}
+	return nil
+}
+
+// key returns the key of data object in the replica.
+func key(dType string, name string) string {
+	return dType + "/" + name
+}
@@ -0,0 +1,482 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+func TestReplica_apply(t *testing.T) {
+	ctx := context.Background()
+
+	type args struct {
+		cursor  int64
+		entries map[string]*Entry
+		feed    *changeFeed
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantApplied int
+		wantCursor  int64
+		wantKeys    []string
+	}{
+		{
+			name: "create_and_update",
+			args: args{
+				cursor:  0,
+				entries: map[string]*Entry{},
+				feed: &changeFeed{
+					Cursor: 3,
+					Changes: []*feedChange{
+						{Entry: Entry{Seq: 1, Type: "text", Name: "note", Version: 1}},
+						{Entry: Entry{Seq: 2, Type: "card", Name: "visa", Version: 1}},
+						{Entry: Entry{Seq: 3, Type: "text", Name: "note", Version: 2}},
+					},
+				},
+			},
+			wantApplied: 3,
+			wantCursor:  3,
+			wantKeys:    []string{"card/visa", "text/note"},
+		},
+		{
+			name: "delete",
+			args: args{
+				cursor: 2,
+				entries: map[string]*Entry{
+					"text/note": {Seq: 2, Type: "text", Name: "note"},
+				},
+				feed: &changeFeed{
+					Cursor: 5,
+					Changes: []*feedChange{
+						{Entry: Entry{Seq: 5, Type: "text", Name: "note"}, Deleted: true},
+					},
+				},
+			},
+			wantApplied: 1,
+			wantCursor:  5,
+			wantKeys:    []string{},
+		},
+		{
+			name: "already_applied",
+			args: args{
+				cursor: 4,
+				entries: map[string]*Entry{
+					"text/note": {Seq: 4, Type: "text", Name: "note"},
+				},
+				feed: &changeFeed{
+					Cursor: 4,
+					Changes: []*feedChange{
+						{Entry: Entry{Seq: 3, Type: "text", Name: "note"}, Deleted: true},
+					},
+				},
+			},
+			wantApplied: 0,
+			wantCursor:  4,
+			wantKeys:    []string{"text/note"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplica(ctx, nil)
+			r.Cursor = tt.args.cursor
+			r.Entries = tt.args.entries
+
+			got := r.apply(tt.args.feed)
+			if got != tt.wantApplied {
+				t.Errorf("Replica.apply() = %v, want %v", got, tt.wantApplied)
+			}
+			if r.Cursor != tt.wantCursor {
+				t.Errorf("Replica.apply() cursor = %v, want %v", r.Cursor, tt.wantCursor)
+			}
+			keys := make([]string, 0)
+			for _, e := range r.List("") {
+				keys = append(keys, key(e.Type, e.Name))
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("Replica.apply() entries = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestReplica_Pull(t *testing.T) {
+	ctx := context.Background()
+	changes := []*feedChange{
+		{Entry: Entry{Seq: 1, Type: "text", Name: "first", Version: 1}},
+		{Entry: Entry{Seq: 2, Type: "text", Name: "second", Version: 1}},
+		{Entry: Entry{Seq: 3, Type: "text", Name: "first"}, Deleted: true},
+	}
+
+	// The server returns one change at a time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+		feed := &changeFeed{Cursor: since, Changes: []*feedChange{}}
+		for _, ch := range changes {
+			if ch.Seq > since {
+				feed.Changes = append(feed.Changes, ch)
+				feed.Cursor = ch.Seq
+				feed.More = ch.Seq < changes[len(changes)-1].Seq
+				break
+			}
+		}
+		json.NewEncoder(w).Encode(feed)
+	}))
+	defer srv.Close()
+
+	r := NewReplica(ctx, &config.ClientConfig{Address: srv.URL})
+	got, err := r.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Replica.Pull() error = %v", err)
+	}
+	if got != len(changes) {
+		t.Errorf("Replica.Pull() = %v, want %v", got, len(changes))
+	}
+	if _, ok := r.Get("text", "first"); ok {
+		t.Errorf("Replica.Pull() deleted entry is in the replica")
+	}
+	if _, ok := r.Get("text", "second"); !ok {
+		t.Errorf("Replica.Pull() created entry is not in the replica")
+	}
+
+	got, err = r.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Replica.Pull() error = %v", err)
+	}
+	if got != 0 {
+		t.Errorf("Replica.Pull() repeated = %v, want 0", got)
+	}
+}
+
+func TestReplica_Pull_binary(t *testing.T) {
+	ctx := context.Background()
+
+	// The changes of binary data come without value,
+	// the values are not requested until data object is read
+	requested := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/user/sync":
+			json.NewEncoder(w).Encode(&changeFeed{
+				Cursor: 2,
+				Changes: []*feedChange{
+					{Entry: Entry{Seq: 1, Type: "binary", Name: "file", Version: 1}},
+					{Entry: Entry{Seq: 2, Type: "binary", Name: "changed", Version: 2}},
+				},
+			})
+		default:
+			requested++
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	r := NewReplica(ctx, &config.ClientConfig{Address: srv.URL, CacheDir: t.TempDir(), Login: "user"})
+	blob := spool(t, r, []byte("value"))
+	r.Entries["binary/file"] = &Entry{Type: "binary", Name: "file", Version: 1, Blob: blob}
+	r.Entries["binary/changed"] = &Entry{Type: "binary", Name: "changed", Version: 1, Blob: blob}
+
+	_, err := r.Pull(ctx)
+	if err != nil {
+		t.Fatalf("Replica.Pull() error = %v", err)
+	}
+	if requested != 0 {
+		t.Errorf("Replica.Pull() requested %v values, want 0", requested)
+	}
+
+	e, ok := r.Get("binary", "file")
+	if !ok || !reflect.DeepEqual(e.Blob, blob) {
+		t.Errorf("Replica.Pull() binary value = %v, want %v", e, blob)
+	}
+	e, ok = r.Get("binary", "changed")
+	if !ok || e.Blob != nil || e.Data != nil {
+		t.Errorf("Replica.Pull() value of the other version = %v, want nil", e.Blob)
+	}
+}
+
+func TestReplica_Push(t *testing.T) {
+	ctx := context.Background()
+
+	// The server accepts changes based on the current version of data object
+	version := map[string]int{"text/note": 1}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := strings.TrimPrefix(r.URL.Path, "/api/user/data/")
+		if tag := r.Header.Get("If-Match"); tag != "" && tag != `"`+strconv.Itoa(version[k])+`"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		version[k]++
+		w.Header().Set("ETag", `"`+strconv.Itoa(version[k])+`"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		pending      []*Change
+		wantRejected bool
+		wantErr      error
+		wantVersion  int
+	}{
+		{
+			name: "rebased_changes",
+			pending: []*Change{
+				{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+				{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+			},
+			wantRejected: false,
+			wantErr:      nil,
+			wantVersion:  3,
+		},
+		{
+			name: "conflict",
+			pending: []*Change{
+				{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+			},
+			wantRejected: true,
+			wantErr:      errs.ErrConflict,
+			wantVersion:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplica(ctx, &config.ClientConfig{Address: srv.URL})
+			for _, ch := range tt.pending {
+				r.Queue(ch)
+			}
+
+			rejected, err := r.Push(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Replica.Push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (len(rejected) > 0) != tt.wantRejected {
+				t.Errorf("Replica.Push() rejected = %v, wantRejected %v", rejected, tt.wantRejected)
+			}
+			if len(r.Conflicts) != len(rejected) {
+				t.Errorf("Replica.Push() kept %d conflicts, want %d", len(r.Conflicts), len(rejected))
+			}
+			if len(r.Pending) != 0 {
+				t.Errorf("Replica.Push() left %d changes in the queue", len(r.Pending))
+			}
+			if version["text/note"] != tt.wantVersion {
+				t.Errorf("Replica.Push() server version = %v, want %v", version["text/note"], tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestReplica_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		entry       *Entry
+		conflict    *Change
+		keepLocal   bool
+		wantPending *Change
+	}{
+		{
+			name:        "keep_local",
+			entry:       &Entry{Type: "text", Name: "note", Version: 3},
+			conflict:    &Change{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+			keepLocal:   true,
+			wantPending: &Change{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 3}},
+		},
+		{
+			name:        "keep_local_deleted_on_server",
+			entry:       nil,
+			conflict:    &Change{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+			keepLocal:   true,
+			wantPending: &Change{Op: OpCreate, Entry: Entry{Type: "text", Name: "note", Version: 0}},
+		},
+		{
+			name:        "keep_server",
+			entry:       &Entry{Type: "text", Name: "note", Version: 3},
+			conflict:    &Change{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1}},
+			keepLocal:   false,
+			wantPending: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplica(ctx, &config.ClientConfig{})
+			if tt.entry != nil {
+				r.Entries[key(tt.entry.Type, tt.entry.Name)] = tt.entry
+			}
+			r.addConflict(tt.conflict)
+
+			err := r.Resolve("text", "note", tt.keepLocal)
+			if err != nil {
+				t.Fatalf("Replica.Resolve() error = %v", err)
+			}
+			if len(r.Conflicts) != 0 {
+				t.Errorf("Replica.Resolve() left %d conflicts", len(r.Conflicts))
+			}
+			var got *Change
+			if len(r.Pending) > 0 {
+				got = r.Pending[0]
+			}
+			if !reflect.DeepEqual(got, tt.wantPending) {
+				t.Errorf("Replica.Resolve() pending = %+v, want %+v", got, tt.wantPending)
+			}
+
+			err = r.Resolve("text", "note", tt.keepLocal)
+			if !errors.Is(err, errs.ErrNotExist) {
+				t.Errorf("Replica.Resolve() repeated error = %v, want %v", err, errs.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestReplica_send(t *testing.T) {
+	ctx := context.Background()
+
+	maxSize := int64(1 << 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxSize {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if r.ParseMultipartForm(maxSize) != nil || r.FormValue("metadata") != `"meta"` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		files := r.MultipartForm.File["file"]
+		if len(files) != 1 || files[0].Filename != "file.txt" ||
+			files[0].Header.Get("Content-Type") != "text/plain" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, err := files[0].Open()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		value, err := io.ReadAll(f)
+		if err != nil || string(value) != "value" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		ch          *Change
+		spooled     bool
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name: "streamed",
+			ch: &Change{Op: OpCreate, Entry: Entry{
+				Type: "binary", Name: "file", Data: []byte("value"), Metadata: []byte(`"meta"`),
+				FileName: "file.txt", ContentType: "text/plain",
+			}},
+			wantVersion: 1,
+			wantErr:     nil,
+		},
+		{
+			name: "spooled",
+			ch: &Change{Op: OpCreate, Entry: Entry{
+				Type: "binary", Name: "file", Data: []byte("value"), Metadata: []byte(`"meta"`),
+				FileName: "file.txt", ContentType: "text/plain",
+			}},
+			spooled:     true,
+			wantVersion: 1,
+			wantErr:     nil,
+		},
+		{
+			name: "too_large",
+			ch: &Change{Op: OpCreate, Entry: Entry{
+				Type: "binary", Name: "large", Data: bytes.Repeat([]byte("a"), int(maxSize)),
+			}},
+			wantVersion: 0,
+			wantErr:     errs.ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplica(ctx, &config.ClientConfig{Address: srv.URL, CacheDir: t.TempDir(), Login: "user"})
+			if tt.spooled {
+				tt.ch.Blob = spool(t, r, tt.ch.Data)
+				tt.ch.Data = nil
+			}
+			got, err := r.send(ctx, tt.ch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Replica.send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantVersion {
+				t.Errorf("Replica.send() version = %v, want %v", got, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestReplica_view(t *testing.T) {
+	ctx := context.Background()
+	r := NewReplica(ctx, nil)
+	r.Entries = map[string]*Entry{
+		"text/note":   {Type: "text", Name: "note", Version: 1, Data: []byte("old")},
+		"card/visa":   {Type: "card", Name: "visa", Version: 1},
+		"text/unused": {Type: "text", Name: "unused", Version: 2},
+	}
+	r.Pending = []*Change{
+		{Op: OpUpdate, Entry: Entry{Type: "text", Name: "note", Version: 1, Data: []byte("new")}},
+		{Op: OpDelete, Entry: Entry{Type: "card", Name: "visa", Version: 1}},
+		{Op: OpCreate, Entry: Entry{Type: "binary", Name: "file"}},
+	}
+
+	tests := []struct {
+		name     string
+		dType    string
+		wantKeys []string
+	}{
+		{
+			name:     "all",
+			dType:    "",
+			wantKeys: []string{"binary/file", "text/note", "text/unused"},
+		},
+		{
+			name:     "filtered",
+			dType:    "card",
+			wantKeys: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := make([]string, 0)
+			for _, e := range r.List(tt.dType) {
+				keys = append(keys, key(e.Type, e.Name))
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("Replica.List() = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	e, ok := r.Get("text", "note")
+	if !ok || string(e.Data) != "new" {
+		t.Errorf("Replica.Get() = %v, want the queued value", e)
+	}
+}
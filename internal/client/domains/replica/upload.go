@@ -0,0 +1,281 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+)
+
+// ChunkSize is the size of chunks of the value sent by chunked upload,
+// binary values larger than it are sent by chunks.
+const ChunkSize = 4 << 20
+
+// maxChunkAttempts is the number of attempts to send the chunk,
+// which is rejected by the server because of the wrong checksum.
+const maxChunkAttempts = 3
+
+// uploadState contains the state of chunked upload received from the server.
+type uploadState struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"`
+}
+
+// upload sends the value of the change by chunks and commits it, returns the new
+// version of data object. The identifier of the started upload is saved with the change,
+// so the upload interrupted by the connection loss is continued from the offset
+// received by the server with the next push. If the server rejects the committed value
+// because it was corrupted while sending, the upload is started again once.
+func (r *Replica) upload(ctx context.Context, ch *Change) (int, error) {
+	version, err := r.sendUpload(ctx, ch)
+	if errors.Is(err, errs.ErrChecksumMismatch) && ch.UploadID == "" {
+		version, err = r.sendUpload(ctx, ch)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("upload: %w", err)
+	}
+	return version, nil
+}
+
+// sendUpload starts the upload or continues the started one from the offset
+// received by the server, sends the rest of chunks and commits the upload.
+func (r *Replica) sendUpload(ctx context.Context, ch *Change) (int, error) {
+	var offset int64
+	if ch.UploadID != "" {
+		state, err := r.uploadState(ctx, ch.UploadID)
+		switch {
+		case errors.Is(err, errs.ErrNotExist):
+			// The upload is expired, it is started again
+			ch.UploadID = ""
+		case err != nil:
+			return 0, fmt.Errorf("sendUpload: %w", err)
+		default:
+			offset = state.Offset
+		}
+	}
+
+	if ch.UploadID == "" {
+		state, err := r.initUpload(ctx, ch)
+		if err != nil {
+			return 0, fmt.Errorf("sendUpload: %w", err)
+		}
+		ch.UploadID = state.ID
+		err = r.save()
+		if err != nil {
+			return 0, fmt.Errorf("sendUpload: save replica failed %w", err)
+		}
+	}
+
+	value, err := r.openValue(&ch.Entry)
+	if err != nil {
+		return 0, fmt.Errorf("sendUpload: %w", err)
+	}
+	defer value.Close()
+
+	// Only the chunk being sent is kept in memory
+	size := ch.size()
+	buf := make([]byte, min(ChunkSize, size))
+	attempts := 0
+	for offset < size {
+		chunk := buf[:min(ChunkSize, size-offset)]
+		_, err = value.ReadAt(chunk, offset)
+		if err != nil {
+			return 0, fmt.Errorf("sendUpload: read value failed %w", err)
+		}
+		next, err := r.sendChunk(ctx, ch.UploadID, offset, chunk)
+		if errors.Is(err, errs.ErrChecksumMismatch) && attempts < maxChunkAttempts {
+			attempts++
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("sendUpload: %w", err)
+		}
+		offset = next
+		attempts = 0
+	}
+
+	version, err := r.commitUpload(ctx, ch)
+	if err != nil {
+		return 0, fmt.Errorf("sendUpload: %w", err)
+	}
+	return version, nil
+}
+
+// initUpload starts new upload of the value of the change on the server.
+func (r *Replica) initUpload(ctx context.Context, ch *Change) (*uploadState, error) {
+	body, err := json.Marshal(struct {
+		Name        string          `json:"name"`
+		Type        string          `json:"type"`
+		Metadata    json.RawMessage `json:"metadata,omitempty"`
+		Size        int64           `json:"size"`
+		Checksum    string          `json:"checksum"`
+		Update      bool            `json:"update"`
+		FileName    string          `json:"file_name,omitempty"`
+		ContentType string          `json:"content_type,omitempty"`
+	}{
+		Name:        ch.Name,
+		Type:        ch.Type,
+		Metadata:    ch.Metadata,
+		Size:        ch.size(),
+		Checksum:    ch.checksum(),
+		Update:      ch.Op == OpUpdate,
+		FileName:    ch.FileName,
+		ContentType: ch.ContentType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initUpload: marshal upload failed %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	resp, err := r.doUploadRequest(ctx, http.MethodPost, "", body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("initUpload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("initUpload: %w", err)
+	}
+
+	var state uploadState
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil || state.ID == "" {
+		return nil, fmt.Errorf("initUpload: decode upload failed %w", errs.ErrServerInternal)
+	}
+	return &state, nil
+}
+
+// uploadState requests the server for the state of the upload.
+func (r *Replica) uploadState(ctx context.Context, id string) (*uploadState, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	resp, err := r.doUploadRequest(ctx, http.MethodGet, "/"+id, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("uploadState: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return nil, fmt.Errorf("uploadState: %w", err)
+	}
+
+	var state uploadState
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil {
+		return nil, fmt.Errorf("uploadState: decode upload failed %w", errs.ErrServerInternal)
+	}
+	return &state, nil
+}
+
+// sendChunk sends the chunk of the value at the offset with its checksum and returns
+// the offset of the next chunk. If the server has received another number of bytes,
+// the offset from the server is returned, so the upload is continued from it.
+func (r *Replica) sendChunk(ctx context.Context, id string, offset int64, chunk []byte) (int64, error) {
+	sum := sha256.Sum256(chunk)
+	header := http.Header{}
+	header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	header.Set("Upload-Checksum", "sha256 "+base64.StdEncoding.EncodeToString(sum[:]))
+	header.Set("Content-Type", "application/octet-stream")
+
+	ctx, cancel := context.WithTimeout(ctx, utils.TransferTimeout)
+	defer cancel()
+
+	resp, err := r.doUploadRequest(ctx, http.MethodPut, "/"+id, chunk, header)
+	if err != nil {
+		return 0, fmt.Errorf("sendChunk: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || next < 0 {
+			return 0, fmt.Errorf("sendChunk: incorrect offset from the server %w", errs.ErrServerInternal)
+		}
+		return next, nil
+	}
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return 0, fmt.Errorf("sendChunk: %w", err)
+	}
+
+	return offset + int64(len(chunk)), nil
+}
+
+// commitUpload requests the server to save the uploaded value as data object,
+// returns the new version of data object. The server deletes the upload, if the value
+// was corrupted while sending, so the identifier of the upload is reset.
+func (r *Replica) commitUpload(ctx context.Context, ch *Change) (int, error) {
+	header := http.Header{}
+	if ch.Version > 0 {
+		header.Set("If-Match", utils.ETag(ch.Version))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, utils.TransferTimeout)
+	defer cancel()
+
+	resp, err := r.doUploadRequest(ctx, http.MethodPost, "/"+ch.UploadID+"/commit", nil, header)
+	if err != nil {
+		return 0, fmt.Errorf("commitUpload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		if errors.Is(err, errs.ErrChecksumMismatch) {
+			ch.UploadID = ""
+		}
+		return 0, fmt.Errorf("commitUpload: %w", err)
+	}
+
+	return utils.GetVersionFromETag(resp.Header.Get("ETag")), nil
+}
+
+// doUploadRequest sends the request with the body to the upload endpoint
+// with the path, ErrOffline is returned if the server is unavailable.
+// The context must live until the body of the response is read.
+func (r *Replica) doUploadRequest(ctx context.Context, method string, path string, body []byte,
+	header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.Address+"/api/user/uploads"+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("doUploadRequest: new request failed %w", err)
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if r.cfg.Cookie != nil {
+		req.AddCookie(r.cfg.Cookie)
+	}
+	req.Header.Set(utils.DeviceHeader, r.cfg.Device)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			return nil, fmt.Errorf("doUploadRequest: %w", errs.ErrOffline)
+		}
+		return nil, fmt.Errorf("doUploadRequest: send request failed %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// uploadServer keeps the uploads in memory like the server,
+// the connection is dropped once when the chunk at dropOffset is sent.
+type uploadServer struct {
+	value      []byte
+	received   map[int64]int
+	committed  []byte
+	dropOffset int64
+	dropped    bool
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/user/uploads")
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"upload","offset":0}`))
+	case r.Method == http.MethodGet && path == "/upload":
+		json.NewEncoder(w).Encode(map[string]any{"id": "upload", "offset": len(s.value)})
+	case r.Method == http.MethodPut && path == "/upload":
+		offset, _ := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if offset == s.dropOffset && !s.dropped {
+			s.dropped = true
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		if offset != int64(len(s.value)) {
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(s.value)))
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		s.value = append(s.value, chunk...)
+		s.received[offset]++
+	case r.Method == http.MethodPost && path == "/upload/commit":
+		s.committed = s.value
+		w.Header().Set("ETag", `"1"`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestReplica_upload(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("0123456789"), ChunkSize/4)
+
+	tests := []struct {
+		name       string
+		dropOffset int64
+		spooled    bool
+		wantPushes int
+	}{
+		{
+			name:       "uploaded",
+			dropOffset: -1,
+			wantPushes: 1,
+		},
+		{
+			name:       "resumed",
+			dropOffset: ChunkSize,
+			wantPushes: 2,
+		},
+		{
+			name:       "spooled_resumed",
+			dropOffset: ChunkSize,
+			spooled:    true,
+			wantPushes: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &uploadServer{received: make(map[int64]int), dropOffset: tt.dropOffset}
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			r := NewReplica(ctx, &config.ClientConfig{Address: srv.URL, CacheDir: t.TempDir(), Login: "user"})
+			ch := &Change{Op: OpCreate, Entry: Entry{Type: "binary", Name: "file", Data: value}}
+			if tt.spooled {
+				ch.Blob = spool(t, r, value)
+				ch.Data = nil
+			}
+			r.Pending = []*Change{ch}
+
+			pushes := 0
+			for len(r.Pending) > 0 && pushes < 3 {
+				pushes++
+				_, err := r.Push(ctx)
+				if err != nil && !errors.Is(err, errs.ErrOffline) {
+					t.Fatalf("Replica.Push() error = %v", err)
+				}
+			}
+			if pushes != tt.wantPushes {
+				t.Errorf("Replica.Push() pushes = %v, want %v", pushes, tt.wantPushes)
+			}
+			if !bytes.Equal(s.committed, value) {
+				t.Errorf("Replica.Push() committed %v bytes, want %v", len(s.committed), len(value))
+			}
+			if e, ok := r.Entries["binary/file"]; tt.spooled && (!ok || e.Blob == nil || e.Version != 1) {
+				t.Errorf("Replica.Push() sent value is not kept, entry = %v", e)
+			}
+			for offset, count := range s.received {
+				if count != 1 {
+					t.Errorf("Replica.Push() chunk at %v received %v times", offset, count)
+				}
+			}
+		})
+	}
+}
@@ -15,8 +15,31 @@ import (
 // RWManager contains reader and writer
 // for interacting with input and output.
 type RWManager struct {
-	reader *bufio.Reader
-	writer *bufio.Writer
+	reader    *bufio.Reader
+	writer    *bufio.Writer
+	errWriter *bufio.Writer
+	quiet     bool
+	json      bool
+	result    any
+}
+
+// Option describes the function for setting RWManager options.
+type Option func(m *RWManager)
+
+// WithJSON sets JSON output mode, in which results of commands are not written
+// as text but are kept for writing them as JSON document. The prompts and other
+// text are written into the error output to keep the output for JSON documents only.
+func WithJSON(enabled bool) Option {
+	return func(m *RWManager) {
+		m.json = enabled
+	}
+}
+
+// WithErrOutput sets the separate output for errors.
+func WithErrOutput(errOut io.Writer) Option {
+	return func(m *RWManager) {
+		m.errWriter = bufio.NewWriter(errOut)
+	}
 }
 
 // RWService describes methods for reading data from the input
@@ -26,14 +49,39 @@ type RWService interface {
 	Write(ctx context.Context, out string) error
 	Writeln(ctx context.Context, out string) error
 	Error(ctx context.Context, e error) error
+	WriteResult(ctx context.Context, v any, text string) error
+	WriteDocument(ctx context.Context, doc string) error
+	PopResult(ctx context.Context) any
+	Interactive(ctx context.Context) bool
 }
 
 // NewRWManager creates and returns new RWManager object.
-func NewRWManager(ctx context.Context, in io.Reader, out io.Writer) RWService {
-	return &RWManager{
-		reader: bufio.NewReader(in),
-		writer: bufio.NewWriter(out),
+func NewRWManager(ctx context.Context, in io.Reader, out io.Writer, opts ...Option) RWService {
+	writer := bufio.NewWriter(out)
+	m := &RWManager{
+		reader:    bufio.NewReader(in),
+		writer:    writer,
+		errWriter: writer,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
+}
+
+// NewQuietRWManager creates and returns new RWManager object for the non-interactive
+// mode, which doesn't write prompts and writes errors into the separate output.
+func NewQuietRWManager(ctx context.Context, in io.Reader, out io.Writer, errOut io.Writer, opts ...Option) RWService {
+	m := &RWManager{
+		reader:    bufio.NewReader(in),
+		writer:    bufio.NewWriter(out),
+		errWriter: bufio.NewWriter(errOut),
+		quiet:     true,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Read reads data from the input and returns it.
@@ -51,30 +99,78 @@ func (m *RWManager) Read(ctx context.Context) (string, error) {
 
 // Write writes the requested text into the output.
 func (m *RWManager) Write(ctx context.Context, out string) error {
-	_, err := fmt.Fprintf(m.writer, "%s", out)
+	if m.quiet {
+		return nil
+	}
+	w := m.textWriter()
+	_, err := fmt.Fprintf(w, "%s", out)
 	if err != nil {
 		return fmt.Errorf("Write: print into the output failed %w", err)
 	}
-	m.writer.Flush()
+	w.Flush()
 	return nil
 }
 
 // Writeln writes the requested text into the output from the new line.
 func (m *RWManager) Writeln(ctx context.Context, out string) error {
-	_, err := fmt.Fprintf(m.writer, "%s\n", out)
+	w := m.textWriter()
+	_, err := fmt.Fprintf(w, "%s\n", out)
 	if err != nil {
 		return fmt.Errorf("WriteString: print into the output failed %w", err)
 	}
-	m.writer.Flush()
+	w.Flush()
 	return nil
 }
 
 // Error writes error into the output.
 func (m *RWManager) Error(ctx context.Context, e error) error {
-	_, err := fmt.Fprintf(m.writer, "%s\n", e.Error())
+	_, err := fmt.Fprintf(m.errWriter, "%s\n", e.Error())
 	if err != nil {
 		return fmt.Errorf("Error: print into the output failed %w", err)
 	}
+	m.errWriter.Flush()
+	return nil
+}
+
+// WriteResult keeps the result of the command and writes its text
+// representation into the output, if JSON output mode is disabled.
+func (m *RWManager) WriteResult(ctx context.Context, v any, text string) error {
+	m.result = v
+	if m.json {
+		return nil
+	}
+	return m.Writeln(ctx, text)
+}
+
+// WriteDocument writes the document with the result of the command
+// into the output in any output mode.
+func (m *RWManager) WriteDocument(ctx context.Context, doc string) error {
+	_, err := fmt.Fprintf(m.writer, "%s\n", doc)
+	if err != nil {
+		return fmt.Errorf("WriteDocument: print into the output failed %w", err)
+	}
 	m.writer.Flush()
 	return nil
 }
+
+// PopResult returns the kept result of the last command and forgets it.
+func (m *RWManager) PopResult(ctx context.Context) any {
+	v := m.result
+	m.result = nil
+	return v
+}
+
+// Interactive reports whether the user answers the prompts,
+// the answers are prepared in the non-interactive mode.
+func (m *RWManager) Interactive(ctx context.Context) bool {
+	return !m.quiet
+}
+
+// textWriter returns the writer for the text which is not the result
+// of the command, it is the error output in JSON mode.
+func (m *RWManager) textWriter() *bufio.Writer {
+	if m.json {
+		return m.errWriter
+	}
+	return m.writer
+}
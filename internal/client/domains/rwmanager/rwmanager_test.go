@@ -0,0 +1,56 @@
+package rwmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRWManager_JSONOutput(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		json    bool
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "text",
+			json:    false,
+			wantOut: "Login: Logged in as user\nSuccess\n{}\n",
+			wantErr: "failed\n",
+		},
+		{
+			name:    "json",
+			json:    true,
+			wantOut: "{}\n",
+			wantErr: "Login: Logged in as user\nfailed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var errOut bytes.Buffer
+			rw := NewRWManager(ctx, strings.NewReader(""), &out, WithJSON(tt.json), WithErrOutput(&errOut))
+
+			rw.Write(ctx, "Login: ")
+			rw.Writeln(ctx, "Logged in as user")
+			rw.WriteResult(ctx, "ok", "Success")
+			rw.Error(ctx, errors.New("failed"))
+			rw.WriteDocument(ctx, "{}")
+
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+			if errOut.String() != tt.wantErr {
+				t.Errorf("error output = %q, want %q", errOut.String(), tt.wantErr)
+			}
+			if got := rw.PopResult(ctx); got != "ok" {
+				t.Errorf("PopResult() = %v, want %v", got, "ok")
+			}
+		})
+	}
+}
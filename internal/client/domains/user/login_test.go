@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+func TestUserService_Login_TwoFactor(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, time.Now().Add(time.Hour))
+
+	// The server requires the one-time code after the password
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/user/login":
+			var u User
+			json.NewDecoder(r.Body).Decode(&u)
+			switch u.Code {
+			case "":
+				w.WriteHeader(http.StatusAccepted)
+			case "123456":
+				http.SetCookie(w, &http.Cookie{Name: "auth", Value: token, Path: "/api/user"})
+				w.WriteHeader(http.StatusOK)
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		case "/api/user/salt":
+			w.Write([]byte(`{"salt":"c2FsdHNhbHRzYWx0c2FsdA=="}`))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		input      string
+		code       *string
+		wantErr    error
+		wantCookie bool
+	}{
+		{
+			name:       "prompted_code",
+			input:      "user\npassword\n123456\nmaster\n",
+			wantErr:    nil,
+			wantCookie: true,
+		},
+		{
+			name:       "preset_code",
+			input:      "user\npassword\nmaster\n",
+			code:       func() *string { c := "123456"; return &c }(),
+			wantErr:    nil,
+			wantCookie: true,
+		},
+		{
+			name:       "missing_code",
+			input:      "user\npassword\nmaster\n",
+			code:       func() *string { c := ""; return &c }(),
+			wantErr:    errs.ErrCodeRequired,
+			wantCookie: false,
+		},
+		{
+			name:       "invalid_code",
+			input:      "user\npassword\n000000\nmaster\n",
+			wantErr:    errs.ErrUnauthorized,
+			wantCookie: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClientConfig{Address: srv.URL}
+			rw := rwmanager.NewRWManager(ctx, strings.NewReader(tt.input), io.Discard)
+			s := NewUserService(ctx, rw, cfg)
+			if tt.code != nil {
+				s.SetCode(*tt.code)
+			}
+
+			err := s.Login(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (cfg.Cookie != nil) != tt.wantCookie {
+				t.Errorf("UserService.Login() cookie = %v, wantCookie %v", cfg.Cookie, tt.wantCookie)
+			}
+		})
+	}
+}
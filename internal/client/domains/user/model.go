@@ -2,16 +2,41 @@
 // for user registration, login and authorization.
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // User contains information about user.
 type User struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Code     string `json:"code,omitempty"`
+}
+
+// ActiveSession contains information about the active login session
+// of the user and its client device.
+type ActiveSession struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
 // Service describes methods related with user.
 type Service interface {
 	Register(ctx context.Context) error
 	Login(ctx context.Context) error
+	Resume(ctx context.Context) error
+	Logout(ctx context.Context) error
+	Sessions(ctx context.Context) error
+	RevokeSession(ctx context.Context) error
+	ChangePassword(ctx context.Context) error
+	Unregister(ctx context.Context) error
+	EnableTwoFactor(ctx context.Context) error
+	DisableTwoFactor(ctx context.Context) error
 }
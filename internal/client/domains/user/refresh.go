@@ -0,0 +1,107 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/client/utils"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// RefreshTransport contains the transport which refreshes the expired
+// access token with the refresh token and repeats the rejected request.
+type RefreshTransport struct {
+	mu   sync.Mutex
+	base http.RoundTripper
+	cfg  *config.ClientConfig
+}
+
+// NewRefreshTransport creates and returns new refresh transport
+// over the base transport.
+func NewRefreshTransport(ctx context.Context, cfg *config.ClientConfig, base http.RoundTripper) *RefreshTransport {
+	return &RefreshTransport{
+		base: base,
+		cfg:  cfg,
+	}
+}
+
+// RoundTrip sends the request. If the server doesn't accept the access token,
+// the token is refreshed and the request is sent again with the new token.
+func (t *RefreshTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !t.canRetry(r) {
+		return resp, err
+	}
+
+	err = t.refresh(r)
+	if err != nil {
+		return resp, nil
+	}
+
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		retry.Body, err = r.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+
+	retry.Header.Del("Cookie")
+	retry.AddCookie(t.cfg.Cookie)
+	return t.base.RoundTrip(retry)
+}
+
+// canRetry checks whether the request with the access token can be repeated.
+func (t *RefreshTransport) canRetry(r *http.Request) bool {
+	if t.cfg.RefreshCookie == nil || strings.HasSuffix(r.URL.Path, "/api/user/token/refresh") {
+		return false
+	}
+	if _, err := r.Cookie("auth"); err != nil {
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// refresh requests the server for new access and refresh tokens, unless
+// the token has already been refreshed after the request was sent.
+func (t *RefreshTransport) refresh(r *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sent, _ := r.Cookie("auth")
+	if t.cfg.Cookie != nil && sent.Value != t.cfg.Cookie.Value {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	target := t.cfg.Address + "/api/user/token/refresh"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return fmt.Errorf("refresh: new request failed %w", err)
+	}
+	req.AddCookie(t.cfg.RefreshCookie)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("refresh: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("refresh: %w", err)
+	}
+
+	setCookies(t.cfg, resp)
+
+	// If the session is not saved, the user only has to login after restart
+	SaveSession(ctx, t.cfg)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+func TestRefreshTransport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	refreshed := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/user/token/refresh" {
+			c, err := r.Cookie("refresh")
+			if err != nil || c.Value != "refresh-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			refreshed++
+			http.SetCookie(w, &http.Cookie{Name: "auth", Value: "access-2", Path: "/api/user"})
+			http.SetCookie(w, &http.Cookie{Name: "refresh", Value: "refresh-2", Path: "/api/user"})
+			return
+		}
+		c, err := r.Cookie("auth")
+		if err != nil || c.Value != "access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	cfg := &config.ClientConfig{
+		Address:       srv.URL,
+		Cookie:        &http.Cookie{Name: "auth", Value: "access-1"},
+		RefreshCookie: &http.Cookie{Name: "refresh", Value: "refresh-1"},
+	}
+	client := &http.Client{Transport: NewRefreshTransport(ctx, cfg, http.DefaultTransport)}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/api/user/data/text/note",
+		strings.NewReader("value"))
+	if err != nil {
+		t.Fatalf("new request failed %v", err)
+	}
+	req.AddCookie(cfg.Cookie)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("RefreshTransport.RoundTrip() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || string(body) != "value" {
+		t.Errorf("RefreshTransport.RoundTrip() = %v %q, want %v %q", resp.StatusCode, body, http.StatusOK, "value")
+	}
+	if refreshed != 1 {
+		t.Errorf("RefreshTransport.RoundTrip() refreshed %d times, want 1", refreshed)
+	}
+	if cfg.Cookie.Value != "access-2" || cfg.RefreshCookie.Value != "refresh-2" {
+		t.Errorf("RefreshTransport.RoundTrip() cookies = %v %v, want the new tokens",
+			cfg.Cookie.Value, cfg.RefreshCookie.Value)
+	}
+}
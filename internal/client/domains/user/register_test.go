@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+func TestUserService_Register_Policy(t *testing.T) {
+	ctx := context.Background()
+	token := newTestToken(t, time.Now().Add(time.Hour))
+
+	// The server accepts only the long password
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/user/register":
+			var u User
+			json.NewDecoder(r.Body).Decode(&u)
+			if len(u.Password) < 8 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"violations":[{"field":"password","rule":"password_length",` +
+					`"message":"password must contain from 8 characters to 72 bytes"}]}`))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "auth", Value: token, Path: "/api/user"})
+			w.WriteHeader(http.StatusOK)
+		case "/api/user/salt":
+			w.Write([]byte(`{"salt":"c2FsdHNhbHRzYWx0c2FsdA=="}`))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		input       string
+		interactive bool
+		wantErr     error
+		wantErrOut  string
+	}{
+		{
+			name:        "retry",
+			input:       "user\nshort\nuser\nlong-password\nmaster\nmaster\n",
+			interactive: true,
+			wantErr:     nil,
+			wantErrOut:  "password must contain from 8 characters to 72 bytes",
+		},
+		{
+			name:        "non_interactive",
+			input:       "user\nshort\nmaster\nmaster\n",
+			interactive: false,
+			wantErr:     errs.ErrPolicyViolation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errOut strings.Builder
+			rw := rwmanager.NewQuietRWManager(ctx, strings.NewReader(tt.input), io.Discard, &errOut)
+			if tt.interactive {
+				rw = rwmanager.NewRWManager(ctx, strings.NewReader(tt.input), &errOut)
+			}
+			cfg := &config.ClientConfig{Address: srv.URL}
+			s := NewUserService(ctx, rw, cfg)
+
+			err := s.Register(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(errOut.String(), tt.wantErrOut) {
+				t.Errorf("UserService.Register() output = %q, want %q", errOut.String(), tt.wantErrOut)
+			}
+			if (cfg.Cookie != nil) != (tt.wantErr == nil) {
+				t.Errorf("UserService.Register() cookie = %v", cfg.Cookie)
+			}
+		})
+	}
+}
@@ -4,19 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/pavlegich/gophkeeper/internal/client/domains/replica"
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 	"github.com/pavlegich/gophkeeper/internal/client/utils"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
 )
 
 // UserService contains objects for user service.
 type UserService struct {
-	rw  rwmanager.RWService
-	cfg *config.ClientConfig
+	rw   rwmanager.RWService
+	cfg  *config.ClientConfig
+	code *string
 }
 
 // NewUserService creates and returns new user service.
@@ -27,112 +35,746 @@ func NewUserService(ctx context.Context, rw rwmanager.RWService, cfg *config.Cli
 	}
 }
 
+// RegisterAttempts is the number of attempts to choose login and password,
+// which meet the requirements of the server.
+const RegisterAttempts = 3
+
 // Register requests the server for user registration.
 func (s *UserService) Register(ctx context.Context) error {
 	u := &User{}
+
+	for attempt := 1; ; attempt++ {
+		err := s.readCredentials(ctx, u)
+		if err != nil {
+			return fmt.Errorf("Register: %w", err)
+		}
+
+		err = s.register(ctx, u)
+		if err == nil {
+			break
+		}
+
+		// The user can choose other login and password, if they violate the policy
+		if !errors.Is(err, errs.ErrPolicyViolation) || !s.rw.Interactive(ctx) || attempt == RegisterAttempts {
+			return fmt.Errorf("Register: %w", err)
+		}
+		s.rw.Error(ctx, utils.GetKnownErr(err))
+	}
+	s.cfg.Login = u.Login
+
+	err := s.unlock(ctx, true)
+	if err != nil {
+		return fmt.Errorf("Register: unlock encryption failed %w", err)
+	}
+
+	err = SaveSession(ctx, s.cfg)
+	if err != nil {
+		s.rw.Error(ctx, fmt.Errorf("couldn't save the session, login is required after restart"))
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Login requests the server for user login.
+func (s *UserService) Login(ctx context.Context) error {
+	u := &User{}
+	err := s.readCredentials(ctx, u)
+	if err != nil {
+		return fmt.Errorf("Login: %w", err)
+	}
+
+	accepted, err := s.login(ctx, u)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			offlineErr := s.unlockOffline(ctx, u.Login)
+			if offlineErr == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("Login: %w", err)
+	}
+
+	// The second step of login with the one-time code
+	if accepted {
+		u.Code, err = s.readCode(ctx)
+		if err != nil {
+			return fmt.Errorf("Login: %w", err)
+		}
+		_, err = s.login(ctx, u)
+		if err != nil {
+			return fmt.Errorf("Login: %w", err)
+		}
+	}
+
+	s.cfg.Login = u.Login
+
+	err = s.unlock(ctx, false)
+	if err != nil {
+		return fmt.Errorf("Login: unlock encryption failed %w", err)
+	}
+
+	err = SaveSession(ctx, s.cfg)
+	if err != nil {
+		s.rw.Error(ctx, fmt.Errorf("couldn't save the session, login is required after restart"))
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// SetCode sets the one-time code for the second step of login instead of
+// reading it from the input, e.g. in the non-interactive mode. If the code
+// is empty and the server requires it, login fails.
+func (s *UserService) SetCode(code string) {
+	s.code = &code
+}
+
+// readCredentials reads the login and the password of the user from the input.
+func (s *UserService) readCredentials(ctx context.Context, u *User) error {
 	var err error
 
 	s.rw.Write(ctx, "Login: ")
 	u.Login, err = s.rw.Read(ctx)
 	if err != nil {
-		return fmt.Errorf("Register: couldn't read login %w", err)
+		return fmt.Errorf("readCredentials: couldn't read login %w", err)
 	}
 
 	s.rw.Write(ctx, "Password: ")
 	u.Password, err = s.rw.Read(ctx)
 	if err != nil {
-		return fmt.Errorf("Register: couldn't read password %w", err)
+		return fmt.Errorf("readCredentials: couldn't read password %w", err)
+	}
+	return nil
+}
+
+// register sends one registration request and sets the session cookies
+// from the response, the request context is canceled before the next attempt.
+func (s *UserService) register(ctx context.Context, u *User) error {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	resp, err := s.sendRegister(ctx, u)
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("register: user register failed %w", err)
 	}
 
+	s.cfg.Cookie = nil
+	s.cfg.RefreshCookie = nil
+	setCookies(s.cfg, resp)
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("register: cookie not found")
+	}
+	return nil
+}
+
+// sendRegister sends the credentials of the new user to the server.
+func (s *UserService) sendRegister(ctx context.Context, u *User) (*http.Response, error) {
 	body, err := json.Marshal(u)
 	if err != nil {
-		return fmt.Errorf("Register: marshal user failed %w", err)
+		return nil, fmt.Errorf("sendRegister: marshal user failed %w", err)
 	}
 
 	target := s.cfg.Address + "/api/user/register"
-	ctxReq, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctxReq, http.MethodPost, target, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("sendRegister: new request failed %w", err)
+	}
+	req.Header.Set(utils.DeviceHeader, s.cfg.Device)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("sendRegister: send request failed %w", err)
+	}
+	return resp, nil
+}
+
+// sendLogin sends the user's credentials to the server.
+func (s *UserService) sendLogin(ctx context.Context, u *User) (*http.Response, error) {
+	body, err := json.Marshal(u)
+	if err != nil {
+		return nil, fmt.Errorf("sendLogin: marshal user failed %w", err)
+	}
+
+	target := s.cfg.Address + "/api/user/login"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("Register: new request failed %w", err)
+		return nil, fmt.Errorf("sendLogin: new request failed %w", err)
 	}
+	req.Header.Set(utils.DeviceHeader, s.cfg.Device)
 
 	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("Register: send request failed %w", err)
+		return nil, fmt.Errorf("sendLogin: send request failed %w", err)
+	}
+	return resp, nil
+}
+
+// login sends one login request and sets the session cookies from the response.
+// Returns true, if the server requires the one-time code, the request context
+// is canceled before the code is read, so the user has no time limit for it.
+func (s *UserService) login(ctx context.Context, u *User) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	resp, err := s.sendLogin(ctx, u)
+	if err != nil {
+		return false, fmt.Errorf("login: %w", err)
 	}
 	defer resp.Body.Close()
 
-	err = utils.CheckStatusCode(resp.StatusCode)
+	if resp.StatusCode == http.StatusAccepted {
+		return true, nil
+	}
+
+	err = utils.CheckStatusCode(resp)
 	if err != nil {
-		return fmt.Errorf("Register: user register failed %w", err)
+		return false, fmt.Errorf("login: user login failed %w", err)
 	}
 
-	for _, c := range resp.Cookies() {
-		if c.Name == "auth" && c != nil {
-			s.cfg.Cookie = c
+	s.cfg.Cookie = nil
+	s.cfg.RefreshCookie = nil
+	setCookies(s.cfg, resp)
+	if s.cfg.Cookie == nil {
+		return false, fmt.Errorf("login: cookie not found")
+	}
+	return false, nil
+}
+
+// readCode reads the one-time code or the recovery code from the input.
+func (s *UserService) readCode(ctx context.Context) (string, error) {
+	if s.code != nil {
+		if *s.code == "" {
+			return "", fmt.Errorf("readCode: %w", errs.ErrCodeRequired)
 		}
+		return *s.code, nil
+	}
+
+	s.rw.Write(ctx, "One-time code: ")
+	code, err := s.rw.Read(ctx)
+	if err != nil {
+		return "", fmt.Errorf("readCode: couldn't read one-time code %w", err)
+	}
+	return code, nil
+}
+
+// EnableTwoFactor requests the server for the secret of two-factor authentication,
+// shows it for adding into the authenticator application, reads the one-time code
+// for confirmation and writes the recovery codes.
+func (s *UserService) EnableTwoFactor(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("EnableTwoFactor: %w", errs.ErrUnauthorized)
+	}
+
+	var enrollment struct {
+		URI    string `json:"uri"`
+		Secret string `json:"secret"`
+	}
+	err := s.sendJSON(ctx, http.MethodPost, "/api/user/2fa/enroll", nil, &enrollment)
+	if err != nil {
+		return fmt.Errorf("EnableTwoFactor: enroll failed %w", err)
+	}
+
+	s.rw.Write(ctx, "Add the key into the authenticator application:\n"+
+		enrollment.URI+"\nSecret: "+enrollment.Secret+"\n")
+	s.rw.Write(ctx, "One-time code: ")
+	code, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("EnableTwoFactor: couldn't read one-time code %w", err)
 	}
-	if s.cfg == nil {
-		return fmt.Errorf("Register: cookie not found")
+
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	err = s.sendJSON(ctx, http.MethodPost, "/api/user/2fa/confirm", &User{Code: code}, &confirmed)
+	if err != nil {
+		return fmt.Errorf("EnableTwoFactor: confirm failed %w", err)
 	}
 
-	s.rw.Writeln(ctx, utils.Success)
+	s.rw.WriteResult(ctx, confirmed.RecoveryCodes, "Recovery codes, keep them in a safe place:\n"+
+		strings.Join(confirmed.RecoveryCodes, "\n"))
 
 	return nil
 }
 
-// Login requests the server for user login.
-func (s *UserService) Login(ctx context.Context) error {
-	u := &User{}
+// DisableTwoFactor reads the one-time code or the recovery code
+// and requests the server to disable two-factor authentication.
+func (s *UserService) DisableTwoFactor(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("DisableTwoFactor: %w", errs.ErrUnauthorized)
+	}
+
+	s.rw.Write(ctx, "One-time or recovery code: ")
+	code, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("DisableTwoFactor: couldn't read code %w", err)
+	}
+
+	err = s.sendJSON(ctx, http.MethodDelete, "/api/user/2fa", &User{Code: code}, nil)
+	if err != nil {
+		return fmt.Errorf("DisableTwoFactor: %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// ChangePassword reads the current and the new passwords and requests the server
+// to change the password. Other sessions of the user are ended by the server.
+func (s *UserService) ChangePassword(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("ChangePassword: %w", errs.ErrUnauthorized)
+	}
+
+	var change struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
 	var err error
 
-	s.rw.Write(ctx, "Login: ")
-	u.Login, err = s.rw.Read(ctx)
+	s.rw.Write(ctx, "Current password: ")
+	change.OldPassword, err = s.rw.Read(ctx)
 	if err != nil {
-		return fmt.Errorf("Login: couldn't read login %w", err)
+		return fmt.Errorf("ChangePassword: couldn't read current password %w", err)
 	}
+	s.rw.Write(ctx, "New password: ")
+	change.NewPassword, err = s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: couldn't read new password %w", err)
+	}
+	s.rw.Write(ctx, "Repeat new password: ")
+	repeated, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: couldn't read new password %w", err)
+	}
+	if repeated != change.NewPassword {
+		return fmt.Errorf("ChangePassword: %w", errs.ErrPasswordMismatch)
+	}
+
+	err = s.sendJSON(ctx, http.MethodPost, "/api/user/password", &change, nil)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Unregister reads the password and, if the server requires it, the one-time code,
+// and requests the server to delete the user with all the user's data. The stored
+// session and the local replica of the user are removed.
+func (s *UserService) Unregister(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("Unregister: %w", errs.ErrUnauthorized)
+	}
+
+	u := &User{}
+	var err error
 
 	s.rw.Write(ctx, "Password: ")
 	u.Password, err = s.rw.Read(ctx)
 	if err != nil {
-		return fmt.Errorf("Login: couldn't read password %w", err)
+		return fmt.Errorf("Unregister: couldn't read password %w", err)
 	}
 
-	body, err := json.Marshal(u)
+	err = s.sendJSON(ctx, http.MethodDelete, "/api/user", u, nil)
+	if errors.Is(err, errs.ErrCodeRequired) {
+		u.Code, err = s.readCode(ctx)
+		if err != nil {
+			return fmt.Errorf("Unregister: %w", err)
+		}
+		err = s.sendJSON(ctx, http.MethodDelete, "/api/user", u, nil)
+	}
 	if err != nil {
-		return fmt.Errorf("Login: marshal user failed %w", err)
+		return fmt.Errorf("Unregister: %w", err)
 	}
 
-	target := s.cfg.Address + "/api/user/login"
+	// The user is already deleted, so the local files are removed as far as possible
+	err = replica.RemoveCache(s.cfg, s.cfg.Login)
+	if err != nil {
+		s.rw.Error(ctx, fmt.Errorf("couldn't remove the local copy of data"))
+	}
+	err = RemoveSession(ctx, s.cfg)
+	if err != nil {
+		s.rw.Error(ctx, fmt.Errorf("couldn't remove the stored session"))
+	}
+	s.cfg.Cookie = nil
+	s.cfg.RefreshCookie = nil
+	s.cfg.Cipher = nil
+	s.cfg.Login = ""
+	s.cfg.Salt = nil
+	s.cfg.KeyCheck = nil
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// sendJSON sends the request of the logged in user with the value in JSON format
+// and decodes the response body into the result, if it is not nil. ErrCodeRequired
+// is returned, if the server requires the one-time code for the request.
+func (s *UserService) sendJSON(ctx context.Context, method string, path string, v any, result any) error {
+	var body bytes.Buffer
+	if v != nil {
+		err := json.NewEncoder(&body).Encode(v)
+		if err != nil {
+			return fmt.Errorf("sendJSON: marshal request failed %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Address+path, &body)
+	if err != nil {
+		return fmt.Errorf("sendJSON: new request failed %w", err)
+	}
+	req.AddCookie(s.cfg.Cookie)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		return fmt.Errorf("sendJSON: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return fmt.Errorf("sendJSON: %w", errs.ErrCodeRequired)
+	}
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("sendJSON: %w", err)
+	}
+
+	if result != nil {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return fmt.Errorf("sendJSON: decode response failed %w", err)
+		}
+	}
+	return nil
+}
+
+// Resume continues the session loaded from the session file: reads the master
+// password and derives the key. If the server doesn't accept the session,
+// the session is removed and the user has to login again.
+func (s *UserService) Resume(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("Resume: %w", errs.ErrUnauthorized)
+	}
+
+	s.rw.Writeln(ctx, "Logged in as "+s.cfg.Login)
+	err := s.unlock(ctx, false)
+	if err != nil {
+		if utils.IsConnectionError(err) {
+			offlineErr := s.unlockOffline(ctx, s.cfg.Login)
+			if offlineErr == nil {
+				return nil
+			}
+		}
+		if errors.Is(err, errs.ErrUnauthorized) {
+			s.cfg.Cookie = nil
+			s.cfg.RefreshCookie = nil
+			s.cfg.Login = ""
+			RemoveSession(ctx, s.cfg)
+			return fmt.Errorf("Resume: %w", errs.ErrSessionExpired)
+		}
+		return fmt.Errorf("Resume: unlock encryption failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// Logout requests the server for user logout, removes the stored session
+// and forgets the user's key. The user is logged out locally even if
+// the server is unavailable.
+func (s *UserService) Logout(ctx context.Context) error {
+	if s.cfg.Cookie == nil && s.cfg.Login == "" {
+		return fmt.Errorf("Logout: %w", errs.ErrUnauthorized)
+	}
+
+	if s.cfg.Cookie != nil {
+		err := s.logout(ctx)
+		if err != nil && !utils.IsConnectionError(err) && !errors.Is(err, errs.ErrUnauthorized) {
+			return fmt.Errorf("Logout: %w", err)
+		}
+	}
+
+	s.cfg.Cookie = nil
+	s.cfg.RefreshCookie = nil
+	s.cfg.Cipher = nil
+	s.cfg.Login = ""
+	s.cfg.Salt = nil
+	s.cfg.KeyCheck = nil
+
+	err := RemoveSession(ctx, s.cfg)
+	if err != nil {
+		return fmt.Errorf("Logout: %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// logout requests the server for ending the session.
+func (s *UserService) logout(ctx context.Context) error {
+	target := s.cfg.Address + "/api/user/logout"
+	ctxReq, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctxReq, http.MethodPost, target, nil)
+	if err != nil {
+		return fmt.Errorf("logout: new request failed %w", err)
+	}
+	req.AddCookie(s.cfg.Cookie)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		return fmt.Errorf("logout: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("logout: %w", err)
+	}
+
+	return nil
+}
+
+// Sessions requests the server for the active sessions of the user
+// and writes them as a table.
+func (s *UserService) Sessions(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("Sessions: %w", errs.ErrUnauthorized)
+	}
+
+	target := s.cfg.Address + "/api/user/sessions"
+	ctxReq, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctxReq, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("Sessions: new request failed %w", err)
+	}
+	req.AddCookie(s.cfg.Cookie)
+
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		return fmt.Errorf("Sessions: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("Sessions: get sessions failed %w", err)
+	}
+
+	var sessions []*ActiveSession
+	err = json.NewDecoder(resp.Body).Decode(&sessions)
+	if err != nil {
+		return fmt.Errorf("Sessions: decode sessions failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, sessions, formatSessionsTable(sessions))
+
+	return nil
+}
+
+// RevokeSession reads the session identifier from the input and requests
+// the server to revoke the session, e.g. the session of the lost device.
+func (s *UserService) RevokeSession(ctx context.Context) error {
+	if s.cfg.Cookie == nil {
+		return fmt.Errorf("RevokeSession: %w", errs.ErrUnauthorized)
+	}
+
+	s.rw.Write(ctx, "Session: ")
+	id, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("RevokeSession: couldn't read session %w", err)
+	}
+
+	target := s.cfg.Address + "/api/user/sessions/" + url.PathEscape(id)
 	ctxReq, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
-	req, err := http.NewRequestWithContext(ctxReq, http.MethodPost, target, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctxReq, http.MethodDelete, target, nil)
 	if err != nil {
-		return fmt.Errorf("Login: new request failed %w", err)
+		return fmt.Errorf("RevokeSession: new request failed %w", err)
 	}
+	req.AddCookie(s.cfg.Cookie)
 
 	resp, err := utils.DoRequestWithRetry(ctx, req)
 	if err != nil {
-		return fmt.Errorf("Login: send request failed %w", err)
+		return fmt.Errorf("RevokeSession: send request failed %w", err)
 	}
 	defer resp.Body.Close()
 
-	err = utils.CheckStatusCode(resp.StatusCode)
+	err = utils.CheckStatusCode(resp)
 	if err != nil {
-		return fmt.Errorf("Login: user register failed %w", err)
+		return fmt.Errorf("RevokeSession: revoke session failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// formatSessionsTable returns information about sessions formatted as a table,
+// the current session is marked with asterisk.
+func formatSessionsTable(sessions []*ActiveSession) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDEVICE\tIP\tLAST USED\tCREATED")
+	for _, sess := range sessions {
+		id := sess.ID
+		if sess.Current {
+			id = "*" + id
+		}
+		device := sess.Device
+		if device == "" {
+			device = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", id, device, sess.IP,
+			sess.LastUsedAt.Format("02/01/2006 15:04:05"), sess.CreatedAt.Format("02/01/2006 15:04:05"))
 	}
+	tw.Flush()
 
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// setCookies keeps the cookies with access and refresh tokens from the response.
+func setCookies(cfg *config.ClientConfig, resp *http.Response) {
 	for _, c := range resp.Cookies() {
-		if c.Name == "auth" && c != nil {
-			s.cfg.Cookie = c
+		switch c.Name {
+		case "auth":
+			cfg.Cookie = c
+		case "refresh":
+			cfg.RefreshCookie = c
 		}
 	}
-	if s.cfg == nil {
-		return fmt.Errorf("Login: cookie not found")
+}
+
+// unlock requests the user's salt and key check value from the server, reads
+// the master password from the input and derives the key for encrypting the user's
+// data. If confirm is true, master password is requested twice. If the user has
+// no key check value yet, it is created with the derived key and saved on the server.
+func (s *UserService) unlock(ctx context.Context, confirm bool) error {
+	target := s.cfg.Address + "/api/user/salt"
+	ctxReq, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctxReq, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("unlock: new request failed %w", err)
 	}
+	req.AddCookie(s.cfg.Cookie)
 
-	s.rw.Writeln(ctx, utils.Success)
+	resp, err := utils.DoRequestWithRetry(ctx, req)
+	if err != nil {
+		return fmt.Errorf("unlock: send request failed %w", err)
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckStatusCode(resp)
+	if err != nil {
+		return fmt.Errorf("unlock: get salt failed %w", err)
+	}
+
+	var body struct {
+		Salt     []byte `json:"salt"`
+		KeyCheck []byte `json:"key_check"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return fmt.Errorf("unlock: decode salt failed %w", err)
+	}
+
+	err = s.deriveKey(ctx, body.Salt, body.KeyCheck, confirm)
+	if err != nil {
+		return fmt.Errorf("unlock: derive key failed %w", err)
+	}
+	if body.KeyCheck != nil {
+		return nil
+	}
+
+	keyCheck, err := s.cfg.Cipher.NewKeyCheck()
+	if err == nil {
+		err = s.sendJSON(ctx, http.MethodPut, "/api/user/salt", map[string][]byte{"key_check": keyCheck}, nil)
+	}
+	if err != nil {
+		s.cfg.Cipher = nil
+		return fmt.Errorf("unlock: save key check failed %w", err)
+	}
+	s.cfg.KeyCheck = keyCheck
+
+	return nil
+}
+
+// unlockOffline reads the master password from the input and derives the key
+// with the user's salt stored in the local cache, so the user can work with
+// the local copy of data when the server is unavailable.
+func (s *UserService) unlockOffline(ctx context.Context, login string) error {
+	salt, keyCheck, err := replica.CachedSalt(s.cfg, login)
+	if err != nil {
+		return fmt.Errorf("unlockOffline: get cached salt failed %w", err)
+	}
+
+	s.rw.Error(ctx, fmt.Errorf("%w, the local copy is used", errs.ErrOffline))
+	s.cfg.Cookie = nil
+	s.cfg.Login = login
+
+	err = s.deriveKey(ctx, salt, keyCheck, false)
+	if err != nil {
+		return fmt.Errorf("unlockOffline: derive key failed %w", err)
+	}
+
+	s.rw.WriteResult(ctx, nil, utils.Success)
+
+	return nil
+}
+
+// deriveKey reads the master password from the input and derives the key
+// for encrypting the user's data. If confirm is true, master password is requested twice.
+// The key is verified by the key check value, if the user has it, and the wrong
+// master password is rejected before any data is decrypted or encrypted.
+func (s *UserService) deriveKey(ctx context.Context, salt []byte, keyCheck []byte, confirm bool) error {
+	s.rw.Write(ctx, "Master password: ")
+	password, err := s.rw.Read(ctx)
+	if err != nil {
+		return fmt.Errorf("deriveKey: couldn't read master password %w", err)
+	}
+	if confirm {
+		s.rw.Write(ctx, "Repeat master password: ")
+		repeated, err := s.rw.Read(ctx)
+		if err != nil {
+			return fmt.Errorf("deriveKey: couldn't read master password %w", err)
+		}
+		if repeated != password {
+			return fmt.Errorf("deriveKey: %w", errs.ErrPasswordMismatch)
+		}
+	}
+
+	cipher, err := encryption.NewCipher(password, salt)
+	if err != nil {
+		return fmt.Errorf("deriveKey: create cipher failed %w", err)
+	}
+	if keyCheck != nil {
+		err = cipher.CheckKey(keyCheck)
+		if err != nil {
+			return fmt.Errorf("deriveKey: %w", errs.ErrWrongMaster)
+		}
+	}
+	s.cfg.Cipher = cipher
+	s.cfg.Salt = salt
+	s.cfg.KeyCheck = keyCheck
 
 	return nil
 }
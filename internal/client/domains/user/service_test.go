@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
@@ -40,3 +41,43 @@ func TestNewUserService(t *testing.T) {
 		})
 	}
 }
+
+func Test_formatSessionsTable(t *testing.T) {
+	used := time.Date(2024, time.January, 31, 9, 15, 30, 0, time.UTC)
+	created := time.Date(2024, time.January, 30, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		sessions []*ActiveSession
+		want     string
+	}{
+		{
+			name: "ok",
+			sessions: []*ActiveSession{
+				{
+					ID:         "a1",
+					Device:     "laptop",
+					IP:         "192.0.2.1",
+					CreatedAt:  created,
+					LastUsedAt: used,
+					Current:    true,
+				},
+				{
+					ID:         "b2",
+					IP:         "192.0.2.7",
+					CreatedAt:  created,
+					LastUsedAt: created,
+				},
+			},
+			want: "ID   DEVICE  IP         LAST USED            CREATED\n" +
+				"*a1  laptop  192.0.2.1  31/01/2024 09:15:30  30/01/2024 18:00:00\n" +
+				"b2   -       192.0.2.7  30/01/2024 18:00:00  30/01/2024 18:00:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSessionsTable(tt.sessions); got != tt.want {
+				t.Errorf("formatSessionsTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// Session contains the login session of the user stored on the disk,
+// so the user stays logged in after the client restart.
+type Session struct {
+	Address      string    `json:"address"`
+	Login        string    `json:"login"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// LoadSession reads the stored session and puts its cookie and login into
+// the config. Nothing is loaded, if there is no session for the server address.
+// The expired session is removed and ErrSessionExpired is returned.
+func LoadSession(ctx context.Context, cfg *config.ClientConfig) error {
+	if cfg.SessionFile == "" {
+		return nil
+	}
+
+	body, err := os.ReadFile(cfg.SessionFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("LoadSession: read session file failed %w", err)
+	}
+
+	var sess Session
+	err = json.Unmarshal(body, &sess)
+	if err != nil {
+		return fmt.Errorf("LoadSession: unmarshal session failed %w", err)
+	}
+	if sess.Address != cfg.Address || sess.Token == "" {
+		return nil
+	}
+	if !sess.ExpiresAt.IsZero() && !time.Now().Before(sess.ExpiresAt) {
+		err = RemoveSession(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("LoadSession: %w", err)
+		}
+		return fmt.Errorf("LoadSession: %w", errs.ErrSessionExpired)
+	}
+
+	cfg.Cookie = &http.Cookie{
+		Name:  "auth",
+		Value: sess.Token,
+		Path:  "/api/user",
+	}
+	cfg.RefreshCookie = nil
+	if sess.RefreshToken != "" {
+		cfg.RefreshCookie = &http.Cookie{
+			Name:  "refresh",
+			Value: sess.RefreshToken,
+			Path:  "/api/user",
+		}
+	}
+	cfg.Login = sess.Login
+
+	return nil
+}
+
+// SaveSession writes the session of the logged in user into the session file,
+// which is readable only by the owner.
+func SaveSession(ctx context.Context, cfg *config.ClientConfig) error {
+	if cfg.SessionFile == "" || cfg.Cookie == nil {
+		return nil
+	}
+
+	// The session lasts while the refresh token is valid
+	sess := &Session{
+		Address:   cfg.Address,
+		Login:     cfg.Login,
+		Token:     cfg.Cookie.Value,
+		ExpiresAt: tokenExpiry(cfg.Cookie.Value),
+	}
+	if cfg.RefreshCookie != nil {
+		sess.RefreshToken = cfg.RefreshCookie.Value
+		sess.ExpiresAt = cfg.RefreshCookie.Expires
+	}
+	body, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("SaveSession: marshal session failed %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(cfg.SessionFile), 0700)
+	if err != nil {
+		return fmt.Errorf("SaveSession: create session directory failed %w", err)
+	}
+	tmp := cfg.SessionFile + ".tmp"
+	err = os.WriteFile(tmp, body, 0600)
+	if err != nil {
+		return fmt.Errorf("SaveSession: write session file failed %w", err)
+	}
+	err = os.Rename(tmp, cfg.SessionFile)
+	if err != nil {
+		return fmt.Errorf("SaveSession: rename session file failed %w", err)
+	}
+
+	return nil
+}
+
+// RemoveSession removes the stored session.
+func RemoveSession(ctx context.Context, cfg *config.ClientConfig) error {
+	if cfg.SessionFile == "" {
+		return nil
+	}
+	err := os.Remove(cfg.SessionFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("RemoveSession: remove session file failed %w", err)
+	}
+	return nil
+}
+
+// tokenExpiry returns the expiration time of the token. The token is not verified,
+// the client only needs to know when the server stops accepting it.
+// Zero time is returned if the expiration time is unknown.
+func tokenExpiry(token string) time.Time {
+	claims := &jwt.RegisteredClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
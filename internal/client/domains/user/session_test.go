@@ -0,0 +1,150 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+func newTestToken(t *testing.T, exp time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("create token failed %v", err)
+	}
+	return token
+}
+
+func TestLoadSession(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		address    string
+		exp        time.Time
+		wantErr    error
+		wantCookie bool
+		wantFile   bool
+	}{
+		{
+			name:       "valid",
+			address:    "http://localhost:8080",
+			exp:        time.Now().Add(time.Hour),
+			wantErr:    nil,
+			wantCookie: true,
+			wantFile:   true,
+		},
+		{
+			name:       "expired",
+			address:    "http://localhost:8080",
+			exp:        time.Now().Add(-time.Hour),
+			wantErr:    errs.ErrSessionExpired,
+			wantCookie: false,
+			wantFile:   false,
+		},
+		{
+			name:       "other_server",
+			address:    "http://example.com",
+			exp:        time.Now().Add(time.Hour),
+			wantErr:    nil,
+			wantCookie: false,
+			wantFile:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gophkeeper", "session.json")
+			saved := &config.ClientConfig{
+				Address:     "http://localhost:8080",
+				SessionFile: path,
+				Login:       "user",
+				Cookie:      &http.Cookie{Name: "auth", Value: newTestToken(t, tt.exp)},
+			}
+			err := SaveSession(ctx, saved)
+			if err != nil {
+				t.Fatalf("SaveSession() error = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("SaveSession() session file not found %v", err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("SaveSession() file mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+			}
+
+			cfg := &config.ClientConfig{Address: tt.address, SessionFile: path}
+			err = LoadSession(ctx, cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (cfg.Cookie != nil) != tt.wantCookie {
+				t.Errorf("LoadSession() cookie = %v, wantCookie %v", cfg.Cookie, tt.wantCookie)
+			}
+			if tt.wantCookie && (cfg.Cookie.Value != saved.Cookie.Value || cfg.Login != saved.Login) {
+				t.Errorf("LoadSession() = %v %v, want %v %v", cfg.Login, cfg.Cookie.Value, saved.Login, saved.Cookie.Value)
+			}
+			if _, err := os.Stat(path); (err == nil) != tt.wantFile {
+				t.Errorf("LoadSession() session file exists = %v, want %v", err == nil, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestUserService_Logout(t *testing.T) {
+	ctx := context.Background()
+
+	var requested bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/user/logout" {
+			requested = true
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	cfg := &config.ClientConfig{
+		Address:     srv.URL,
+		SessionFile: path,
+		Login:       "user",
+		Cookie:      &http.Cookie{Name: "auth", Value: newTestToken(t, time.Now().Add(time.Hour))},
+	}
+	err := SaveSession(ctx, cfg)
+	if err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	rw := rwmanager.NewRWManager(ctx, strings.NewReader(""), io.Discard)
+	s := NewUserService(ctx, rw, cfg)
+	err = s.Logout(ctx)
+	if err != nil {
+		t.Fatalf("UserService.Logout() error = %v", err)
+	}
+	if !requested {
+		t.Errorf("UserService.Logout() logout is not requested")
+	}
+	if cfg.Cookie != nil || cfg.Login != "" {
+		t.Errorf("UserService.Logout() session is not cleared")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("UserService.Logout() session file is not removed")
+	}
+
+	err = s.Logout(ctx)
+	if !errors.Is(err, errs.ErrUnauthorized) {
+		t.Errorf("UserService.Logout() repeated error = %v, want %v", err, errs.ErrUnauthorized)
+	}
+}
@@ -6,4 +6,5 @@ var (
 	ErrExit           = errors.New("exit requested")
 	ErrUnknownCommand = errors.New("unknown command")
 	ErrEmptyInput     = errors.New("input is empty")
+	ErrInvalidArgs    = errors.New("invalid command arguments")
 )
@@ -10,4 +10,10 @@ var (
 	ErrInvalidCardCV     = errors.New("invalid card cv")
 	ErrInvalidMetadata   = errors.New("invalid metadata")
 	ErrInvalidFilePath   = errors.New("invalid file path")
+	ErrDecryptFailed     = errors.New("couldn't decrypt data, check the master password")
+	ErrWrongMaster       = errors.New("wrong master password, try again")
+	ErrPasswordMismatch  = errors.New("passwords do not match")
+	ErrInvalidVersion    = errors.New("invalid version")
+	ErrInvalidField      = errors.New("data has no such field")
+	ErrInvalidQuery      = errors.New("invalid search query, use up to 16 terms separated by spaces")
 )
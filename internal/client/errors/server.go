@@ -1,6 +1,11 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var (
 	ErrBadRequest        = errors.New("please, check the entry and try again")
@@ -10,4 +15,48 @@ var (
 	ErrNotExist          = errors.New("not exist")
 	ErrUnknownStatusCode = errors.New("unknown status code")
 	ErrConnectionRefused = errors.New("server do not response, try again")
+	ErrOffline           = errors.New("server is unavailable")
+	ErrConflict          = errors.New("data was changed by another client, the local change is kept, see conflicts and resolve them")
+	ErrSessionExpired    = errors.New("session expired, please, login again")
+	ErrCodeRequired      = errors.New("one-time code is required")
+	ErrTooManyRequests   = errors.New("too many attempts")
+	ErrForbidden         = errors.New("wrong password, try again")
+	ErrPolicyViolation   = errors.New("login or password doesn't meet the requirements")
+	ErrTooLarge          = errors.New("data is too large for the server")
+	ErrChecksumMismatch  = errors.New("data was corrupted while sending, try again")
+	ErrDigestMismatch    = errors.New("data was corrupted while receiving, try again")
 )
+
+// RetryAfterError contains the time to wait before the next attempt,
+// when the server rejects the request because of too many attempts.
+type RetryAfterError struct {
+	Wait time.Duration
+}
+
+// Error returns the message with the time to wait.
+func (e *RetryAfterError) Error() string {
+	if e.Wait <= 0 {
+		return ErrTooManyRequests.Error() + ", try again later"
+	}
+	return fmt.Sprintf("%s, try again in %s", ErrTooManyRequests, e.Wait)
+}
+
+// Is reports that the error is ErrTooManyRequests.
+func (e *RetryAfterError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
+
+// PolicyError contains the reasons why the server rejected the login or the password.
+type PolicyError struct {
+	Reasons []string
+}
+
+// Error returns the message with the reasons, one per line.
+func (e *PolicyError) Error() string {
+	return ErrPolicyViolation.Error() + ":\n- " + strings.Join(e.Reasons, "\n- ")
+}
+
+// Is reports that the error is ErrPolicyViolation.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
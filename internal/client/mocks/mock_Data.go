@@ -34,18 +34,32 @@ func (m *MockDataService) EXPECT() *MockDataServiceMockRecorder {
 	return m.recorder
 }
 
+// Conflicts mocks base method.
+func (m *MockDataService) Conflicts(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Conflicts", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Conflicts indicates an expected call of Conflicts.
+func (mr *MockDataServiceMockRecorder) Conflicts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Conflicts", reflect.TypeOf((*MockDataService)(nil).Conflicts), ctx)
+}
+
 // CreateOrUpdate mocks base method.
-func (m *MockDataService) CreateOrUpdate(ctx context.Context, act string) error {
+func (m *MockDataService) CreateOrUpdate(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrUpdate", ctx, act)
+	ret := m.ctrl.Call(m, "CreateOrUpdate", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateOrUpdate indicates an expected call of CreateOrUpdate.
-func (mr *MockDataServiceMockRecorder) CreateOrUpdate(ctx, act interface{}) *gomock.Call {
+func (mr *MockDataServiceMockRecorder) CreateOrUpdate(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdate", reflect.TypeOf((*MockDataService)(nil).CreateOrUpdate), ctx, act)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdate", reflect.TypeOf((*MockDataService)(nil).CreateOrUpdate), ctx)
 }
 
 // Delete mocks base method.
@@ -62,6 +76,20 @@ func (mr *MockDataServiceMockRecorder) Delete(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDataService)(nil).Delete), ctx)
 }
 
+// Folders mocks base method.
+func (m *MockDataService) Folders(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Folders", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Folders indicates an expected call of Folders.
+func (mr *MockDataServiceMockRecorder) Folders(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Folders", reflect.TypeOf((*MockDataService)(nil).Folders), ctx)
+}
+
 // GetValue mocks base method.
 func (m *MockDataService) GetValue(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -75,3 +103,181 @@ func (mr *MockDataServiceMockRecorder) GetValue(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetValue", reflect.TypeOf((*MockDataService)(nil).GetValue), ctx)
 }
+
+// GetVersion mocks base method.
+func (m *MockDataService) GetVersion(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersion", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetVersion indicates an expected call of GetVersion.
+func (mr *MockDataServiceMockRecorder) GetVersion(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockDataService)(nil).GetVersion), ctx)
+}
+
+// History mocks base method.
+func (m *MockDataService) History(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// History indicates an expected call of History.
+func (mr *MockDataServiceMockRecorder) History(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockDataService)(nil).History), ctx)
+}
+
+// List mocks base method.
+func (m *MockDataService) List(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// List indicates an expected call of List.
+func (mr *MockDataServiceMockRecorder) List(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDataService)(nil).List), ctx)
+}
+
+// Move mocks base method.
+func (m *MockDataService) Move(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockDataServiceMockRecorder) Move(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockDataService)(nil).Move), ctx)
+}
+
+// Resolve mocks base method.
+func (m *MockDataService) Resolve(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockDataServiceMockRecorder) Resolve(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockDataService)(nil).Resolve), ctx)
+}
+
+// Restore mocks base method.
+func (m *MockDataService) Restore(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockDataServiceMockRecorder) Restore(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockDataService)(nil).Restore), ctx)
+}
+
+// Search mocks base method.
+func (m *MockDataService) Search(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockDataServiceMockRecorder) Search(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockDataService)(nil).Search), ctx)
+}
+
+// Sync mocks base method.
+func (m *MockDataService) Sync(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sync", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Sync indicates an expected call of Sync.
+func (mr *MockDataServiceMockRecorder) Sync(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockDataService)(nil).Sync), ctx)
+}
+
+// Tag mocks base method.
+func (m *MockDataService) Tag(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tag", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Tag indicates an expected call of Tag.
+func (mr *MockDataServiceMockRecorder) Tag(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tag", reflect.TypeOf((*MockDataService)(nil).Tag), ctx)
+}
+
+// Untag mocks base method.
+func (m *MockDataService) Untag(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Untag", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Untag indicates an expected call of Untag.
+func (mr *MockDataServiceMockRecorder) Untag(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Untag", reflect.TypeOf((*MockDataService)(nil).Untag), ctx)
+}
+
+// MockDataReader is a mock of DataReader interface.
+type MockDataReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataReaderMockRecorder
+}
+
+// MockDataReaderMockRecorder is the mock recorder for MockDataReader.
+type MockDataReaderMockRecorder struct {
+	mock *MockDataReader
+}
+
+// NewMockDataReader creates a new mock instance.
+func NewMockDataReader(ctrl *gomock.Controller) *MockDataReader {
+	mock := &MockDataReader{ctrl: ctrl}
+	mock.recorder = &MockDataReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataReader) EXPECT() *MockDataReaderMockRecorder {
+	return m.recorder
+}
+
+// Read mocks base method.
+func (m *MockDataReader) Read(ctx context.Context) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", ctx)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockDataReaderMockRecorder) Read(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockDataReader)(nil).Read), ctx)
+}
@@ -3,6 +3,7 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"syscall"
@@ -29,6 +30,9 @@ func GetKnownErr(err error) error {
 	if errors.Is(err, errs.ErrAlreadyExists) {
 		return errs.ErrAlreadyExists
 	}
+	if errors.Is(err, errs.ErrConflict) {
+		return errs.ErrConflict
+	}
 	if errors.Is(err, errs.ErrServerInternal) {
 		return errs.ErrServerInternal
 	}
@@ -41,7 +45,33 @@ func GetKnownErr(err error) error {
 	if errors.Is(err, errs.ErrUnauthorized) {
 		return errs.ErrUnauthorized
 	}
-	if errors.Is(err, syscall.ECONNREFUSED) {
+	if errors.Is(err, errs.ErrForbidden) {
+		return errs.ErrForbidden
+	}
+	if errors.Is(err, errs.ErrSessionExpired) {
+		return errs.ErrSessionExpired
+	}
+	if errors.Is(err, errs.ErrCodeRequired) {
+		return errs.ErrCodeRequired
+	}
+	if errors.Is(err, errs.ErrTooLarge) {
+		return errs.ErrTooLarge
+	}
+	if errors.Is(err, errs.ErrChecksumMismatch) {
+		return errs.ErrChecksumMismatch
+	}
+	if errors.Is(err, errs.ErrDigestMismatch) {
+		return errs.ErrDigestMismatch
+	}
+	var retryErr *errs.RetryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr
+	}
+	var policyErr *errs.PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, errs.ErrOffline) {
 		return errs.ErrConnectionRefused
 	}
 	if errors.Is(err, errs.ErrInvalidCardNumber) {
@@ -62,12 +92,133 @@ func GetKnownErr(err error) error {
 	if errors.Is(err, errs.ErrInvalidFilePath) {
 		return errs.ErrInvalidFilePath
 	}
+	if errors.Is(err, errs.ErrDecryptFailed) {
+		return errs.ErrDecryptFailed
+	}
+	if errors.Is(err, errs.ErrWrongMaster) {
+		return errs.ErrWrongMaster
+	}
+	if errors.Is(err, errs.ErrPasswordMismatch) {
+		return errs.ErrPasswordMismatch
+	}
+	if errors.Is(err, errs.ErrInvalidVersion) {
+		return errs.ErrInvalidVersion
+	}
+	if errors.Is(err, errs.ErrInvalidField) {
+		return errs.ErrInvalidField
+	}
+	if errors.Is(err, errs.ErrInvalidQuery) {
+		return errs.ErrInvalidQuery
+	}
 	if errors.Is(err, errs.ErrUnknownCommand) {
 		return errs.ErrUnknownCommand
 	}
+	if errors.Is(err, errs.ErrInvalidArgs) {
+		return errs.ErrInvalidArgs
+	}
 	return nil
 }
 
+// Exit codes of the client in the non-interactive mode.
+const (
+	ExitOK = iota
+	ExitFailure
+	ExitUsage
+	ExitUnauthorized
+	ExitNotFound
+	ExitConflict
+	ExitUnavailable
+	ExitServer
+	ExitRateLimited
+)
+
+// GetExitCode returns the exit code for the class of the error.
+func GetExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, errs.ErrTooManyRequests) {
+		return ExitRateLimited
+	}
+	if errors.Is(err, errs.ErrPolicyViolation) {
+		return ExitUsage
+	}
+	switch GetKnownErr(err) {
+	case errs.ErrUnknownCommand, errs.ErrInvalidArgs, errs.ErrEmptyInput, errs.ErrBadRequest,
+		errs.ErrInvalidDataType, errs.ErrInvalidCardNumber, errs.ErrInvalidCardDate,
+		errs.ErrInvalidCardCV, errs.ErrInvalidMetadata, errs.ErrInvalidFilePath, errs.ErrInvalidVersion,
+		errs.ErrTooLarge:
+		return ExitUsage
+	case errs.ErrUnauthorized, errs.ErrForbidden, errs.ErrSessionExpired, errs.ErrCodeRequired, errs.ErrDecryptFailed,
+		errs.ErrPasswordMismatch, errs.ErrWrongMaster:
+		return ExitUnauthorized
+	case errs.ErrNotExist, errs.ErrInvalidField:
+		return ExitNotFound
+	case errs.ErrAlreadyExists, errs.ErrConflict:
+		return ExitConflict
+	case errs.ErrConnectionRefused:
+		return ExitUnavailable
+	case errs.ErrServerInternal:
+		return ExitServer
+	}
+	return ExitFailure
+}
+
+// errorClasses contains names of the error classes for the exit codes.
+var errorClasses = map[int]string{
+	ExitFailure:      "failure",
+	ExitUsage:        "usage",
+	ExitUnauthorized: "unauthorized",
+	ExitNotFound:     "not_found",
+	ExitConflict:     "conflict",
+	ExitUnavailable:  "unavailable",
+	ExitServer:       "server",
+	ExitRateLimited:  "rate_limited",
+}
+
+// CommandError contains the error of the command for JSON output.
+type CommandError struct {
+	Code    int    `json:"code"`
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+// CommandResult contains the result of the command for JSON output.
+type CommandResult struct {
+	OK     bool          `json:"ok"`
+	Result any           `json:"result"`
+	Error  *CommandError `json:"error,omitempty"`
+}
+
+// FormatResult returns JSON document with the result of the command
+// and the code, class and message of its error, if any.
+func FormatResult(result any, err error) string {
+	res := &CommandResult{OK: err == nil, Result: result}
+	if err != nil {
+		msg := err
+		if known := GetKnownErr(err); known != nil {
+			msg = known
+		}
+		code := GetExitCode(err)
+		res.Result = nil
+		res.Error = &CommandError{
+			Code:    code,
+			Class:   errorClasses[code],
+			Message: msg.Error(),
+		}
+	}
+
+	doc, mErr := json.Marshal(res)
+	if mErr != nil {
+		doc, _ = json.Marshal(&CommandResult{Error: &CommandError{
+			Code:    ExitFailure,
+			Class:   errorClasses[ExitFailure],
+			Message: mErr.Error(),
+		}})
+	}
+	return string(doc)
+}
+
 // DoWithRetryIfEmpty tries to implement function three times, if the input is empty.
 func DoWithRetryIfEmpty(ctx context.Context, rw rwmanager.RWService, f func(ctx context.Context) error) error {
 	var err error
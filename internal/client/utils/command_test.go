@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/pavlegich/gophkeeper/internal/client/domains/rwmanager"
 	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
@@ -171,3 +173,117 @@ func TestGetKnownErr(t *testing.T) {
 		})
 	}
 }
+
+func TestGetExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "ok",
+			err:  nil,
+			want: ExitOK,
+		},
+		{
+			name: "invalid_args",
+			err:  fmt.Errorf("ParseArgs: %w", errs.ErrInvalidArgs),
+			want: ExitUsage,
+		},
+		{
+			name: "unauthorized",
+			err:  fmt.Errorf("GetValue: %w", errs.ErrUnauthorized),
+			want: ExitUnauthorized,
+		},
+		{
+			name: "not_exist",
+			err:  fmt.Errorf("GetValue: %w", errs.ErrNotExist),
+			want: ExitNotFound,
+		},
+		{
+			name: "conflict",
+			err:  fmt.Errorf("Push: %w", errs.ErrConflict),
+			want: ExitConflict,
+		},
+		{
+			name: "offline",
+			err:  fmt.Errorf("Sync: %w", errs.ErrOffline),
+			want: ExitUnavailable,
+		},
+		{
+			name: "server_internal",
+			err:  fmt.Errorf("List: %w", errs.ErrServerInternal),
+			want: ExitServer,
+		},
+		{
+			name: "rate_limited",
+			err:  fmt.Errorf("Login: %w", &errs.RetryAfterError{Wait: time.Minute}),
+			want: ExitRateLimited,
+		},
+		{
+			name: "unknown_error",
+			err:  errors.New("something went wrong"),
+			want: ExitFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetExitCode(tt.err); got != tt.want {
+				t.Errorf("GetExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	type args struct {
+		result any
+		err    error
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "success_without_result",
+			args: args{
+				result: nil,
+				err:    nil,
+			},
+			want: `{"ok":true,"result":null}`,
+		},
+		{
+			name: "success_with_result",
+			args: args{
+				result: map[string]int{"received": 2},
+				err:    nil,
+			},
+			want: `{"ok":true,"result":{"received":2}}`,
+		},
+		{
+			name: "known_error",
+			args: args{
+				result: "value",
+				err:    fmt.Errorf("GetValue: %w", errs.ErrNotExist),
+			},
+			want: `{"ok":false,"result":null,"error":{"code":4,"class":"not_found","message":"` +
+				errs.ErrNotExist.Error() + `"}}`,
+		},
+		{
+			name: "unknown_error",
+			args: args{
+				result: nil,
+				err:    errors.New("something went wrong"),
+			},
+			want: `{"ok":false,"result":null,"error":{"code":1,"class":"failure","message":"something went wrong"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatResult(tt.args.result, tt.args.err); got != tt.want {
+				t.Errorf("FormatResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
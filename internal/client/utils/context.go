@@ -12,6 +12,8 @@ const (
 	// List of const variables contains variables for
 	// put values into and get values from the context.
 	ContextActionKey contextKey = iota
+	ContextFieldKey
+	ContextContentKey
 )
 
 // GetActionFromContext finds and returns user id from the context.
@@ -26,3 +28,17 @@ func GetActionFromContext(ctx context.Context) (string, error) {
 	}
 	return userID, nil
 }
+
+// GetFieldFromContext returns the name of data field requested to output,
+// empty string is returned if the whole data is requested.
+func GetFieldFromContext(ctx context.Context) string {
+	field, _ := ctx.Value(ContextFieldKey).(string)
+	return field
+}
+
+// GetContentFromContext returns the text data read from the file in the non-interactive
+// mode, it is stored verbatim instead of reading the text from the input.
+func GetContentFromContext(ctx context.Context) ([]byte, bool) {
+	content, ok := ctx.Value(ContextContentKey).([]byte)
+	return content, ok
+}
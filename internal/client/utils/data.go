@@ -1,14 +1,19 @@
 package utils
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -53,42 +58,141 @@ func checksum(number int) int {
 	return luhn % 10
 }
 
-func SaveFromMultipartToFile(ctx context.Context, r *http.Response, path string) error {
+// GetFileFromMultipart reads the file field from the multipart response
+// and returns its content.
+func GetFileFromMultipart(ctx context.Context, r *http.Response) ([]byte, error) {
 	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
-		return fmt.Errorf("SaveFromMultipartToFile: couldn't get media type %w", err)
+		return nil, fmt.Errorf("GetFileFromMultipart: couldn't get media type %w", err)
 	}
 
 	if !strings.HasPrefix(mediaType, "multipart/") {
-		return fmt.Errorf("SaveFromMultipartToFile: %w", errs.ErrNotExist)
+		return nil, fmt.Errorf("GetFileFromMultipart: %w", errs.ErrNotExist)
 	}
 
 	multipartReader := multipart.NewReader(r.Body, params["boundary"])
 	defer r.Body.Close()
 
 	field, err := multipartReader.NextPart()
-	if err != nil && !errors.Is(err, io.EOF) {
-		return fmt.Errorf("SaveFromMultipartToFile: get next multi part failed %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("GetFileFromMultipart: get next multi part failed %w", err)
 	}
 	defer field.Close()
 
 	if field.FormName() != "file" {
-		return fmt.Errorf("SaveFromMultipartToFile: no field with name file")
+		return nil, fmt.Errorf("GetFileFromMultipart: no field with name file")
 	}
 
-	file, err := os.Create(path)
+	file, err := io.ReadAll(field)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("GetFileFromMultipart: couldn't read multiform field %w", err)
+	}
+
+	return file, nil
+}
+
+// VerifyDigest compares the SHA-256 digest of the value with the digest
+// from the Digest header of the response, the value without digest is accepted.
+func VerifyDigest(r *http.Response, value []byte) error {
+	want, err := getDigest(r)
 	if err != nil {
-		return fmt.Errorf("SaveFromMultipartToFile: create file failed %w", err)
+		return fmt.Errorf("VerifyDigest: %w", err)
 	}
-	defer file.Close()
-	bytes, err := io.ReadAll(field)
-	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
-		return fmt.Errorf("SaveFromMultipartToFile: couldn't read multiform field %w", err)
+	if want == nil {
+		return nil
 	}
-	_, err = file.Write(bytes)
+	sum := sha256.Sum256(value)
+	if !bytes.Equal(sum[:], want) {
+		return fmt.Errorf("VerifyDigest: %w", errs.ErrDigestMismatch)
+	}
+	return nil
+}
+
+// NewDigestReader returns the reader of the response body, which compares
+// the SHA-256 digest of the body with the digest from the Digest header of
+// the response, when the body is read to the end. ErrDigestMismatch is returned
+// instead of io.EOF, if they differ. The body without digest is read as is.
+func NewDigestReader(r *http.Response) (io.Reader, error) {
+	want, err := getDigest(r)
 	if err != nil {
-		return fmt.Errorf("SaveFromMultipartToFile: write to file failed %w", err)
+		return nil, fmt.Errorf("NewDigestReader: %w", err)
 	}
+	if want == nil {
+		return r.Body, nil
+	}
+	return &digestReader{
+		r:    r.Body,
+		hash: sha256.New(),
+		want: want,
+	}, nil
+}
 
-	return nil
+// digestReader calculates the digest of the body while it is read.
+type digestReader struct {
+	r    io.Reader
+	hash hash.Hash
+	want []byte
+}
+
+// Read reads the body and compares its digest at the end of the body.
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) && !bytes.Equal(d.hash.Sum(nil), d.want) {
+		return n, fmt.Errorf("Read: %w", errs.ErrDigestMismatch)
+	}
+	return n, err
+}
+
+// getDigest returns the SHA-256 digest from the Digest header of the response,
+// nil is returned if the header doesn't contain it.
+func getDigest(r *http.Response) ([]byte, error) {
+	for _, digest := range strings.Split(r.Header.Get("Digest"), ",") {
+		alg, encoded, ok := strings.Cut(strings.TrimSpace(digest), "=")
+		if !ok || !strings.EqualFold(alg, "sha-256") {
+			continue
+		}
+		want, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("getDigest: decode digest failed %w", errs.ErrDigestMismatch)
+		}
+		return want, nil
+	}
+	return nil, nil
+}
+
+// GetFileName returns the original file name from the Content-Disposition header
+// of the response without directories, empty name is returned if it is not specified.
+func GetFileName(r *http.Response) string {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return CleanFileName(params["filename"])
+}
+
+// GetMetadata returns the encrypted metadata of binary data object from
+// the response header, nil is returned if the server doesn't send it.
+func GetMetadata(r *http.Response) (json.RawMessage, error) {
+	value := r.Header.Get(MetadataHeader)
+	if value == "" {
+		return nil, nil
+	}
+	metadata, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("GetMetadata: decode metadata failed %w", err)
+	}
+	return metadata, nil
+}
+
+// CleanFileName returns the base name of the file, so the file received from
+// the server is not saved outside the current directory, the name which cannot
+// be used as the file name is dropped.
+func CleanFileName(name string) string {
+	name = filepath.Base(filepath.FromSlash(strings.ReplaceAll(name, "\\", "/")))
+	switch name {
+	case ".", "..", string(filepath.Separator):
+		return ""
+	}
+	return name
 }
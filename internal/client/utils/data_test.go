@@ -1,7 +1,15 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
 	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 )
 
 func TestIsValidDataType(t *testing.T) {
@@ -102,3 +110,111 @@ func Test_checksum(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyDigest(t *testing.T) {
+	value := []byte("content")
+	sum := sha256.Sum256(value)
+	digest := "sha-256=" + base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		digest  string
+		value   []byte
+		wantErr error
+	}{
+		{
+			name:    "ok",
+			digest:  digest,
+			value:   value,
+			wantErr: nil,
+		},
+		{
+			name:    "several_digests",
+			digest:  "md5=AAAA, " + digest,
+			value:   value,
+			wantErr: nil,
+		},
+		{
+			name:    "no_digest",
+			digest:  "",
+			value:   value,
+			wantErr: nil,
+		},
+		{
+			name:    "corrupted",
+			digest:  digest,
+			value:   []byte("contend"),
+			wantErr: errs.ErrDigestMismatch,
+		},
+		{
+			name:    "invalid_digest",
+			digest:  "sha-256=%%%",
+			value:   value,
+			wantErr: errs.ErrDigestMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: make(http.Header)}
+			if tt.digest != "" {
+				resp.Header.Set("Digest", tt.digest)
+			}
+			if err := VerifyDigest(resp, tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyDigest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// The streamed body is verified at its end
+			resp.Body = io.NopCloser(bytes.NewReader(tt.value))
+			r, err := NewDigestReader(resp)
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewDigestReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		want        string
+	}{
+		{
+			name:        "ok",
+			disposition: `attachment; filename="report 2024.pdf"`,
+			want:        "report 2024.pdf",
+		},
+		{
+			name:        "encoded",
+			disposition: `attachment; filename*=utf-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.txt`,
+			want:        "отчёт.txt",
+		},
+		{
+			name:        "path",
+			disposition: `attachment; filename="../../etc/passwd"`,
+			want:        "passwd",
+		},
+		{
+			name:        "parent_directory",
+			disposition: `attachment; filename=".."`,
+			want:        "",
+		},
+		{
+			name:        "no_disposition",
+			disposition: "",
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: make(http.Header)}
+			resp.Header.Set("Content-Disposition", tt.disposition)
+			if got := GetFileName(resp); got != tt.want {
+				t.Errorf("GetFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,45 +2,149 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 )
 
-// CheckStatusCode checks status code and returns the answer.
-func CheckStatusCode(code int) error {
-	switch code {
+// DeviceHeader is the request header with the name of the client device.
+const DeviceHeader = "X-Device"
+
+// MetadataHeader is the response header with the encrypted metadata
+// of binary data object encoded in base64.
+const MetadataHeader = "X-Metadata"
+
+// TransferTimeout is the timeout of requests transferring the value
+// of binary data, which can be large.
+const TransferTimeout = 30 * time.Minute
+
+// client is the HTTP client for requests to the server.
+var client = &http.Client{}
+
+// SetTransport sets the transport of the HTTP client for requests to the server.
+func SetTransport(rt http.RoundTripper) {
+	client.Transport = rt
+}
+
+// ETag returns the entity tag for the version of data object.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// GetVersionFromETag returns the version of data object from its entity tag,
+// zero is returned if the tag is incorrect.
+func GetVersionFromETag(tag string) int {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	version, err := strconv.Atoi(strings.Trim(tag, `"`))
+	if err != nil || version < 0 {
+		return 0
+	}
+	return version
+}
+
+// CheckStatusCode checks status code of the response and returns the answer.
+func CheckStatusCode(resp *http.Response) error {
+	switch resp.StatusCode {
 	case http.StatusOK:
 		return nil
 	case http.StatusBadRequest:
+		if reasons := getViolations(resp); len(reasons) > 0 {
+			return &errs.PolicyError{Reasons: reasons}
+		}
 		return errs.ErrBadRequest
 	case http.StatusUnauthorized:
 		return errs.ErrUnauthorized
+	case http.StatusForbidden:
+		return errs.ErrForbidden
 	case http.StatusConflict:
 		return errs.ErrAlreadyExists
 	case http.StatusInternalServerError:
 		return errs.ErrServerInternal
 	case http.StatusNoContent:
 		return errs.ErrNotExist
+	case http.StatusPreconditionFailed:
+		return errs.ErrConflict
+	case http.StatusTooManyRequests:
+		return &errs.RetryAfterError{Wait: GetRetryAfter(resp.Header)}
+	case http.StatusRequestEntityTooLarge:
+		return errs.ErrTooLarge
+	case http.StatusUnprocessableEntity:
+		return errs.ErrChecksumMismatch
 	default:
-		return fmt.Errorf("%w%d", errs.ErrUnknownStatusCode, code)
+		return fmt.Errorf("%w%d", errs.ErrUnknownStatusCode, resp.StatusCode)
+	}
+}
+
+// getViolations returns the messages of the violated rules
+// from the body of the response, if there are any.
+func getViolations(resp *http.Response) []string {
+	if resp.Body == nil || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	var body struct {
+		Violations []struct {
+			Message string `json:"message"`
+		} `json:"violations"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) != nil {
+		return nil
+	}
+	reasons := make([]string, 0, len(body.Violations))
+	for _, v := range body.Violations {
+		reasons = append(reasons, v.Message)
+	}
+	return reasons
+}
+
+// GetRetryAfter returns the time to wait from the Retry-After header,
+// which contains the number of seconds or the date. Zero is returned,
+// if the header is empty or incorrect.
+func GetRetryAfter(h http.Header) time.Duration {
+	value := strings.TrimSpace(h.Get("Retry-After"))
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
 	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date).Round(time.Second), 0)
+	}
+	return 0
+}
+
+// IsConnectionError checks whether the request failed because
+// the server is unavailable.
+func IsConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
 }
 
 // DoRequestWithRetry requests with retries.
-// If request is successful, returns response.
+// If request is successful, returns response. The body of the request
+// is obtained again for every retry, if the request is able to.
 func DoRequestWithRetry(ctx context.Context, r *http.Request) (*http.Response, error) {
 	var err error = nil
 	var resp *http.Response
 
 	intervals := []time.Duration{0, time.Second, 3 * time.Second, 5 * time.Second}
-	for _, interval := range intervals {
+	for i, interval := range intervals {
 		time.Sleep(interval)
-		resp, err = http.DefaultClient.Do(r)
+		if i > 0 && r.GetBody != nil {
+			r.Body, err = r.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("GetRequestWithRetry: get request body failed %w", err)
+			}
+		}
+		resp, err = client.Do(r)
 		if !errors.Is(err, syscall.ECONNREFUSED) {
 			break
 		}
@@ -4,13 +4,15 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	errs "github.com/pavlegich/gophkeeper/internal/client/errors"
 )
 
 func TestCheckStatusCode(t *testing.T) {
 	type args struct {
-		code int
+		code   int
+		header http.Header
 	}
 	tests := []struct {
 		name    string
@@ -66,6 +68,31 @@ func TestCheckStatusCode(t *testing.T) {
 			want:    errs.ErrNotExist,
 			wantErr: true,
 		},
+		{
+			name: "precondition_failed_status",
+			args: args{
+				code: http.StatusPreconditionFailed,
+			},
+			want:    errs.ErrConflict,
+			wantErr: true,
+		},
+		{
+			name: "too_many_requests_status",
+			args: args{
+				code:   http.StatusTooManyRequests,
+				header: http.Header{"Retry-After": []string{"90"}},
+			},
+			want:    errs.ErrTooManyRequests,
+			wantErr: true,
+		},
+		{
+			name: "too_large_status",
+			args: args{
+				code: http.StatusRequestEntityTooLarge,
+			},
+			want:    errs.ErrTooLarge,
+			wantErr: true,
+		},
 		{
 			name: "unknown_status",
 			args: args{
@@ -77,7 +104,7 @@ func TestCheckStatusCode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := CheckStatusCode(tt.args.code)
+			err := CheckStatusCode(&http.Response{StatusCode: tt.args.code, Header: tt.args.header})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CheckStatusCode() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -88,3 +115,75 @@ func TestCheckStatusCode(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{
+			name:   "seconds",
+			header: http.Header{"Retry-After": []string{"90"}},
+			want:   90 * time.Second,
+		},
+		{
+			name:   "past_date",
+			header: http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}},
+			want:   0,
+		},
+		{
+			name:   "empty",
+			header: http.Header{},
+			want:   0,
+		},
+		{
+			name:   "incorrect",
+			header: http.Header{"Retry-After": []string{"soon"}},
+			want:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetRetryAfter(tt.header); got != tt.want {
+				t.Errorf("GetRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVersionFromETag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want int
+	}{
+		{
+			name: "ok",
+			tag:  `"4"`,
+			want: 4,
+		},
+		{
+			name: "weak_tag",
+			tag:  `W/"2"`,
+			want: 2,
+		},
+		{
+			name: "empty",
+			tag:  "",
+			want: 0,
+		},
+		{
+			name: "not_number",
+			tag:  `"abc"`,
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetVersionFromETag(tt.tag); got != tt.want {
+				t.Errorf("GetVersionFromETag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package blob contains the storages of large values, which are addressed
+// by SHA-256 of their content, so the same value is stored once.
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+)
+
+// ErrBlobNotFound is returned if there is no value with the key in the storage.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned if the key is not SHA-256 digest in hex.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store describes methods for saving, loading and deleting
+// the values by the keys derived from their content. The values
+// are streamed, so they are never kept in memory entirely.
+type Store interface {
+	Stage(ctx context.Context, value io.Reader) (*Staged, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Staged contains the value written into the temporary file, its key and size.
+// The key is known only after the value is read, so the value is available
+// by the key only after it is committed, the file is removed by Discard.
+type Staged struct {
+	Key    string
+	Size   int64
+	file   *os.File
+	commit func(ctx context.Context, s *Staged) error
+}
+
+// Commit saves the staged value into the storage by its key.
+func (s *Staged) Commit(ctx context.Context) error {
+	err := s.commit(ctx, s)
+	if err != nil {
+		return fmt.Errorf("Commit: %w", err)
+	}
+	return nil
+}
+
+// Discard removes the temporary file of the staged value, if it still exists.
+func (s *Staged) Discard() error {
+	s.file.Close()
+	err := os.Remove(s.file.Name())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Discard: remove file failed %w", err)
+	}
+	return nil
+}
+
+// stage writes the value into the temporary file in the directory
+// and calculates its key and size while it is written.
+func stage(value io.Reader, dir string, commit func(ctx context.Context, s *Staged) error) (*Staged, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("stage: create directory failed %w", err)
+	}
+	file, err := os.CreateTemp(dir, "blob.*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("stage: create temporary file failed %w", err)
+	}
+	s := &Staged{
+		file:   file,
+		commit: commit,
+	}
+
+	h := sha256.New()
+	s.Size, err = io.Copy(io.MultiWriter(file, h), value)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		s.Discard()
+		return nil, fmt.Errorf("stage: write file failed %w", err)
+	}
+	s.Key = hex.EncodeToString(h.Sum(nil))
+	return s, nil
+}
+
+// NewStore returns the storage selected in the server configuration,
+// nil is returned if the values are stored in the database.
+func NewStore(ctx context.Context, cfg *config.ServerConfig) (Store, error) {
+	switch cfg.BlobStore {
+	case "":
+		return nil, nil
+	case "fs":
+		return NewFileStore(cfg.BlobDir), nil
+	case "s3":
+		return NewS3Store(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey), nil
+	}
+	return nil, fmt.Errorf("NewStore: unknown blob store %s", cfg.BlobStore)
+}
+
+// Key returns the key of the value, which is SHA-256 digest of the value in hex.
+func Key(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkKey checks whether the key is SHA-256 digest in hex,
+// so it is safe to use it in paths.
+func checkKey(key string) error {
+	if len(key) != 2*sha256.Size {
+		return ErrInvalidKey
+	}
+	for _, c := range key {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStore contains the directory, where the values are stored in files named
+// by their keys in subdirectories by the first characters of the keys.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns new storage of values in the directory.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		dir: dir,
+	}
+}
+
+// Stage writes the value into the temporary file in the tmp subdirectory,
+// so the committed file is moved into its place atomically and the value
+// is never read partially.
+func (s *FileStore) Stage(ctx context.Context, value io.Reader) (*Staged, error) {
+	staged, err := stage(value, filepath.Join(s.dir, "tmp"), s.commit)
+	if err != nil {
+		return nil, fmt.Errorf("Stage: %w", err)
+	}
+	return staged, nil
+}
+
+// commit moves the file of the staged value into the path by its key,
+// if there is no such value yet.
+func (s *FileStore) commit(ctx context.Context, staged *Staged) error {
+	path := s.path(staged.Key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("commit: create directory failed %w", err)
+	}
+	err = staged.file.Close()
+	if err != nil {
+		return fmt.Errorf("commit: close file failed %w", err)
+	}
+	err = os.Rename(staged.file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("commit: rename file failed %w", err)
+	}
+	return nil
+}
+
+// Open opens the file with the value by the key for reading.
+func (s *FileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+	file, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("Open: %w", ErrBlobNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Open: open file failed %w", err)
+	}
+	return file, nil
+}
+
+// Delete deletes the file with the value, if it exists.
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Delete: remove file failed %w", err)
+	}
+	return nil
+}
+
+// path returns the path to the file with the value, e.g. dir/ab/cd/abcd...
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key[2:4], key)
+}
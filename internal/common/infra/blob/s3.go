@@ -0,0 +1,196 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is SHA-256 of the empty request body in hex.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Store contains the settings of S3-compatible storage, where the values
+// are stored as objects named by their keys in the bucket. Requests are signed
+// with AWS Signature Version 4 and use path-style addressing, which is supported
+// by the most of S3-compatible storages.
+type S3Store struct {
+	client    *http.Client
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+// NewS3Store returns new storage of values in the bucket
+// of S3-compatible storage with the endpoint, e.g. http://localhost:9000.
+func NewS3Store(endpoint string, bucket string, region string, accessKey string, secretKey string) *S3Store {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Store{
+		client:    &http.Client{},
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+}
+
+// Stage writes the value into the temporary file, since the storage requires
+// the length and the hash of the object before it is uploaded.
+func (s *S3Store) Stage(ctx context.Context, value io.Reader) (*Staged, error) {
+	staged, err := stage(value, os.TempDir(), s.commit)
+	if err != nil {
+		return nil, fmt.Errorf("Stage: %w", err)
+	}
+	return staged, nil
+}
+
+// commit uploads the staged value from the temporary file as the object.
+// The storage checks the value with its hash from the signed header.
+func (s *S3Store) commit(ctx context.Context, staged *Staged) error {
+	_, err := staged.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("commit: seek file failed %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodPut, staged.Key, staged.file, staged.Size, staged.Key)
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("commit: unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Open downloads the object with the key, the returned body of the response
+// must be closed.
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("Open: %w", ErrBlobNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("Open: unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// Delete deletes the object with the key, the storage
+// responds with success if there is no such object.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Delete: unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// do sends the signed request for the object with the key,
+// the body of the request has the specified length.
+func (s *S3Store) do(ctx context.Context, method string, key string, body io.Reader, size int64,
+	payloadHash string) (*http.Response, error) {
+	target := s.endpoint + "/" + s.bucket + "/" + key
+	if body == nil || size == 0 {
+		// The request with zero length and not empty body is sent in chunks
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("do: new request failed %w", err)
+	}
+	req.ContentLength = size
+
+	signRequest(req, payloadHash, s.accessKey, s.secretKey, s.region, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: request failed %w", err)
+	}
+	return resp, nil
+}
+
+// signRequest sets the Authorization header with AWS Signature Version 4
+// of the request. The host and all the headers of the request are signed.
+func signRequest(req *http.Request, payloadHash string, accessKey string, secretKey string,
+	region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
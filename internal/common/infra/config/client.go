@@ -7,14 +7,36 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/caarlos0/env/v6"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+)
+
+// Output formats of the client.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
 )
 
 // ClientConfig contains values of client flags and environments.
 type ClientConfig struct {
-	Address string `env:"ADDRESS" json:"address"`
-	Cookie  *http.Cookie
+	Address       string `env:"ADDRESS" json:"address"`
+	Device        string `env:"DEVICE" json:"device"`
+	CacheDir      string `env:"CACHE_DIR" json:"cache_dir"`
+	Output        string `env:"OUTPUT" json:"output"`
+	SessionFile   string `env:"SESSION_FILE" json:"session_file"`
+	TLSCA         string `env:"TLS_CA_FILE" json:"tls_ca_file"`
+	TLSCert       string `env:"TLS_CERT_FILE" json:"tls_cert_file"`
+	TLSKey        string `env:"TLS_KEY_FILE" json:"tls_key_file"`
+	Legacy        bool   `env:"LEGACY_PLAINTEXT" json:"legacy_plaintext"`
+	Cookie        *http.Cookie
+	RefreshCookie *http.Cookie
+	Cipher        *encryption.Cipher
+	Login         string
+	Salt          []byte
+	KeyCheck      []byte
 }
 
 // NewClientConfig returns new client config.
@@ -26,13 +48,41 @@ func NewClientConfig(ctx context.Context) *ClientConfig {
 // when launching the client.
 func (cfg *ClientConfig) ParseFlags(ctx context.Context) error {
 	flag.StringVar(&cfg.Address, "a", "http://localhost:8080", "HTTP-server endpoint address 'protocol://host:port'")
+	hostname, _ := os.Hostname()
+	flag.StringVar(&cfg.Device, "device", hostname, "Name of the client device")
+	cacheDir, err := os.UserCacheDir()
+	if err == nil {
+		cacheDir = filepath.Join(cacheDir, "gophkeeper")
+	}
+	flag.StringVar(&cfg.CacheDir, "cache", cacheDir, "Directory for the encrypted local copy of data, cache is disabled if empty")
+
+	sessionFile, err := os.UserConfigDir()
+	if err == nil {
+		sessionFile = filepath.Join(sessionFile, "gophkeeper", "session.json")
+	}
+	flag.StringVar(&cfg.SessionFile, "session", sessionFile, "File for storing the login session, session is not stored if empty")
+	flag.StringVar(&cfg.Output, "output", OutputText, "Output format of commands results (text/json)")
+	flag.StringVar(&cfg.TLSCA, "ca", "", "Path to PEM file with CA certificates for verifying the server, system CAs are used if empty")
+	flag.StringVar(&cfg.TLSCert, "cert", "", "Path to PEM file with client certificate for mutual TLS")
+	flag.StringVar(&cfg.TLSKey, "key", "", "Path to PEM file with private key of client certificate")
+	flag.BoolVar(&cfg.Legacy, "legacy", false, "Read the values saved before the client-side encryption as plain values")
 
 	flag.Parse()
 
-	err := env.Parse(cfg)
+	err = env.Parse(cfg)
 	if err != nil {
 		return fmt.Errorf("ParseFlags: wrong environment values %w", err)
 	}
 
+	if cfg.TLSCert != "" && cfg.TLSKey == "" {
+		return fmt.Errorf("ParseFlags: private key is required with client certificate")
+	}
+
+	if cfg.Output != OutputText && cfg.Output != OutputJSON {
+		output := cfg.Output
+		cfg.Output = OutputText
+		return fmt.Errorf("ParseFlags: unknown output format %s", output)
+	}
+
 	return nil
 }
@@ -14,10 +14,37 @@ import (
 
 // ServerConfig contains values of server flags and environments.
 type ServerConfig struct {
-	Address  string        `env:"ADDRESS" json:"address"`
-	DSN      string        `env:"DATABASE_DSN" json:"database_dsn"`
-	TokenExp time.Duration `end:"TOKEN_EXP" json:"token_exp"`
-	Token    *hash.Token
+	Address       string        `env:"ADDRESS" json:"address"`
+	GRPCAddress   string        `env:"GRPC_ADDRESS" json:"grpc_address"`
+	Storage       string        `env:"STORAGE" json:"storage"`
+	DSN           string        `env:"DATABASE_DSN" json:"database_dsn"`
+	SQLitePath    string        `env:"SQLITE_PATH" json:"sqlite_path"`
+	TLSCert       string        `env:"TLS_CERT_FILE" json:"tls_cert_file"`
+	TLSKey        string        `env:"TLS_KEY_FILE" json:"tls_key_file"`
+	TLSMinVersion string        `env:"TLS_MIN_VERSION" json:"tls_min_version"`
+	TLSClientCA   string        `env:"TLS_CLIENT_CA_FILE" json:"tls_client_ca_file"`
+	TokenExp      time.Duration `env:"TOKEN_EXP" json:"token_exp"`
+	RefreshExp    time.Duration `env:"REFRESH_TOKEN_EXP" json:"refresh_token_exp"`
+	TokenKeyFile  string        `env:"TOKEN_KEY_FILE" json:"token_key_file"`
+	KeyRotation   time.Duration `env:"TOKEN_KEY_ROTATION" json:"token_key_rotation"`
+	KeyRefresh    time.Duration `env:"TOKEN_KEY_REFRESH" json:"token_key_refresh"`
+	AuthRate      int           `env:"AUTH_RATE_LIMIT" json:"auth_rate_limit"`
+	LockAttempts  int           `env:"LOCKOUT_ATTEMPTS" json:"lockout_attempts"`
+	LockPeriod    time.Duration `env:"LOCKOUT_PERIOD" json:"lockout_period"`
+	PassLength    int           `env:"PASSWORD_MIN_LENGTH" json:"password_min_length"`
+	PassClasses   int           `env:"PASSWORD_MIN_CLASSES" json:"password_min_classes"`
+	PassEntropy   float64       `env:"PASSWORD_MIN_ENTROPY" json:"password_min_entropy"`
+	LoginPattern  string        `env:"LOGIN_PATTERN" json:"login_pattern"`
+	MaxDataSize   int64         `env:"MAX_DATA_SIZE" json:"max_data_size"`
+	BlobStore     string        `env:"BLOB_STORE" json:"blob_store"`
+	BlobDir       string        `env:"BLOB_DIR" json:"blob_dir"`
+	BlobThreshold int64         `env:"BLOB_THRESHOLD" json:"blob_threshold"`
+	S3Endpoint    string        `env:"S3_ENDPOINT" json:"s3_endpoint"`
+	S3Bucket      string        `env:"S3_BUCKET" json:"s3_bucket"`
+	S3Region      string        `env:"S3_REGION" json:"s3_region"`
+	S3AccessKey   string        `env:"S3_ACCESS_KEY" json:"s3_access_key"`
+	S3SecretKey   string        `env:"S3_SECRET_KEY" json:"s3_secret_key"`
+	Token         *hash.Token
 }
 
 // NewServerConfig returns new server config.
@@ -29,8 +56,36 @@ func NewServerConfig(ctx context.Context) *ServerConfig {
 // when launching the server.
 func (cfg *ServerConfig) ParseFlags(ctx context.Context) error {
 	flag.StringVar(&cfg.Address, "a", "localhost:8080", "HTTP-server endpoint address host:port")
+	flag.StringVar(&cfg.GRPCAddress, "g", "", "gRPC-server endpoint address host:port, gRPC server is disabled if empty")
+	flag.StringVar(&cfg.Storage, "s", "postgres", "Storage of users and data (postgres/sqlite/memory)")
 	flag.StringVar(&cfg.DSN, "d", "postgresql://localhost:5432/postgres", "URI (DSN) to database")
-	flag.DurationVar(&cfg.TokenExp, "exp", 3*time.Hour, "Expiration period for token")
+	flag.StringVar(&cfg.SQLitePath, "sp", "gophkeeper.db", "Path to the file of embedded SQLite database")
+	flag.StringVar(&cfg.TLSCert, "cert", "", "Path to PEM file with TLS certificate, TLS is disabled if empty")
+	flag.StringVar(&cfg.TLSKey, "key", "", "Path to PEM file with TLS private key")
+	flag.StringVar(&cfg.TLSMinVersion, "tlsmin", "1.2", "Minimum TLS version (1.2/1.3)")
+	flag.StringVar(&cfg.TLSClientCA, "client-ca", "", "Path to PEM file with CA certificates for verifying client certificates, "+
+		"client certificates are not required if empty")
+	flag.DurationVar(&cfg.TokenExp, "exp", 15*time.Minute, "Expiration period for access token")
+	flag.DurationVar(&cfg.RefreshExp, "rexp", 30*24*time.Hour, "Expiration period for refresh token and session")
+	flag.StringVar(&cfg.TokenKeyFile, "k", "", "Path to PEM file with keys for signing tokens, keys are stored in database if empty")
+	flag.DurationVar(&cfg.KeyRotation, "kr", 0, "Rotation period for token signing key, zero disables rotation")
+	flag.DurationVar(&cfg.KeyRefresh, "kf", time.Minute, "Period for reloading token signing keys from the storage")
+	flag.IntVar(&cfg.AuthRate, "rl", 20, "Maximum login and registration requests per minute from one IP address, zero disables limiting")
+	flag.IntVar(&cfg.LockAttempts, "la", 5, "Failed login attempts before the login is locked, zero disables lockout")
+	flag.DurationVar(&cfg.LockPeriod, "lp", 30*time.Second, "Lockout period of the login, doubled for every next failed attempt")
+	flag.IntVar(&cfg.PassLength, "pl", 8, "Minimum length of the password")
+	flag.IntVar(&cfg.PassClasses, "pc", 2, "Minimum number of character classes (lowercase, uppercase, digits, symbols) in the password")
+	flag.Float64Var(&cfg.PassEntropy, "pe", 40, "Minimum estimated entropy of the password in bits")
+	flag.StringVar(&cfg.LoginPattern, "lr", `^[A-Za-z0-9][A-Za-z0-9._@-]{2,63}$`, "Regular expression for the login format")
+	flag.Int64Var(&cfg.MaxDataSize, "ms", 512<<20, "Maximum size of data object value in bytes, zero disables the limit")
+	flag.StringVar(&cfg.BlobStore, "bs", "", "Storage of binary values (fs/s3), values are stored in database if empty")
+	flag.StringVar(&cfg.BlobDir, "bd", "blobs", "Directory of the file storage of binary values")
+	flag.Int64Var(&cfg.BlobThreshold, "bt", 64<<10, "Minimum size in bytes of binary value kept in the storage of binary values")
+	flag.StringVar(&cfg.S3Endpoint, "s3-endpoint", "", "Endpoint of S3-compatible storage, e.g. http://localhost:9000")
+	flag.StringVar(&cfg.S3Bucket, "s3-bucket", "", "Bucket of S3-compatible storage")
+	flag.StringVar(&cfg.S3Region, "s3-region", "us-east-1", "Region of S3-compatible storage")
+	flag.StringVar(&cfg.S3AccessKey, "s3-access-key", "", "Access key of S3-compatible storage")
+	flag.StringVar(&cfg.S3SecretKey, "s3-secret-key", "", "Secret key of S3-compatible storage")
 
 	flag.Parse()
 
@@ -39,5 +94,30 @@ func (cfg *ServerConfig) ParseFlags(ctx context.Context) error {
 		return fmt.Errorf("ParseFlags: wrong environment values %w", err)
 	}
 
+	if cfg.TLSEnabled() && cfg.TLSKey == "" {
+		return fmt.Errorf("ParseFlags: TLS private key is required with TLS certificate")
+	}
+	switch cfg.Storage {
+	case "postgres", "sqlite":
+	case "memory":
+		if cfg.BlobStore != "" {
+			return fmt.Errorf("ParseFlags: storage of binary values is not used with memory storage")
+		}
+	default:
+		return fmt.Errorf("ParseFlags: unknown storage %s", cfg.Storage)
+	}
+	switch cfg.BlobStore {
+	case "", "fs":
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return fmt.Errorf("ParseFlags: endpoint and bucket are required for S3 storage")
+		}
+	default:
+		return fmt.Errorf("ParseFlags: unknown storage of binary values %s", cfg.BlobStore)
+	}
+	if cfg.TLSClientCA != "" && !cfg.TLSEnabled() {
+		return fmt.Errorf("ParseFlags: client certificates require TLS certificate of the server")
+	}
+
 	return nil
 }
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsVersions contains supported minimum versions of TLS.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSEnabled reports whether the server accepts only TLS connections.
+func (cfg *ServerConfig) TLSEnabled() bool {
+	return cfg.TLSCert != ""
+}
+
+// TLSConfig returns TLS configuration of the server, nil is returned if TLS is disabled.
+// If the client CA file is specified, clients must present certificates signed by it.
+func (cfg *ServerConfig) TLSConfig() (*tls.Config, error) {
+	if !cfg.TLSEnabled() {
+		return nil, nil
+	}
+
+	minVersion, ok := tlsVersions[cfg.TLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("TLSConfig: unsupported TLS version %s", cfg.TLSMinVersion)
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("TLSConfig: load certificate failed %w", err)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:   minVersion,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if cfg.TLSClientCA != "" {
+		pool, err := loadCertPool(cfg.TLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("TLSConfig: %w", err)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsCfg, nil
+}
+
+// TLSConfig returns TLS configuration of the client with the custom CA bundle
+// and the client certificate, nil is returned if neither is specified.
+func (cfg *ClientConfig) TLSConfig() (*tls.Config, error) {
+	if cfg.TLSCA == "" && cfg.TLSCert == "" {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.TLSCA != "" {
+		pool, err := loadCertPool(cfg.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("TLSConfig: %w", err)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("TLSConfig: load client certificate failed %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// loadCertPool reads PEM certificates from the file into the new pool.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loadCertPool: read CA file failed %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("loadCertPool: no certificates found in %s", path)
+	}
+	return pool, nil
+}
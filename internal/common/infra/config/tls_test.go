@@ -0,0 +1,125 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert creates the certificate signed by the parent, or self-signed if the parent
+// is nil, writes it with its key into the directory and returns the paths.
+func writeCert(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	tmpl *x509.Certificate) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed %v", err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.Subject = pkix.Name{CommonName: name}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("create certificate failed %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certPath := filepath.Join(dir, name+".pem")
+	keyPath := filepath.Join(dir, name+".key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certPath, keyPath, cert, key
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	caPath, _, ca, caKey := writeCert(t, dir, "ca", nil, nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	srvCert, srvKey, _, _ := writeCert(t, dir, "server", ca, caKey, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	cliCert, cliKey, _, _ := writeCert(t, dir, "client", ca, caKey, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	serverCfg := &ServerConfig{TLSCert: srvCert, TLSKey: srvKey, TLSMinVersion: "1.3", TLSClientCA: caPath}
+	srvTLS, err := serverCfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("ServerConfig.TLSConfig() error = %v", err)
+	}
+	if srvTLS.MinVersion != tls.VersionTLS13 || srvTLS.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("ServerConfig.TLSConfig() = %v, want TLS 1.3 with client certificates", srvTLS)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = srvTLS
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		cfg     *ClientConfig
+		wantErr bool
+	}{
+		{
+			name:    "client_certificate",
+			cfg:     &ClientConfig{TLSCA: caPath, TLSCert: cliCert, TLSKey: cliKey},
+			wantErr: false,
+		},
+		{
+			name:    "without_client_certificate",
+			cfg:     &ClientConfig{TLSCA: caPath},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_server",
+			cfg:     &ClientConfig{TLSCert: cliCert, TLSKey: cliKey},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cliTLS, err := tt.cfg.TLSConfig()
+			if err != nil {
+				t.Fatalf("ClientConfig.TLSConfig() error = %v", err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: cliTLS}}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("request error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if cfg, err := (&ServerConfig{}).TLSConfig(); cfg != nil || err != nil {
+		t.Errorf("ServerConfig.TLSConfig() disabled = %v, %v, want nil", cfg, err)
+	}
+	if _, err := (&ServerConfig{TLSCert: srvCert, TLSKey: srvKey, TLSMinVersion: "1.0"}).TLSConfig(); err == nil {
+		t.Errorf("ServerConfig.TLSConfig() unsupported version error = nil")
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"net/url"
 
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose/v3"
 )
 
 //go:embed migrations/*.sql
 var embedMigrations embed.FS
 
+//go:embed sqlite/*.sql
+var embedSQLiteMigrations embed.FS
+
 // Init initializes database and creates the tables
 // from the specified migrations.
 func Init(ctx context.Context, path string) (*sql.DB, error) {
@@ -39,3 +47,51 @@ func Init(ctx context.Context, path string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// InitSQLite opens the embedded SQLite database in the file and creates
+// the tables from the SQLite migrations. Foreign keys are enabled and the write
+// lock is taken at the beginning of transactions, so concurrent transactions
+// wait for each other instead of failing on upgrading the lock.
+func InitSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_txlock", "immediate")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_journal_mode", "WAL")
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("InitSQLite: couldn't open database %w", err)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("InitSQLite: open database file failed %w", err)
+	}
+
+	// Migrations
+	goose.SetBaseFS(embedSQLiteMigrations)
+	err = goose.SetDialect("sqlite3")
+	if err != nil {
+		return nil, fmt.Errorf("InitSQLite: goose set dialect failed %w", err)
+	}
+	err = goose.Up(db, "sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("InitSQLite: goose up failed %w", err)
+	}
+
+	return db, nil
+}
+
+// IsUniqueViolation checks whether the error is the violation
+// of the unique constraint in PostgreSQL or SQLite.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgerrcode.UniqueViolation
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
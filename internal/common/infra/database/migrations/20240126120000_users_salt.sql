@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- salt for deriving the client-side encryption key from the master password
+ALTER TABLE users ADD COLUMN IF NOT EXISTS salt bytea;
+UPDATE users SET salt = decode(md5(random()::text || id::text), 'hex') WHERE salt IS NULL;
+ALTER TABLE users ALTER COLUMN salt SET NOT NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE users DROP COLUMN salt;
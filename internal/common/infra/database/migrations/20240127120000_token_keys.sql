@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+CREATE TABLE IF NOT EXISTS token_keys (
+    id varchar(64) PRIMARY KEY,
+    private_key bytea NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE token_keys;
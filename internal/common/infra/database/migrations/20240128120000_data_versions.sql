@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE data ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS data_versions (
+    id serial PRIMARY KEY,
+    data_id integer NOT NULL REFERENCES data (id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    data bytea,
+    metadata jsonb,
+    device varchar(128) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT NOW(),
+    UNIQUE (data_id, version)
+);
+
+-- existing data becomes the first version
+INSERT INTO data_versions (data_id, version, data, metadata, created_at)
+SELECT id, version, data, metadata, created_at FROM data;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE data_versions;
+ALTER TABLE data DROP COLUMN version;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- per-user change sequence
+ALTER TABLE users ADD COLUMN IF NOT EXISTS data_seq bigint NOT NULL DEFAULT 0;
+ALTER TABLE data ADD COLUMN IF NOT EXISTS seq bigint NOT NULL DEFAULT 0;
+
+UPDATE data d SET seq = s.rn FROM (
+    SELECT id, row_number() OVER (PARTITION BY user_id ORDER BY id) AS rn FROM data
+) s WHERE d.id = s.id;
+UPDATE users u SET data_seq = COALESCE((SELECT MAX(seq) FROM data WHERE user_id = u.id), 0);
+
+-- deleted data objects for synchronization
+CREATE TABLE IF NOT EXISTS data_tombstones (
+    user_id integer REFERENCES users (id),
+    name varchar(64),
+    data_type data_type,
+    seq bigint NOT NULL,
+    deleted_at timestamp DEFAULT NOW(),
+    PRIMARY KEY (user_id, data_type, name)
+);
+
+CREATE INDEX IF NOT EXISTS data_user_id_seq_idx ON data (user_id, seq);
+CREATE INDEX IF NOT EXISTS data_tombstones_user_id_seq_idx ON data_tombstones (user_id, seq);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX data_tombstones_user_id_seq_idx;
+DROP INDEX data_user_id_seq_idx;
+DROP TABLE data_tombstones;
+ALTER TABLE data DROP COLUMN seq;
+ALTER TABLE users DROP COLUMN data_seq;
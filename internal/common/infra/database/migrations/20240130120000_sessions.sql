@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- login sessions with hashes of refresh tokens
+CREATE TABLE IF NOT EXISTS sessions (
+    id varchar(64) PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_hash bytea UNIQUE NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    last_used_at timestamp NOT NULL DEFAULT NOW(),
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX sessions_user_id_idx;
+DROP TABLE sessions;
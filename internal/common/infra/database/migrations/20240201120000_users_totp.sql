@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- TOTP secrets for two-factor authentication
+CREATE TABLE IF NOT EXISTS users_totp (
+    user_id integer PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret bytea NOT NULL,
+    enabled boolean NOT NULL DEFAULT false,
+    last_step bigint NOT NULL DEFAULT 0
+);
+
+-- hashes of one-time recovery codes
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash bytea NOT NULL,
+    used_at timestamp,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE recovery_codes;
+DROP TABLE users_totp;
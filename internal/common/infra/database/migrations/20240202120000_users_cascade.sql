@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- data of the user is deleted together with the user
+ALTER TABLE data DROP CONSTRAINT IF EXISTS data_user_id_fkey;
+ALTER TABLE data ADD CONSTRAINT data_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE data_tombstones DROP CONSTRAINT IF EXISTS data_tombstones_user_id_fkey;
+ALTER TABLE data_tombstones ADD CONSTRAINT data_tombstones_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE data_tombstones DROP CONSTRAINT data_tombstones_user_id_fkey;
+ALTER TABLE data_tombstones ADD CONSTRAINT data_tombstones_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE data DROP CONSTRAINT data_user_id_fkey;
+ALTER TABLE data ADD CONSTRAINT data_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id);
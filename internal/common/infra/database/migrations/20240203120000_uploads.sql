@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- chunked upload sessions of large data values
+CREATE TABLE IF NOT EXISTS uploads (
+    id varchar(64) PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name varchar(64) NOT NULL,
+    data_type data_type NOT NULL,
+    metadata bytea,
+    size bigint NOT NULL,
+    received bigint NOT NULL DEFAULT 0,
+    checksum varchar(64) NOT NULL DEFAULT '',
+    is_update boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    expires_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS upload_chunks (
+    upload_id varchar(64) REFERENCES uploads (id) ON DELETE CASCADE,
+    chunk_offset bigint,
+    data bytea NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
+
+CREATE INDEX IF NOT EXISTS uploads_expires_at_idx ON uploads (expires_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX uploads_expires_at_idx;
+DROP TABLE upload_chunks;
+DROP TABLE uploads;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- keys of binary values kept in the blob storage instead of the data column
+ALTER TABLE data ADD COLUMN IF NOT EXISTS blob_key varchar(64);
+ALTER TABLE data_versions ADD COLUMN IF NOT EXISTS blob_key varchar(64);
+
+CREATE INDEX IF NOT EXISTS data_blob_key_idx ON data (blob_key) WHERE blob_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS data_versions_blob_key_idx ON data_versions (blob_key) WHERE blob_key IS NOT NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX data_versions_blob_key_idx;
+DROP INDEX data_blob_key_idx;
+ALTER TABLE data_versions DROP COLUMN blob_key;
+ALTER TABLE data DROP COLUMN blob_key;
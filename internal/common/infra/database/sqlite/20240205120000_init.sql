@@ -0,0 +1,138 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- schema of the embedded database, it follows the PostgreSQL migrations
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    login varchar(64) UNIQUE NOT NULL,
+    password blob NOT NULL,
+    salt blob NOT NULL,
+    data_seq bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS token_keys (
+    id varchar(64) PRIMARY KEY,
+    private_key blob NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS data (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id integer REFERENCES users (id) ON DELETE CASCADE,
+    name varchar(64),
+    data_type text CHECK (data_type IN ('credentials', 'text', 'binary', 'card')),
+    data blob,
+    blob_key varchar(64),
+    metadata blob,
+    version integer NOT NULL DEFAULT 1,
+    seq bigint NOT NULL DEFAULT 0,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS data_user_id_idx ON data (user_id);
+CREATE INDEX IF NOT EXISTS data_name_idx ON data (name);
+CREATE INDEX IF NOT EXISTS data_user_id_seq_idx ON data (user_id, seq);
+CREATE INDEX IF NOT EXISTS data_blob_key_idx ON data (blob_key) WHERE blob_key IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS data_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    data_id integer NOT NULL REFERENCES data (id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    data blob,
+    blob_key varchar(64),
+    metadata blob,
+    device varchar(128) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (data_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS data_versions_blob_key_idx ON data_versions (blob_key) WHERE blob_key IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS data_tombstones (
+    user_id integer REFERENCES users (id) ON DELETE CASCADE,
+    name varchar(64),
+    data_type text,
+    seq bigint NOT NULL,
+    deleted_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, data_type, name)
+);
+
+CREATE INDEX IF NOT EXISTS data_tombstones_user_id_seq_idx ON data_tombstones (user_id, seq);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id varchar(64) PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_hash blob UNIQUE NOT NULL,
+    device varchar(128) NOT NULL DEFAULT '',
+    ip varchar(64) NOT NULL DEFAULT '',
+    user_agent varchar(256) NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS users_totp (
+    user_id integer PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret blob NOT NULL,
+    enabled boolean NOT NULL DEFAULT false,
+    last_step bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash blob NOT NULL,
+    used_at timestamp,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id varchar(64) PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name varchar(64) NOT NULL,
+    data_type text NOT NULL,
+    metadata blob,
+    size bigint NOT NULL,
+    received bigint NOT NULL DEFAULT 0,
+    checksum varchar(64) NOT NULL DEFAULT '',
+    is_update boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS upload_chunks (
+    upload_id varchar(64) REFERENCES uploads (id) ON DELETE CASCADE,
+    chunk_offset bigint,
+    data blob NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
+
+CREATE INDEX IF NOT EXISTS uploads_expires_at_idx ON uploads (expires_at);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX uploads_expires_at_idx;
+DROP TABLE upload_chunks;
+DROP TABLE uploads;
+DROP TABLE recovery_codes;
+DROP TABLE users_totp;
+DROP INDEX sessions_user_id_idx;
+DROP TABLE sessions;
+DROP INDEX data_tombstones_user_id_seq_idx;
+DROP TABLE data_tombstones;
+DROP INDEX data_versions_blob_key_idx;
+DROP TABLE data_versions;
+DROP INDEX data_blob_key_idx;
+DROP INDEX data_user_id_seq_idx;
+DROP INDEX data_name_idx;
+DROP INDEX data_user_id_idx;
+DROP TABLE data;
+DROP TABLE token_keys;
+DROP TABLE users;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- original file name, content type, size and SHA-256 of binary values
+ALTER TABLE data ADD COLUMN file_name varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE data ADD COLUMN content_type varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE data ADD COLUMN size bigint NOT NULL DEFAULT 0;
+ALTER TABLE data ADD COLUMN checksum varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE data_versions ADD COLUMN file_name varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE data_versions ADD COLUMN content_type varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE data_versions ADD COLUMN size bigint NOT NULL DEFAULT 0;
+ALTER TABLE data_versions ADD COLUMN checksum varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE uploads ADD COLUMN file_name varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE uploads ADD COLUMN content_type varchar(255) NOT NULL DEFAULT '';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE uploads DROP COLUMN content_type;
+ALTER TABLE uploads DROP COLUMN file_name;
+ALTER TABLE data_versions DROP COLUMN checksum;
+ALTER TABLE data_versions DROP COLUMN size;
+ALTER TABLE data_versions DROP COLUMN content_type;
+ALTER TABLE data_versions DROP COLUMN file_name;
+ALTER TABLE data DROP COLUMN checksum;
+ALTER TABLE data DROP COLUMN size;
+ALTER TABLE data DROP COLUMN content_type;
+ALTER TABLE data DROP COLUMN file_name;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- nested folders of the user, deleting the folder deletes its subfolders
+-- and moves its data objects to the root
+CREATE TABLE IF NOT EXISTS folders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    parent_id integer REFERENCES folders (id) ON DELETE CASCADE,
+    name varchar(64) NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS folders_user_id_parent_id_name_idx ON folders (user_id, COALESCE(parent_id, 0), name);
+
+ALTER TABLE data ADD COLUMN folder_id integer REFERENCES folders (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS data_folder_id_idx ON data (folder_id);
+
+-- free-form tags of data objects
+CREATE TABLE IF NOT EXISTS data_tags (
+    data_id integer NOT NULL REFERENCES data (id) ON DELETE CASCADE,
+    tag varchar(64) NOT NULL,
+    PRIMARY KEY (data_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS data_tags_tag_idx ON data_tags (tag);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX data_tags_tag_idx;
+DROP TABLE data_tags;
+DROP INDEX data_folder_id_idx;
+ALTER TABLE data DROP COLUMN folder_id;
+DROP INDEX folders_user_id_parent_id_name_idx;
+DROP TABLE folders;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- keys of the values in the blob storage, the row of the key is locked while
+-- the value is saved and while it is deleted, so the value used by new data
+-- is never deleted concurrently
+CREATE TABLE IF NOT EXISTS blobs (
+    blob_key varchar(64) PRIMARY KEY
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE blobs;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- key check value for verifying the client-side encryption key derived from
+-- the master password, it is set by the client on the first unlock
+ALTER TABLE users ADD COLUMN key_check blob;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE users DROP COLUMN key_check;
@@ -0,0 +1,149 @@
+// Package encryption contains objects and methods for deriving
+// the encryption key from the master password and encrypting
+// the private data on the client side.
+package encryption
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Version1 is the format version for the data encrypted with
+// XChaCha20-Poly1305 by the key derived with Argon2id.
+const Version1 byte = 1
+
+// SaltSize is the size of the salt for key derivation in bytes.
+const SaltSize = 16
+
+// Argon2id parameters for key derivation of the Version1 format.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported encryption format version")
+	ErrMalformedData      = errors.New("malformed encrypted data")
+	ErrDecryptFailed      = errors.New("decryption failed")
+	ErrWrongKey           = errors.New("key doesn't match the key check value")
+)
+
+// keyCheckValue is the constant encrypted into the key check value, it is
+// decrypted for verifying that the key is derived from the right master password.
+var keyCheckValue = []byte("gophkeeper key check")
+
+// Cipher contains AEAD object for encrypting and decrypting data.
+type Cipher struct {
+	aead    cipher.AEAD
+	version byte
+}
+
+// NewCipher derives the key from the master password and salt
+// and returns new cipher object.
+func NewCipher(password string, salt []byte) (*Cipher, error) {
+	if len(salt) < SaltSize {
+		return nil, fmt.Errorf("NewCipher: salt is too short")
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize)
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("NewCipher: create aead failed %w", err)
+	}
+	return &Cipher{
+		aead:    aead,
+		version: Version1,
+	}, nil
+}
+
+// GenerateSalt returns new random salt for key derivation.
+func GenerateSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateSalt: read random bytes failed %w", err)
+	}
+	return salt, nil
+}
+
+// Encrypt encrypts and authenticates plaintext and additional data,
+// returns the result in format: version | nonce | ciphertext.
+func (c *Cipher) Encrypt(plaintext []byte, additional []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	out := make([]byte, 1+nonceSize, 1+nonceSize+len(plaintext)+c.aead.Overhead())
+	out[0] = c.version
+	_, err := rand.Read(out[1:])
+	if err != nil {
+		return nil, fmt.Errorf("Encrypt: generate nonce failed %w", err)
+	}
+	return c.aead.Seal(out, out[1:], plaintext, append([]byte{c.version}, additional...)), nil
+}
+
+// NewKeyCheck returns the key check value, the constant encrypted with the key.
+// It is stored together with the salt, so the key derived from the wrong master
+// password is detected before any data is decrypted or encrypted with it.
+func (c *Cipher) NewKeyCheck() ([]byte, error) {
+	check, err := c.Encrypt(keyCheckValue, []byte("key_check"))
+	if err != nil {
+		return nil, fmt.Errorf("NewKeyCheck: %w", err)
+	}
+	return check, nil
+}
+
+// CheckKey decrypts the key check value and returns ErrWrongKey,
+// if it isn't encrypted with the key of the cipher.
+func (c *Cipher) CheckKey(check []byte) error {
+	plain, err := c.Decrypt(check, []byte("key_check"))
+	if err != nil || !bytes.Equal(plain, keyCheckValue) {
+		return fmt.Errorf("CheckKey: %w", ErrWrongKey)
+	}
+	return nil
+}
+
+// IsEncrypted reports whether data starts with the known format version byte.
+// The values saved before the client-side encryption have no version byte,
+// but the plain value can start with the same byte as well, so the result is
+// only a guess and must be used only for reading such values on purpose.
+func IsEncrypted(data []byte) bool {
+	return len(data) > 0 && (data[0] == Version1 || data[0] == Version2)
+}
+
+// Decrypt checks the format version, decrypts and authenticates
+// the data with additional data, returns plaintext. The data
+// of the Version2 format is decrypted by chunks.
+func (c *Cipher) Decrypt(data []byte, additional []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("Decrypt: %w", ErrMalformedData)
+	}
+	if data[0] == Version2 {
+		r, err := c.NewDecryptReader(bytes.NewReader(data), additional)
+		if err != nil {
+			return nil, fmt.Errorf("Decrypt: %w", err)
+		}
+		plaintext, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("Decrypt: %w", err)
+		}
+		return plaintext, nil
+	}
+	if data[0] != c.version {
+		return nil, fmt.Errorf("Decrypt: %w %d", ErrUnsupportedVersion, data[0])
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(data) < 1+nonceSize+c.aead.Overhead() {
+		return nil, fmt.Errorf("Decrypt: %w", ErrMalformedData)
+	}
+	nonce, ciphertext := data[1:1+nonceSize], data[1+nonceSize:]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, append([]byte{data[0]}, additional...))
+	if err != nil {
+		return nil, fmt.Errorf("Decrypt: %w", ErrDecryptFailed)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,165 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCipher_EncryptDecrypt(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, SaltSize)
+	c, err := NewCipher("master", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	wrong, err := NewCipher("wrong", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	type args struct {
+		plaintext []byte
+		encAD     []byte
+		decAD     []byte
+		decrypter *Cipher
+		modify    func(b []byte)
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name: "ok",
+			args: args{
+				plaintext: []byte(`{"login": "user", "password": "pass"}`),
+				encAD:     []byte("credentials/github"),
+				decAD:     []byte("credentials/github"),
+				decrypter: c,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "empty_plaintext",
+			args: args{
+				plaintext: []byte{},
+				decrypter: c,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "wrong_password",
+			args: args{
+				plaintext: []byte("text"),
+				decrypter: wrong,
+			},
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name: "wrong_additional_data",
+			args: args{
+				plaintext: []byte("text"),
+				encAD:     []byte("text/first"),
+				decAD:     []byte("text/second"),
+				decrypter: c,
+			},
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name: "unsupported_version",
+			args: args{
+				plaintext: []byte("text"),
+				decrypter: c,
+				modify:    func(b []byte) { b[0] = 0 },
+			},
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name: "modified_ciphertext",
+			args: args{
+				plaintext: []byte("text"),
+				decrypter: c,
+				modify:    func(b []byte) { b[len(b)-1] ^= 0xff },
+			},
+			wantErr: ErrDecryptFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := c.Encrypt(tt.args.plaintext, tt.args.encAD)
+			if err != nil {
+				t.Fatalf("Cipher.Encrypt() error = %v", err)
+			}
+			if encrypted[0] != Version1 {
+				t.Errorf("Cipher.Encrypt() version = %d, want %d", encrypted[0], Version1)
+			}
+			if tt.args.modify != nil {
+				tt.args.modify(encrypted)
+			}
+			got, err := tt.args.decrypter.Decrypt(encrypted, tt.args.decAD)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Cipher.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, tt.args.plaintext) {
+				t.Errorf("Cipher.Decrypt() = %s, want %s", got, tt.args.plaintext)
+			}
+		})
+	}
+}
+
+func TestNewCipher(t *testing.T) {
+	_, err := NewCipher("master", []byte("short"))
+	if err == nil {
+		t.Errorf("NewCipher() expected error for short salt")
+	}
+}
+
+func TestCipher_CheckKey(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, SaltSize)
+	c, err := NewCipher("master", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	check, err := c.NewKeyCheck()
+	if err != nil {
+		t.Fatalf("Cipher.NewKeyCheck() error = %v", err)
+	}
+	wrong, err := NewCipher("wrong", salt)
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		c       *Cipher
+		check   []byte
+		wantErr error
+	}{
+		{
+			name:    "right_key",
+			c:       c,
+			check:   check,
+			wantErr: nil,
+		},
+		{
+			name:    "wrong_key",
+			c:       wrong,
+			check:   check,
+			wantErr: ErrWrongKey,
+		},
+		{
+			name:    "malformed_check",
+			c:       c,
+			check:   check[:10],
+			wantErr: ErrWrongKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.c.CheckKey(tt.check)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Cipher.CheckKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version2 is the format version for the data encrypted with XChaCha20-Poly1305
+// by the key derived with Argon2id in chunks, so the large values are encrypted
+// and decrypted as a stream.
+const Version2 byte = 2
+
+// ChunkSize is the size of plaintext in one encrypted chunk of the Version2 format.
+const ChunkSize = 64 << 10
+
+// prefixSize is the size of the random nonce prefix of the Version2 format,
+// the rest of the nonce is the number of the chunk and the flag of the last chunk.
+const prefixSize = 15
+
+// NewEncryptWriter returns the writer, which encrypts and authenticates plaintext
+// written into it with additional data and writes the result into w in format:
+// version | nonce prefix | chunk | ... | last chunk. Each chunk is sealed with
+// its own nonce, which contains the number of the chunk and the flag of the last
+// chunk, so the chunks cannot be reordered, dropped or truncated unnoticed.
+// The last chunk is written, when the writer is closed.
+func (c *Cipher) NewEncryptWriter(w io.Writer, additional []byte) (io.WriteCloser, error) {
+	header := make([]byte, 1+prefixSize)
+	header[0] = Version2
+	_, err := rand.Read(header[1:])
+	if err != nil {
+		return nil, fmt.Errorf("NewEncryptWriter: generate nonce prefix failed %w", err)
+	}
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("NewEncryptWriter: write header failed %w", err)
+	}
+	return &encryptWriter{
+		aead:       c.aead,
+		w:          w,
+		nonce:      newStreamNonce(header[1:]),
+		additional: append([]byte{Version2}, additional...),
+		buf:        make([]byte, 0, ChunkSize),
+	}, nil
+}
+
+// NewDecryptReader checks the format version and returns the reader of plaintext
+// decrypted and authenticated with additional data from r. The Version2 data
+// is decrypted by chunks, the reader returns the error, if the chunk is modified
+// or the data is truncated. The Version1 data is decrypted at once.
+func (c *Cipher) NewDecryptReader(r io.Reader, additional []byte) (io.Reader, error) {
+	header := make([]byte, 1+prefixSize)
+	_, err := io.ReadFull(r, header[:1])
+	if err != nil {
+		return nil, fmt.Errorf("NewDecryptReader: %w", ErrMalformedData)
+	}
+	switch header[0] {
+	case Version1:
+		data, err := io.ReadAll(io.MultiReader(bytes.NewReader(header[:1]), r))
+		if err != nil {
+			return nil, fmt.Errorf("NewDecryptReader: read data failed %w", err)
+		}
+		plaintext, err := c.Decrypt(data, additional)
+		if err != nil {
+			return nil, fmt.Errorf("NewDecryptReader: %w", err)
+		}
+		return bytes.NewReader(plaintext), nil
+	case Version2:
+	default:
+		return nil, fmt.Errorf("NewDecryptReader: %w %d", ErrUnsupportedVersion, header[0])
+	}
+
+	_, err = io.ReadFull(r, header[1:])
+	if err != nil {
+		return nil, fmt.Errorf("NewDecryptReader: %w", ErrMalformedData)
+	}
+	return &decryptReader{
+		aead:       c.aead,
+		r:          bufio.NewReaderSize(r, ChunkSize+c.aead.Overhead()),
+		nonce:      newStreamNonce(header[1:]),
+		additional: append([]byte{Version2}, additional...),
+		chunk:      make([]byte, ChunkSize+c.aead.Overhead()),
+	}, nil
+}
+
+// streamNonce contains the nonce of the chunk: the random prefix,
+// the number of the chunk and the flag of the last chunk.
+type streamNonce []byte
+
+// newStreamNonce returns the nonce of the first chunk with the prefix.
+func newStreamNonce(prefix []byte) streamNonce {
+	nonce := make(streamNonce, prefixSize+8+1)
+	copy(nonce, prefix)
+	return nonce
+}
+
+// next returns the nonce of the current chunk and moves to the next chunk.
+func (n streamNonce) next(last bool) []byte {
+	counter := n[prefixSize : prefixSize+8]
+	nonce := append([]byte(nil), n...)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	binary.BigEndian.PutUint64(counter, binary.BigEndian.Uint64(counter)+1)
+	return nonce
+}
+
+// encryptWriter encrypts plaintext by chunks. The full chunk is kept
+// until more plaintext is written, since the last chunk is sealed differently.
+type encryptWriter struct {
+	aead       cipher.AEAD
+	w          io.Writer
+	nonce      streamNonce
+	additional []byte
+	buf        []byte
+	out        []byte
+	closed     bool
+}
+
+// Write encrypts the full chunks of plaintext and writes them.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("Write: encrypt writer is closed")
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == ChunkSize {
+			err := e.seal(false)
+			if err != nil {
+				return written, fmt.Errorf("Write: %w", err)
+			}
+		}
+		n := min(len(p), ChunkSize-len(e.buf))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close encrypts and writes the last chunk, it doesn't close the underlying writer.
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	err := e.seal(true)
+	if err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
+	return nil
+}
+
+// seal encrypts the buffered chunk and writes it.
+func (e *encryptWriter) seal(last bool) error {
+	e.out = e.aead.Seal(e.out[:0], e.nonce.next(last), e.buf, e.additional)
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.out)
+	if err != nil {
+		return fmt.Errorf("seal: write chunk failed %w", err)
+	}
+	return nil
+}
+
+// decryptReader decrypts the data by chunks.
+type decryptReader struct {
+	aead       cipher.AEAD
+	r          *bufio.Reader
+	nonce      streamNonce
+	additional []byte
+	chunk      []byte
+	plaintext  []byte
+	err        error
+}
+
+// Read returns the decrypted plaintext, io.EOF is returned only after
+// the last chunk is authenticated.
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plaintext) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.open()
+	}
+	n := copy(p, d.plaintext)
+	d.plaintext = d.plaintext[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk. The chunk is the last one,
+// if it is shorter than the full chunk or there is no data after it.
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	last := false
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case errors.Is(err, io.EOF):
+		// The last chunk is not received
+		return fmt.Errorf("open: %w", ErrMalformedData)
+	case err != nil:
+		return fmt.Errorf("open: read chunk failed %w", err)
+	default:
+		_, err = d.r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return fmt.Errorf("open: read chunk failed %w", err)
+		}
+	}
+
+	d.plaintext, err = d.aead.Open(d.chunk[:0], d.nonce.next(last), d.chunk[:n], d.additional)
+	if err != nil {
+		return fmt.Errorf("open: %w", ErrDecryptFailed)
+	}
+	if last {
+		return io.EOF
+	}
+	return nil
+}
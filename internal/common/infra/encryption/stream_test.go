@@ -0,0 +1,164 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCipher_Stream(t *testing.T) {
+	c, err := NewCipher("master", bytes.Repeat([]byte{1}, SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	overhead := c.aead.Overhead()
+	chunk := ChunkSize + overhead
+
+	tests := []struct {
+		name      string
+		size      int
+		decAD     []byte
+		modify    func(b []byte) []byte
+		wantErr   error
+		wantBytes int
+	}{
+		{
+			name:      "empty",
+			size:      0,
+			wantBytes: 1 + prefixSize + overhead,
+		},
+		{
+			name:      "one_chunk",
+			size:      100,
+			wantBytes: 1 + prefixSize + 100 + overhead,
+		},
+		{
+			name:      "full_chunks",
+			size:      2 * ChunkSize,
+			wantBytes: 1 + prefixSize + 2*chunk,
+		},
+		{
+			name:      "partial_chunk",
+			size:      2*ChunkSize + 1,
+			wantBytes: 1 + prefixSize + 2*chunk + 1 + overhead,
+		},
+		{
+			name:    "wrong_additional_data",
+			size:    100,
+			decAD:   []byte("binary/other/data"),
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name:    "modified_chunk",
+			size:    2 * ChunkSize,
+			modify:  func(b []byte) []byte { b[1+prefixSize+10] ^= 0xff; return b },
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name:    "truncated_at_chunk",
+			size:    2*ChunkSize + 1,
+			modify:  func(b []byte) []byte { return b[:1+prefixSize+2*chunk] },
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name:    "swapped_chunks",
+			size:    3 * ChunkSize,
+			modify:  func(b []byte) []byte { swapChunks(b[1+prefixSize:], chunk); return b },
+			wantErr: ErrDecryptFailed,
+		},
+		{
+			name:    "no_chunks",
+			size:    100,
+			modify:  func(b []byte) []byte { return b[:1+prefixSize] },
+			wantErr: ErrMalformedData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := bytes.Repeat([]byte("0123456789"), tt.size/10+1)[:tt.size]
+			ad := []byte("binary/scan/data")
+
+			var buf bytes.Buffer
+			w, err := c.NewEncryptWriter(&buf, ad)
+			if err != nil {
+				t.Fatalf("Cipher.NewEncryptWriter() error = %v", err)
+			}
+			// Plaintext is written in pieces, which are not aligned with chunks
+			for p := plaintext; len(p) > 0; {
+				n := min(len(p), 1000)
+				if _, err := w.Write(p[:n]); err != nil {
+					t.Fatalf("encryptWriter.Write() error = %v", err)
+				}
+				p = p[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("encryptWriter.Close() error = %v", err)
+			}
+			encrypted := buf.Bytes()
+			if tt.wantBytes != 0 && len(encrypted) != tt.wantBytes {
+				t.Errorf("encrypted length = %d, want %d", len(encrypted), tt.wantBytes)
+			}
+			if tt.modify != nil {
+				encrypted = tt.modify(encrypted)
+			}
+			decAD := ad
+			if tt.decAD != nil {
+				decAD = tt.decAD
+			}
+
+			r, err := c.NewDecryptReader(bytes.NewReader(encrypted), decAD)
+			if err != nil {
+				t.Fatalf("Cipher.NewDecryptReader() error = %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decryptReader.Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, plaintext) {
+				t.Errorf("decryptReader.Read() = %d bytes, want %d bytes", len(got), len(plaintext))
+			}
+
+			// The stream is decrypted at once as well
+			got, err = c.Decrypt(encrypted, decAD)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Cipher.Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, plaintext) {
+				t.Errorf("Cipher.Decrypt() = %d bytes, want %d bytes", len(got), len(plaintext))
+			}
+		})
+	}
+}
+
+func TestCipher_NewDecryptReader_version1(t *testing.T) {
+	c, err := NewCipher("master", bytes.Repeat([]byte{1}, SaltSize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	encrypted, err := c.Encrypt([]byte("value"), []byte("binary/scan/data"))
+	if err != nil {
+		t.Fatalf("Cipher.Encrypt() error = %v", err)
+	}
+
+	r, err := c.NewDecryptReader(bytes.NewReader(encrypted), []byte("binary/scan/data"))
+	if err != nil {
+		t.Fatalf("Cipher.NewDecryptReader() error = %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "value" {
+		t.Errorf("decryptReader.Read() = %s, error = %v", got, err)
+	}
+
+	_, err = c.NewDecryptReader(bytes.NewReader([]byte("plain")), nil)
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Cipher.NewDecryptReader() error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+// swapChunks swaps the first two chunks of the encrypted data.
+func swapChunks(chunks []byte, size int) {
+	first := append([]byte(nil), chunks[:size]...)
+	copy(chunks, chunks[size:2*size])
+	copy(chunks[size:], first)
+}
@@ -6,44 +6,81 @@ import (
 	"context"
 	"crypto/rsa"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
 // Claims contains objects for claiming JWT
-// and catching ID.
+// and catching ID of the user and its session.
 type Claims struct {
 	jwt.RegisteredClaims
-	ID int
+	ID        int
+	SessionID string `json:"sid,omitempty"`
 }
 
 // Token contains objects for signing tokens.
+// New tokens are signed with the newest key, tokens signed
+// with any of the active keys are valid.
 type Token struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	mu         sync.RWMutex
+	signKey    *Key
+	verifyKeys map[string]*rsa.PublicKey
 	tokenExp   time.Duration
 }
 
-// NewToken returns new token object.
-func NewToken(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, tokenExp time.Duration) *Token {
+// NewToken returns new token object without keys,
+// keys should be set with SetKeys before creating tokens.
+func NewToken(tokenExp time.Duration) *Token {
 	return &Token{
-		privateKey: privateKey,
-		publicKey:  publicKey,
+		verifyKeys: make(map[string]*rsa.PublicKey),
 		tokenExp:   tokenExp,
 	}
 }
 
-// Create creates token and returns it as a string.
-func (t *Token) Create(ctx context.Context, id int) (string, error) {
+// SetKeys replaces the active keys of the token. Keys must be ordered
+// from the oldest to the newest, the newest key is used for signing.
+func (t *Token) SetKeys(keys []*Key) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("SetKeys: %w", ErrNoKeys)
+	}
+	t.setKeys(keys, keys[len(keys)-1])
+	return nil
+}
+
+// setKeys replaces the active keys of the token and the key used for signing.
+func (t *Token) setKeys(keys []*Key, signKey *Key) {
+	verifyKeys := make(map[string]*rsa.PublicKey, len(keys))
+	for _, k := range keys {
+		verifyKeys[k.ID] = &k.Private.PublicKey
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.signKey = signKey
+	t.verifyKeys = verifyKeys
+}
+
+// Create creates token of the user session and returns it as a string.
+func (t *Token) Create(ctx context.Context, id int, sessionID string) (string, error) {
+	t.mu.RLock()
+	signKey := t.signKey
+	t.mu.RUnlock()
+	if signKey == nil {
+		return "", fmt.Errorf("Create: %w", ErrNoKeys)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(t.tokenExp)),
 		},
-		ID: id,
+		ID:        id,
+		SessionID: sessionID,
 	})
+	token.Header["kid"] = signKey.ID
 
-	tokenString, err := token.SignedString(t.privateKey)
+	tokenString, err := token.SignedString(signKey.Private)
 	if err != nil {
 		return "", fmt.Errorf("Create: sign string with key failed %w", err)
 	}
@@ -53,21 +90,40 @@ func (t *Token) Create(ctx context.Context, id int) (string, error) {
 
 // Validate returns received data from the token for authentication.
 func (t *Token) Validate(tokenString string) (int, error) {
+	claims, err := t.Parse(tokenString)
+	if err != nil {
+		return -1, fmt.Errorf("Validate: %w", err)
+	}
+	return claims.ID, nil
+}
+
+// Parse validates the token and returns its claims.
+func (t *Token) Parse(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims,
 		func(j *jwt.Token) (interface{}, error) {
 			if _, ok := j.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("Validate: unexpected signing method: %v", j.Header["alg"])
+				return nil, fmt.Errorf("Parse: unexpected signing method: %v", j.Header["alg"])
+			}
+			kid, ok := j.Header["kid"].(string)
+			if !ok {
+				return nil, fmt.Errorf("Parse: key id not found in header")
 			}
-			return t.publicKey, nil
+			t.mu.RLock()
+			publicKey, ok := t.verifyKeys[kid]
+			t.mu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("Parse: unknown key id %s", kid)
+			}
+			return publicKey, nil
 		})
 	if err != nil {
-		return -1, fmt.Errorf("Validate: parse token failed %w", err)
+		return nil, fmt.Errorf("Parse: parse token failed %w", err)
 	}
 	if !token.Valid {
-		return -1, fmt.Errorf("Validate: token is not valid")
+		return nil, fmt.Errorf("Parse: token is not valid")
 	}
 
-	return claims.ID, nil
+	return claims, nil
 }
@@ -0,0 +1,223 @@
+package hash
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryKeyStorage is an in-memory key storage for tests.
+type memoryKeyStorage struct {
+	keys []*Key
+}
+
+func (s *memoryKeyStorage) LoadKeys(ctx context.Context) ([]*Key, error) {
+	return append([]*Key{}, s.keys...), nil
+}
+
+func (s *memoryKeyStorage) SaveKey(ctx context.Context, key *Key) error {
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStorage) DeleteKeys(ctx context.Context, ids []string) error {
+	kept := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		deleted := false
+		for _, id := range ids {
+			if k.ID == id {
+				deleted = true
+			}
+		}
+		if !deleted {
+			kept = append(kept, k)
+		}
+	}
+	s.keys = kept
+	return nil
+}
+
+func TestToken_CreateValidate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	oldKey, err := NewKey(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	newKey, err := NewKey(now)
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+
+	oldToken := NewToken(time.Hour)
+	if _, err := oldToken.Create(ctx, 1, "first"); err == nil {
+		t.Fatalf("Token.Create() without keys expected error")
+	}
+	oldToken.SetKeys([]*Key{oldKey})
+	signedOld, err := oldToken.Create(ctx, 1, "first")
+	if err != nil {
+		t.Fatalf("Token.Create() error = %v", err)
+	}
+
+	rotated := NewToken(time.Hour)
+	rotated.SetKeys([]*Key{oldKey, newKey})
+	signedNew, err := rotated.Create(ctx, 2, "second")
+	if err != nil {
+		t.Fatalf("Token.Create() error = %v", err)
+	}
+	claims, err := rotated.Parse(signedNew)
+	if err != nil || claims.SessionID != "second" {
+		t.Fatalf("Token.Parse() = %v, error = %v, want session id %v", claims, err, "second")
+	}
+
+	onlyNew := NewToken(time.Hour)
+	onlyNew.SetKeys([]*Key{newKey})
+
+	tests := []struct {
+		name    string
+		token   *Token
+		signed  string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "old_key_still_active",
+			token:   rotated,
+			signed:  signedOld,
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "new_key",
+			token:   rotated,
+			signed:  signedNew,
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name:    "old_key_removed",
+			token:   onlyNew,
+			signed:  signedOld,
+			want:    -1,
+			wantErr: true,
+		},
+		{
+			name:    "new_key_unknown_for_old_token",
+			token:   oldToken,
+			signed:  signedNew,
+			want:    -1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.token.Validate(tt.signed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Token.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Token.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key, err := NewKey(time.Date(2024, time.January, 27, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	data, err := key.MarshalPEM()
+	if err != nil {
+		t.Fatalf("Key.MarshalPEM() error = %v", err)
+	}
+	keys, err := ParseKeys(append(data, data...))
+	if err != nil {
+		t.Fatalf("ParseKeys() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ParseKeys() got %d keys, want 2", len(keys))
+	}
+	if keys[0].ID != key.ID || !keys[0].CreatedAt.Equal(key.CreatedAt) {
+		t.Errorf("ParseKeys() = %v %v, want %v %v", keys[0].ID, keys[0].CreatedAt, key.ID, key.CreatedAt)
+	}
+	if _, err := ParseKeys([]byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n")); err == nil {
+		t.Errorf("ParseKeys() expected error for unknown block")
+	}
+	block, _ := pem.Decode(data)
+	delete(block.Headers, createdAtHeader)
+	if _, err := ParseKeys(pem.EncodeToMemory(block)); !errors.Is(err, ErrNoCreatedAt) {
+		t.Errorf("ParseKeys() error = %v, want %v", err, ErrNoCreatedAt)
+	}
+}
+
+func TestRotator_Refresh(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.January, 27, 12, 0, 0, 0, time.UTC)
+	storage := &memoryKeyStorage{}
+	token := NewToken(time.Hour)
+	r := NewRotator(token, storage, 24*time.Hour, time.Minute)
+
+	steps := []struct {
+		name     string
+		now      time.Time
+		wantKeys int
+		wantSign int
+	}{
+		{
+			name:     "initial_key",
+			now:      start,
+			wantKeys: 1,
+			wantSign: 0,
+		},
+		{
+			name:     "no_rotation_yet",
+			now:      start.Add(23 * time.Hour),
+			wantKeys: 1,
+			wantSign: 0,
+		},
+		{
+			name:     "rotation_new_key_published",
+			now:      start.Add(24 * time.Hour),
+			wantKeys: 2,
+			wantSign: 0,
+		},
+		{
+			name:     "new_key_signs_after_refresh",
+			now:      start.Add(24*time.Hour + time.Minute),
+			wantKeys: 2,
+			wantSign: 1,
+		},
+		{
+			name:     "old_key_valid_until_tokens_expire",
+			now:      start.Add(25*time.Hour + time.Minute),
+			wantKeys: 2,
+			wantSign: 1,
+		},
+		{
+			name:     "old_key_deleted",
+			now:      start.Add(25*time.Hour + time.Minute + time.Second),
+			wantKeys: 1,
+			wantSign: 0,
+		},
+	}
+	for _, st := range steps {
+		t.Run(st.name, func(t *testing.T) {
+			r.now = func() time.Time { return st.now }
+			if err := r.Refresh(ctx); err != nil {
+				t.Fatalf("Rotator.Refresh() error = %v", err)
+			}
+			if len(storage.keys) != st.wantKeys {
+				t.Errorf("Rotator.Refresh() stored %d keys, want %d", len(storage.keys), st.wantKeys)
+			}
+			if len(token.verifyKeys) != st.wantKeys {
+				t.Errorf("Rotator.Refresh() active %d keys, want %d", len(token.verifyKeys), st.wantKeys)
+			}
+			if token.signKey.ID != storage.keys[st.wantSign].ID {
+				t.Errorf("Rotator.Refresh() signing key is not the key %d", st.wantSign)
+			}
+		})
+	}
+}
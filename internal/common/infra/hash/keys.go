@@ -0,0 +1,125 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// createdAtHeader is the PEM header with the key creation time.
+const createdAtHeader = "Created-At"
+
+var (
+	ErrNoKeys       = errors.New("no keys for signing tokens")
+	ErrNoCreatedAt  = errors.New("key has no Created-At header")
+	ErrNotRSAKey    = errors.New("key is not RSA private key")
+	ErrUnknownBlock = errors.New("unknown PEM block type")
+)
+
+// Key contains RSA private key for signing tokens,
+// its identifier and creation time.
+type Key struct {
+	ID        string
+	Private   *rsa.PrivateKey
+	CreatedAt time.Time
+}
+
+// NewKey generates and returns new key.
+func NewKey(createdAt time.Time) (*Key, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("NewKey: generate private key failed %w", err)
+	}
+	return newKeyFromPrivate(privateKey, createdAt)
+}
+
+// newKeyFromPrivate returns new key object for the private key,
+// key identifier is derived from the public key, so it is the same
+// for every server replica.
+func newKeyFromPrivate(privateKey *rsa.PrivateKey, createdAt time.Time) (*Key, error) {
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("newKeyFromPrivate: marshal public key failed %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(sum[:12]),
+		Private:   privateKey,
+		CreatedAt: createdAt.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// MarshalPEM returns the key in PEM format with the creation time header.
+func (k *Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return nil, fmt.Errorf("MarshalPEM: marshal private key failed %w", err)
+	}
+	block := &pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			createdAtHeader: k.CreatedAt.Format(time.RFC3339),
+		},
+		Bytes: der,
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// ParseKeys parses all the PEM encoded RSA private keys from data
+// and returns them in the same order. Every key must have the Created-At
+// header with the creation time in RFC 3339 format, since the order
+// of rotation and the expiration of keys depend on it.
+func ParseKeys(data []byte) ([]*Key, error) {
+	keys := make([]*Key, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var privateKey *rsa.PrivateKey
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			pk, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("ParseKeys: parse PKCS1 private key failed %w", err)
+			}
+			privateKey = pk
+		case "PRIVATE KEY":
+			pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("ParseKeys: parse PKCS8 private key failed %w", err)
+			}
+			rsaKey, ok := pk.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("ParseKeys: %w", ErrNotRSAKey)
+			}
+			privateKey = rsaKey
+		default:
+			return nil, fmt.Errorf("ParseKeys: %w %s", ErrUnknownBlock, block.Type)
+		}
+
+		v, ok := block.Headers[createdAtHeader]
+		if !ok {
+			return nil, fmt.Errorf("ParseKeys: %w", ErrNoCreatedAt)
+		}
+		createdAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("ParseKeys: parse creation time failed %w", err)
+		}
+
+		key, err := newKeyFromPrivate(privateKey, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("ParseKeys: create key failed %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,111 @@
+package hash
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	"go.uber.org/zap"
+)
+
+// Rotator contains objects for keeping the token keys up to date
+// with the key storage and rotating them.
+type Rotator struct {
+	token    *Token
+	storage  KeyStorage
+	rotation time.Duration
+	refresh  time.Duration
+	now      func() time.Time
+}
+
+// NewRotator returns new rotator object. If rotation is positive,
+// new key is generated when the newest key becomes older than rotation.
+// New key is published for the refresh interval before it signs tokens,
+// so the other server replicas load it before they get tokens signed with it.
+func NewRotator(token *Token, storage KeyStorage, rotation, refresh time.Duration) *Rotator {
+	return &Rotator{
+		token:    token,
+		storage:  storage,
+		rotation: rotation,
+		refresh:  refresh,
+		now:      time.Now,
+	}
+}
+
+// Refresh loads the keys from the storage, generates new key if there are
+// no keys or the newest key should be rotated, deletes keys that can't sign
+// valid tokens anymore and sets the active keys to the token. The newest key
+// published for at least the refresh interval is used for signing.
+func (r *Rotator) Refresh(ctx context.Context) error {
+	keys, err := r.storage.LoadKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("Refresh: load keys failed %w", err)
+	}
+
+	now := r.now()
+	if len(keys) == 0 || (r.rotation > 0 && now.Sub(keys[len(keys)-1].CreatedAt) >= r.rotation) {
+		key, err := NewKey(now)
+		if err != nil {
+			return fmt.Errorf("Refresh: create new key failed %w", err)
+		}
+		err = r.storage.SaveKey(ctx, key)
+		if err != nil {
+			return fmt.Errorf("Refresh: save new key failed %w", err)
+		}
+		keys = append(keys, key)
+		logger.Log.Info("token signing key rotated", zap.String("kid", key.ID))
+	}
+
+	// The oldest key signs tokens until any newer key is published.
+	sign := 0
+	for i, k := range keys {
+		if now.Sub(k.CreatedAt) >= r.refresh {
+			sign = i
+		}
+	}
+
+	// Key is expired when all the tokens signed with it are expired,
+	// i.e. the next key has been signing tokens for longer than token expiration.
+	active := make([]*Key, 0, len(keys))
+	expired := make([]string, 0)
+	for i, k := range keys {
+		if i < sign && now.Sub(keys[i+1].CreatedAt.Add(r.refresh)) > r.token.tokenExp {
+			expired = append(expired, k.ID)
+			continue
+		}
+		active = append(active, k)
+	}
+	if len(expired) > 0 {
+		err = r.storage.DeleteKeys(ctx, expired)
+		if err != nil {
+			return fmt.Errorf("Refresh: delete expired keys failed %w", err)
+		}
+		logger.Log.Info("expired token signing keys deleted", zap.Strings("kid", expired))
+	}
+
+	r.token.setKeys(active, keys[sign])
+
+	return nil
+}
+
+// Run refreshes the keys with the refresh interval until context is done,
+// so the keys rotated by other server replicas become active.
+func (r *Rotator) Run(ctx context.Context) {
+	if r.refresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Refresh(ctx)
+			if err != nil {
+				logger.Log.Error("Run: refresh token keys failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// KeyStorage describes methods for loading, saving and deleting
+// the keys for signing tokens.
+type KeyStorage interface {
+	LoadKeys(ctx context.Context) ([]*Key, error)
+	SaveKey(ctx context.Context, key *Key) error
+	DeleteKeys(ctx context.Context, ids []string) error
+}
+
+// FileKeyStorage contains path to the file with PEM encoded keys.
+type FileKeyStorage struct {
+	path string
+}
+
+// NewFileKeyStorage returns new storage for keys in the PEM file.
+func NewFileKeyStorage(path string) *FileKeyStorage {
+	return &FileKeyStorage{
+		path: path,
+	}
+}
+
+// LoadKeys reads the keys from the file, returns them
+// sorted from the oldest to the newest.
+func (s *FileKeyStorage) LoadKeys(ctx context.Context) ([]*Key, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Key{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadKeys: read file failed %w", err)
+	}
+	keys, err := ParseKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("LoadKeys: parse keys failed %w", err)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// SaveKey appends the key to the file.
+func (s *FileKeyStorage) SaveKey(ctx context.Context, key *Key) error {
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return fmt.Errorf("SaveKey: marshal key failed %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("SaveKey: open file failed %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("SaveKey: write key failed %w", err)
+	}
+	return nil
+}
+
+// DeleteKeys rewrites the file without the requested keys.
+func (s *FileKeyStorage) DeleteKeys(ctx context.Context, ids []string) error {
+	keys, err := s.LoadKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteKeys: load keys failed %w", err)
+	}
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		if deleted[k.ID] {
+			continue
+		}
+		data, err := k.MarshalPEM()
+		if err != nil {
+			return fmt.Errorf("DeleteKeys: marshal key failed %w", err)
+		}
+		buf.Write(data)
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, buf.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("DeleteKeys: write file failed %w", err)
+	}
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		return fmt.Errorf("DeleteKeys: replace file failed %w", err)
+	}
+	return nil
+}
+
+// DBKeyStorage contains database for storing keys.
+type DBKeyStorage struct {
+	db *sql.DB
+}
+
+// NewDBKeyStorage returns new storage for keys in the database.
+func NewDBKeyStorage(db *sql.DB) *DBKeyStorage {
+	return &DBKeyStorage{
+		db: db,
+	}
+}
+
+// LoadKeys reads the keys from the database, returns them
+// sorted from the oldest to the newest.
+func (s *DBKeyStorage) LoadKeys(ctx context.Context) ([]*Key, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT private_key FROM token_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("LoadKeys: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*Key, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, fmt.Errorf("LoadKeys: scan row failed %w", err)
+		}
+		parsed, err := ParseKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("LoadKeys: parse key failed %w", err)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("LoadKeys: rows.Err %w", err)
+	}
+
+	return keys, nil
+}
+
+// SaveKey saves the key into the database.
+func (s *DBKeyStorage) SaveKey(ctx context.Context, key *Key) error {
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return fmt.Errorf("SaveKey: marshal key failed %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO token_keys (id, private_key, created_at) 
+	VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING`, key.ID, data, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("SaveKey: insert key failed %w", err)
+	}
+	return nil
+}
+
+// DeleteKeys deletes the requested keys from the database.
+func (s *DBKeyStorage) DeleteKeys(ctx context.Context, ids []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteKeys: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		_, err = tx.ExecContext(ctx, `DELETE FROM token_keys WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("DeleteKeys: delete key failed %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DeleteKeys: commit transaction failed %w", err)
+	}
+	return nil
+}
+
+// MemoryKeyStorage contains the keys kept in memory,
+// they are lost when the server is stopped.
+type MemoryKeyStorage struct {
+	mu   sync.Mutex
+	keys []*Key
+}
+
+// NewMemoryKeyStorage returns new storage for keys in memory.
+func NewMemoryKeyStorage() *MemoryKeyStorage {
+	return &MemoryKeyStorage{
+		keys: make([]*Key, 0),
+	}
+}
+
+// LoadKeys returns the stored keys sorted from the oldest to the newest.
+func (s *MemoryKeyStorage) LoadKeys(ctx context.Context) ([]*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]*Key, len(s.keys))
+	copy(keys, s.keys)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// SaveKey saves the key, if there is no key with the same id.
+func (s *MemoryKeyStorage) SaveKey(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.ID == key.ID {
+			return nil
+		}
+	}
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+// DeleteKeys deletes the requested keys.
+func (s *MemoryKeyStorage) DeleteKeys(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		if !deleted[k.ID] {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+	return nil
+}
@@ -0,0 +1,98 @@
+// Package totp contains methods for generating and verifying
+// time-based one-time passwords (RFC 6238).
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Parameters of the one-time passwords, which are supported by
+// the most of authenticator applications.
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	SecretSize = 20
+)
+
+// encoding is the encoding of secrets in otpauth URI.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret generates new random secret.
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, fmt.Errorf("NewSecret: %w", err)
+	}
+	return secret, nil
+}
+
+// EncodeSecret returns the secret in base32 encoding for entering it
+// into authenticator application manually.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI returns otpauth URI of the secret for the account,
+// which is usually shown as QR code.
+func URI(issuer string, account string, secret []byte) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", EncodeSecret(secret))
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(Digits))
+	params.Set("period", strconv.Itoa(int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Step returns the number of the time step for the time.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the one-time password of the secret for the time step.
+func Code(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
+
+// Verify checks the one-time password for the time, the passwords of skew
+// steps before and after the time are accepted for clock drift.
+// The step of the matched password is returned.
+func Verify(secret []byte, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(t)
+	for i := -int64(skew); i <= int64(skew); i++ {
+		expected := Code(secret, current+i)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return current + i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,120 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// Test vectors from RFC 6238 for SHA1, the last six digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{
+			name: "59",
+			unix: 59,
+			want: "287082",
+		},
+		{
+			name: "1111111109",
+			unix: 1111111109,
+			want: "081804",
+		},
+		{
+			name: "1111111111",
+			unix: 1111111111,
+			want: "050471",
+		},
+		{
+			name: "1234567890",
+			unix: 1234567890,
+			want: "005924",
+		},
+		{
+			name: "2000000000",
+			unix: 2000000000,
+			want: "279037",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(secret, Step(time.Unix(tt.unix, 0))); got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		wantStep int64
+		want     bool
+	}{
+		{
+			name:     "current_step",
+			code:     "050471",
+			at:       now,
+			wantStep: Step(now),
+			want:     true,
+		},
+		{
+			name:     "previous_step",
+			code:     "050471",
+			at:       now.Add(Period),
+			wantStep: Step(now),
+			want:     true,
+		},
+		{
+			name:     "too_old",
+			code:     "050471",
+			at:       now.Add(2 * Period),
+			wantStep: 0,
+			want:     false,
+		},
+		{
+			name:     "wrong_code",
+			code:     "123456",
+			at:       now,
+			wantStep: 0,
+			want:     false,
+		},
+		{
+			name:     "wrong_length",
+			code:     "50471",
+			at:       now,
+			wantStep: 0,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, got := Verify(secret, tt.code, tt.at, 1)
+			if got != tt.want || step != tt.wantStep {
+				t.Errorf("Verify() = %v %v, want %v %v", step, got, tt.wantStep, tt.want)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("GophKeeper", "user@example.com", []byte("12345678901234567890"))
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("URI() = %v, parse error %v", uri, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/GophKeeper:user@example.com" {
+		t.Errorf("URI() = %v, unexpected label", uri)
+	}
+	if got := u.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("URI() secret = %v, want %v", got, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	}
+}
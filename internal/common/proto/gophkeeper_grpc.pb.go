@@ -0,0 +1,680 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: gophkeeper.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Users_Register_FullMethodName = "/gophkeeper.Users/Register"
+	Users_Login_FullMethodName    = "/gophkeeper.Users/Login"
+	Users_Refresh_FullMethodName  = "/gophkeeper.Users/Refresh"
+	Users_Logout_FullMethodName   = "/gophkeeper.Users/Logout"
+)
+
+// UsersClient is the client API for Users service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UsersClient interface {
+	Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error)
+	Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error)
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*Session, error)
+	Logout(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type usersClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUsersClient(cc grpc.ClientConnInterface) UsersClient {
+	return &usersClient{cc}
+}
+
+func (c *usersClient) Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Users_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersClient) Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Users_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Users_Refresh_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersClient) Logout(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Users_Logout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UsersServer is the server API for Users service.
+// All implementations must embed UnimplementedUsersServer
+// for forward compatibility
+type UsersServer interface {
+	Register(context.Context, *Credentials) (*Session, error)
+	Login(context.Context, *Credentials) (*Session, error)
+	Refresh(context.Context, *RefreshRequest) (*Session, error)
+	Logout(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	mustEmbedUnimplementedUsersServer()
+}
+
+// UnimplementedUsersServer must be embedded to have forward compatible implementations.
+type UnimplementedUsersServer struct {
+}
+
+func (UnimplementedUsersServer) Register(context.Context, *Credentials) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedUsersServer) Login(context.Context, *Credentials) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedUsersServer) Refresh(context.Context, *RefreshRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedUsersServer) Logout(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedUsersServer) mustEmbedUnimplementedUsersServer() {}
+
+// UnsafeUsersServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UsersServer will
+// result in compilation errors.
+type UnsafeUsersServer interface {
+	mustEmbedUnimplementedUsersServer()
+}
+
+func RegisterUsersServer(s grpc.ServiceRegistrar, srv UsersServer) {
+	s.RegisterService(&Users_ServiceDesc, srv)
+}
+
+func _Users_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Users_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServer).Register(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Users_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Users_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServer).Login(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Users_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Users_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Users_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Users_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServer).Logout(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Users_ServiceDesc is the grpc.ServiceDesc for Users service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Users_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gophkeeper.Users",
+	HandlerType: (*UsersServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Users_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Users_Login_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _Users_Refresh_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Users_Logout_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gophkeeper.proto",
+}
+
+const (
+	Data_Create_FullMethodName   = "/gophkeeper.Data/Create"
+	Data_Get_FullMethodName      = "/gophkeeper.Data/Get"
+	Data_Update_FullMethodName   = "/gophkeeper.Data/Update"
+	Data_Delete_FullMethodName   = "/gophkeeper.Data/Delete"
+	Data_List_FullMethodName     = "/gophkeeper.Data/List"
+	Data_Sync_FullMethodName     = "/gophkeeper.Data/Sync"
+	Data_Upload_FullMethodName   = "/gophkeeper.Data/Upload"
+	Data_Download_FullMethodName = "/gophkeeper.Data/Download"
+)
+
+// DataClient is the client API for Data service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DataClient interface {
+	Create(ctx context.Context, in *DataObject, opts ...grpc.CallOption) (*DataVersion, error)
+	Get(ctx context.Context, in *DataKey, opts ...grpc.CallOption) (*DataObject, error)
+	// Update updates data object from the stream of chunks like Upload, the first
+	// chunk contains the type, name and metadata of the object and the expected
+	// version, any version is acceptable if it is zero.
+	Update(ctx context.Context, opts ...grpc.CallOption) (Data_UpdateClient, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// Upload creates new data object from the stream of chunks, the first chunk
+	// contains the type, name and metadata of the object.
+	Upload(ctx context.Context, opts ...grpc.CallOption) (Data_UploadClient, error)
+	// Download streams the value of data object by chunks, the first chunk
+	// contains the type, name, metadata and version of the object.
+	Download(ctx context.Context, in *DataKey, opts ...grpc.CallOption) (Data_DownloadClient, error)
+}
+
+type dataClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataClient(cc grpc.ClientConnInterface) DataClient {
+	return &dataClient{cc}
+}
+
+func (c *dataClient) Create(ctx context.Context, in *DataObject, opts ...grpc.CallOption) (*DataVersion, error) {
+	out := new(DataVersion)
+	err := c.cc.Invoke(ctx, Data_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClient) Get(ctx context.Context, in *DataKey, opts ...grpc.CallOption) (*DataObject, error) {
+	out := new(DataObject)
+	err := c.cc.Invoke(ctx, Data_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClient) Update(ctx context.Context, opts ...grpc.CallOption) (Data_UpdateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Data_ServiceDesc.Streams[0], Data_Update_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataUpdateClient{stream}
+	return x, nil
+}
+
+type Data_UpdateClient interface {
+	Send(*DataChunk) error
+	CloseAndRecv() (*DataVersion, error)
+	grpc.ClientStream
+}
+
+type dataUpdateClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataUpdateClient) Send(m *DataChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dataUpdateClient) CloseAndRecv() (*DataVersion, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(DataVersion)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Data_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Data_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, Data_Sync_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClient) Upload(ctx context.Context, opts ...grpc.CallOption) (Data_UploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Data_ServiceDesc.Streams[1], Data_Upload_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataUploadClient{stream}
+	return x, nil
+}
+
+type Data_UploadClient interface {
+	Send(*DataChunk) error
+	CloseAndRecv() (*DataVersion, error)
+	grpc.ClientStream
+}
+
+type dataUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataUploadClient) Send(m *DataChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dataUploadClient) CloseAndRecv() (*DataVersion, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(DataVersion)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataClient) Download(ctx context.Context, in *DataKey, opts ...grpc.CallOption) (Data_DownloadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Data_ServiceDesc.Streams[2], Data_Download_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataDownloadClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Data_DownloadClient interface {
+	Recv() (*DataChunk, error)
+	grpc.ClientStream
+}
+
+type dataDownloadClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataDownloadClient) Recv() (*DataChunk, error) {
+	m := new(DataChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DataServer is the server API for Data service.
+// All implementations must embed UnimplementedDataServer
+// for forward compatibility
+type DataServer interface {
+	Create(context.Context, *DataObject) (*DataVersion, error)
+	Get(context.Context, *DataKey) (*DataObject, error)
+	// Update updates data object from the stream of chunks like Upload, the first
+	// chunk contains the type, name and metadata of the object and the expected
+	// version, any version is acceptable if it is zero.
+	Update(Data_UpdateServer) error
+	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	// Upload creates new data object from the stream of chunks, the first chunk
+	// contains the type, name and metadata of the object.
+	Upload(Data_UploadServer) error
+	// Download streams the value of data object by chunks, the first chunk
+	// contains the type, name, metadata and version of the object.
+	Download(*DataKey, Data_DownloadServer) error
+	mustEmbedUnimplementedDataServer()
+}
+
+// UnimplementedDataServer must be embedded to have forward compatible implementations.
+type UnimplementedDataServer struct {
+}
+
+func (UnimplementedDataServer) Create(context.Context, *DataObject) (*DataVersion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedDataServer) Get(context.Context, *DataKey) (*DataObject, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedDataServer) Update(Data_UpdateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedDataServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedDataServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedDataServer) Sync(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedDataServer) Upload(Data_UploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedDataServer) Download(*DataKey, Data_DownloadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Download not implemented")
+}
+func (UnimplementedDataServer) mustEmbedUnimplementedDataServer() {}
+
+// UnsafeDataServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataServer will
+// result in compilation errors.
+type UnsafeDataServer interface {
+	mustEmbedUnimplementedDataServer()
+}
+
+func RegisterDataServer(s grpc.ServiceRegistrar, srv DataServer) {
+	s.RegisterService(&Data_ServiceDesc, srv)
+}
+
+func _Data_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataObject)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).Create(ctx, req.(*DataObject))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Data_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).Get(ctx, req.(*DataKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Data_Update_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DataServer).Update(&dataUpdateServer{stream})
+}
+
+type Data_UpdateServer interface {
+	SendAndClose(*DataVersion) error
+	Recv() (*DataChunk, error)
+	grpc.ServerStream
+}
+
+type dataUpdateServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataUpdateServer) SendAndClose(m *DataVersion) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dataUpdateServer) Recv() (*DataChunk, error) {
+	m := new(DataChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Data_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Data_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Data_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Data_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DataServer).Upload(&dataUploadServer{stream})
+}
+
+type Data_UploadServer interface {
+	SendAndClose(*DataVersion) error
+	Recv() (*DataChunk, error)
+	grpc.ServerStream
+}
+
+type dataUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataUploadServer) SendAndClose(m *DataVersion) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dataUploadServer) Recv() (*DataChunk, error) {
+	m := new(DataChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Data_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DataKey)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataServer).Download(m, &dataDownloadServer{stream})
+}
+
+type Data_DownloadServer interface {
+	Send(*DataChunk) error
+	grpc.ServerStream
+}
+
+type dataDownloadServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataDownloadServer) Send(m *DataChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Data_ServiceDesc is the grpc.ServiceDesc for Data service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Data_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gophkeeper.Data",
+	HandlerType: (*DataServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _Data_Create_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Data_Get_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Data_Delete_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Data_List_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Data_Sync_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Update",
+			Handler:       _Data_Update_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Upload",
+			Handler:       _Data_Upload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Download",
+			Handler:       _Data_Download_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gophkeeper.proto",
+}
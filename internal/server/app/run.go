@@ -3,8 +3,6 @@ package app
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
 	"fmt"
 	"os"
 	"os/signal"
@@ -13,11 +11,11 @@ import (
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
-	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
 	"github.com/pavlegich/gophkeeper/internal/server"
 	"github.com/pavlegich/gophkeeper/internal/server/controllers/handlers"
+	"github.com/pavlegich/gophkeeper/internal/server/storage"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
 )
@@ -43,22 +41,28 @@ func Run() error {
 		return fmt.Errorf("Run: parse flags failed %w", err)
 	}
 
-	// Database
-	db, err := database.Init(ctx, cfg.DSN)
+	// Storage
+	store, err := storage.NewStorage(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("Run: database initialization failed %w", err)
+		return fmt.Errorf("Run: storage initialization failed %w", err)
 	}
-	defer db.Close()
+	defer store.Close()
 
-	// Generate keys for token
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	// Keys for token
+	keyStorage := store.Keys
+	if cfg.TokenKeyFile != "" {
+		keyStorage = hash.NewFileKeyStorage(cfg.TokenKeyFile)
+	}
+	cfg.Token = hash.NewToken(cfg.TokenExp)
+	rotator := hash.NewRotator(cfg.Token, keyStorage, cfg.KeyRotation, cfg.KeyRefresh)
+	err = rotator.Refresh(ctx)
 	if err != nil {
-		return fmt.Errorf("Run: generate private key failed")
+		return fmt.Errorf("Run: token keys initialization failed %w", err)
 	}
-	cfg.Token = hash.NewToken(privateKey, &privateKey.PublicKey, cfg.TokenExp)
+	go rotator.Run(ctx)
 
 	// Router
-	ctrl := handlers.NewController(ctx, db, cfg)
+	ctrl := handlers.NewController(ctx, store, cfg)
 	router, err := ctrl.BuildRoute(ctx)
 	if err != nil {
 		return fmt.Errorf("Run: build server route failed %w", err)
@@ -70,16 +74,44 @@ func Run() error {
 		return fmt.Errorf("Run: server initialization failed %w", err)
 	}
 
+	// gRPC server
+	var grpcSrv *server.GRPCServer
+	if cfg.GRPCAddress != "" {
+		gs, err := ctrl.BuildGRPC(ctx)
+		if err != nil {
+			return fmt.Errorf("Run: build gRPC server failed %w", err)
+		}
+		grpcSrv = server.NewGRPCServer(ctx, gs, cfg)
+
+		go func() {
+			logger.Log.Info("running gRPC server", zap.String("addr", grpcSrv.GetAddress(ctx)))
+			err := grpcSrv.Serve(ctx)
+			if err != nil {
+				logger.Log.Error("gRPC server failed",
+					zap.Error(err))
+				stop()
+			}
+		}()
+	}
+
 	// Server graceful shutdown
 	go func() {
 		<-ctx.Done()
 		if ctx.Err() != nil {
-			ctxShutdown, cancelShutdown := context.WithTimeout(ctx, 5*time.Second)
+			// The parent context is already done, so the timeout is counted from the background
+			ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancelShutdown()
 
 			logger.Log.Info("shutting down gracefully...",
 				zap.Error(ctx.Err()))
 
+			if grpcSrv != nil {
+				err := grpcSrv.Shutdown(ctxShutdown)
+				if err != nil {
+					logger.Log.Error("gRPC server shutdown failed",
+						zap.Error(err))
+				}
+			}
 			err := srv.Shutdown(ctxShutdown)
 			if err != nil {
 				logger.Log.Error("server shutdown failed",
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pavlegich/gophkeeper/internal/server/controllers/interceptors"
+	dataGRPC "github.com/pavlegich/gophkeeper/internal/server/domains/data/controllers/grpc"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
+	usersGRPC "github.com/pavlegich/gophkeeper/internal/server/domains/user/controllers/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// BuildGRPC creates new gRPC server with interceptors and registers handlers in it.
+// The server accepts only TLS connections if TLS is enabled.
+func (c *Controller) BuildGRPC(ctx context.Context) (*grpc.Server, error) {
+	sessions := user.NewUserService(ctx, c.store.Users)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			interceptors.Recovery,
+			interceptors.WithRateLimit(c.getLimiter()),
+			interceptors.WithAuth(c.cfg.Token, sessions),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptors.StreamRecovery,
+			interceptors.WithStreamAuth(c.cfg.Token, sessions),
+		),
+	}
+
+	tlsCfg, err := c.cfg.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("BuildGRPC: %w", err)
+	}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	s := grpc.NewServer(opts...)
+	err = usersGRPC.Activate(ctx, s, c.cfg, c.store.Users)
+	if err != nil {
+		return nil, fmt.Errorf("BuildGRPC: activate user handlers failed %w", err)
+	}
+	dataGRPC.Activate(ctx, s, c.cfg, c.store.Data)
+
+	return s, nil
+}
@@ -4,27 +4,30 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
 	"github.com/pavlegich/gophkeeper/internal/server/controllers/middlewares"
 	data "github.com/pavlegich/gophkeeper/internal/server/domains/data/controllers/http"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
 	users "github.com/pavlegich/gophkeeper/internal/server/domains/user/controllers/http"
+	"github.com/pavlegich/gophkeeper/internal/server/storage"
 )
 
-// Controller contains database and configuration
+// Controller contains storage and configuration
 // for building the server router.
 type Controller struct {
-	db  *sql.DB
-	cfg *config.ServerConfig
+	store   *storage.Storage
+	cfg     *config.ServerConfig
+	limiter *middlewares.Limiter
 }
 
 // NewController creates and returns new server controller.
-func NewController(ctx context.Context, db *sql.DB, cfg *config.ServerConfig) *Controller {
+func NewController(ctx context.Context, store *storage.Storage, cfg *config.ServerConfig) *Controller {
 	return &Controller{
-		db:  db,
-		cfg: cfg,
+		store: store,
+		cfg:   cfg,
 	}
 }
 
@@ -34,10 +37,24 @@ func (c *Controller) BuildRoute(ctx context.Context) (*chi.Mux, error) {
 
 	r.Use(middlewares.WithLogging)
 	r.Use(middlewares.Recovery)
-	r.Use(middlewares.WithAuth(c.cfg.Token))
+	sessions := user.NewUserService(ctx, c.store.Users)
+	r.Use(middlewares.WithAuth(c.cfg.Token, sessions))
+	r.Use(middlewares.WithRateLimit(c.getLimiter()))
 
-	users.Activate(ctx, r, c.cfg, c.db)
-	data.Activate(ctx, r, c.cfg, c.db)
+	err := users.Activate(ctx, r, c.cfg, c.store.Users)
+	if err != nil {
+		return nil, fmt.Errorf("BuildRoute: activate user handlers failed %w", err)
+	}
+	data.Activate(ctx, r, c.cfg, c.store.Data)
 
 	return r, nil
 }
+
+// getLimiter returns the limiter of authentication requests,
+// it is shared between the HTTP and gRPC servers.
+func (c *Controller) getLimiter() *middlewares.Limiter {
+	if c.limiter == nil {
+		c.limiter = middlewares.NewLimiter(c.cfg.AuthRate, c.cfg.LockAttempts, c.cfg.LockPeriod)
+	}
+	return c.limiter
+}
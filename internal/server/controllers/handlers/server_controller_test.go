@@ -2,20 +2,28 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
+	"github.com/pavlegich/gophkeeper/internal/server/storage"
 )
 
 func TestNewController(t *testing.T) {
 	ctx := context.Background()
 	cfg := config.NewServerConfig(ctx)
 	type args struct {
-		ctx context.Context
-		db  *sql.DB
-		cfg *config.ServerConfig
+		ctx   context.Context
+		store *storage.Storage
+		cfg   *config.ServerConfig
 	}
 	tests := []struct {
 		name string
@@ -25,21 +33,431 @@ func TestNewController(t *testing.T) {
 		{
 			name: "ok",
 			args: args{
-				ctx: ctx,
-				db:  nil,
-				cfg: cfg,
+				ctx:   ctx,
+				store: nil,
+				cfg:   cfg,
 			},
 			want: &Controller{
-				db:  nil,
-				cfg: cfg,
+				store: nil,
+				cfg:   cfg,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewController(tt.args.ctx, tt.args.db, tt.args.cfg); !reflect.DeepEqual(got, tt.want) {
+			if got := NewController(tt.args.ctx, tt.args.store, tt.args.cfg); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewController() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestController_BuildRoute(t *testing.T) {
+	ctx := context.Background()
+	type step struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		contentType string
+		ifMatch     string
+		wantStatus  int
+		wantBody    string
+		wantNoBody  string
+		wantHeader  http.Header
+	}
+	steps := []step{
+		{
+			name:       "unauthorized",
+			method:     http.MethodGet,
+			path:       "/api/user/data",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "register",
+			method:      http.MethodPost,
+			path:        "/api/user/register",
+			body:        `{"login":"alice","password":"Correct-Horse-42"}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "register_login_busy",
+			method:      http.MethodPost,
+			path:        "/api/user/register",
+			body:        `{"login":"alice","password":"Correct-Horse-42"}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "create",
+			method:      http.MethodPost,
+			path:        "/api/user/data/text/note",
+			body:        "--b\r\nContent-Disposition: form-data; name=\"data\"\r\n\r\nfirst\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "create_conflict",
+			method:      http.MethodPost,
+			path:        "/api/user/data/text/note",
+			body:        "--b\r\nContent-Disposition: form-data; name=\"data\"\r\n\r\nfirst\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "update",
+			method:      http.MethodPut,
+			path:        "/api/user/data/text/note",
+			body:        "--b\r\nContent-Disposition: form-data; name=\"data\"\r\n\r\nsecond\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			ifMatch:     `"1"`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "update_version_differ",
+			method:      http.MethodPut,
+			path:        "/api/user/data/text/note",
+			body:        "--b\r\nContent-Disposition: form-data; name=\"data\"\r\n\r\nthird\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			ifMatch:     `"1"`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:       "value",
+			method:     http.MethodGet,
+			path:       "/api/user/data/text/note",
+			wantStatus: http.StatusOK,
+			wantBody:   "second",
+		},
+		{
+			name:       "version_value",
+			method:     http.MethodGet,
+			path:       "/api/user/data/text/note/versions/1",
+			wantStatus: http.StatusOK,
+			wantBody:   "first",
+			wantHeader: http.Header{"Etag": {`"1"`}},
+		},
+		{
+			name:       "restore_version_differ",
+			method:     http.MethodPost,
+			path:       "/api/user/data/text/note/versions/1/restore",
+			ifMatch:    `"1"`,
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "restore",
+			method:     http.MethodPost,
+			path:       "/api/user/data/text/note/versions/1/restore",
+			ifMatch:    `"2"`,
+			wantStatus: http.StatusOK,
+			wantHeader: http.Header{"Etag": {`"3"`}},
+		},
+		{
+			name:       "restored_value",
+			method:     http.MethodGet,
+			path:       "/api/user/data/text/note",
+			wantStatus: http.StatusOK,
+			wantBody:   "first",
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       "/api/user/data/text/note",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "deleted_value",
+			method:     http.MethodGet,
+			path:       "/api/user/data/text/note",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "sync",
+			method:     http.MethodGet,
+			path:       "/api/user/sync?since=3",
+			wantStatus: http.StatusOK,
+			wantBody:   `"deleted":true`,
+		},
+		{
+			name:   "create_file",
+			method: http.MethodPost,
+			path:   "/api/user/data/binary/scan",
+			// The value is sent after metadata, so it is streamed into the storage
+			body: "--b\r\nContent-Disposition: form-data; name=\"metadata\"\r\n\r\n{}\r\n" +
+				"--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"C:\\\\docs\\\\scan 1.pdf\"\r\n" +
+				"Content-Type: application/pdf\r\n\r\nbinary value\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "file_value",
+			method:     http.MethodGet,
+			path:       "/api/user/data/binary/scan",
+			wantStatus: http.StatusOK,
+			wantBody:   "binary value",
+			wantHeader: http.Header{
+				"Content-Type":        {"application/pdf"},
+				"Content-Disposition": {`attachment; filename="scan 1.pdf"`},
+				"Digest":              {"sha-256=AAsvLBMe83t7ZZoPSTeUMY3jq89+gdPmih80S0hI1rk="},
+				"Content-Length":      {"12"},
+			},
+		},
+		{
+			name:       "file_sync",
+			method:     http.MethodGet,
+			path:       "/api/user/sync?since=4",
+			wantStatus: http.StatusOK,
+			wantBody:   `"file_name":"scan 1.pdf"`,
+			wantNoBody: `"data"`,
+		},
+		{
+			name:       "file_list",
+			method:     http.MethodGet,
+			path:       "/api/user/data?type=binary",
+			wantStatus: http.StatusOK,
+			wantBody:   `"file_name":"scan 1.pdf","content_type":"application/pdf","size":12`,
+		},
+		{
+			name:        "folder_create",
+			method:      http.MethodPost,
+			path:        "/api/user/folders",
+			body:        `{"name":"work"}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+			wantBody:    `"id":1,"name":"work"`,
+		},
+		{
+			name:        "subfolder_create",
+			method:      http.MethodPost,
+			path:        "/api/user/folders",
+			body:        `{"name":"servers","parent_id":1}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+			wantBody:    `"id":2,"name":"servers","parent_id":1`,
+		},
+		{
+			name:        "folder_name_busy",
+			method:      http.MethodPost,
+			path:        "/api/user/folders",
+			body:        `{"name":"work"}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "folder_into_subfolder",
+			method:      http.MethodPut,
+			path:        "/api/user/folders/1",
+			body:        `{"name":"work","parent_id":2}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "move",
+			method:      http.MethodPut,
+			path:        "/api/user/data/binary/scan/folder",
+			body:        `{"folder_id":2}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "tag",
+			method:     http.MethodPut,
+			path:       "/api/user/data/binary/scan/tags/docs",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "list_by_folder_and_tag",
+			method:     http.MethodGet,
+			path:       "/api/user/data?folder=2&tag=docs",
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"scan","type":"binary"`,
+		},
+		{
+			name:       "list_by_other_tag",
+			method:     http.MethodGet,
+			path:       "/api/user/data?tag=other",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "folder_delete",
+			method:     http.MethodDelete,
+			path:       "/api/user/folders/1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "moved_to_root",
+			method:     http.MethodGet,
+			path:       "/api/user/data?type=binary",
+			wantStatus: http.StatusOK,
+			wantBody:   `"tags":["docs"]`,
+			wantNoBody: `"folder_id"`,
+		},
+		{
+			name:       "folders_deleted",
+			method:     http.MethodGet,
+			path:       "/api/user/folders",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "untag",
+			method:     http.MethodDelete,
+			path:       "/api/user/data/binary/scan/tags/docs",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "untag_missing",
+			method:     http.MethodDelete,
+			path:       "/api/user/data/binary/scan/tags/docs",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "create_with_metadata",
+			method: http.MethodPost,
+			path:   "/api/user/data/credentials/GitHub_work",
+			body: "--b\r\nContent-Disposition: form-data; name=\"data\"\r\n\r\nsecret\r\n" +
+				"--b\r\nContent-Disposition: form-data; name=\"metadata\"\r\n\r\n{\"url\":\"github.com\"}\r\n--b--\r\n",
+			contentType: "multipart/form-data; boundary=b",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "search_by_name",
+			method:     http.MethodGet,
+			path:       "/api/user/data/search?q=git*+hub_",
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"GitHub_work"`,
+		},
+		{
+			name:       "search_by_type",
+			method:     http.MethodGet,
+			path:       "/api/user/data/search?q=type%3Abinary+type%3Atext",
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"scan"`,
+			wantNoBody: `"name":"GitHub_work"`,
+		},
+		{
+			name:       "search_like_wildcard",
+			method:     http.MethodGet,
+			path:       "/api/user/data/search?q=gith_b",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "search_by_metadata",
+			method:     http.MethodGet,
+			path:       "/api/user/data/search?q=url%3Dgithub.com",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "search_empty_query",
+			method:     http.MethodGet,
+			path:       "/api/user/data/search",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "unregister",
+			method:      http.MethodDelete,
+			path:        "/api/user",
+			body:        `{"password":"Correct-Horse-42"}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+		},
+	}
+	storages := map[string]func(t *testing.T) *storage.Storage{
+		"memory": func(t *testing.T) *storage.Storage {
+			return storage.NewMemoryStorage(ctx)
+		},
+		"sqlite": func(t *testing.T) *storage.Storage {
+			cfg := config.NewServerConfig(ctx)
+			cfg.Storage = "sqlite"
+			cfg.SQLitePath = filepath.Join(t.TempDir(), "gophkeeper.db")
+			store, err := storage.NewStorage(ctx, cfg)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+		"sqlite_blobs": func(t *testing.T) *storage.Storage {
+			cfg := config.NewServerConfig(ctx)
+			cfg.Storage = "sqlite"
+			cfg.SQLitePath = filepath.Join(t.TempDir(), "gophkeeper.db")
+			cfg.BlobStore = "fs"
+			cfg.BlobDir = t.TempDir()
+			cfg.BlobThreshold = 1
+			store, err := storage.NewStorage(ctx, cfg)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			store := newStorage(t)
+			cfg := config.NewServerConfig(ctx)
+			cfg.PassLength = 8
+			cfg.LoginPattern = `^[a-z]+$`
+			cfg.RefreshExp = time.Hour
+			cfg.Token = hash.NewToken(time.Minute)
+			err := hash.NewRotator(cfg.Token, store.Keys, 0, 0).Refresh(ctx)
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			router, err := NewController(ctx, store, cfg).BuildRoute(ctx)
+			if err != nil {
+				t.Fatalf("BuildRoute() error = %v", err)
+			}
+			srv := httptest.NewServer(router)
+			defer srv.Close()
+
+			// The standard cookie jar checks that the session cookies are sent to /api/user too
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				t.Fatalf("cookiejar.New() error = %v", err)
+			}
+			client := srv.Client()
+			client.Jar = jar
+
+			for _, st := range steps {
+				req, err := http.NewRequest(st.method, srv.URL+st.path, strings.NewReader(st.body))
+				if err != nil {
+					t.Fatalf("%s: NewRequest() error = %v", st.name, err)
+				}
+				if st.contentType != "" {
+					req.Header.Set("Content-Type", st.contentType)
+				}
+				if st.ifMatch != "" {
+					req.Header.Set("If-Match", st.ifMatch)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("%s: Do() error = %v", st.name, err)
+				}
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatalf("%s: ReadAll() error = %v", st.name, err)
+				}
+				if resp.StatusCode != st.wantStatus {
+					t.Fatalf("%s: status = %d, want %d", st.name, resp.StatusCode, st.wantStatus)
+				}
+				if !strings.Contains(string(body), st.wantBody) {
+					t.Errorf("%s: body = %s, want %s", st.name, body, st.wantBody)
+				}
+				if st.wantNoBody != "" && strings.Contains(string(body), st.wantNoBody) {
+					t.Errorf("%s: body = %s, want without %s", st.name, body, st.wantNoBody)
+				}
+				for k := range st.wantHeader {
+					if got := resp.Header.Get(k); got != st.wantHeader.Get(k) {
+						t.Errorf("%s: header %s = %s, want %s", st.name, k, got, st.wantHeader.Get(k))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package interceptors contains interceptors of the gRPC server.
+package interceptors
+
+import (
+	"context"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	"github.com/pavlegich/gophkeeper/internal/server/controllers/middlewares"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods contains methods of the gRPC server available without authorization.
+var publicMethods = map[string]bool{
+	pb.Users_Register_FullMethodName: true,
+	pb.Users_Login_FullMethodName:    true,
+	pb.Users_Refresh_FullMethodName:  true,
+}
+
+// authorize checks and validates authorization token from the request metadata
+// and checks that its session is not revoked, returns the context with the user
+// and session identifiers.
+func authorize(ctx context.Context, token *hash.Token, sessions middlewares.SessionChecker) (context.Context, error) {
+	value := utils.GetTokenFromMetadata(ctx)
+	if value == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is required")
+	}
+	claims, err := token.Parse(value)
+	if err != nil || claims.SessionID == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is invalid")
+	}
+	err = sessions.CheckSession(ctx, claims.SessionID)
+	if err != nil {
+		logger.Log.Info("authorize: session is not accepted",
+			zap.Int("user_id", claims.ID),
+			zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "session is not accepted")
+	}
+	ctx = context.WithValue(ctx, utils.ContextIDKey, claims.ID)
+	ctx = context.WithValue(ctx, utils.ContextSessionKey, claims.SessionID)
+	return ctx, nil
+}
+
+// WithAuth checks authorization of unary gRPC requests as middlewares.WithAuth
+// does for HTTP requests.
+func WithAuth(token *hash.Token, sessions middlewares.SessionChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authorize(ctx, token, sessions)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStream contains the server stream with the context of authorized user.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of authorized user.
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// WithStreamAuth checks authorization of streaming gRPC requests.
+func WithStreamAuth(token *hash.Token, sessions middlewares.SessionChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authorize(ss.Context(), token, sessions)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+}
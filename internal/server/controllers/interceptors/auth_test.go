@@ -0,0 +1,110 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubSessions accepts all the sessions except the revoked one.
+type stubSessions struct {
+	revoked string
+}
+
+func (s *stubSessions) CheckSession(ctx context.Context, sessionID string) error {
+	if sessionID == s.revoked {
+		return errs.ErrSessionRevoked
+	}
+	return nil
+}
+
+func TestWithAuth(t *testing.T) {
+	ctx := context.Background()
+	key, err := hash.NewKey(time.Now())
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	token := hash.NewToken(time.Hour)
+	token.SetKeys([]*hash.Key{key})
+
+	valid, _ := token.Create(ctx, 7, "session")
+	revoked, _ := token.Create(ctx, 7, "revoked")
+	interceptor := WithAuth(token, &stubSessions{revoked: "revoked"})
+
+	tests := []struct {
+		name     string
+		method   string
+		auth     string
+		wantCode codes.Code
+		wantID   int
+	}{
+		{
+			name:     "ok",
+			method:   pb.Data_List_FullMethodName,
+			auth:     "Bearer " + valid,
+			wantCode: codes.OK,
+			wantID:   7,
+		},
+		{
+			name:     "public_method",
+			method:   pb.Users_Login_FullMethodName,
+			auth:     "",
+			wantCode: codes.OK,
+			wantID:   -1,
+		},
+		{
+			name:     "no_token",
+			method:   pb.Data_List_FullMethodName,
+			auth:     "",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "wrong_scheme",
+			method:   pb.Data_List_FullMethodName,
+			auth:     "Basic " + valid,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid_token",
+			method:   pb.Data_List_FullMethodName,
+			auth:     "Bearer invalid",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "revoked_session",
+			method:   pb.Data_List_FullMethodName,
+			auth:     "Bearer " + revoked,
+			wantCode: codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx := ctx
+			if tt.auth != "" {
+				reqCtx = metadata.NewIncomingContext(ctx, metadata.Pairs(utils.AuthMetadata, tt.auth))
+			}
+			gotID := -1
+			handler := func(ctx context.Context, req any) (any, error) {
+				gotID, _ = utils.GetUserIDFromContext(ctx)
+				return nil, nil
+			}
+
+			_, err := interceptor(reqCtx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("WithAuth() code = %v, want %v", got, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && gotID != tt.wantID {
+				t.Errorf("WithAuth() user id = %v, want %v", gotID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package interceptors
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	"github.com/pavlegich/gophkeeper/internal/server/controllers/middlewares"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WithRateLimit limits the number of registration and login requests per IP address
+// and locks the login after failed attempts with the same limiter as the HTTP server.
+// Rejected requests get ResourceExhausted status with the time to wait in seconds
+// in the "retry-after" metadata.
+func WithRateLimit(limiter *middlewares.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		isLogin := info.FullMethod == pb.Users_Login_FullMethodName
+		if !isLogin && info.FullMethod != pb.Users_Register_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		ip := utils.GetIPFromPeer(ctx)
+		login := ""
+		if creds, ok := req.(*pb.Credentials); ok && isLogin {
+			login = strings.ToLower(strings.TrimSpace(creds.GetLogin()))
+		}
+
+		wait := limiter.Allow(ip, login)
+		if wait > 0 {
+			logger.Log.Info("WithRateLimit: too many requests",
+				zap.String("ip", ip),
+				zap.String("login", login),
+				zap.Duration("wait", wait))
+			seconds := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", seconds))
+			return nil, status.Errorf(codes.ResourceExhausted, "too many requests, retry after %s seconds", seconds)
+		}
+
+		resp, err := handler(ctx, req)
+		if !isLogin {
+			return resp, err
+		}
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			limiter.Fail(login)
+		case codes.OK:
+			limiter.Reset(login)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,39 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Recovery recovers server operation when a panic occurs in unary gRPC handler.
+func Recovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Error("server panic",
+				zap.String("method", info.FullMethod),
+				zap.Any("error", r),
+			)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// StreamRecovery recovers server operation when a panic occurs in streaming gRPC handler.
+func StreamRecovery(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Error("server panic",
+				zap.String("method", info.FullMethod),
+				zap.Any("error", r),
+			)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(srv, ss)
+}
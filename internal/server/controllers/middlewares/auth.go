@@ -5,15 +5,24 @@ import (
 	"net/http"
 
 	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
 	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
 )
 
-// WithAuth checks and validates authorization token.
-func WithAuth(token *hash.Token) func(http.Handler) http.Handler {
+// SessionChecker describes the check of the user session,
+// error is returned if the session is revoked or expired.
+type SessionChecker interface {
+	CheckSession(ctx context.Context, sessionID string) error
+}
+
+// WithAuth checks and validates authorization token
+// and checks that its session is not revoked.
+func WithAuth(token *hash.Token, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.RequestURI == "/api/user/register" || r.RequestURI == "/api/user/login" ||
-				r.RequestURI == "/" {
+				r.RequestURI == "/api/user/token/refresh" || r.RequestURI == "/" {
 				h.ServeHTTP(w, r)
 				return
 			}
@@ -26,12 +35,21 @@ func WithAuth(token *hash.Token) func(http.Handler) http.Handler {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
-			id, err := token.Validate(cookie.Value)
+			claims, err := token.Parse(cookie.Value)
+			if err != nil || claims.SessionID == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			err = sessions.CheckSession(r.Context(), claims.SessionID)
 			if err != nil {
+				logger.Log.Info("WithAuth: session is not accepted",
+					zap.Int("user_id", claims.ID),
+					zap.Error(err))
 				w.WriteHeader(http.StatusUnauthorized)
 				return
 			}
-			ctx := context.WithValue(r.Context(), utils.ContextIDKey, id)
+			ctx := context.WithValue(r.Context(), utils.ContextIDKey, claims.ID)
+			ctx = context.WithValue(ctx, utils.ContextSessionKey, claims.SessionID)
 			h.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
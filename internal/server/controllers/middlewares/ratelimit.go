@@ -0,0 +1,245 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// RateWindow is the period for counting requests from one IP address.
+	RateWindow = time.Minute
+	// MaxLockout is the maximum lockout period of the login,
+	// the failed attempts are forgotten after this period.
+	MaxLockout = time.Hour
+	// maxLoginBody is the maximum size of the login request body read by the limiter.
+	maxLoginBody = 1 << 16
+)
+
+// ipWindow contains the number of requests from the IP address in the current window.
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+// loginFailures contains the failed login attempts and the lockout of the login.
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Limiter contains the state of rate limiting of authentication requests
+// per IP address and the lockout of logins after failed attempts.
+type Limiter struct {
+	mu        sync.Mutex
+	rate      int
+	attempts  int
+	period    time.Duration
+	ips       map[string]*ipWindow
+	logins    map[string]*loginFailures
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter creates and returns new limiter. Rate is the maximum number of
+// authentication requests from one IP address per minute. After the number of
+// failed attempts the login is locked for the period, which is doubled for every
+// next failed attempt. Zero rate or attempts disables the corresponding limit.
+func NewLimiter(rate int, attempts int, period time.Duration) *Limiter {
+	return &Limiter{
+		rate:     rate,
+		attempts: attempts,
+		period:   period,
+		ips:      make(map[string]*ipWindow),
+		logins:   make(map[string]*loginFailures),
+		now:      time.Now,
+	}
+}
+
+// Allow counts the request from the IP address with the login and returns
+// the time to wait, if the request is not allowed.
+func (l *Limiter) Allow(ip string, login string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	if f, ok := l.logins[login]; ok && login != "" && now.Before(f.lockedUntil) {
+		return f.lockedUntil.Sub(now)
+	}
+
+	if l.rate > 0 {
+		w, ok := l.ips[ip]
+		if !ok || now.Sub(w.start) >= RateWindow {
+			w = &ipWindow{start: now}
+			l.ips[ip] = w
+		}
+		if w.count >= l.rate {
+			return w.start.Add(RateWindow).Sub(now)
+		}
+		w.count++
+	}
+
+	return 0
+}
+
+// Fail registers the failed attempt of the login and locks it,
+// if the number of failed attempts is exceeded.
+func (l *Limiter) Fail(login string) {
+	if l.attempts <= 0 || login == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	f, ok := l.logins[login]
+	if !ok {
+		f = &loginFailures{}
+		l.logins[login] = f
+	}
+	f.count++
+	f.lastFailure = now
+
+	if f.count >= l.attempts {
+		lockout := MaxLockout
+		if exp := f.count - l.attempts; exp < 32 {
+			lockout = min(l.period*time.Duration(math.Pow(2, float64(exp))), MaxLockout)
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+}
+
+// Reset forgets the failed attempts of the login after successful login.
+func (l *Limiter) Reset(login string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.logins, login)
+}
+
+// sweep removes expired windows and forgotten failed attempts once in the window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < RateWindow {
+		return
+	}
+	l.lastSweep = now
+
+	for ip, w := range l.ips {
+		if now.Sub(w.start) >= RateWindow {
+			delete(l.ips, ip)
+		}
+	}
+	for login, f := range l.logins {
+		if now.Sub(f.lastFailure) >= MaxLockout && !now.Before(f.lockedUntil) {
+			delete(l.logins, login)
+		}
+	}
+}
+
+// statusWriter contains the response writer and the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader saves the status code and writes it into the response.
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// WithRateLimit limits the number of registration and login requests per IP address
+// and locks the login after failed attempts. The password change and the account
+// deletion check the current password as well, so they are limited too and lock
+// the account after failed attempts, then the password cannot be guessed with
+// the stolen session. The middleware follows WithAuth, which sets the user of them.
+// Rejected requests get 429 Too Many Requests with the time to wait in seconds
+// in the Retry-After header.
+func WithRateLimit(limiter *Limiter) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			login, failStatus, limited := limitedRequest(r)
+			if !limited {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			ip := utils.GetIPFromRequest(r)
+			wait := limiter.Allow(ip, login)
+			if wait > 0 {
+				logger.Log.Info("WithRateLimit: too many requests",
+					zap.String("ip", ip),
+					zap.String("login", login),
+					zap.Duration("wait", wait))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(sw, r)
+
+			if login == "" {
+				return
+			}
+			switch sw.status {
+			case failStatus:
+				limiter.Fail(login)
+			case http.StatusOK:
+				limiter.Reset(login)
+			}
+		})
+	}
+}
+
+// limitedRequest checks whether the request is limited and returns the login
+// locked after failed attempts and the status of the failed attempt. The account
+// checked by the current password is locked by the identifier of the user.
+func limitedRequest(r *http.Request) (string, int, bool) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/user/register":
+		return "", 0, true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/user/login":
+		return readLogin(r), http.StatusUnauthorized, true
+	case r.Method == http.MethodPost && r.URL.Path == "/api/user/password",
+		r.Method == http.MethodDelete && r.URL.Path == "/api/user":
+		userID, err := utils.GetUserIDFromContext(r.Context())
+		if err != nil {
+			return "", 0, true
+		}
+		return "user_id:" + strconv.Itoa(userID), http.StatusForbidden, true
+	}
+	return "", 0, false
+}
+
+// readLogin reads the login from the request body and puts the body back for the handler.
+func readLogin(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLoginBody))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var u struct {
+		Login string `json:"login"`
+	}
+	if json.Unmarshal(body, &u) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(u.Login))
+}
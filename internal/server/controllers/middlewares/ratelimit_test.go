@@ -0,0 +1,183 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+)
+
+func TestLimiter_Fail(t *testing.T) {
+	now := time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(0, 3, 30*time.Second)
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		fails    int
+		wantWait time.Duration
+	}{
+		{
+			name:     "under_limit",
+			fails:    2,
+			wantWait: 0,
+		},
+		{
+			name:     "locked",
+			fails:    1,
+			wantWait: 30 * time.Second,
+		},
+		{
+			name:     "doubled",
+			fails:    1,
+			wantWait: time.Minute,
+		},
+		{
+			name:     "max_lockout",
+			fails:    20,
+			wantWait: MaxLockout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.fails; i++ {
+				l.Fail("user")
+			}
+			if got := l.Allow("192.0.2.1", "user"); got != tt.wantWait {
+				t.Errorf("Limiter.Allow() = %v, want %v", got, tt.wantWait)
+			}
+		})
+	}
+
+	if got := l.Allow("192.0.2.1", "other"); got != 0 {
+		t.Errorf("Limiter.Allow() other login = %v, want 0", got)
+	}
+	l.Reset("user")
+	if got := l.Allow("192.0.2.1", "user"); got != 0 {
+		t.Errorf("Limiter.Allow() after reset = %v, want 0", got)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	now := time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, 2, 30*time.Second)
+	l.now = func() time.Time { return now }
+
+	// The handler accepts only the correct password
+	h := WithRateLimit(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/user/login" && !strings.Contains(readLogin(r), "user") {
+			t.Errorf("WithRateLimit() request body is not restored")
+		}
+		if r.URL.Path == "/api/user/password" || r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		ip             string
+		userID         int
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "first_failure",
+			path:       "/api/user/login",
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "second_failure",
+			path:       "/api/user/login",
+			ip:         "192.0.2.2",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "login_locked",
+			path:           "/api/user/login",
+			ip:             "192.0.2.3",
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "30",
+		},
+		{
+			name:       "register_allowed",
+			path:       "/api/user/register",
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "ip_limited",
+			path:           "/api/user/register",
+			ip:             "192.0.2.1",
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "60",
+		},
+		{
+			name:       "other_endpoint",
+			path:       "/api/user/data",
+			ip:         "192.0.2.1",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "password_first_failure",
+			path:       "/api/user/password",
+			ip:         "192.0.2.4",
+			userID:     1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "delete_second_failure",
+			method:     http.MethodDelete,
+			path:       "/api/user",
+			ip:         "192.0.2.5",
+			userID:     1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:           "account_locked",
+			path:           "/api/user/password",
+			ip:             "192.0.2.6",
+			userID:         1,
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "30",
+		},
+		{
+			name:       "other_account",
+			method:     http.MethodDelete,
+			path:       "/api/user",
+			ip:         "192.0.2.6",
+			userID:     2,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, tt.path, strings.NewReader(`{"login":"user","password":"wrong"}`))
+			r.RemoteAddr = tt.ip + ":1234"
+			if tt.userID != 0 {
+				r = r.WithContext(context.WithValue(r.Context(), utils.ContextIDKey, tt.userID))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("WithRateLimit() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("WithRateLimit() Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+// Package grpc contains object of data gRPC handler,
+// functions for registering the data handler in gRPC server
+// and data handlers.
+package grpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ChunkSize is the maximum size of data value in one streamed chunk.
+const ChunkSize = 64 << 10
+
+// DataHandler contains objects for work
+// with data gRPC handlers.
+type DataHandler struct {
+	pb.UnimplementedDataServer
+	Config  *config.ServerConfig
+	Service data.Service
+}
+
+// Activate registers data handler in gRPC server.
+func Activate(ctx context.Context, s *grpc.Server, cfg *config.ServerConfig, repo data.Repository) {
+	ds := data.NewDataService(ctx, repo)
+	pb.RegisterDataServer(s, newHandler(ctx, cfg, ds))
+}
+
+// newHandler initializes gRPC handler for data object.
+func newHandler(ctx context.Context, cfg *config.ServerConfig, s data.Service) *DataHandler {
+	return &DataHandler{
+		Config:  cfg,
+		Service: s,
+	}
+}
+
+// Create uploads new data into the storage.
+func (h *DataHandler) Create(ctx context.Context, req *pb.DataObject) (*pb.DataVersion, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Create: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+
+	d := &data.Data{
+		UserID:   userID,
+		Type:     req.GetType(),
+		Name:     req.GetName(),
+		Data:     req.GetData(),
+		Metadata: req.GetMetadata(),
+		Device:   utils.GetDeviceFromMetadata(ctx),
+	}
+
+	err = h.Service.Create(ctx, d)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Create: upload new data failed",
+			zap.Error(err))
+		return nil, createStatus(err)
+	}
+
+	return &pb.DataVersion{Version: int32(d.Version)}, nil
+}
+
+// Get returns the requested version of data,
+// the current version is returned if the version is zero.
+func (h *DataHandler) Get(ctx context.Context, req *pb.DataKey) (*pb.DataObject, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Get: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+
+	d, err := h.unload(ctx, req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Get: unload requested data failed",
+			zap.Error(err))
+		return nil, dataStatus(err)
+	}
+	value, err := readValue(d)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Get: read data failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "read data failed")
+	}
+
+	return &pb.DataObject{
+		Type:      d.Type,
+		Name:      d.Name,
+		Data:      value,
+		Metadata:  d.Metadata,
+		Version:   int32(d.Version),
+		CreatedAt: timestamppb.New(d.CreatedAt),
+	}, nil
+}
+
+// Delete deletes requested data from the storage.
+func (h *DataHandler) Delete(ctx context.Context, req *pb.DeleteRequest) (*emptypb.Empty, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Delete: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+
+	err = h.Service.Delete(ctx, req.GetType(), req.GetName(), int(req.GetExpectedVersion()))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Delete: delete requested data failed",
+			zap.Error(err))
+		return nil, dataStatus(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// List returns information about all the user's data,
+// filtered by data type, if it is specified.
+func (h *DataHandler) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("List: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+
+	items, err := h.Service.List(ctx, data.Filter{Type: req.GetType()})
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("List: get data list failed",
+			zap.Error(err))
+		return nil, dataStatus(err)
+	}
+
+	resp := &pb.ListResponse{Items: make([]*pb.Item, 0, len(items))}
+	for _, item := range items {
+		resp.Items = append(resp.Items, &pb.Item{
+			Type:      item.Type,
+			Name:      item.Name,
+			Metadata:  item.Metadata,
+			CreatedAt: timestamppb.New(item.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// Sync returns the user's data changes since the cursor, not more than limit.
+func (h *DataHandler) Sync(ctx context.Context, req *pb.SyncRequest) (*pb.SyncResponse, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Sync: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+
+	if req.GetSince() < 0 || req.GetLimit() < 0 {
+		logger.Log.With(zap.String("user_id", idString)).Error("Sync: incorrect cursor or limit",
+			zap.Int64("since", req.GetSince()),
+			zap.Int32("limit", req.GetLimit()))
+		return nil, status.Error(codes.InvalidArgument, "cursor and limit must not be negative")
+	}
+
+	feed, err := h.Service.Changes(ctx, req.GetSince(), int(req.GetLimit()))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Sync: get changes failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get changes failed")
+	}
+
+	resp := &pb.SyncResponse{
+		Cursor:  feed.Cursor,
+		More:    feed.More,
+		Changes: make([]*pb.Change, 0, len(feed.Changes)),
+	}
+	for _, c := range feed.Changes {
+		resp.Changes = append(resp.Changes, &pb.Change{
+			Seq:       c.Seq,
+			Type:      c.Type,
+			Name:      c.Name,
+			Deleted:   c.Deleted,
+			Version:   int32(c.Version),
+			Data:      c.Data,
+			Metadata:  c.Metadata,
+			CreatedAt: timestamppb.New(c.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// Upload creates new data object from the stream of chunks, the type, name
+// and metadata are taken from the first chunk. The chunks are streamed into
+// the storage while they are received. The size of the value is limited
+// by the maximum size of data value from the configuration.
+func (h *DataHandler) Upload(stream pb.Data_UploadServer) error {
+	ctx := stream.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Upload: get user id from context failed",
+			zap.Error(err))
+		return status.Error(codes.Internal, "get user failed")
+	}
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "no chunks received")
+	}
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Upload: receive chunk failed",
+			zap.Error(err))
+		return err
+	}
+
+	d := &data.Data{
+		UserID:   userID,
+		Type:     first.GetType(),
+		Name:     first.GetName(),
+		Metadata: first.GetMetadata(),
+		Device:   utils.GetDeviceFromMetadata(ctx),
+	}
+	err = h.storeChunks(stream, first, d, func(d *data.Data) error {
+		return h.Service.Create(ctx, d)
+	})
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Upload: upload new data failed",
+			zap.Error(err))
+		return createStatus(err)
+	}
+
+	return stream.SendAndClose(&pb.DataVersion{Version: int32(d.Version)})
+}
+
+// Update updates data object from the stream of chunks like Upload, the type,
+// name, metadata and the expected version are taken from the first chunk.
+// Data object is updated only if its current version equals to the expected
+// version, any version is acceptable if it is zero.
+func (h *DataHandler) Update(stream pb.Data_UpdateServer) error {
+	ctx := stream.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Update: get user id from context failed",
+			zap.Error(err))
+		return status.Error(codes.Internal, "get user failed")
+	}
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "no chunks received")
+	}
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Update: receive chunk failed",
+			zap.Error(err))
+		return err
+	}
+
+	d := &data.Data{
+		UserID:   userID,
+		Type:     first.GetType(),
+		Name:     first.GetName(),
+		Metadata: first.GetMetadata(),
+		Device:   utils.GetDeviceFromMetadata(ctx),
+	}
+	err = h.storeChunks(stream, first, d, func(d *data.Data) error {
+		return h.Service.Edit(ctx, d, int(first.GetVersion()))
+	})
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Update: update user's data failed",
+			zap.Error(err))
+		return dataStatus(err)
+	}
+
+	return stream.SendAndClose(&pb.DataVersion{Version: int32(d.Version)})
+}
+
+// chunkStream is the stream of data chunks received from the client.
+type chunkStream interface {
+	Recv() (*pb.DataChunk, error)
+}
+
+// storeChunks sets the content of data object to the stream of chunks starting
+// with the first chunk and stores data object by the store function, so the chunks
+// are streamed into the storage while they are received.
+func (h *DataHandler) storeChunks(stream chunkStream, first *pb.DataChunk, d *data.Data,
+	store func(d *data.Data) error) error {
+	pr, pw := io.Pipe()
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		// The storage gets the error of receiving, if any
+		pw.CloseWithError(h.receiveChunks(stream, pw, first.GetContent()))
+	}()
+
+	d.Content = pr
+	err := store(d)
+	// The chunks are not received anymore, if the storage hasn't read all of them
+	pr.Close()
+	<-received
+	return err
+}
+
+// receiveChunks writes the content of the first chunk and the next received chunks
+// into the writer until the stream ends. ErrDataTooLarge is returned, if the size
+// of the value exceeds the maximum size of data value.
+func (h *DataHandler) receiveChunks(stream chunkStream, w io.Writer, content []byte) error {
+	var size int64
+	for {
+		size += int64(len(content))
+		if h.Config.MaxDataSize > 0 && size > h.Config.MaxDataSize {
+			return fmt.Errorf("receiveChunks: %w", errs.ErrDataTooLarge)
+		}
+		if len(content) > 0 {
+			_, err := w.Write(content)
+			if err != nil {
+				return fmt.Errorf("receiveChunks: write chunk failed %w", err)
+			}
+		}
+
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("receiveChunks: receive chunk failed %w", err)
+		}
+		content = chunk.GetContent()
+	}
+}
+
+// Download streams the requested version of data by chunks, the current
+// version is streamed if the version is zero.
+func (h *DataHandler) Download(req *pb.DataKey, stream pb.Data_DownloadServer) error {
+	ctx := stream.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Download: get user id from context failed",
+			zap.Error(err))
+		return status.Error(codes.Internal, "get user failed")
+	}
+
+	d, err := h.unload(ctx, req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Download: unload requested data failed",
+			zap.Error(err))
+		return dataStatus(err)
+	}
+
+	value := io.Reader(bytes.NewReader(d.Data))
+	if d.Content != nil {
+		defer d.Content.Close()
+		value = d.Content
+	}
+	r := bufio.NewReaderSize(value, ChunkSize)
+
+	chunk := &pb.DataChunk{
+		Type:     d.Type,
+		Name:     d.Name,
+		Metadata: d.Metadata,
+		Version:  int32(d.Version),
+	}
+	for {
+		// The sent message must not be modified, so each chunk has its own buffer
+		buf := make([]byte, ChunkSize)
+		n, err := io.ReadFull(r, buf)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err == nil {
+			// The full chunk is the last one, if nothing follows it
+			_, err = r.Peek(1)
+			last = errors.Is(err, io.EOF)
+		}
+		if err != nil && !last {
+			logger.Log.With(zap.String("user_id", idString)).Error("Download: read data failed",
+				zap.Error(err))
+			return status.Error(codes.Internal, "read data failed")
+		}
+		chunk.Content = buf[:n]
+
+		err = stream.Send(chunk)
+		if err != nil {
+			logger.Log.With(zap.String("user_id", idString)).Error("Download: send chunk failed",
+				zap.Error(err))
+			return err
+		}
+		if last {
+			return nil
+		}
+		chunk = &pb.DataChunk{}
+	}
+}
+
+// unload returns the requested version of data,
+// the current version is returned if the version is zero.
+func (h *DataHandler) unload(ctx context.Context, req *pb.DataKey) (*data.Data, error) {
+	if req.GetVersion() != 0 {
+		return h.Service.UnloadVersion(ctx, req.GetType(), req.GetName(), int(req.GetVersion()))
+	}
+	return h.Service.Unload(ctx, req.GetType(), req.GetName())
+}
+
+// readValue returns the value of data object, the value streamed
+// from the blob storage is read entirely, since it is sent in one message.
+func readValue(d *data.Data) ([]byte, error) {
+	if d.Content == nil {
+		return d.Data, nil
+	}
+	defer d.Content.Close()
+	value, err := io.ReadAll(d.Content)
+	if err != nil {
+		return nil, fmt.Errorf("readValue: read data failed %w", err)
+	}
+	return value, nil
+}
+
+// createStatus returns the gRPC status of the error of data creation.
+func createStatus(err error) error {
+	if errors.Is(err, errs.ErrDataAlreadyUpload) {
+		return status.Error(codes.AlreadyExists, errs.ErrDataAlreadyUpload.Error())
+	}
+	return dataStatus(err)
+}
+
+// dataStatus returns the gRPC status of the error of data service.
+func dataStatus(err error) error {
+	switch {
+	case errors.Is(err, errs.ErrDataNotFound):
+		return status.Error(codes.NotFound, errs.ErrDataNotFound.Error())
+	case errors.Is(err, errs.ErrDataVersionDiffer):
+		return status.Error(codes.FailedPrecondition, errs.ErrDataVersionDiffer.Error())
+	case errors.Is(err, errs.ErrDataTypeIncorrect):
+		return status.Error(codes.InvalidArgument, errs.ErrDataTypeIncorrect.Error())
+	case errors.Is(err, errs.ErrDataTooLarge):
+		return status.Error(codes.ResourceExhausted, errs.ErrDataTooLarge.Error())
+	default:
+		return status.Error(codes.Internal, "data request failed")
+	}
+}
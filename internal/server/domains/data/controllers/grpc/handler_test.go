@@ -0,0 +1,371 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/mocks"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient runs gRPC server with the data handler over in-memory connection
+// and returns the client, all the requests are made by the user with id 1.
+func newTestClient(t *testing.T, s data.Service) pb.DataClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	withUser := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := context.WithValue(ss.Context(), utils.ContextIDKey, 1)
+		return handler(srv, &userStream{ServerStream: ss, ctx: ctx})
+	}
+	srv := grpc.NewServer(grpc.StreamInterceptor(withUser))
+	cfg := &config.ServerConfig{MaxDataSize: 1 << 10}
+	pb.RegisterDataServer(srv, newHandler(context.Background(), cfg, s))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewDataClient(conn)
+}
+
+// userStream contains the server stream with the context of the user.
+type userStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *userStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestDataHandler_Download(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := mocks.NewMockDataService(ctrl)
+	client := newTestClient(t, s)
+
+	value := bytes.Repeat([]byte("0123456789"), ChunkSize/4)
+
+	tests := []struct {
+		name       string
+		key        *pb.DataKey
+		stored     *data.Data
+		content    []byte
+		err        error
+		wantChunks int
+		wantCode   codes.Code
+	}{
+		{
+			name: "large_binary",
+			key:  &pb.DataKey{Type: "binary", Name: "file"},
+			stored: &data.Data{
+				Type: "binary", Name: "file", Data: value,
+				Metadata: []byte(`{"a":"b"}`), Version: 2,
+			},
+			wantChunks: 3,
+			wantCode:   codes.OK,
+		},
+		{
+			name: "streamed_binary",
+			key:  &pb.DataKey{Type: "binary", Name: "blob"},
+			stored: &data.Data{
+				Type: "binary", Name: "blob", Version: 3,
+			},
+			content:    value[:2*ChunkSize],
+			wantChunks: 2,
+			wantCode:   codes.OK,
+		},
+		{
+			name: "empty",
+			key:  &pb.DataKey{Type: "binary", Name: "empty", Version: 1},
+			stored: &data.Data{
+				Type: "binary", Name: "empty", Version: 1,
+			},
+			wantChunks: 1,
+			wantCode:   codes.OK,
+		},
+		{
+			name:     "not_found",
+			key:      &pb.DataKey{Type: "binary", Name: "none"},
+			err:      errs.ErrDataNotFound,
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.content
+			if tt.content != nil {
+				tt.stored.Content = io.NopCloser(bytes.NewReader(tt.content))
+			} else if tt.stored != nil {
+				want = tt.stored.Data
+			}
+			if tt.key.Version != 0 {
+				s.EXPECT().UnloadVersion(gomock.Any(), tt.key.Type, tt.key.Name, int(tt.key.Version)).
+					Return(tt.stored, tt.err).Times(1)
+			} else {
+				s.EXPECT().Unload(gomock.Any(), tt.key.Type, tt.key.Name).
+					Return(tt.stored, tt.err).Times(1)
+			}
+
+			stream, err := client.Download(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			var got bytes.Buffer
+			var first *pb.DataChunk
+			chunks := 0
+			for {
+				chunk, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					if code := status.Code(err); code != tt.wantCode {
+						t.Fatalf("Download() code = %v, want %v", code, tt.wantCode)
+					}
+					return
+				}
+				if first == nil {
+					first = chunk
+				}
+				chunks++
+				got.Write(chunk.GetContent())
+			}
+
+			if tt.wantCode != codes.OK {
+				t.Fatalf("Download() expected code %v", tt.wantCode)
+			}
+			if chunks != tt.wantChunks {
+				t.Errorf("Download() chunks = %v, want %v", chunks, tt.wantChunks)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("Download() data length = %v, want %v", got.Len(), len(want))
+			}
+			if first.GetVersion() != int32(tt.stored.Version) || !bytes.Equal(first.GetMetadata(), tt.stored.Metadata) {
+				t.Errorf("Download() first chunk = %v, want version %v", first, tt.stored.Version)
+			}
+		})
+	}
+}
+
+func TestDataHandler_Upload(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := mocks.NewMockDataService(ctrl)
+	client := newTestClient(t, s)
+
+	tests := []struct {
+		name        string
+		chunks      []*pb.DataChunk
+		err         error
+		wantData    []byte
+		wantVersion int32
+		wantCode    codes.Code
+	}{
+		{
+			name: "ok",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Metadata: []byte(`{}`), Content: []byte("first ")},
+				{Content: []byte("second")},
+			},
+			wantData:    []byte("first second"),
+			wantVersion: 1,
+			wantCode:    codes.OK,
+		},
+		{
+			name: "already_uploaded",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Content: []byte("value")},
+			},
+			err:      errs.ErrDataAlreadyUpload,
+			wantData: []byte("value"),
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name: "too_large",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Content: bytes.Repeat([]byte("a"), 1000)},
+				{Content: bytes.Repeat([]byte("a"), 1000)},
+			},
+			err:      errs.ErrDataTooLarge,
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "no_chunks",
+			chunks:   nil,
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.chunks != nil {
+				s.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, d *data.Data) error {
+						// The value is streamed into the service while the chunks are received
+						value, err := io.ReadAll(d.Content)
+						if err != nil {
+							if !errors.Is(err, tt.err) {
+								t.Errorf("Service.Create() read error = %v, want %v", err, tt.err)
+							}
+							return err
+						}
+						if d.UserID != 1 || d.Type != tt.chunks[0].Type || d.Name != tt.chunks[0].Name ||
+							!bytes.Equal(value, tt.wantData) {
+							t.Errorf("Service.Create() data = %v, value = %s", d, value)
+						}
+						d.Version = int(tt.wantVersion)
+						return tt.err
+					}).Times(1)
+			}
+
+			stream, err := client.Upload(ctx)
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+			for _, chunk := range tt.chunks {
+				if err := stream.Send(chunk); err != nil {
+					t.Fatalf("Upload() send error = %v", err)
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Upload() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && resp.GetVersion() != tt.wantVersion {
+				t.Errorf("Upload() version = %v, want %v", resp.GetVersion(), tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDataHandler_Update(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := mocks.NewMockDataService(ctrl)
+	client := newTestClient(t, s)
+
+	tests := []struct {
+		name         string
+		chunks       []*pb.DataChunk
+		err          error
+		wantData     []byte
+		wantExpected int
+		wantVersion  int32
+		wantCode     codes.Code
+	}{
+		{
+			name: "ok",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Metadata: []byte(`{}`), Version: 1, Content: []byte("first ")},
+				{Content: []byte("second")},
+			},
+			wantData:     []byte("first second"),
+			wantExpected: 1,
+			wantVersion:  2,
+			wantCode:     codes.OK,
+		},
+		{
+			name: "version_differ",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Version: 1, Content: []byte("value")},
+			},
+			err:          errs.ErrDataVersionDiffer,
+			wantData:     []byte("value"),
+			wantExpected: 1,
+			wantCode:     codes.FailedPrecondition,
+		},
+		{
+			name: "not_found",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Content: []byte("value")},
+			},
+			err:          errs.ErrDataNotFound,
+			wantData:     []byte("value"),
+			wantExpected: 0,
+			wantCode:     codes.NotFound,
+		},
+		{
+			name: "too_large",
+			chunks: []*pb.DataChunk{
+				{Type: "binary", Name: "file", Content: bytes.Repeat([]byte("a"), 1000)},
+				{Content: bytes.Repeat([]byte("a"), 1000)},
+			},
+			err:      errs.ErrDataTooLarge,
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "no_chunks",
+			chunks:   nil,
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.chunks != nil {
+				s.EXPECT().Edit(gomock.Any(), gomock.Any(), tt.wantExpected).
+					DoAndReturn(func(ctx context.Context, d *data.Data, version int) error {
+						// The value is streamed into the service while the chunks are received
+						value, err := io.ReadAll(d.Content)
+						if err != nil {
+							if !errors.Is(err, tt.err) {
+								t.Errorf("Service.Edit() read error = %v, want %v", err, tt.err)
+							}
+							return err
+						}
+						if d.UserID != 1 || d.Type != tt.chunks[0].Type || d.Name != tt.chunks[0].Name ||
+							!bytes.Equal(value, tt.wantData) {
+							t.Errorf("Service.Edit() data = %v, value = %s", d, value)
+						}
+						d.Version = int(tt.wantVersion)
+						return tt.err
+					}).Times(1)
+			}
+
+			stream, err := client.Update(ctx)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			for _, chunk := range tt.chunks {
+				if err := stream.Send(chunk); err != nil {
+					t.Fatalf("Update() send error = %v", err)
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Update() code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && resp.GetVersion() != tt.wantVersion {
+				t.Errorf("Update() version = %v, want %v", resp.GetVersion(), tt.wantVersion)
+			}
+		})
+	}
+}
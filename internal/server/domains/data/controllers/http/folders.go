@@ -0,0 +1,266 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+)
+
+// HandleFolderList writes all the user's folders into response body in JSON format.
+func (h *DataHandler) HandleFolderList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderList: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	folders, err := h.Service.Folders(ctx)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderList: get folders failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, idString, folders)
+}
+
+// HandleFolderCreate creates new folder with name and optional parent folder
+// identifier from the request body in JSON format, the created folder is written
+// into response body in JSON format.
+func (h *DataHandler) HandleFolderCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderCreate: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req data.Folder
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, utils.MultipartOverhead)).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderCreate: decode request body failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req.ID = 0
+	req.UserID = userID
+
+	err = h.Service.CreateFolder(ctx, &req)
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderCreate: create folder failed",
+			zap.Error(err))
+		return
+	}
+
+	writeJSON(w, idString, &req)
+}
+
+// HandleFolderUpdate renames the folder and moves it into the parent folder
+// with name and optional parent folder identifier from the request body in JSON format.
+func (h *DataHandler) HandleFolderUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderUpdate: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "folderID"))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderUpdate: parse folder id failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req data.Folder
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, utils.MultipartOverhead)).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderUpdate: decode request body failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req.ID = id
+	req.UserID = userID
+
+	err = h.Service.EditFolder(ctx, &req)
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderUpdate: update folder failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleFolderDelete deletes the folder with its subfolders,
+// data objects of the deleted folders are moved to the root.
+func (h *DataHandler) HandleFolderDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderDelete: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "folderID"))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderDelete: parse folder id failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.DeleteFolder(ctx, id)
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleFolderDelete: delete folder failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleDataMove moves data object into the folder with identifier from the request
+// body in JSON format, zero or absent identifier moves data object to the root.
+func (h *DataHandler) HandleDataMove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataMove: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		FolderID int `json:"folder_id"`
+	}
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, utils.MultipartOverhead)).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataMove: decode request body failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.Move(ctx, chi.URLParam(r, "dataType"), chi.URLParam(r, "dataName"), req.FolderID)
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataMove: move data failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleDataTag adds the tag from the path to data object.
+func (h *DataHandler) HandleDataTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataTag: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Service.Tag(ctx, chi.URLParam(r, "dataType"), chi.URLParam(r, "dataName"), chi.URLParam(r, "tag"))
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataTag: tag data failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleDataUntag removes the tag from the path from data object.
+func (h *DataHandler) HandleDataUntag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUntag: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Service.Untag(ctx, chi.URLParam(r, "dataType"), chi.URLParam(r, "dataName"), chi.URLParam(r, "tag"))
+	if err != nil {
+		w.WriteHeader(folderStatus(err))
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUntag: untag data failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// folderStatus returns the status code of the response for the error
+// of working with folders and tags.
+func folderStatus(err error) int {
+	switch {
+	case errors.Is(err, errs.ErrFolderNotFound), errors.Is(err, errs.ErrDataNotFound):
+		return http.StatusNoContent
+	case errors.Is(err, errs.ErrFolderNameBusy):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrFolderIncorrect), errors.Is(err, errs.ErrTagIncorrect):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes the object into response body in JSON format.
+func writeJSON(w http.ResponseWriter, idString string, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("writeJSON: marshal response failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
@@ -4,20 +4,23 @@
 package http
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"mime"
-	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
 	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
-	repo "github.com/pavlegich/gophkeeper/internal/server/domains/data/repository"
 	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 	"github.com/pavlegich/gophkeeper/internal/server/utils"
 	"go.uber.org/zap"
@@ -31,8 +34,8 @@ type DataHandler struct {
 }
 
 // Activate activates handler for data object.
-func Activate(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, db *sql.DB) {
-	s := data.NewDataService(ctx, repo.NewDataRepository(ctx, db))
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, repo data.Repository) {
+	s := data.NewDataService(ctx, repo)
 	newHandler(ctx, r, cfg, s)
 }
 
@@ -42,10 +45,169 @@ func newHandler(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, s dat
 		Config:  cfg,
 		Service: s,
 	}
+	r.Get("/api/user/data", h.HandleDataList)
+	r.Get("/api/user/data/search", h.HandleDataSearch)
+	r.Get("/api/user/sync", h.HandleSync)
 	r.Post("/api/user/data/{dataType}/{dataName}", h.HandleDataUpload)
 	r.Get("/api/user/data/{dataType}/{dataName}", h.HandleDataValue)
 	r.Put("/api/user/data/{dataType}/{dataName}", h.HandleDataUpdate)
 	r.Delete("/api/user/data/{dataType}/{dataName}", h.HandleDataDelete)
+	r.Get("/api/user/data/{dataType}/{dataName}/versions", h.HandleDataVersions)
+	r.Get("/api/user/data/{dataType}/{dataName}/versions/{version}", h.HandleDataVersionValue)
+	r.Post("/api/user/data/{dataType}/{dataName}/versions/{version}/restore", h.HandleDataRestore)
+	r.Post("/api/user/uploads", h.HandleUploadInit)
+	r.Get("/api/user/uploads/{uploadID}", h.HandleUploadState)
+	r.Put("/api/user/uploads/{uploadID}", h.HandleUploadChunk)
+	r.Post("/api/user/uploads/{uploadID}/commit", h.HandleUploadCommit)
+	r.Delete("/api/user/uploads/{uploadID}", h.HandleUploadAbort)
+	r.Put("/api/user/data/{dataType}/{dataName}/folder", h.HandleDataMove)
+	r.Put("/api/user/data/{dataType}/{dataName}/tags/{tag}", h.HandleDataTag)
+	r.Delete("/api/user/data/{dataType}/{dataName}/tags/{tag}", h.HandleDataUntag)
+	r.Get("/api/user/folders", h.HandleFolderList)
+	r.Post("/api/user/folders", h.HandleFolderCreate)
+	r.Put("/api/user/folders/{folderID}", h.HandleFolderUpdate)
+	r.Delete("/api/user/folders/{folderID}", h.HandleFolderDelete)
+}
+
+// HandleDataList writes information about all the user's data
+// into response body in JSON format. Data can be filtered by type,
+// folder and tag with the query parameters "type", "folder" and "tag".
+func (h *DataHandler) HandleDataList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter := data.Filter{
+		Type: r.URL.Query().Get("type"),
+		Tag:  r.URL.Query().Get("tag"),
+	}
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataList: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if v := r.URL.Query().Get("folder"); v != "" {
+		filter.FolderID, err = strconv.Atoi(v)
+		if err != nil || filter.FolderID < 1 {
+			logger.Log.With(zap.String("user_id", idString)).Error("HandleDataList: parse folder failed",
+				zap.String("folder", v))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	items, err := h.Service.List(ctx, filter)
+	if err != nil {
+		if errors.Is(err, errs.ErrDataTypeIncorrect) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataList: get data list failed",
+			zap.Error(err))
+		return
+	}
+
+	resp, err := json.Marshal(items)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataList: marshal data list failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleDataSearch writes information about the user's data matching
+// the search query from the query parameter "q" into response body in JSON format.
+func (h *DataHandler) HandleDataSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataSearch: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	items, err := h.Service.Search(ctx, r.URL.Query().Get("q"))
+	if err != nil {
+		if errors.Is(err, errs.ErrQueryIncorrect) || errors.Is(err, errs.ErrDataTypeIncorrect) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataSearch: search data failed",
+			zap.Error(err))
+		return
+	}
+
+	writeJSON(w, idString, items)
+}
+
+// HandleSync writes the user's data changes since the cursor from the query
+// parameter "since" into response body in JSON format. The number of changes
+// can be limited with the query parameter "limit".
+func (h *DataHandler) HandleSync(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSync: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		since, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || since < 0 {
+			logger.Log.With(zap.String("user_id", idString)).Error("HandleSync: parse cursor failed",
+				zap.String("since", v))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			logger.Log.With(zap.String("user_id", idString)).Error("HandleSync: parse limit failed",
+				zap.String("limit", v))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	feed, err := h.Service.Changes(ctx, since, limit)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSync: get changes failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(feed)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSync: marshal changes failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
 }
 
 // HandleDataUpload uploads new data into the storage.
@@ -65,14 +227,24 @@ func (h *DataHandler) HandleDataUpload(w http.ResponseWriter, r *http.Request) {
 		UserID: userID,
 		Type:   chi.URLParam(r, "dataType"),
 		Name:   chi.URLParam(r, "dataName"),
+		Device: utils.GetDeviceFromRequest(r),
 	}
 
+	if !h.limitBody(w, r) {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpload: request body is too large",
+			zap.Int64("content_length", r.ContentLength))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 	req, err = utils.GetMultipartDataFromRequest(ctx, r, req)
-
 	if err != nil {
-		if errors.Is(err, mime.ErrInvalidMediaParameter) {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case errors.Is(err, mime.ErrInvalidMediaParameter):
 			w.WriteHeader(http.StatusBadRequest)
-		} else {
+		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpload: read data from the request failed",
@@ -82,9 +254,13 @@ func (h *DataHandler) HandleDataUpload(w http.ResponseWriter, r *http.Request) {
 
 	err = h.Service.Create(ctx, req)
 	if err != nil {
-		if errors.Is(err, errs.ErrDataAlreadyUpload) {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errs.ErrDataAlreadyUpload):
 			w.WriteHeader(http.StatusConflict)
-		} else {
+		case errors.As(err, &maxErr):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpload: upload new data failed",
@@ -92,6 +268,7 @@ func (h *DataHandler) HandleDataUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(req.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -124,25 +301,12 @@ func (h *DataHandler) HandleDataValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if dType == "binary" {
-		var buf bytes.Buffer
-		multipartWriter := multipart.NewWriter(&buf)
-		defer multipartWriter.Close()
-		dataPart, err := multipartWriter.CreateFormField("file")
-		if err != nil {
-			logger.Log.With(zap.String("user_id", idString)).Error("HandleDataValue: create form field failed",
-				zap.Error(err))
-		}
-		dataPart.Write(storedData.Data)
-		w.Header().Set("Content-Type", multipartWriter.FormDataContentType())
-		w.WriteHeader(http.StatusOK)
-		w.Write(buf.Bytes())
-		return
+	w.Header().Set("ETag", utils.ETag(storedData.Version))
+	err = writeData(w, storedData)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataValue: write data failed",
+			zap.Error(err))
 	}
-
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(storedData.Data))
 }
 
 // HandleDataUpdate updates the requested data in storage.
@@ -162,15 +326,32 @@ func (h *DataHandler) HandleDataUpdate(w http.ResponseWriter, r *http.Request) {
 		UserID: userID,
 		Type:   chi.URLParam(r, "dataType"),
 		Name:   chi.URLParam(r, "dataName"),
+		Device: utils.GetDeviceFromRequest(r),
 	}
 
-	req, err = utils.GetMultipartDataFromRequest(ctx, r, req)
+	version, err := utils.GetVersionFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpdate: get expected version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
+	if !h.limitBody(w, r) {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpdate: request body is too large",
+			zap.Int64("content_length", r.ContentLength))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	req, err = utils.GetMultipartDataFromRequest(ctx, r, req)
 	if err != nil {
-		if errors.Is(err, mime.ErrInvalidMediaParameter) {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		case errors.Is(err, mime.ErrInvalidMediaParameter):
 			w.WriteHeader(http.StatusBadRequest)
-
-		} else {
+		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpdate: read data from the request failed",
@@ -178,11 +359,17 @@ func (h *DataHandler) HandleDataUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.Service.Edit(ctx, req)
+	err = h.Service.Edit(ctx, req, version)
 	if err != nil {
-		if errors.Is(err, errs.ErrDataNotFound) {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errs.ErrDataNotFound):
 			w.WriteHeader(http.StatusNoContent)
-		} else {
+		case errors.Is(err, errs.ErrDataVersionDiffer):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case errors.As(err, &maxErr):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataUpdate: update user's data failed",
@@ -190,6 +377,7 @@ func (h *DataHandler) HandleDataUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", utils.ETag(req.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -209,17 +397,526 @@ func (h *DataHandler) HandleDataDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.Service.Delete(ctx, dType, dName)
+	version, err := utils.GetVersionFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataDelete: get expected version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = h.Service.Delete(ctx, dType, dName, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrDataNotFound):
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, errs.ErrDataVersionDiffer):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataDelete: delete requested data failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleDataVersions writes information about all the versions
+// of requested data into response body in JSON format.
+func (h *DataHandler) HandleDataVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dType := chi.URLParam(r, "dataType")
+	dName := chi.URLParam(r, "dataName")
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersions: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	revisions, err := h.Service.Versions(ctx, dType, dName)
 	if err != nil {
 		if errors.Is(err, errs.ErrDataNotFound) {
 			w.WriteHeader(http.StatusNoContent)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
-		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataDelete: delete requested data failed",
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersions: get data versions failed",
+			zap.Error(err))
+		return
+	}
+
+	resp, err := json.Marshal(revisions)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersions: marshal data versions failed",
 			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleDataVersionValue writes the requested version of data into response body
+// if this version found in storage successfuly.
+func (h *DataHandler) HandleDataVersionValue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dType := chi.URLParam(r, "dataType")
+	dName := chi.URLParam(r, "dataName")
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersionValue: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersionValue: parse version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	storedData, err := h.Service.UnloadVersion(ctx, dType, dName, version)
+	if err != nil {
+		if errors.Is(err, errs.ErrDataNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersionValue: unload requested data version failed",
+			zap.Error(err))
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(storedData.Version))
+	err = writeData(w, storedData)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataVersionValue: write data failed",
+			zap.Error(err))
+	}
+}
+
+// HandleDataRestore rolls the requested data back to the requested version. If the
+// request has If-Match header, data is restored only if its current version equals
+// to it. The new version is written into the ETag header.
+func (h *DataHandler) HandleDataRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataRestore: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataRestore: parse version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	expected, err := utils.GetVersionFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataRestore: get expected version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &data.Data{
+		UserID: userID,
+		Type:   chi.URLParam(r, "dataType"),
+		Name:   chi.URLParam(r, "dataName"),
+		Device: utils.GetDeviceFromRequest(r),
+	}
+
+	err = h.Service.Restore(ctx, req, version, expected)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrDataNotFound):
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, errs.ErrDataVersionDiffer):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleDataRestore: restore data version failed",
+			zap.Error(err))
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(req.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUploadInit starts new chunked upload of data object value with type, name,
+// size, metadata, optional SHA-256 checksum of the value in hex and optional original
+// file name and content type of the binary value from the request body in JSON format.
+// If the field "update" is true, the committed value updates the existing data object.
+// The state of the upload is written into response body in JSON format.
+func (h *DataHandler) HandleUploadInit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadInit: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req data.Upload
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, utils.MultipartOverhead)).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadInit: decode request body failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.Size < 0 || !isValidChecksum(req.Checksum) {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadInit: incorrect upload",
+			zap.String("name", req.Name), zap.Int64("size", req.Size), zap.String("checksum", req.Checksum))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if h.Config.MaxDataSize > 0 && req.Size > h.Config.MaxDataSize {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadInit: data is too large",
+			zap.Int64("size", req.Size))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	req.UserID = userID
+	req.Checksum = strings.ToLower(req.Checksum)
+
+	err = h.Service.InitUpload(ctx, &req)
+	if err != nil {
+		if errors.Is(err, errs.ErrDataTypeIncorrect) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadInit: init upload failed",
+			zap.Error(err))
+		return
+	}
+
+	w.Header().Set("Location", "/api/user/uploads/"+req.ID)
+	writeUpload(w, &req)
+}
+
+// HandleUploadState writes the state of the requested upload into response body
+// in JSON format, the size of received chunks is also set in the Upload-Offset header.
+func (h *DataHandler) HandleUploadState(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadState: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	upload, err := h.Service.GetUpload(ctx, chi.URLParam(r, "uploadID"))
+	if err != nil {
+		if errors.Is(err, errs.ErrUploadNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadState: get upload failed",
+			zap.Error(err))
+		return
+	}
+
+	writeUpload(w, upload)
+}
+
+// HandleUploadChunk saves the chunk of the value from the request body at the offset
+// from the Upload-Offset header, which must be equal to the size of received chunks,
+// otherwise the current size is returned in the header with 409 Conflict.
+// The chunk is checked with the checksum from the Upload-Checksum header, if any.
+func (h *DataHandler) HandleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := chi.URLParam(r, "uploadID")
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	offset, err := utils.GetOffsetFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: get chunk offset failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	checksum, err := utils.GetChecksumFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: get chunk checksum failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if r.ContentLength > data.MaxChunkSize {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: chunk is too large",
+			zap.Int64("content_length", r.ContentLength))
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, data.MaxChunkSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: read chunk failed",
+			zap.Error(err))
+		return
+	}
+
+	upload, err := h.Service.WriteChunk(ctx, id, offset, chunk, checksum)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrUploadNotFound):
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, errs.ErrUploadOffset):
+			// The client continues the upload from the current offset
+			if current, err := h.Service.GetUpload(ctx, id); err == nil {
+				w.Header().Set(utils.UploadOffsetHeader, strconv.FormatInt(current.Offset, 10))
+			}
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, errs.ErrChecksumMismatch):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, errs.ErrDataTooLarge):
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadChunk: write chunk failed",
+			zap.Error(err))
+		return
+	}
+
+	w.Header().Set(utils.UploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUploadCommit saves the value of the complete upload as data object.
+// The upload, which updates data object, accepts the expected version
+// in the If-Match header.
+func (h *DataHandler) HandleUploadCommit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadCommit: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	version, err := utils.GetVersionFromRequest(r)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadCommit: get expected version failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.Service.CommitUpload(ctx, chi.URLParam(r, "uploadID"), version, utils.GetDeviceFromRequest(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrUploadNotFound), errors.Is(err, errs.ErrDataNotFound):
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, errs.ErrUploadIncomplete):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, errs.ErrChecksumMismatch):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, errs.ErrDataAlreadyUpload):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, errs.ErrDataVersionDiffer):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadCommit: commit upload failed",
+			zap.Error(err))
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(d.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUploadAbort deletes the requested upload with all the received chunks.
+func (h *DataHandler) HandleUploadAbort(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadAbort: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Service.AbortUpload(ctx, chi.URLParam(r, "uploadID"))
+	if err != nil {
+		if errors.Is(err, errs.ErrUploadNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUploadAbort: abort upload failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeUpload writes the state of the upload into response in JSON format.
+func writeUpload(w http.ResponseWriter, upload *data.Upload) {
+	resp, err := json.Marshal(upload)
+	if err != nil {
+		logger.Log.Error("writeUpload: marshal upload failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(utils.UploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// isValidChecksum checks whether the checksum of the value is empty
+// or contains SHA-256 digest in hex.
+func isValidChecksum(checksum string) bool {
+	if checksum == "" {
+		return true
+	}
+	sum, err := hex.DecodeString(checksum)
+	return err == nil && len(sum) == 32
+}
+
+// writeData writes the data value into response. Binary data is written with
+// the original content type, file name in the Content-Disposition header,
+// SHA-256 digest of the value in the Digest header and metadata, which contains
+// the encrypted file information, in the X-Metadata header. The value streamed
+// from the blob storage is copied into response and closed.
+func writeData(w http.ResponseWriter, d *data.Data) error {
+	if d.Content != nil {
+		defer d.Content.Close()
+	}
+	if d.Type == "binary" {
+		checksum, size := d.Checksum, d.Size
+		if d.Content == nil {
+			size = int64(len(d.Data))
+			if checksum == "" {
+				sum := sha256.Sum256(d.Data)
+				checksum = hex.EncodeToString(sum[:])
+			}
+		}
+		digest, err := hex.DecodeString(checksum)
+		if err != nil {
+			return fmt.Errorf("writeData: decode checksum failed %w", err)
+		}
+
+		contentType := d.ContentType
+		if contentType == "" {
+			contentType = data.DefaultContentType
+		}
+		w.Header().Set("Content-Type", contentType)
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": d.FileName})
+		if d.FileName != "" && disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		// The streamed value is described by the size and the checksum saved with it
+		if len(digest) > 0 {
+			w.Header().Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(digest))
+		}
+		if len(d.Metadata) > 0 {
+			w.Header().Set(utils.MetadataHeader, base64.StdEncoding.EncodeToString(d.Metadata))
+		}
+		if size > 0 || d.Content == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		err = copyValue(w, d)
+		if err != nil {
+			return fmt.Errorf("writeData: %w", err)
+		}
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	err := copyValue(w, d)
+	if err != nil {
+		return fmt.Errorf("writeData: %w", err)
+	}
+	return nil
+}
+
+// copyValue writes the value of data object into response,
+// the value streamed from the blob storage is copied by parts.
+func copyValue(w http.ResponseWriter, d *data.Data) error {
+	var err error
+	if d.Content != nil {
+		_, err = io.Copy(w, d.Content)
+	} else {
+		_, err = w.Write(d.Data)
+	}
+	if err != nil {
+		return fmt.Errorf("copyValue: write data failed %w", err)
+	}
+	return nil
+}
+
+// limitBody limits the size of the request body by the maximum size of data value,
+// false is returned if the declared content length already exceeds the limit.
+func (h *DataHandler) limitBody(w http.ResponseWriter, r *http.Request) bool {
+	if h.Config.MaxDataSize <= 0 {
+		return true
+	}
+	limit := h.Config.MaxDataSize + utils.MultipartOverhead
+	if r.ContentLength > limit {
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return true
 }
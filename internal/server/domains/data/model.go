@@ -5,6 +5,8 @@ package data
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"time"
 )
 
@@ -16,7 +18,115 @@ type Data struct {
 	Type      string    `db:"type" json:"type"`
 	Data      []byte    `db:"data" json:"data"`
 	Metadata  []byte    `db:"metadata" json:"metadata"`
+	Version   int       `db:"version" json:"version"`
+	Seq       int64     `db:"seq" json:"seq"`
+	Device    string    `db:"device" json:"device"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	File
+	// Content streams the value instead of Data: the value read from the request
+	// or the binary value read from the blob storage, which must be closed.
+	// The size and the checksum of the streamed binary value are set, when
+	// the value is read to the end.
+	Content io.ReadCloser `db:"-" json:"-"`
+}
+
+// File contains information about the file of binary data object: the original
+// name and the content type declared by the client, the size and SHA-256 in hex
+// of the stored value. The fields are empty for the other data types.
+type File struct {
+	FileName    string `db:"file_name" json:"file_name,omitempty"`
+	ContentType string `db:"content_type" json:"content_type,omitempty"`
+	Size        int64  `db:"size" json:"size,omitempty"`
+	Checksum    string `db:"checksum" json:"checksum,omitempty"`
+}
+
+// Item contains information about stored data object
+// without its value, it is used for listing the stored data.
+type Item struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	FolderID  int             `json:"folder_id,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	File
+}
+
+// Filter contains the conditions for listing the stored data objects:
+// data type, the folder which directly contains data object and the tag
+// of data object. The empty conditions are not applied.
+type Filter struct {
+	Type     string
+	FolderID int
+	Tag      string
+}
+
+// Query contains the conditions of searching for data objects: substrings
+// and prefixes of the name, which are matched case-insensitively, and data types.
+// All the conditions must be met, except data types, one of which must match.
+type Query struct {
+	Names    []string
+	Prefixes []string
+	Types    []string
+}
+
+// Folder contains information about the user's folder for organizing
+// data objects. Folders are nested, the folder without parent is in the root.
+type Folder struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Name      string    `json:"name"`
+	ParentID  int       `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Revision contains information about one of the immutable
+// versions of data object.
+type Revision struct {
+	Version   int       `json:"version"`
+	Device    string    `json:"device"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Change contains information about created, updated or deleted
+// data object for synchronization between clients.
+type Change struct {
+	Seq       int64           `json:"seq"`
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Deleted   bool            `json:"deleted"`
+	Version   int             `json:"version,omitempty"`
+	Data      []byte          `json:"data,omitempty"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	File
+}
+
+// ChangeFeed contains the changes since the requested cursor and the cursor
+// for requesting the next changes.
+type ChangeFeed struct {
+	Cursor  int64     `json:"cursor"`
+	More    bool      `json:"more"`
+	Changes []*Change `json:"changes"`
+}
+
+// Upload contains the state of chunked upload of data object value. Value is sent
+// by chunks, which are saved in the storage, so the interrupted upload is continued
+// from the offset. After all the chunks are received, the upload is committed
+// as the new data object or the new version of the existing one.
+type Upload struct {
+	ID          string          `json:"id"`
+	UserID      int             `json:"-"`
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	Size        int64           `json:"size"`
+	Offset      int64           `json:"offset"`
+	Checksum    string          `json:"checksum,omitempty"`
+	FileName    string          `json:"file_name,omitempty"`
+	ContentType string          `json:"content_type,omitempty"`
+	Update      bool            `json:"update"`
+	ExpiresAt   time.Time       `json:"expires_at"`
 }
 
 // Service describes methods related with data object
@@ -24,15 +134,51 @@ type Data struct {
 type Service interface {
 	Create(ctx context.Context, data *Data) error
 	Unload(ctx context.Context, dType string, name string) (*Data, error)
-	Edit(ctx context.Context, data *Data) error
-	Delete(ctx context.Context, dType string, name string) error
+	List(ctx context.Context, filter Filter) ([]*Item, error)
+	Search(ctx context.Context, q string) ([]*Item, error)
+	Edit(ctx context.Context, data *Data, version int) error
+	Delete(ctx context.Context, dType string, name string, version int) error
+	Versions(ctx context.Context, dType string, name string) ([]*Revision, error)
+	UnloadVersion(ctx context.Context, dType string, name string, version int) (*Data, error)
+	Restore(ctx context.Context, data *Data, version int, expected int) error
+	Changes(ctx context.Context, since int64, limit int) (*ChangeFeed, error)
+	InitUpload(ctx context.Context, upload *Upload) error
+	GetUpload(ctx context.Context, id string) (*Upload, error)
+	WriteChunk(ctx context.Context, id string, offset int64, chunk []byte, checksum []byte) (*Upload, error)
+	CommitUpload(ctx context.Context, id string, version int, device string) (*Data, error)
+	AbortUpload(ctx context.Context, id string) error
+	Folders(ctx context.Context) ([]*Folder, error)
+	CreateFolder(ctx context.Context, folder *Folder) error
+	EditFolder(ctx context.Context, folder *Folder) error
+	DeleteFolder(ctx context.Context, id int) error
+	Move(ctx context.Context, dType string, name string, folderID int) error
+	Tag(ctx context.Context, dType string, name string, tag string) error
+	Untag(ctx context.Context, dType string, name string, tag string) error
 }
 
 // Repository describes methods related with data object
 // for communication between services and database.
 type Repository interface {
 	GetDataByName(ctx context.Context, dType string, name string) (*Data, error)
+	GetDataList(ctx context.Context, filter Filter) ([]*Item, error)
+	SearchData(ctx context.Context, query *Query) ([]*Item, error)
 	CreateData(ctx context.Context, data *Data) error
-	UpdateData(ctx context.Context, data *Data) error
-	DeleteDataByName(ctx context.Context, dType string, name string) error
+	UpdateData(ctx context.Context, data *Data, version int) error
+	DeleteDataByName(ctx context.Context, dType string, name string, version int) error
+	GetDataVersions(ctx context.Context, dType string, name string) ([]*Revision, error)
+	GetDataVersion(ctx context.Context, dType string, name string, version int) (*Data, error)
+	RestoreDataVersion(ctx context.Context, data *Data, version int, expected int) error
+	GetChanges(ctx context.Context, since int64, limit int) ([]*Change, error)
+	CreateUpload(ctx context.Context, upload *Upload) error
+	GetUpload(ctx context.Context, id string) (*Upload, error)
+	AppendUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (*Upload, error)
+	GetUploadContent(ctx context.Context, upload *Upload) ([]byte, error)
+	DeleteUpload(ctx context.Context, id string) error
+	GetFolders(ctx context.Context) ([]*Folder, error)
+	CreateFolder(ctx context.Context, folder *Folder) error
+	UpdateFolder(ctx context.Context, folder *Folder) error
+	DeleteFolder(ctx context.Context, id int) error
+	SetDataFolder(ctx context.Context, dType string, name string, folderID int) error
+	AddDataTag(ctx context.Context, dType string, name string, tag string) error
+	RemoveDataTag(ctx context.Context, dType string, name string, tag string) error
 }
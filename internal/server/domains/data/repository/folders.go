@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+)
+
+// GetFolders gets all the user's folders from the storage ordered by identifier,
+// so the parent folder goes before its subfolders.
+func (r *Repository) GetFolders(ctx context.Context) ([]*data.Folder, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetFolders: couldn't read user id from the context %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, name, parent_id, created_at 
+	FROM folders WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetFolders: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	folders := make([]*data.Folder, 0)
+	for rows.Next() {
+		var f data.Folder
+		var parentID sql.NullInt64
+		err = rows.Scan(&f.ID, &f.UserID, &f.Name, &parentID, &f.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("GetFolders: scan row failed %w", err)
+		}
+		f.ParentID = int(parentID.Int64)
+		folders = append(folders, &f)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetFolders: rows.Err %w", err)
+	}
+
+	return folders, nil
+}
+
+// CreateFolder saves new folder into the storage.
+func (r *Repository) CreateFolder(ctx context.Context, f *data.Folder) error {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO folders (user_id, parent_id, name) 
+	VALUES ($1, $2, $3) RETURNING id, created_at`, f.UserID, nullID(f.ParentID), f.Name)
+	err := row.Scan(&f.ID, &f.CreatedAt)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("CreateFolder: %w", errs.ErrFolderNameBusy)
+		}
+		return fmt.Errorf("CreateFolder: insert folder failed %w", err)
+	}
+	return nil
+}
+
+// UpdateFolder renames the folder and moves it into the parent folder.
+func (r *Repository) UpdateFolder(ctx context.Context, f *data.Folder) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE folders SET name = $1, parent_id = $2 
+	WHERE id = $3 AND user_id = $4`, f.Name, nullID(f.ParentID), f.ID, f.UserID)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return fmt.Errorf("UpdateFolder: %w", errs.ErrFolderNameBusy)
+		}
+		return fmt.Errorf("UpdateFolder: update folder failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("UpdateFolder: couldn't get rows affected %w", err)
+	}
+	if rowsCount == 0 {
+		return fmt.Errorf("UpdateFolder: nothing to update, %w", errs.ErrFolderNotFound)
+	}
+	return nil
+}
+
+// DeleteFolder deletes the user's folder with its subfolders from the storage,
+// data objects of the deleted folders are moved to the root.
+func (r *Repository) DeleteFolder(ctx context.Context, id int) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteFolder: couldn't read user id from the context %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteFolder: delete folder failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteFolder: couldn't get rows affected %w", err)
+	}
+	if rowsCount == 0 {
+		return fmt.Errorf("DeleteFolder: nothing to delete, %w", errs.ErrFolderNotFound)
+	}
+	return nil
+}
+
+// SetDataFolder moves data object into the folder, zero folder identifier
+// moves data object to the root.
+func (r *Repository) SetDataFolder(ctx context.Context, dType string, name string, folderID int) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("SetDataFolder: couldn't read user id from the context %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `UPDATE data SET folder_id = $1 
+	WHERE user_id = $2 AND data_type = $3 AND name = $4`, nullID(folderID), userID, dType, name)
+	if err != nil {
+		return fmt.Errorf("SetDataFolder: update data failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetDataFolder: couldn't get rows affected %w", err)
+	}
+	if rowsCount == 0 {
+		return fmt.Errorf("SetDataFolder: nothing to move, %w", errs.ErrDataNotFound)
+	}
+	return nil
+}
+
+// AddDataTag adds the tag to data object, the existing tag is kept.
+func (r *Repository) AddDataTag(ctx context.Context, dType string, name string, tag string) error {
+	id, err := r.getDataID(ctx, dType, name)
+	if err != nil {
+		return fmt.Errorf("AddDataTag: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO data_tags (data_id, tag) VALUES ($1, $2) 
+	ON CONFLICT (data_id, tag) DO NOTHING`, id, tag)
+	if err != nil {
+		return fmt.Errorf("AddDataTag: insert tag failed %w", err)
+	}
+	return nil
+}
+
+// RemoveDataTag removes the tag from data object.
+func (r *Repository) RemoveDataTag(ctx context.Context, dType string, name string, tag string) error {
+	id, err := r.getDataID(ctx, dType, name)
+	if err != nil {
+		return fmt.Errorf("RemoveDataTag: %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM data_tags WHERE data_id = $1 AND tag = $2`, id, tag)
+	if err != nil {
+		return fmt.Errorf("RemoveDataTag: delete tag failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RemoveDataTag: couldn't get rows affected %w", err)
+	}
+	if rowsCount == 0 {
+		return fmt.Errorf("RemoveDataTag: nothing to remove, %w", errs.ErrDataNotFound)
+	}
+	return nil
+}
+
+// getDataID returns the identifier of the user's data object.
+func (r *Repository) getDataID(ctx context.Context, dType string, name string) (int, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getDataID: couldn't read user id from the context %w", err)
+	}
+
+	var id int
+	err = r.db.QueryRowContext(ctx, `SELECT id FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`,
+		userID, dType, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("getDataID: %w", errs.ErrDataNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getDataID: scan row failed %w", err)
+	}
+	return id, nil
+}
+
+// getTags returns the tags of the user's data objects by the keys of data objects.
+func (r *Repository) getTags(ctx context.Context, userID int) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT d.data_type, d.name, t.tag FROM data_tags t 
+	JOIN data d ON d.id = t.data_id WHERE d.user_id = $1 ORDER BY t.tag`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getTags: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var dType, name, tag string
+		err = rows.Scan(&dType, &name, &tag)
+		if err != nil {
+			return nil, fmt.Errorf("getTags: scan row failed %w", err)
+		}
+		tags[dType+"/"+name] = append(tags[dType+"/"+name], tag)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("getTags: rows.Err %w", err)
+	}
+	return tags, nil
+}
+
+// nullID returns NULL for zero identifier of the folder, which means the root.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,753 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+)
+
+// objectKey identifies data object of the user.
+type objectKey struct {
+	userID int
+	dType  string
+	name   string
+}
+
+// memoryObject contains the current state of data object, all its versions,
+// the folder and the tags of data object.
+type memoryObject struct {
+	current  *data.Data
+	versions []*data.Data
+	folderID int
+	tags     []string
+}
+
+// item returns information about the current version of data object for listing.
+func (obj *memoryObject) item() *data.Item {
+	return &data.Item{
+		Name:      obj.current.Name,
+		Type:      obj.current.Type,
+		Metadata:  bytes.Clone(obj.current.Metadata),
+		CreatedAt: obj.current.CreatedAt,
+		FolderID:  obj.folderID,
+		Tags:      slices.Clone(obj.tags),
+		File:      obj.current.File,
+	}
+}
+
+// memoryUpload contains the state of the upload and its received chunks.
+type memoryUpload struct {
+	upload *data.Upload
+	chunks [][]byte
+}
+
+// MemoryRepository contains data objects, their versions, tombstones, uploads
+// and folders kept in memory, they are lost when the server is stopped.
+type MemoryRepository struct {
+	mu           sync.Mutex
+	lastID       int
+	lastFolderID int
+	objects      map[objectKey]*memoryObject
+	tombstones   map[objectKey]*data.Change
+	seqs         map[int]int64
+	uploads      map[string]*memoryUpload
+	folders      map[int]*data.Folder
+}
+
+// NewMemoryDataRepository returns new repository object in memory.
+func NewMemoryDataRepository(ctx context.Context) *MemoryRepository {
+	return &MemoryRepository{
+		objects:    make(map[objectKey]*memoryObject),
+		tombstones: make(map[objectKey]*data.Change),
+		seqs:       make(map[int]int64),
+		uploads:    make(map[string]*memoryUpload),
+		folders:    make(map[int]*data.Folder),
+	}
+}
+
+// GetDataByName gets data by name from the storage and returns data object.
+func (r *MemoryRepository) GetDataByName(ctx context.Context, dType string, name string) (*data.Data, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataByName: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{userID, dType, name}]
+	if !ok {
+		return nil, fmt.Errorf("GetDataByName: %w", errs.ErrDataNotFound)
+	}
+	return copyData(obj.current), nil
+}
+
+// GetDataList gets information about all the user's data from the storage
+// and returns it. Data is filtered by type, folder and tag, if they are specified.
+func (r *MemoryRepository) GetDataList(ctx context.Context, filter data.Filter) ([]*data.Item, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataList: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := make([]*data.Item, 0)
+	for key, obj := range r.objects {
+		if key.userID != userID || (filter.Type != "" && key.dType != filter.Type) ||
+			(filter.FolderID != 0 && obj.folderID != filter.FolderID) ||
+			(filter.Tag != "" && !slices.Contains(obj.tags, filter.Tag)) {
+			continue
+		}
+		items = append(items, obj.item())
+	}
+	sortItems(items)
+
+	return items, nil
+}
+
+// SearchData returns information about the user's data objects matching the query.
+func (r *MemoryRepository) SearchData(ctx context.Context, q *data.Query) ([]*data.Item, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SearchData: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := make([]*data.Item, 0)
+	for key, obj := range r.objects {
+		if key.userID != userID || !matchQuery(obj.current, q) {
+			continue
+		}
+		items = append(items, obj.item())
+	}
+	sortItems(items)
+
+	return items, nil
+}
+
+// CreateData saves new data object into the storage.
+func (r *MemoryRepository) CreateData(ctx context.Context, d *data.Data) error {
+	err := readContent(d)
+	if err != nil {
+		return fmt.Errorf("CreateData: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := objectKey{d.UserID, d.Type, d.Name}
+	if _, ok := r.objects[key]; ok {
+		return fmt.Errorf("CreateData: %w", errs.ErrDataAlreadyUpload)
+	}
+
+	r.lastID++
+	d.ID = r.lastID
+	d.Version = 1
+	d.Seq = r.nextSeq(d.UserID)
+
+	current := copyData(d)
+	current.CreatedAt = time.Now()
+	r.objects[key] = &memoryObject{
+		current:  current,
+		versions: []*data.Data{newVersion(current)},
+	}
+	delete(r.tombstones, key)
+
+	return nil
+}
+
+// UpdateData updates user data in storage and saves the new data version.
+// If version is not zero, data is updated only if its current version equals to it.
+func (r *MemoryRepository) UpdateData(ctx context.Context, d *data.Data, version int) error {
+	err := readContent(d)
+	if err != nil {
+		return fmt.Errorf("UpdateData: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{d.UserID, d.Type, d.Name}]
+	if !ok {
+		return fmt.Errorf("UpdateData: nothing to update, %w", errs.ErrDataNotFound)
+	}
+	if version != 0 && obj.current.Version != version {
+		return fmt.Errorf("UpdateData: nothing to update, %w", errs.ErrDataVersionDiffer)
+	}
+
+	d.ID = obj.current.ID
+	d.Version = obj.current.Version + 1
+	d.Seq = r.nextSeq(d.UserID)
+
+	obj.current.Data = bytes.Clone(d.Data)
+	obj.current.Metadata = bytes.Clone(d.Metadata)
+	obj.current.File = d.File
+	obj.current.Version = d.Version
+	obj.current.Seq = d.Seq
+	obj.current.Device = d.Device
+	obj.versions = append(obj.versions, newVersion(obj.current))
+
+	return nil
+}
+
+// DeleteDataByName deletes requested data by it's name and saves the tombstone
+// for synchronization. If version is not zero, data is deleted only if its current
+// version equals to it.
+func (r *MemoryRepository) DeleteDataByName(ctx context.Context, dType string, name string, version int) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteDataByName: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := objectKey{userID, dType, name}
+	obj, ok := r.objects[key]
+	if !ok {
+		return fmt.Errorf("DeleteDataByName: nothing to delete, %w", errs.ErrDataNotFound)
+	}
+	if version != 0 && obj.current.Version != version {
+		return fmt.Errorf("DeleteDataByName: nothing to delete, %w", errs.ErrDataVersionDiffer)
+	}
+
+	delete(r.objects, key)
+	r.tombstones[key] = &data.Change{
+		Seq:       r.nextSeq(userID),
+		Name:      name,
+		Type:      dType,
+		Deleted:   true,
+		CreatedAt: time.Now(),
+	}
+
+	return nil
+}
+
+// GetDataVersions gets information about all the versions of requested data
+// from the storage, returns them from the newest to the oldest.
+func (r *MemoryRepository) GetDataVersions(ctx context.Context, dType string, name string) ([]*data.Revision, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersions: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{userID, dType, name}]
+	if !ok {
+		return nil, fmt.Errorf("GetDataVersions: %w", errs.ErrDataNotFound)
+	}
+
+	revisions := make([]*data.Revision, 0, len(obj.versions))
+	for i := len(obj.versions) - 1; i >= 0; i-- {
+		v := obj.versions[i]
+		revisions = append(revisions, &data.Revision{
+			Version:   v.Version,
+			Device:    v.Device,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+
+	return revisions, nil
+}
+
+// GetDataVersion gets the requested version of data from the storage
+// and returns data object.
+func (r *MemoryRepository) GetDataVersion(ctx context.Context, dType string, name string, version int) (*data.Data, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersion: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, err := r.findVersion(objectKey{userID, dType, name}, version)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersion: %w", err)
+	}
+	return copyData(v), nil
+}
+
+// RestoreDataVersion sets the value of the requested data version as the current
+// value of data and saves it as the new data version. If expected is not zero,
+// data is restored only if its current version equals to it.
+func (r *MemoryRepository) RestoreDataVersion(ctx context.Context, d *data.Data, version int, expected int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := objectKey{d.UserID, d.Type, d.Name}
+	v, err := r.findVersion(key, version)
+	if err != nil {
+		return fmt.Errorf("RestoreDataVersion: nothing to restore, %w", err)
+	}
+	obj := r.objects[key]
+	if expected != 0 && obj.current.Version != expected {
+		return fmt.Errorf("RestoreDataVersion: nothing to restore, %w", errs.ErrDataVersionDiffer)
+	}
+
+	d.ID = obj.current.ID
+	d.Version = obj.current.Version + 1
+	d.Seq = r.nextSeq(d.UserID)
+	d.Data = bytes.Clone(v.Data)
+	d.Metadata = bytes.Clone(v.Metadata)
+	d.File = v.File
+
+	obj.current.Data = bytes.Clone(v.Data)
+	obj.current.Metadata = bytes.Clone(v.Metadata)
+	obj.current.File = v.File
+	obj.current.Version = d.Version
+	obj.current.Seq = d.Seq
+	obj.current.Device = d.Device
+	obj.versions = append(obj.versions, newVersion(obj.current))
+
+	return nil
+}
+
+// GetChanges gets the user's data created, updated or deleted after the change
+// with since sequence number, returns not more than limit changes ordered by sequence.
+// The values of binary data are not returned, they are requested separately.
+func (r *MemoryRepository) GetChanges(ctx context.Context, since int64, limit int) ([]*data.Change, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetChanges: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := make([]*data.Change, 0)
+	for key, obj := range r.objects {
+		if key.userID != userID || obj.current.Seq <= since {
+			continue
+		}
+		ch := &data.Change{
+			Seq:       obj.current.Seq,
+			Name:      obj.current.Name,
+			Type:      obj.current.Type,
+			Version:   obj.current.Version,
+			Metadata:  bytes.Clone(obj.current.Metadata),
+			CreatedAt: obj.current.CreatedAt,
+			File:      obj.current.File,
+		}
+		if ch.Type != "binary" {
+			ch.Data = bytes.Clone(obj.current.Data)
+		}
+		changes = append(changes, ch)
+	}
+	for key, ts := range r.tombstones {
+		if key.userID != userID || ts.Seq <= since {
+			continue
+		}
+		ch := *ts
+		changes = append(changes, &ch)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Seq < changes[j].Seq
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes, nil
+}
+
+// CreateUpload saves new upload into the storage,
+// the expired uploads of all the users are deleted.
+func (r *MemoryRepository) CreateUpload(ctx context.Context, u *data.Upload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, up := range r.uploads {
+		if up.upload.ExpiresAt.Before(now) {
+			delete(r.uploads, id)
+		}
+	}
+	if _, ok := r.uploads[u.ID]; ok {
+		return fmt.Errorf("CreateUpload: upload %s already exists", u.ID)
+	}
+
+	stored := *u
+	stored.Metadata = bytes.Clone(u.Metadata)
+	stored.Offset = 0
+	r.uploads[u.ID] = &memoryUpload{
+		upload: &stored,
+		chunks: make([][]byte, 0),
+	}
+
+	return nil
+}
+
+// GetUpload gets the user's upload, which is not expired, from the storage.
+func (r *MemoryRepository) GetUpload(ctx context.Context, id string) (*data.Upload, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpload: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	up, err := r.findUpload(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpload: %w", err)
+	}
+	u := *up.upload
+	return &u, nil
+}
+
+// AppendUploadChunk saves the chunk of the user's upload, if the offset equals to
+// the size of received chunks, and returns the upload with the new offset.
+func (r *MemoryRepository) AppendUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (*data.Upload, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	up, err := r.findUpload(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", err)
+	}
+	if offset != up.upload.Offset {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", errs.ErrUploadOffset)
+	}
+	if up.upload.Offset+int64(len(chunk)) > up.upload.Size {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", errs.ErrDataTooLarge)
+	}
+
+	up.chunks = append(up.chunks, bytes.Clone(chunk))
+	up.upload.Offset += int64(len(chunk))
+
+	u := *up.upload
+	return &u, nil
+}
+
+// GetUploadContent gets all the received chunks of the upload
+// and returns them joined in order.
+func (r *MemoryRepository) GetUploadContent(ctx context.Context, u *data.Upload) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	up, ok := r.uploads[u.ID]
+	if !ok {
+		return nil, fmt.Errorf("GetUploadContent: %w", errs.ErrUploadNotFound)
+	}
+	return bytes.Join(up.chunks, nil), nil
+}
+
+// DeleteUpload deletes the user's upload with all its chunks from the storage.
+func (r *MemoryRepository) DeleteUpload(ctx context.Context, id string) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteUpload: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	up, ok := r.uploads[id]
+	if !ok || up.upload.UserID != userID {
+		return fmt.Errorf("DeleteUpload: %w", errs.ErrUploadNotFound)
+	}
+	delete(r.uploads, id)
+
+	return nil
+}
+
+// DeleteUserData deletes all the data objects, tombstones and uploads of the user,
+// it is called by the user repository in memory after the user is deleted.
+func (r *MemoryRepository) DeleteUserData(userID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.objects {
+		if key.userID == userID {
+			delete(r.objects, key)
+		}
+	}
+	for key := range r.tombstones {
+		if key.userID == userID {
+			delete(r.tombstones, key)
+		}
+	}
+	for id, up := range r.uploads {
+		if up.upload.UserID == userID {
+			delete(r.uploads, id)
+		}
+	}
+	for id, f := range r.folders {
+		if f.UserID == userID {
+			delete(r.folders, id)
+		}
+	}
+	delete(r.seqs, userID)
+}
+
+// GetFolders gets all the user's folders from the storage ordered by identifier,
+// so the parent folder goes before its subfolders.
+func (r *MemoryRepository) GetFolders(ctx context.Context) ([]*data.Folder, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetFolders: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	folders := make([]*data.Folder, 0)
+	for _, f := range r.folders {
+		if f.UserID == userID {
+			c := *f
+			folders = append(folders, &c)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].ID < folders[j].ID
+	})
+	return folders, nil
+}
+
+// CreateFolder saves new folder into the storage.
+func (r *MemoryRepository) CreateFolder(ctx context.Context, f *data.Folder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.folderNameBusy(f) {
+		return fmt.Errorf("CreateFolder: %w", errs.ErrFolderNameBusy)
+	}
+
+	r.lastFolderID++
+	f.ID = r.lastFolderID
+	f.CreatedAt = time.Now()
+	c := *f
+	r.folders[f.ID] = &c
+	return nil
+}
+
+// UpdateFolder renames the folder and moves it into the parent folder.
+func (r *MemoryRepository) UpdateFolder(ctx context.Context, f *data.Folder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.folders[f.ID]
+	if !ok || stored.UserID != f.UserID {
+		return fmt.Errorf("UpdateFolder: nothing to update, %w", errs.ErrFolderNotFound)
+	}
+	if r.folderNameBusy(f) {
+		return fmt.Errorf("UpdateFolder: %w", errs.ErrFolderNameBusy)
+	}
+	stored.Name = f.Name
+	stored.ParentID = f.ParentID
+	return nil
+}
+
+// DeleteFolder deletes the user's folder with its subfolders from the storage,
+// data objects of the deleted folders are moved to the root.
+func (r *MemoryRepository) DeleteFolder(ctx context.Context, id int) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteFolder: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.folders[id]
+	if !ok || f.UserID != userID {
+		return fmt.Errorf("DeleteFolder: nothing to delete, %w", errs.ErrFolderNotFound)
+	}
+
+	deleted := map[int]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, f := range r.folders {
+			if !deleted[f.ID] && deleted[f.ParentID] {
+				deleted[f.ID] = true
+				changed = true
+			}
+		}
+	}
+	for id := range deleted {
+		delete(r.folders, id)
+	}
+	for key, obj := range r.objects {
+		if key.userID == userID && deleted[obj.folderID] {
+			obj.folderID = 0
+		}
+	}
+	return nil
+}
+
+// SetDataFolder moves data object into the folder, zero folder identifier
+// moves data object to the root.
+func (r *MemoryRepository) SetDataFolder(ctx context.Context, dType string, name string, folderID int) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("SetDataFolder: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{userID, dType, name}]
+	if !ok {
+		return fmt.Errorf("SetDataFolder: nothing to move, %w", errs.ErrDataNotFound)
+	}
+	if f, ok := r.folders[folderID]; folderID != 0 && (!ok || f.UserID != userID) {
+		return fmt.Errorf("SetDataFolder: %w", errs.ErrFolderNotFound)
+	}
+	obj.folderID = folderID
+	return nil
+}
+
+// AddDataTag adds the tag to data object, the existing tag is kept.
+func (r *MemoryRepository) AddDataTag(ctx context.Context, dType string, name string, tag string) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("AddDataTag: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{userID, dType, name}]
+	if !ok {
+		return fmt.Errorf("AddDataTag: %w", errs.ErrDataNotFound)
+	}
+	if !slices.Contains(obj.tags, tag) {
+		obj.tags = append(obj.tags, tag)
+		slices.Sort(obj.tags)
+	}
+	return nil
+}
+
+// RemoveDataTag removes the tag from data object.
+func (r *MemoryRepository) RemoveDataTag(ctx context.Context, dType string, name string, tag string) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("RemoveDataTag: couldn't read user id from the context %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	obj, ok := r.objects[objectKey{userID, dType, name}]
+	if !ok {
+		return fmt.Errorf("RemoveDataTag: %w", errs.ErrDataNotFound)
+	}
+	i := slices.Index(obj.tags, tag)
+	if i < 0 {
+		return fmt.Errorf("RemoveDataTag: nothing to remove, %w", errs.ErrDataNotFound)
+	}
+	obj.tags = slices.Delete(obj.tags, i, i+1)
+	return nil
+}
+
+// folderNameBusy checks whether the parent folder of the user already contains
+// another folder with the same name, the mutex must be locked by the caller.
+func (r *MemoryRepository) folderNameBusy(f *data.Folder) bool {
+	for _, stored := range r.folders {
+		if stored.ID != f.ID && stored.UserID == f.UserID &&
+			stored.ParentID == f.ParentID && stored.Name == f.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// nextSeq increments and returns the user's change sequence number,
+// the mutex must be locked by the caller.
+func (r *MemoryRepository) nextSeq(userID int) int64 {
+	r.seqs[userID]++
+	return r.seqs[userID]
+}
+
+// findVersion returns the requested version of data object,
+// the mutex must be locked by the caller.
+func (r *MemoryRepository) findVersion(key objectKey, version int) (*data.Data, error) {
+	obj, ok := r.objects[key]
+	if !ok {
+		return nil, errs.ErrDataNotFound
+	}
+	for _, v := range obj.versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errs.ErrDataNotFound
+}
+
+// findUpload returns the user's upload, which is not expired,
+// the mutex must be locked by the caller.
+func (r *MemoryRepository) findUpload(userID int, id string) (*memoryUpload, error) {
+	up, ok := r.uploads[id]
+	if !ok || up.upload.UserID != userID || !up.upload.ExpiresAt.After(time.Now()) {
+		return nil, errs.ErrUploadNotFound
+	}
+	return up, nil
+}
+
+// newVersion returns the immutable version of the current state of data object.
+func newVersion(current *data.Data) *data.Data {
+	v := copyData(current)
+	v.CreatedAt = time.Now()
+	return v
+}
+
+// copyData returns the copy of data object, so the stored object
+// is not changed by the caller.
+func copyData(d *data.Data) *data.Data {
+	c := *d
+	c.Data = bytes.Clone(d.Data)
+	c.Metadata = bytes.Clone(d.Metadata)
+	return &c
+}
+
+// sortItems sorts information about data objects by type and name.
+func sortItems(items []*data.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Type != items[j].Type {
+			return items[i].Type < items[j].Type
+		}
+		return items[i].Name < items[j].Name
+	})
+}
+
+// matchQuery checks whether data object matches the search query. Only string
+// values of metadata object are matched, as by containment in PostgreSQL.
+func matchQuery(d *data.Data, q *data.Query) bool {
+	if len(q.Types) > 0 && !slices.Contains(q.Types, d.Type) {
+		return false
+	}
+	name := strings.ToLower(d.Name)
+	for _, n := range q.Names {
+		if !strings.Contains(name, n) {
+			return false
+		}
+	}
+	for _, p := range q.Prefixes {
+		if !strings.HasPrefix(name, p) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,13 +3,16 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"time"
 
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/blob"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
 	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
 	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 	"github.com/pavlegich/gophkeeper/internal/server/utils"
@@ -17,29 +20,51 @@ import (
 
 // Repository contains storage objects.
 type Repository struct {
-	db *sql.DB
+	db        *sql.DB
+	blobs     blob.Store
+	threshold int64
 }
 
-// NewDataRepository returns new repository object.
-func NewDataRepository(ctx context.Context, db *sql.DB) *Repository {
-	return &Repository{
+// Option describes the function for setting repository options.
+type Option func(r *Repository)
+
+// WithBlobStore sets the storage for binary values, which are not smaller
+// than the threshold, the smaller values are kept in the database.
+func WithBlobStore(store blob.Store, threshold int64) Option {
+	return func(r *Repository) {
+		r.blobs = store
+		r.threshold = threshold
+	}
+}
+
+// NewDataRepository returns new repository object in PostgreSQL or SQLite database.
+func NewDataRepository(ctx context.Context, db *sql.DB, opts ...Option) *Repository {
+	r := &Repository{
 		db: db,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-// GetDataByName gets data by name from the storage and returns data object.
+// GetDataByName gets data by name from the storage and returns data object,
+// the value in the blob storage is streamed by the content of data object.
 func (r *Repository) GetDataByName(ctx context.Context, dType string, name string) (*data.Data, error) {
 	userID, err := utils.GetUserIDFromContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("GetDataByName: couldn't read user id from the context %w", err)
 	}
 
-	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, name, data_type, data, created_at, metadata 
-	FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`, userID, dType, name)
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, name, data_type, data, blob_key, created_at, metadata, version, 
+	file_name, content_type, size, checksum FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`,
+		userID, dType, name)
 
 	var storedData data.Data
+	var blobKey sql.NullString
 	err = row.Scan(&storedData.ID, &storedData.UserID, &storedData.Name, &storedData.Type,
-		&storedData.Data, &storedData.CreatedAt, &storedData.Metadata)
+		&storedData.Data, &blobKey, &storedData.CreatedAt, &storedData.Metadata, &storedData.Version,
+		&storedData.FileName, &storedData.ContentType, &storedData.Size, &storedData.Checksum)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("GetDataByName: scan row failed %w", errs.ErrDataNotFound)
 	}
@@ -52,17 +77,115 @@ func (r *Repository) GetDataByName(ctx context.Context, dType string, name strin
 		return nil, fmt.Errorf("GetDataByName: row.Err %w", err)
 	}
 
+	err = r.openValue(ctx, &storedData, blobKey)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataByName: %w", err)
+	}
+
 	return &storedData, nil
 }
 
+// GetDataList gets information about all the user's data from the storage
+// and returns it. Data is filtered by type, folder and tag, if they are specified.
+func (r *Repository) GetDataList(ctx context.Context, filter data.Filter) ([]*data.Item, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataList: couldn't read user id from the context %w", err)
+	}
+
+	query := `SELECT name, data_type, metadata, created_at, folder_id, file_name, content_type, size, checksum 
+	FROM data WHERE user_id = $1`
+	args := []any{userID}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(` AND data_type = $%d`, len(args))
+	}
+	if filter.FolderID != 0 {
+		args = append(args, filter.FolderID)
+		query += fmt.Sprintf(` AND folder_id = $%d`, len(args))
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		query += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM data_tags t WHERE t.data_id = data.id AND t.tag = $%d)`,
+			len(args))
+	}
+	query += ` ORDER BY data_type, name`
+
+	items, err := r.queryItems(ctx, userID, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataList: %w", err)
+	}
+
+	return items, nil
+}
+
+// queryItems returns information about the user's data objects selected by the query
+// with their folders and tags.
+func (r *Repository) queryItems(ctx context.Context, userID int, query string, args ...any) ([]*data.Item, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("queryItems: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*data.Item, 0)
+	for rows.Next() {
+		var item data.Item
+		var metadata []byte
+		var folderID sql.NullInt64
+		err = rows.Scan(&item.Name, &item.Type, &metadata, &item.CreatedAt, &folderID,
+			&item.FileName, &item.ContentType, &item.Size, &item.Checksum)
+		if err != nil {
+			return nil, fmt.Errorf("queryItems: scan row failed %w", err)
+		}
+		item.Metadata = metadata
+		item.FolderID = int(folderID.Int64)
+		items = append(items, &item)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("queryItems: rows.Err %w", err)
+	}
+
+	tags, err := r.getTags(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("queryItems: %w", err)
+	}
+	for _, item := range items {
+		item.Tags = tags[item.Type+"/"+item.Name]
+	}
+
+	return items, nil
+}
+
 // CreateData saves new data object into the storage.
 func (r *Repository) CreateData(ctx context.Context, d *data.Data) error {
-	tx, err := r.db.Begin()
+	staged, err := r.stageValue(ctx, d)
+	if err != nil {
+		return fmt.Errorf("CreateData: %w", err)
+	}
+	if staged != nil {
+		defer staged.Discard()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("CreateData: begin transaction failed %w", err)
 	}
 	defer tx.Rollback()
 
+	value, blobKey, err := r.putValue(ctx, tx, d, staged)
+	if err != nil {
+		return fmt.Errorf("CreateData: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			r.discardBlob(ctx, tx, blobKey)
+		}
+	}()
+
 	row := tx.QueryRowContext(ctx, `SELECT id FROM data WHERE user_id = $1 AND name = $2 
 	AND data_type = $3`, d.UserID, d.Name, d.Type)
 	var id int
@@ -73,53 +196,130 @@ func (r *Repository) CreateData(ctx context.Context, d *data.Data) error {
 		return fmt.Errorf("CreateData: scan data row with id failed %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, `INSERT INTO data (user_id, name, data_type, data, metadata) 
-	VALUES ($1, $2, $3, $4, $5)`, d.UserID, d.Name, d.Type, d.Data, d.Metadata)
+	d.Seq, err = nextSeq(ctx, tx, d.UserID)
+	if err != nil {
+		return fmt.Errorf("CreateData: get next change sequence failed %w", err)
+	}
+
+	row = tx.QueryRowContext(ctx, `INSERT INTO data (user_id, name, data_type, data, blob_key, metadata, seq, 
+	file_name, content_type, size, checksum) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
+	RETURNING id, version`, d.UserID, d.Name, d.Type, value, blobKey, d.Metadata, d.Seq,
+		d.FileName, d.ContentType, d.Size, d.Checksum)
 
+	err = row.Scan(&d.ID, &d.Version)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		if database.IsUniqueViolation(err) {
 			return fmt.Errorf("CreateData: %w", errs.ErrDataAlreadyUpload)
 		}
 		return fmt.Errorf("CreateData: insert data failed %w", err)
 	}
 
+	err = insertVersion(ctx, tx, d, value, blobKey)
+	if err != nil {
+		return fmt.Errorf("CreateData: insert data version failed %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM data_tombstones WHERE user_id = $1 AND data_type = $2 AND name = $3`,
+		d.UserID, d.Type, d.Name)
+	if err != nil {
+		return fmt.Errorf("CreateData: delete tombstone failed %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("CreateData: commit transaction failed %w", err)
 	}
+	committed = true
 
 	return nil
 }
 
-// UpdateData updates user data in storage.
-func (r *Repository) UpdateData(ctx context.Context, d *data.Data) error {
-	res, err := r.db.ExecContext(ctx, `UPDATE data SET data = $1, metadata = $2 
-	WHERE user_id = $3 AND name = $4 AND data_type = $5`,
-		d.Data, d.Metadata, d.UserID, d.Name, d.Type)
+// UpdateData updates user data in storage and saves the new data version.
+// If version is not zero, data is updated only if its current version equals to it.
+func (r *Repository) UpdateData(ctx context.Context, d *data.Data, version int) error {
+	staged, err := r.stageValue(ctx, d)
+	if err != nil {
+		return fmt.Errorf("UpdateData: %w", err)
+	}
+	if staged != nil {
+		defer staged.Discard()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpdateData: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	value, blobKey, err := r.putValue(ctx, tx, d, staged)
+	if err != nil {
+		return fmt.Errorf("UpdateData: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			r.discardBlob(ctx, tx, blobKey)
+		}
+	}()
+
+	d.Seq, err = nextSeq(ctx, tx, d.UserID)
+	if err != nil {
+		return fmt.Errorf("UpdateData: get next change sequence failed %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, `UPDATE data SET data = $1, blob_key = $2, metadata = $3, version = version + 1, 
+	seq = $4, file_name = $5, content_type = $6, size = $7, checksum = $8 
+	WHERE user_id = $9 AND name = $10 AND data_type = $11 AND ($12 = 0 OR version = $12) RETURNING id, version`,
+		value, blobKey, d.Metadata, d.Seq, d.FileName, d.ContentType, d.Size, d.Checksum,
+		d.UserID, d.Name, d.Type, version)
+	err = row.Scan(&d.ID, &d.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("UpdateData: nothing to update, %w", checkExists(ctx, tx, d.UserID, d.Type, d.Name))
+	}
 	if err != nil {
 		return fmt.Errorf("UpdateData: update table failed %w", err)
 	}
 
-	rowsCount, err := res.RowsAffected()
+	err = insertVersion(ctx, tx, d, value, blobKey)
 	if err != nil {
-		return fmt.Errorf("UpdateData: couldn't get rows affected %w", err)
+		return fmt.Errorf("UpdateData: insert data version failed %w", err)
 	}
-	if rowsCount == 0 {
-		return fmt.Errorf("UpdateData: nothing to update, %w", errs.ErrDataNotFound)
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("UpdateData: commit transaction failed %w", err)
 	}
+	committed = true
 
 	return nil
 }
 
-// DeleteDataByName deletes requested data by it's name.
-func (r *Repository) DeleteDataByName(ctx context.Context, dType string, name string) error {
+// DeleteDataByName deletes requested data by it's name and saves the tombstone
+// for synchronization. If version is not zero, data is deleted only if its current
+// version equals to it. Binary values of data and its versions are deleted from
+// the blob storage, if they are not used anymore.
+func (r *Repository) DeleteDataByName(ctx context.Context, dType string, name string, version int) error {
 	userID, err := utils.GetUserIDFromContext(ctx)
 	if err != nil {
 		return fmt.Errorf("DeleteDataByName: couldn't read user id from the context %w", err)
 	}
 
-	res, err := r.db.ExecContext(ctx, `DELETE FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`,
-		userID, dType, name)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteDataByName: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	seq, err := nextSeq(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteDataByName: get next change sequence failed %w", err)
+	}
+
+	blobKeys, err := getBlobKeys(ctx, tx, userID, dType, name)
+	if err != nil {
+		return fmt.Errorf("DeleteDataByName: get blob keys failed %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3 
+	AND ($4 = 0 OR version = $4)`, userID, dType, name, version)
 	if err != nil {
 		return fmt.Errorf("DeleteDataByName: couldn't delete data from the storage %w", err)
 	}
@@ -129,8 +329,594 @@ func (r *Repository) DeleteDataByName(ctx context.Context, dType string, name st
 		return fmt.Errorf("DeleteDataByName: couldn't get rows affected %w", err)
 	}
 	if rowsCount == 0 {
-		return fmt.Errorf("DeleteDataByName: nothing to delete, %w", errs.ErrDataNotFound)
+		return fmt.Errorf("DeleteDataByName: nothing to delete, %w", checkExists(ctx, tx, userID, dType, name))
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO data_tombstones (user_id, name, data_type, seq) 
+	VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, data_type, name) 
+	DO UPDATE SET seq = EXCLUDED.seq, deleted_at = CURRENT_TIMESTAMP`, userID, name, dType, seq)
+	if err != nil {
+		return fmt.Errorf("DeleteDataByName: insert tombstone failed %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DeleteDataByName: commit transaction failed %w", err)
+	}
+
+	// Data is already deleted, the blob which failed to be deleted is just left in the storage
+	_ = r.DeleteBlobs(ctx, blobKeys)
+
+	return nil
+}
+
+// GetDataVersions gets information about all the versions of requested data
+// from the storage, returns them from the newest to the oldest.
+func (r *Repository) GetDataVersions(ctx context.Context, dType string, name string) ([]*data.Revision, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersions: couldn't read user id from the context %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT v.version, v.device, v.created_at 
+	FROM data_versions v JOIN data d ON d.id = v.data_id 
+	WHERE d.user_id = $1 AND d.data_type = $2 AND d.name = $3 ORDER BY v.version DESC`,
+		userID, dType, name)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersions: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]*data.Revision, 0)
+	for rows.Next() {
+		var rev data.Revision
+		err = rows.Scan(&rev.Version, &rev.Device, &rev.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("GetDataVersions: scan row failed %w", err)
+		}
+		revisions = append(revisions, &rev)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersions: rows.Err %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("GetDataVersions: %w", errs.ErrDataNotFound)
+	}
+
+	return revisions, nil
+}
+
+// GetDataVersion gets the requested version of data from the storage
+// and returns data object, the value in the blob storage is streamed
+// by the content of data object.
+func (r *Repository) GetDataVersion(ctx context.Context, dType string, name string, version int) (*data.Data, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersion: couldn't read user id from the context %w", err)
+	}
+
+	row := r.db.QueryRowContext(ctx, `SELECT d.id, d.user_id, d.name, d.data_type, v.data, v.blob_key, v.metadata, 
+	v.version, v.device, v.created_at, v.file_name, v.content_type, v.size, v.checksum 
+	FROM data_versions v JOIN data d ON d.id = v.data_id 
+	WHERE d.user_id = $1 AND d.data_type = $2 AND d.name = $3 AND v.version = $4`,
+		userID, dType, name, version)
+
+	var storedData data.Data
+	var blobKey sql.NullString
+	err = row.Scan(&storedData.ID, &storedData.UserID, &storedData.Name, &storedData.Type,
+		&storedData.Data, &blobKey, &storedData.Metadata, &storedData.Version, &storedData.Device, &storedData.CreatedAt,
+		&storedData.FileName, &storedData.ContentType, &storedData.Size, &storedData.Checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("GetDataVersion: scan row failed %w", errs.ErrDataNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersion: scan row failed %w", err)
+	}
+
+	err = r.openValue(ctx, &storedData, blobKey)
+	if err != nil {
+		return nil, fmt.Errorf("GetDataVersion: %w", err)
+	}
+
+	return &storedData, nil
+}
+
+// RestoreDataVersion sets the value of the requested data version as the current
+// value of data and saves it as the new data version. If expected is not zero,
+// data is restored only if its current version equals to it.
+func (r *Repository) RestoreDataVersion(ctx context.Context, d *data.Data, version int, expected int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("RestoreDataVersion: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	d.Seq, err = nextSeq(ctx, tx, d.UserID)
+	if err != nil {
+		return fmt.Errorf("RestoreDataVersion: get next change sequence failed %w", err)
+	}
+
+	// The binary value in the blob storage is shared by the versions, so only its key is copied
+	row := tx.QueryRowContext(ctx, `UPDATE data SET data = v.data, blob_key = v.blob_key, metadata = v.metadata, 
+	version = data.version + 1, seq = $1, file_name = v.file_name, content_type = v.content_type, size = v.size, 
+	checksum = v.checksum FROM data_versions v WHERE v.data_id = data.id AND v.version = $2 
+	AND data.user_id = $3 AND data.data_type = $4 AND data.name = $5 AND ($6 = 0 OR data.version = $6) 
+	RETURNING data.id, data.version, data.data, data.blob_key, data.metadata, data.file_name, data.content_type, 
+	data.size, data.checksum`, d.Seq, version, d.UserID, d.Type, d.Name, expected)
+	var blobKey sql.NullString
+	err = row.Scan(&d.ID, &d.Version, &d.Data, &blobKey, &d.Metadata,
+		&d.FileName, &d.ContentType, &d.Size, &d.Checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("RestoreDataVersion: nothing to restore, %w",
+			checkVersion(ctx, tx, d.UserID, d.Type, d.Name, expected))
+	}
+	if err != nil {
+		return fmt.Errorf("RestoreDataVersion: update table failed %w", err)
+	}
+
+	err = insertVersion(ctx, tx, d, d.Data, blobKey)
+	if err != nil {
+		return fmt.Errorf("RestoreDataVersion: insert data version failed %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("RestoreDataVersion: commit transaction failed %w", err)
 	}
 
 	return nil
 }
+
+// GetChanges gets the user's data created, updated or deleted after the change
+// with since sequence number, returns not more than limit changes ordered by sequence.
+// The values of binary data are not returned, they are requested separately.
+func (r *Repository) GetChanges(ctx context.Context, since int64, limit int) ([]*data.Change, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetChanges: couldn't read user id from the context %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT seq, name, data_type, false, version, 
+	CASE WHEN data_type = 'binary' THEN NULL ELSE data END, metadata, 
+	created_at, file_name, content_type, size, checksum FROM data WHERE user_id = $1 AND seq > $2 
+	UNION ALL 
+	SELECT seq, name, data_type, true, 0, NULL, NULL, deleted_at, '', '', 0, '' 
+	FROM data_tombstones WHERE user_id = $1 AND seq > $2 
+	ORDER BY 1 LIMIT $3`, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetChanges: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]*data.Change, 0)
+	for rows.Next() {
+		var ch data.Change
+		var metadata []byte
+		err = rows.Scan(&ch.Seq, &ch.Name, &ch.Type, &ch.Deleted, &ch.Version,
+			&ch.Data, &metadata, &ch.CreatedAt, &ch.FileName, &ch.ContentType, &ch.Size, &ch.Checksum)
+		if err != nil {
+			return nil, fmt.Errorf("GetChanges: scan row failed %w", err)
+		}
+		ch.Metadata = metadata
+		changes = append(changes, &ch)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetChanges: rows.Err %w", err)
+	}
+
+	return changes, nil
+}
+
+// CreateUpload saves new upload into the storage,
+// the expired uploads of all the users are deleted.
+func (r *Repository) CreateUpload(ctx context.Context, u *data.Upload) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM uploads WHERE expires_at < $1`, time.Now())
+	if err != nil {
+		return fmt.Errorf("CreateUpload: delete expired uploads failed %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO uploads (id, user_id, name, data_type, metadata, size, 
+	checksum, file_name, content_type, is_update, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		u.ID, u.UserID, u.Name, u.Type, []byte(u.Metadata), u.Size, u.Checksum, u.FileName, u.ContentType,
+		u.Update, u.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("CreateUpload: insert upload failed %w", err)
+	}
+
+	return nil
+}
+
+// GetUpload gets the user's upload, which is not expired, from the storage.
+func (r *Repository) GetUpload(ctx context.Context, id string) (*data.Upload, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpload: couldn't read user id from the context %w", err)
+	}
+
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, name, data_type, metadata, size, received, 
+	checksum, file_name, content_type, is_update, expires_at FROM uploads WHERE id = $1 AND user_id = $2 AND expires_at > $3`,
+		id, userID, time.Now())
+	u, err := scanUpload(row)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpload: %w", err)
+	}
+
+	return u, nil
+}
+
+// AppendUploadChunk saves the chunk of the user's upload, if the offset equals to
+// the size of received chunks, and returns the upload with the new offset.
+func (r *Repository) AppendUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (*data.Upload, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: couldn't read user id from the context %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	// The upload row is updated without changes, so it stays locked and the chunks
+	// of the upload are written in turn in both PostgreSQL and SQLite
+	row := tx.QueryRowContext(ctx, `UPDATE uploads SET received = received 
+	WHERE id = $1 AND user_id = $2 AND expires_at > $3 RETURNING id, user_id, name, data_type, metadata, size, 
+	received, checksum, file_name, content_type, is_update, expires_at`, id, userID, time.Now())
+	u, err := scanUpload(row)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", err)
+	}
+	if offset != u.Offset {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", errs.ErrUploadOffset)
+	}
+	if u.Offset+int64(len(chunk)) > u.Size {
+		return nil, fmt.Errorf("AppendUploadChunk: %w", errs.ErrDataTooLarge)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO upload_chunks (upload_id, chunk_offset, data) VALUES ($1, $2, $3)`,
+		id, offset, chunk)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: insert chunk failed %w", err)
+	}
+
+	u.Offset += int64(len(chunk))
+	_, err = tx.ExecContext(ctx, `UPDATE uploads SET received = $1 WHERE id = $2`, u.Offset, id)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: update received size failed %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("AppendUploadChunk: commit transaction failed %w", err)
+	}
+
+	return u, nil
+}
+
+// GetUploadContent gets all the received chunks of the upload
+// and returns them joined in order.
+func (r *Repository) GetUploadContent(ctx context.Context, u *data.Upload) ([]byte, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT data FROM upload_chunks WHERE upload_id = $1 
+	ORDER BY chunk_offset`, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("GetUploadContent: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	content := make([]byte, 0, u.Offset)
+	for rows.Next() {
+		var chunk []byte
+		err = rows.Scan(&chunk)
+		if err != nil {
+			return nil, fmt.Errorf("GetUploadContent: scan row failed %w", err)
+		}
+		content = append(content, chunk...)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetUploadContent: rows.Err %w", err)
+	}
+
+	return content, nil
+}
+
+// DeleteUpload deletes the user's upload with all its chunks from the storage.
+func (r *Repository) DeleteUpload(ctx context.Context, id string) error {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("DeleteUpload: couldn't read user id from the context %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM uploads WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteUpload: delete upload failed %w", err)
+	}
+
+	rowsCount, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteUpload: couldn't get rows affected %w", err)
+	}
+	if rowsCount == 0 {
+		return fmt.Errorf("DeleteUpload: %w", errs.ErrUploadNotFound)
+	}
+
+	return nil
+}
+
+// nextSeq increments and returns the user's change sequence number. The user row
+// stays locked until the end of transaction, so the changes are committed in order.
+func nextSeq(ctx context.Context, tx *sql.Tx, userID int) (int64, error) {
+	var seq int64
+	row := tx.QueryRowContext(ctx, `UPDATE users SET data_seq = data_seq + 1 WHERE id = $1 RETURNING data_seq`, userID)
+	err := row.Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("nextSeq: update sequence failed %w", err)
+	}
+	return seq, nil
+}
+
+// checkExists is called when data was not changed, it returns ErrDataVersionDiffer
+// if data exists, so it was not changed because of its version, or ErrDataNotFound otherwise.
+func checkExists(ctx context.Context, tx *sql.Tx, userID int, dType string, name string) error {
+	var id int
+	row := tx.QueryRowContext(ctx, `SELECT id FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`,
+		userID, dType, name)
+	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrDataNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("checkExists: scan row failed %w", err)
+	}
+	return errs.ErrDataVersionDiffer
+}
+
+// checkVersion is called when data was not restored, it returns ErrDataVersionDiffer
+// if data exists and its current version differs from the expected one, so it was not
+// restored because of its version, or ErrDataNotFound otherwise.
+func checkVersion(ctx context.Context, tx *sql.Tx, userID int, dType string, name string, expected int) error {
+	if expected == 0 {
+		return errs.ErrDataNotFound
+	}
+	var version int
+	row := tx.QueryRowContext(ctx, `SELECT version FROM data WHERE user_id = $1 AND data_type = $2 AND name = $3`,
+		userID, dType, name)
+	err := row.Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrDataNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("checkVersion: scan row failed %w", err)
+	}
+	if version != expected {
+		return errs.ErrDataVersionDiffer
+	}
+	return errs.ErrDataNotFound
+}
+
+// insertVersion saves the current value of data object, which is kept in the database
+// or in the blob storage with the key, as its new immutable version.
+func insertVersion(ctx context.Context, tx *sql.Tx, d *data.Data, value []byte, blobKey sql.NullString) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO data_versions (data_id, version, data, blob_key, metadata, device, 
+	file_name, content_type, size, checksum) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		d.ID, d.Version, value, blobKey, d.Metadata, d.Device, d.FileName, d.ContentType, d.Size, d.Checksum)
+	if err != nil {
+		return fmt.Errorf("insertVersion: insert version failed %w", err)
+	}
+	return nil
+}
+
+// stageValue reads the value of data object. The binary value, which is not smaller
+// than the threshold, is streamed into the blob storage, where it is staged until
+// data object is saved, the other values are read for saving into the database.
+// The value is staged before the transaction is started, so the transaction
+// is not kept open while the value is received.
+func (r *Repository) stageValue(ctx context.Context, d *data.Data) (*blob.Staged, error) {
+	if r.blobs == nil || d.Type != "binary" {
+		err := readContent(d)
+		if err != nil {
+			return nil, fmt.Errorf("stageValue: %w", err)
+		}
+		return nil, nil
+	}
+
+	value := io.Reader(bytes.NewReader(d.Data))
+	if d.Content != nil {
+		// Only the beginning of the value is read to find out where it is kept
+		head, err := io.ReadAll(io.LimitReader(d.Content, r.threshold))
+		if err != nil {
+			return nil, fmt.Errorf("stageValue: read value failed %w", err)
+		}
+		if int64(len(head)) < r.threshold {
+			d.Data, d.Content = head, nil
+			return nil, nil
+		}
+		value = io.MultiReader(bytes.NewReader(head), d.Content)
+	} else if int64(len(d.Data)) < r.threshold {
+		return nil, nil
+	}
+
+	staged, err := r.blobs.Stage(ctx, value)
+	if err != nil {
+		return nil, fmt.Errorf("stageValue: stage blob failed %w", err)
+	}
+	d.Data, d.Content = nil, nil
+	return staged, nil
+}
+
+// putValue commits the staged binary value into the blob storage and returns its key,
+// the value of data object is returned for saving into the database, if the value
+// is not staged. The key stays locked until the end of transaction, so the blob
+// is not deleted until the transaction saves data referencing it.
+func (r *Repository) putValue(ctx context.Context, tx *sql.Tx, d *data.Data, staged *blob.Staged) ([]byte, sql.NullString, error) {
+	if staged == nil {
+		return d.Data, sql.NullString{}, nil
+	}
+	err := lockBlob(ctx, tx, staged.Key)
+	if err != nil {
+		return nil, sql.NullString{}, fmt.Errorf("putValue: %w", err)
+	}
+	err = staged.Commit(ctx)
+	if err != nil {
+		return nil, sql.NullString{}, fmt.Errorf("putValue: commit blob failed %w", err)
+	}
+	return nil, sql.NullString{String: staged.Key, Valid: true}, nil
+}
+
+// readContent reads the streamed value of data object into memory.
+func readContent(d *data.Data) error {
+	if d.Content == nil {
+		return nil
+	}
+	value, err := io.ReadAll(d.Content)
+	if err != nil {
+		return fmt.Errorf("readContent: read value failed %w", err)
+	}
+	d.Data, d.Content = value, nil
+	return nil
+}
+
+// discardBlob rolls back the transaction, which failed to save data with the blob,
+// and deletes the blob, if it is not used by the other data.
+func (r *Repository) discardBlob(ctx context.Context, tx *sql.Tx, blobKey sql.NullString) {
+	tx.Rollback()
+	if !blobKey.Valid {
+		return
+	}
+	// The blob which failed to be deleted is just left in the storage
+	_ = r.DeleteBlobs(context.WithoutCancel(ctx), []string{blobKey.String})
+}
+
+// openValue opens the value of data object in the blob storage for streaming,
+// if the blob key is set, otherwise the value from the database is kept.
+func (r *Repository) openValue(ctx context.Context, d *data.Data, blobKey sql.NullString) error {
+	if !blobKey.Valid {
+		return nil
+	}
+	if r.blobs == nil {
+		return fmt.Errorf("openValue: blob storage is not configured for blob %s", blobKey.String)
+	}
+	content, err := r.blobs.Open(ctx, blobKey.String)
+	if err != nil {
+		return fmt.Errorf("openValue: open blob failed %w", err)
+	}
+	d.Data, d.Content = nil, content
+	return nil
+}
+
+// DeleteBlobs deletes the blobs from the blob storage,
+// if they are not used by any data or data version.
+func (r *Repository) DeleteBlobs(ctx context.Context, keys []string) error {
+	if r.blobs == nil {
+		return nil
+	}
+	for _, key := range keys {
+		err := r.deleteBlob(ctx, key)
+		if err != nil {
+			return fmt.Errorf("DeleteBlobs: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteBlob deletes the blob from the blob storage, if it is not used. The key
+// is locked while the usage is checked and the blob is deleted, so the blob
+// is not deleted while another transaction saves data referencing it.
+func (r *Repository) deleteBlob(ctx context.Context, key string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("deleteBlob: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	err = lockBlob(ctx, tx, key)
+	if err != nil {
+		return fmt.Errorf("deleteBlob: %w", err)
+	}
+
+	var used bool
+	row := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM data WHERE blob_key = $1) 
+	OR EXISTS (SELECT 1 FROM data_versions WHERE blob_key = $1)`, key)
+	err = row.Scan(&used)
+	if err != nil {
+		return fmt.Errorf("deleteBlob: check blob usage failed %w", err)
+	}
+	if used {
+		return nil
+	}
+
+	err = r.blobs.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("deleteBlob: delete blob failed %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM blobs WHERE blob_key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("deleteBlob: delete blob key failed %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("deleteBlob: commit transaction failed %w", err)
+	}
+
+	return nil
+}
+
+// lockBlob saves the key of the blob, if it is not saved yet, and locks it
+// until the end of transaction.
+func lockBlob(ctx context.Context, tx *sql.Tx, key string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO blobs (blob_key) VALUES ($1) 
+	ON CONFLICT (blob_key) DO UPDATE SET blob_key = EXCLUDED.blob_key`, key)
+	if err != nil {
+		return fmt.Errorf("lockBlob: lock blob key failed %w", err)
+	}
+	return nil
+}
+
+// getBlobKeys returns the keys of the blobs used by data and its versions.
+func getBlobKeys(ctx context.Context, tx *sql.Tx, userID int, dType string, name string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT d.blob_key FROM data d 
+	WHERE d.user_id = $1 AND d.data_type = $2 AND d.name = $3 AND d.blob_key IS NOT NULL 
+	UNION SELECT v.blob_key FROM data_versions v JOIN data d ON d.id = v.data_id 
+	WHERE d.user_id = $1 AND d.data_type = $2 AND d.name = $3 AND v.blob_key IS NOT NULL`, userID, dType, name)
+	if err != nil {
+		return nil, fmt.Errorf("getBlobKeys: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, fmt.Errorf("getBlobKeys: scan row failed %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("getBlobKeys: rows.Err %w", err)
+	}
+
+	return keys, nil
+}
+
+// scanUpload scans the row with the upload, ErrUploadNotFound is returned
+// if there is no such upload.
+func scanUpload(row *sql.Row) (*data.Upload, error) {
+	var u data.Upload
+	var metadata []byte
+	err := row.Scan(&u.ID, &u.UserID, &u.Name, &u.Type, &metadata, &u.Size, &u.Offset,
+		&u.Checksum, &u.FileName, &u.ContentType, &u.Update, &u.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("scanUpload: %w", errs.ErrUploadNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanUpload: scan row failed %w", err)
+	}
+	u.Metadata = metadata
+	return &u, nil
+}
@@ -3,14 +3,23 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/blob"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
 	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 	"github.com/pavlegich/gophkeeper/internal/server/mocks"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
 )
 
 func TestNewDataRepository(t *testing.T) {
@@ -93,3 +102,158 @@ func TestRepository_GetDataByName(t *testing.T) {
 		})
 	}
 }
+
+// newSQLiteRepository returns the repository in new SQLite database
+// with the blob storage in the temporary directory.
+func newSQLiteRepository(t *testing.T, threshold int64) (*Repository, *sql.DB, blob.Store) {
+	t.Helper()
+	db, err := database.InitSQLite(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := blob.NewFileStore(t.TempDir())
+	return NewDataRepository(context.Background(), db, WithBlobStore(store, threshold)), db, store
+}
+
+func TestRepository_putValue(t *testing.T) {
+	ctx := context.Background()
+	r, db, _ := newSQLiteRepository(t, 8)
+
+	tests := []struct {
+		name     string
+		data     *data.Data
+		want     []byte
+		wantBlob bool
+	}{
+		{
+			name:     "large_binary",
+			data:     &data.Data{Type: "binary", Data: []byte("large binary value")},
+			want:     []byte("large binary value"),
+			wantBlob: true,
+		},
+		{
+			name:     "small_binary",
+			data:     &data.Data{Type: "binary", Data: []byte("small")},
+			want:     []byte("small"),
+			wantBlob: false,
+		},
+		{
+			name:     "large_text",
+			data:     &data.Data{Type: "text", Data: []byte("large text value")},
+			want:     []byte("large text value"),
+			wantBlob: false,
+		},
+		{
+			name:     "large_streamed_binary",
+			data:     &data.Data{Type: "binary", Content: io.NopCloser(strings.NewReader("large binary value"))},
+			want:     []byte("large binary value"),
+			wantBlob: true,
+		},
+		{
+			name:     "small_streamed_binary",
+			data:     &data.Data{Type: "binary", Content: io.NopCloser(strings.NewReader("small"))},
+			want:     []byte("small"),
+			wantBlob: false,
+		},
+		{
+			name:     "streamed_text",
+			data:     &data.Data{Type: "text", Content: io.NopCloser(strings.NewReader("large text value"))},
+			want:     []byte("large text value"),
+			wantBlob: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staged, err := r.stageValue(ctx, tt.data)
+			if err != nil {
+				t.Fatalf("Repository.stageValue() error = %v", err)
+			}
+			if staged != nil {
+				defer staged.Discard()
+			}
+
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTx() error = %v", err)
+			}
+			defer tx.Rollback()
+
+			value, key, err := r.putValue(ctx, tx, tt.data, staged)
+			if err != nil {
+				t.Fatalf("Repository.putValue() error = %v", err)
+			}
+			if key.Valid != tt.wantBlob || (value == nil) != tt.wantBlob {
+				t.Fatalf("Repository.putValue() = %v, %v, want blob %v", value, key, tt.wantBlob)
+			}
+
+			stored := &data.Data{Data: value}
+			err = r.openValue(ctx, stored, key)
+			if err != nil {
+				t.Fatalf("Repository.openValue() error = %v", err)
+			}
+			if (stored.Content != nil) != tt.wantBlob {
+				t.Fatalf("Repository.openValue() content = %v, want blob %v", stored.Content, tt.wantBlob)
+			}
+			err = readContent(stored)
+			if err != nil || !bytes.Equal(stored.Data, tt.want) {
+				t.Errorf("Repository.openValue() = %s, error = %v, want %s", stored.Data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_blobs(t *testing.T) {
+	r, db, store := newSQLiteRepository(t, 1)
+	ctx := context.WithValue(context.Background(), utils.ContextIDKey, 1)
+	_, err := db.ExecContext(ctx, `INSERT INTO users (login, password, salt) VALUES ('alice', 'hash', 'salt')`)
+	if err != nil {
+		t.Fatalf("insert user error = %v", err)
+	}
+	value := []byte("binary value")
+	key := blob.Key(value)
+	exists := func() bool {
+		r, err := store.Open(ctx, key)
+		if err != nil {
+			return false
+		}
+		r.Close()
+		return true
+	}
+
+	// The blob of the failed write is deleted
+	err = r.CreateData(ctx, &data.Data{UserID: 2, Type: "binary", Name: "file", Data: value})
+	if err == nil {
+		t.Fatalf("CreateData() of unknown user error = nil")
+	}
+	if exists() {
+		t.Errorf("CreateData() failed, but the blob is left in the storage")
+	}
+
+	// The blob is kept while it is used by another data object
+	for _, name := range []string{"first", "second"} {
+		err = r.CreateData(ctx, &data.Data{UserID: 1, Type: "binary", Name: name, Data: value})
+		if err != nil {
+			t.Fatalf("CreateData() error = %v", err)
+		}
+	}
+	err = r.CreateData(ctx, &data.Data{UserID: 1, Type: "binary", Name: "first", Data: value})
+	if !errors.Is(err, errs.ErrDataAlreadyUpload) {
+		t.Fatalf("CreateData() of existing data error = %v, want %v", err, errs.ErrDataAlreadyUpload)
+	}
+	err = r.DeleteDataByName(ctx, "binary", "first", 0)
+	if err != nil {
+		t.Fatalf("DeleteDataByName() error = %v", err)
+	}
+	if !exists() {
+		t.Fatalf("DeleteDataByName() deleted the blob used by another data object")
+	}
+
+	err = r.DeleteDataByName(ctx, "binary", "second", 0)
+	if err != nil {
+		t.Fatalf("DeleteDataByName() error = %v", err)
+	}
+	if exists() {
+		t.Errorf("DeleteDataByName() left the unused blob in the storage")
+	}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+)
+
+// likeEscaper escapes the special characters of the LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchData returns information about the user's data objects matching the query.
+// In PostgreSQL the name is matched with the trigram index, if it is created.
+func (r *Repository) SearchData(ctx context.Context, q *data.Query) ([]*data.Item, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SearchData: couldn't read user id from the context %w", err)
+	}
+
+	query := `SELECT name, data_type, metadata, created_at, folder_id, file_name, content_type, size, checksum 
+	FROM data WHERE user_id = $1`
+	args := []any{userID}
+	if len(q.Types) > 0 {
+		placeholders := make([]string, 0, len(q.Types))
+		for _, t := range q.Types {
+			args = append(args, t)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		query += ` AND data_type IN (` + strings.Join(placeholders, ", ") + `)`
+	}
+	for _, name := range q.Names {
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(name))+"%")
+		query += fmt.Sprintf(` AND LOWER(name) LIKE $%d ESCAPE '\'`, len(args))
+	}
+	for _, prefix := range q.Prefixes {
+		args = append(args, likeEscaper.Replace(strings.ToLower(prefix))+"%")
+		query += fmt.Sprintf(` AND LOWER(name) LIKE $%d ESCAPE '\'`, len(args))
+	}
+	query += ` ORDER BY data_type, name`
+
+	items, err := r.queryItems(ctx, userID, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SearchData: %w", err)
+	}
+
+	return items, nil
+}
@@ -1,8 +1,43 @@
 package data
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+)
+
+const (
+	// DefaultChangesLimit is the number of changes returned at once
+	// if the limit is not specified.
+	DefaultChangesLimit = 100
+	// MaxChangesLimit is the maximum number of changes returned at once.
+	MaxChangesLimit = 1000
+	// UploadExpiration is the time during which the chunked upload
+	// can be continued and committed.
+	UploadExpiration = 24 * time.Hour
+	// MaxChunkSize is the maximum size of one chunk of the upload.
+	MaxChunkSize = 16 << 20
+	// MaxNameLength is the maximum length of the folder name and the tag in bytes.
+	MaxNameLength = 64
+	// MaxFileNameLength is the maximum length of the original file name in bytes.
+	MaxFileNameLength = 255
+	// DefaultContentType is the content type of the binary value
+	// if the client has not declared it.
+	DefaultContentType = "application/octet-stream"
+	// MaxQueryTerms is the maximum number of terms in the search query.
+	MaxQueryTerms = 16
 )
 
 // DataService contatins objects for user service.
@@ -19,6 +54,7 @@ func NewDataService(ctx context.Context, repo Repository) *DataService {
 
 // Create upload new data into the storage.
 func (s *DataService) Create(ctx context.Context, data *Data) error {
+	describeFile(data)
 	err := s.repo.CreateData(ctx, data)
 	if err != nil {
 		return fmt.Errorf("Create: create data failed %w", err)
@@ -35,20 +71,479 @@ func (s *DataService) Unload(ctx context.Context, dType string, name string) (*D
 	return d, nil
 }
 
-// Edit updates requested user's data in storage.
-func (s *DataService) Edit(ctx context.Context, data *Data) error {
-	err := s.repo.UpdateData(ctx, data)
+// List returns information about all the user's data,
+// filtered by data type, folder and tag, if they are specified.
+func (s *DataService) List(ctx context.Context, filter Filter) ([]*Item, error) {
+	if filter.Type != "" && !isValidDataType(filter.Type) {
+		return nil, fmt.Errorf("List: %w", errs.ErrDataTypeIncorrect)
+	}
+	items, err := s.repo.GetDataList(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("List: get data list failed %w", err)
+	}
+	return items, nil
+}
+
+// Search parses the search query and returns information about the data objects
+// matching it.
+func (s *DataService) Search(ctx context.Context, q string) ([]*Item, error) {
+	query, err := ParseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("Search: %w", err)
+	}
+	items, err := s.repo.SearchData(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("Search: search data failed %w", err)
+	}
+	return items, nil
+}
+
+// Edit updates requested user's data in storage. If version is not zero,
+// data is updated only if its current version equals to it.
+func (s *DataService) Edit(ctx context.Context, data *Data, version int) error {
+	describeFile(data)
+	err := s.repo.UpdateData(ctx, data, version)
 	if err != nil {
 		return fmt.Errorf("Edit: edit data failed %w", err)
 	}
 	return nil
 }
 
-// Delete deletes requested user's data from the storage.
-func (s *DataService) Delete(ctx context.Context, dType string, name string) error {
-	err := s.repo.DeleteDataByName(ctx, dType, name)
+// Delete deletes requested user's data from the storage. If version is not zero,
+// data is deleted only if its current version equals to it.
+func (s *DataService) Delete(ctx context.Context, dType string, name string, version int) error {
+	err := s.repo.DeleteDataByName(ctx, dType, name, version)
 	if err != nil {
 		return fmt.Errorf("Delete: delete data failed %w", err)
 	}
 	return nil
 }
+
+// Versions returns information about all the versions of requested user's data.
+func (s *DataService) Versions(ctx context.Context, dType string, name string) ([]*Revision, error) {
+	revisions, err := s.repo.GetDataVersions(ctx, dType, name)
+	if err != nil {
+		return nil, fmt.Errorf("Versions: get data versions failed %w", err)
+	}
+	return revisions, nil
+}
+
+// UnloadVersion unloads the requested version of data by type and name,
+// returns data object.
+func (s *DataService) UnloadVersion(ctx context.Context, dType string, name string, version int) (*Data, error) {
+	d, err := s.repo.GetDataVersion(ctx, dType, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("UnloadVersion: get data version failed %w", err)
+	}
+	return d, nil
+}
+
+// Restore rolls the user's data back to the requested version,
+// the restored value is saved as the new version. If expected is not zero,
+// data is restored only if its current version equals to it.
+func (s *DataService) Restore(ctx context.Context, data *Data, version int, expected int) error {
+	err := s.repo.RestoreDataVersion(ctx, data, version, expected)
+	if err != nil {
+		return fmt.Errorf("Restore: restore data version failed %w", err)
+	}
+	return nil
+}
+
+// Changes returns the user's data changes since the cursor, not more than limit.
+func (s *DataService) Changes(ctx context.Context, since int64, limit int) (*ChangeFeed, error) {
+	if limit <= 0 {
+		limit = DefaultChangesLimit
+	}
+	if limit > MaxChangesLimit {
+		limit = MaxChangesLimit
+	}
+	changes, err := s.repo.GetChanges(ctx, since, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("Changes: get changes failed %w", err)
+	}
+
+	feed := &ChangeFeed{
+		Cursor:  since,
+		More:    len(changes) > limit,
+		Changes: changes,
+	}
+	if feed.More {
+		feed.Changes = changes[:limit]
+	}
+	if len(feed.Changes) > 0 {
+		feed.Cursor = feed.Changes[len(feed.Changes)-1].Seq
+	}
+	return feed, nil
+}
+
+// InitUpload starts new chunked upload of data object value,
+// the identifier and the expiration time of the upload are set.
+func (s *DataService) InitUpload(ctx context.Context, upload *Upload) error {
+	if !isValidDataType(upload.Type) {
+		return fmt.Errorf("InitUpload: %w", errs.ErrDataTypeIncorrect)
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return fmt.Errorf("InitUpload: generate upload id failed %w", err)
+	}
+	upload.ID = hex.EncodeToString(b)
+	upload.Offset = 0
+	if upload.Type == "binary" {
+		upload.FileName = cleanFileName(upload.FileName)
+		upload.ContentType = cleanContentType(upload.ContentType)
+	} else {
+		upload.FileName, upload.ContentType = "", ""
+	}
+	upload.ExpiresAt = time.Now().Add(UploadExpiration)
+
+	err = s.repo.CreateUpload(ctx, upload)
+	if err != nil {
+		return fmt.Errorf("InitUpload: create upload failed %w", err)
+	}
+	return nil
+}
+
+// GetUpload returns the state of the user's upload.
+func (s *DataService) GetUpload(ctx context.Context, id string) (*Upload, error) {
+	upload, err := s.repo.GetUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("GetUpload: get upload failed %w", err)
+	}
+	return upload, nil
+}
+
+// WriteChunk saves the chunk of the value at the offset, which must be equal to the size
+// of already received chunks. If the checksum is specified, it is compared with SHA-256
+// of the chunk. Returns the state of the upload with the new offset.
+func (s *DataService) WriteChunk(ctx context.Context, id string, offset int64, chunk []byte, checksum []byte) (*Upload, error) {
+	if checksum != nil {
+		sum := sha256.Sum256(chunk)
+		if !bytes.Equal(sum[:], checksum) {
+			return nil, fmt.Errorf("WriteChunk: %w", errs.ErrChecksumMismatch)
+		}
+	}
+
+	upload, err := s.repo.AppendUploadChunk(ctx, id, offset, chunk)
+	if err != nil {
+		return nil, fmt.Errorf("WriteChunk: append chunk failed %w", err)
+	}
+	return upload, nil
+}
+
+// CommitUpload saves the value of the complete upload as the new data object
+// or, if the upload updates the data object, as its new version, then the upload
+// is deleted. If version is not zero, data is updated only if its current version
+// equals to it. The upload with the value which differs from the expected checksum
+// is deleted, so it has to be started again.
+func (s *DataService) CommitUpload(ctx context.Context, id string, version int, device string) (*Data, error) {
+	upload, err := s.repo.GetUpload(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("CommitUpload: get upload failed %w", err)
+	}
+	if upload.Offset != upload.Size {
+		return nil, fmt.Errorf("CommitUpload: %w", errs.ErrUploadIncomplete)
+	}
+
+	value, err := s.repo.GetUploadContent(ctx, upload)
+	if err != nil {
+		return nil, fmt.Errorf("CommitUpload: get upload content failed %w", err)
+	}
+	if upload.Checksum != "" {
+		sum := sha256.Sum256(value)
+		if hex.EncodeToString(sum[:]) != upload.Checksum {
+			err = s.repo.DeleteUpload(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("CommitUpload: delete corrupted upload failed %w", err)
+			}
+			return nil, fmt.Errorf("CommitUpload: %w", errs.ErrChecksumMismatch)
+		}
+	}
+
+	d := &Data{
+		UserID:   upload.UserID,
+		Name:     upload.Name,
+		Type:     upload.Type,
+		Data:     value,
+		Metadata: upload.Metadata,
+		Device:   device,
+		File: File{
+			FileName:    upload.FileName,
+			ContentType: upload.ContentType,
+		},
+	}
+	describeFile(d)
+	if upload.Update {
+		err = s.repo.UpdateData(ctx, d, version)
+	} else {
+		err = s.repo.CreateData(ctx, d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CommitUpload: save data failed %w", err)
+	}
+
+	// Data is already saved, the upload is deleted after its expiration otherwise
+	_ = s.repo.DeleteUpload(ctx, id)
+
+	return d, nil
+}
+
+// AbortUpload deletes the user's upload with all the received chunks.
+func (s *DataService) AbortUpload(ctx context.Context, id string) error {
+	err := s.repo.DeleteUpload(ctx, id)
+	if err != nil {
+		return fmt.Errorf("AbortUpload: delete upload failed %w", err)
+	}
+	return nil
+}
+
+// Folders returns all the user's folders.
+func (s *DataService) Folders(ctx context.Context) ([]*Folder, error) {
+	folders, err := s.repo.GetFolders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Folders: get folders failed %w", err)
+	}
+	return folders, nil
+}
+
+// CreateFolder creates new folder in the parent folder of the user
+// or in the root, if the parent is not specified.
+func (s *DataService) CreateFolder(ctx context.Context, folder *Folder) error {
+	if !isValidName(folder.Name) {
+		return fmt.Errorf("CreateFolder: %w", errs.ErrFolderIncorrect)
+	}
+	folders, err := s.repo.GetFolders(ctx)
+	if err != nil {
+		return fmt.Errorf("CreateFolder: get folders failed %w", err)
+	}
+	if folder.ParentID != 0 && findFolder(folders, folder.ParentID) == nil {
+		return fmt.Errorf("CreateFolder: parent %w", errs.ErrFolderNotFound)
+	}
+
+	err = s.repo.CreateFolder(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("CreateFolder: create folder failed %w", err)
+	}
+	return nil
+}
+
+// EditFolder renames the user's folder and moves it into the parent folder,
+// the folder cannot be moved into itself or into its subfolder.
+func (s *DataService) EditFolder(ctx context.Context, folder *Folder) error {
+	if !isValidName(folder.Name) {
+		return fmt.Errorf("EditFolder: %w", errs.ErrFolderIncorrect)
+	}
+	folders, err := s.repo.GetFolders(ctx)
+	if err != nil {
+		return fmt.Errorf("EditFolder: get folders failed %w", err)
+	}
+	if findFolder(folders, folder.ID) == nil {
+		return fmt.Errorf("EditFolder: %w", errs.ErrFolderNotFound)
+	}
+	for id := folder.ParentID; id != 0; {
+		parent := findFolder(folders, id)
+		if parent == nil {
+			return fmt.Errorf("EditFolder: parent %w", errs.ErrFolderNotFound)
+		}
+		if parent.ID == folder.ID {
+			return fmt.Errorf("EditFolder: folder is moved into itself, %w", errs.ErrFolderIncorrect)
+		}
+		id = parent.ParentID
+	}
+
+	err = s.repo.UpdateFolder(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("EditFolder: update folder failed %w", err)
+	}
+	return nil
+}
+
+// DeleteFolder deletes the user's folder with its subfolders,
+// data objects of the deleted folders are moved to the root.
+func (s *DataService) DeleteFolder(ctx context.Context, id int) error {
+	err := s.repo.DeleteFolder(ctx, id)
+	if err != nil {
+		return fmt.Errorf("DeleteFolder: delete folder failed %w", err)
+	}
+	return nil
+}
+
+// Move moves the user's data object into the folder,
+// zero folder identifier moves data object to the root.
+func (s *DataService) Move(ctx context.Context, dType string, name string, folderID int) error {
+	if folderID != 0 {
+		folders, err := s.repo.GetFolders(ctx)
+		if err != nil {
+			return fmt.Errorf("Move: get folders failed %w", err)
+		}
+		if findFolder(folders, folderID) == nil {
+			return fmt.Errorf("Move: %w", errs.ErrFolderNotFound)
+		}
+	}
+
+	err := s.repo.SetDataFolder(ctx, dType, name, folderID)
+	if err != nil {
+		return fmt.Errorf("Move: move data failed %w", err)
+	}
+	return nil
+}
+
+// Tag adds the tag to the user's data object.
+func (s *DataService) Tag(ctx context.Context, dType string, name string, tag string) error {
+	if !isValidName(tag) {
+		return fmt.Errorf("Tag: %w", errs.ErrTagIncorrect)
+	}
+	err := s.repo.AddDataTag(ctx, dType, name, tag)
+	if err != nil {
+		return fmt.Errorf("Tag: add tag failed %w", err)
+	}
+	return nil
+}
+
+// Untag removes the tag from the user's data object.
+func (s *DataService) Untag(ctx context.Context, dType string, name string, tag string) error {
+	err := s.repo.RemoveDataTag(ctx, dType, name, tag)
+	if err != nil {
+		return fmt.Errorf("Untag: remove tag failed %w", err)
+	}
+	return nil
+}
+
+// findFolder returns the folder with the identifier from the list,
+// nil is returned if there is no such folder.
+func findFolder(folders []*Folder, id int) *Folder {
+	for _, f := range folders {
+		if f.ID == id {
+			return f
+		}
+	}
+	return nil
+}
+
+// isValidName checks whether the name of the folder or the tag is not empty,
+// is not longer than 64 bytes and can be used as the element of the path.
+func isValidName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > MaxNameLength {
+		return false
+	}
+	return !strings.ContainsAny(name, "/\\\x00")
+}
+
+// ParseQuery parses the search query of the terms separated by spaces:
+// 'type:{dataType}' is data type, the term ending with '*' is the prefix
+// of the name and the other terms are the substrings of the name,
+// e.g. 'git* type:credentials'. Metadata is encrypted by the client,
+// so the metadata pairs 'key=value' are rejected, the client matches them.
+func ParseQuery(q string) (*Query, error) {
+	terms := strings.Fields(q)
+	if len(terms) == 0 || len(terms) > MaxQueryTerms {
+		return nil, fmt.Errorf("ParseQuery: number of terms %d %w", len(terms), errs.ErrQueryIncorrect)
+	}
+
+	query := &Query{}
+	for _, term := range terms {
+		if t, ok := strings.CutPrefix(term, "type:"); ok {
+			t = strings.ToLower(t)
+			if !isValidDataType(t) {
+				return nil, fmt.Errorf("ParseQuery: data type %s %w", t, errs.ErrDataTypeIncorrect)
+			}
+			query.Types = append(query.Types, t)
+			continue
+		}
+		if strings.Contains(term, "=") {
+			return nil, fmt.Errorf("ParseQuery: metadata term %s %w", term, errs.ErrQueryIncorrect)
+		}
+		if prefix, ok := strings.CutSuffix(term, "*"); ok {
+			if prefix == "" {
+				return nil, fmt.Errorf("ParseQuery: empty name prefix %w", errs.ErrQueryIncorrect)
+			}
+			query.Prefixes = append(query.Prefixes, strings.ToLower(prefix))
+			continue
+		}
+		query.Names = append(query.Names, strings.ToLower(term))
+	}
+
+	return query, nil
+}
+
+// isValidDataType checks whether the data type is supported by the storage.
+func isValidDataType(t string) bool {
+	switch t {
+	case "credentials", "card", "text", "binary":
+		return true
+	}
+	return false
+}
+
+// describeFile sets the size and the SHA-256 checksum of the binary value
+// and cleans the declared file name and content type, the other data types
+// have no file information. The size and the checksum of the streamed value
+// are counted while the value is read.
+func describeFile(d *Data) {
+	if d.Type != "binary" {
+		d.File = File{}
+		return
+	}
+	d.FileName = cleanFileName(d.FileName)
+	d.ContentType = cleanContentType(d.ContentType)
+	if d.Content != nil {
+		d.Size, d.Checksum = 0, ""
+		d.Content = &contentReader{
+			ReadCloser: d.Content,
+			d:          d,
+			hash:       sha256.New(),
+		}
+		return
+	}
+	sum := sha256.Sum256(d.Data)
+	d.Size = int64(len(d.Data))
+	d.Checksum = hex.EncodeToString(sum[:])
+}
+
+// contentReader reads the streamed binary value and sets the size
+// and the checksum of the value, when it is read to the end.
+type contentReader struct {
+	io.ReadCloser
+	d    *Data
+	hash hash.Hash
+}
+
+// Read reads the value and counts its size and checksum.
+func (r *contentReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	r.d.Size += int64(n)
+	if errors.Is(err, io.EOF) {
+		r.d.Checksum = hex.EncodeToString(r.hash.Sum(nil))
+	}
+	return n, err
+}
+
+// cleanFileName returns the base name of the file without the directories
+// of the client, the name which cannot be used as the file name is dropped.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch name {
+	case ".", "..", "/":
+		return ""
+	}
+	if len(name) > MaxFileNameLength || strings.ContainsAny(name, "\x00\r\n") {
+		return ""
+	}
+	return name
+}
+
+// cleanContentType returns the normalized media type with its parameters,
+// the default content type is returned if the type is not specified or is invalid.
+func cleanContentType(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return DefaultContentType
+	}
+	contentType = mime.FormatMediaType(mediaType, params)
+	if contentType == "" || len(contentType) > MaxFileNameLength {
+		return DefaultContentType
+	}
+	return contentType
+}
@@ -1,9 +1,16 @@
 package data
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 )
 
 func TestNewDataService(t *testing.T) {
@@ -37,3 +44,526 @@ func TestNewDataService(t *testing.T) {
 		})
 	}
 }
+
+func Test_isValidDataType(t *testing.T) {
+	tests := []struct {
+		name  string
+		dType string
+		want  bool
+	}{
+		{
+			name:  "credentials",
+			dType: "credentials",
+			want:  true,
+		},
+		{
+			name:  "binary",
+			dType: "binary",
+			want:  true,
+		},
+		{
+			name:  "unknown_type",
+			dType: "photo",
+			want:  false,
+		},
+		{
+			name:  "empty_type",
+			dType: "",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidDataType(tt.dType); got != tt.want {
+				t.Errorf("isValidDataType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_describeFile(t *testing.T) {
+	value := []byte("content")
+	sum := sha256.Sum256(value)
+
+	tests := []struct {
+		name string
+		d    *Data
+		want File
+	}{
+		{
+			name: "binary",
+			d: &Data{Type: "binary", Data: value,
+				File: File{FileName: "report.pdf", ContentType: "application/PDF"}},
+			want: File{FileName: "report.pdf", ContentType: "application/pdf",
+				Size: int64(len(value)), Checksum: hex.EncodeToString(sum[:])},
+		},
+		{
+			name: "client_path",
+			d: &Data{Type: "binary", Data: value,
+				File: File{FileName: `C:\Users\user\photo.png`, ContentType: "image/png"}},
+			want: File{FileName: "photo.png", ContentType: "image/png",
+				Size: int64(len(value)), Checksum: hex.EncodeToString(sum[:])},
+		},
+		{
+			name: "incorrect_name_and_type",
+			d: &Data{Type: "binary", Data: value,
+				File: File{FileName: "../", ContentType: "not a type"}},
+			want: File{FileName: "", ContentType: DefaultContentType,
+				Size: int64(len(value)), Checksum: hex.EncodeToString(sum[:])},
+		},
+		{
+			name: "not_binary",
+			d: &Data{Type: "text", Data: value,
+				File: File{FileName: "notes.txt", ContentType: "text/plain"}},
+			want: File{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			describeFile(tt.d)
+			if tt.d.File != tt.want {
+				t.Errorf("describeFile() = %v, want %v", tt.d.File, tt.want)
+			}
+		})
+	}
+}
+
+// changesRepository is a repository stub that returns stored changes.
+type changesRepository struct {
+	Repository
+	changes []*Change
+}
+
+func (r *changesRepository) GetChanges(ctx context.Context, since int64, limit int) ([]*Change, error) {
+	res := make([]*Change, 0)
+	for _, ch := range r.changes {
+		if ch.Seq > since && len(res) < limit {
+			res = append(res, ch)
+		}
+	}
+	return res, nil
+}
+
+func TestDataService_Changes(t *testing.T) {
+	ctx := context.Background()
+	s := NewDataService(ctx, &changesRepository{
+		changes: []*Change{
+			{Seq: 1, Name: "first", Type: "text"},
+			{Seq: 2, Name: "second", Type: "text"},
+			{Seq: 4, Name: "first", Type: "text", Deleted: true},
+		},
+	})
+
+	type args struct {
+		since int64
+		limit int
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantCursor int64
+		wantMore   bool
+		wantCount  int
+	}{
+		{
+			name:       "all_changes",
+			args:       args{since: 0, limit: 0},
+			wantCursor: 4,
+			wantMore:   false,
+			wantCount:  3,
+		},
+		{
+			name:       "limited",
+			args:       args{since: 0, limit: 2},
+			wantCursor: 2,
+			wantMore:   true,
+			wantCount:  2,
+		},
+		{
+			name:       "since_cursor",
+			args:       args{since: 2, limit: 2},
+			wantCursor: 4,
+			wantMore:   false,
+			wantCount:  1,
+		},
+		{
+			name:       "no_changes",
+			args:       args{since: 4, limit: 2},
+			wantCursor: 4,
+			wantMore:   false,
+			wantCount:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Changes(ctx, tt.args.since, tt.args.limit)
+			if err != nil {
+				t.Fatalf("DataService.Changes() error = %v", err)
+			}
+			if got.Cursor != tt.wantCursor || got.More != tt.wantMore || len(got.Changes) != tt.wantCount {
+				t.Errorf("DataService.Changes() = cursor %d, more %v, %d changes, want cursor %d, more %v, %d changes",
+					got.Cursor, got.More, len(got.Changes), tt.wantCursor, tt.wantMore, tt.wantCount)
+			}
+		})
+	}
+}
+
+// uploadsRepository is a repository stub that keeps uploads and saved data in memory.
+type uploadsRepository struct {
+	Repository
+	uploads map[string]*Upload
+	content map[string][]byte
+	saved   map[string]*Data
+}
+
+func newUploadsRepository() *uploadsRepository {
+	return &uploadsRepository{
+		uploads: make(map[string]*Upload),
+		content: make(map[string][]byte),
+		saved:   make(map[string]*Data),
+	}
+}
+
+func (r *uploadsRepository) CreateUpload(ctx context.Context, u *Upload) error {
+	r.uploads[u.ID] = u
+	return nil
+}
+
+func (r *uploadsRepository) GetUpload(ctx context.Context, id string) (*Upload, error) {
+	u, ok := r.uploads[id]
+	if !ok {
+		return nil, errs.ErrUploadNotFound
+	}
+	return u, nil
+}
+
+func (r *uploadsRepository) AppendUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (*Upload, error) {
+	u, ok := r.uploads[id]
+	if !ok {
+		return nil, errs.ErrUploadNotFound
+	}
+	if offset != u.Offset {
+		return nil, errs.ErrUploadOffset
+	}
+	r.content[id] = append(r.content[id], chunk...)
+	u.Offset += int64(len(chunk))
+	return u, nil
+}
+
+func (r *uploadsRepository) GetUploadContent(ctx context.Context, u *Upload) ([]byte, error) {
+	return r.content[u.ID], nil
+}
+
+func (r *uploadsRepository) DeleteUpload(ctx context.Context, id string) error {
+	delete(r.uploads, id)
+	delete(r.content, id)
+	return nil
+}
+
+func (r *uploadsRepository) CreateData(ctx context.Context, d *Data) error {
+	if _, ok := r.saved[d.Name]; ok {
+		return errs.ErrDataAlreadyUpload
+	}
+	d.Version = 1
+	r.saved[d.Name] = d
+	return nil
+}
+
+func (r *uploadsRepository) UpdateData(ctx context.Context, d *Data, version int) error {
+	stored, ok := r.saved[d.Name]
+	if !ok {
+		return errs.ErrDataNotFound
+	}
+	if version != 0 && version != stored.Version {
+		return errs.ErrDataVersionDiffer
+	}
+	d.Version = stored.Version + 1
+	r.saved[d.Name] = d
+	return nil
+}
+
+func TestDataService_Upload(t *testing.T) {
+	ctx := context.Background()
+
+	value := []byte("first second")
+	sum := sha256.Sum256(value)
+	chunkSum := sha256.Sum256([]byte("first "))
+
+	tests := []struct {
+		name        string
+		upload      *Upload
+		chunks      [][]byte
+		offsets     []int64
+		checksums   [][]byte
+		version     int
+		wantChunk   error
+		wantCommit  error
+		wantVersion int
+	}{
+		{
+			name:        "created",
+			upload:      &Upload{Name: "new", Type: "binary", Size: 12, Checksum: hex.EncodeToString(sum[:])},
+			chunks:      [][]byte{[]byte("first "), []byte("second")},
+			offsets:     []int64{0, 6},
+			checksums:   [][]byte{chunkSum[:], nil},
+			wantVersion: 1,
+		},
+		{
+			name:        "updated",
+			upload:      &Upload{Name: "stored", Type: "binary", Size: 12, Update: true},
+			chunks:      [][]byte{value},
+			offsets:     []int64{0},
+			checksums:   [][]byte{nil},
+			version:     1,
+			wantVersion: 2,
+		},
+		{
+			name:       "version_differs",
+			upload:     &Upload{Name: "stored", Type: "binary", Size: 12, Update: true},
+			chunks:     [][]byte{value},
+			offsets:    []int64{0},
+			checksums:  [][]byte{nil},
+			version:    3,
+			wantCommit: errs.ErrDataVersionDiffer,
+		},
+		{
+			name:       "wrong_offset",
+			upload:     &Upload{Name: "new", Type: "binary", Size: 12},
+			chunks:     [][]byte{[]byte("first "), []byte("second")},
+			offsets:    []int64{0, 3},
+			checksums:  [][]byte{nil, nil},
+			wantChunk:  errs.ErrUploadOffset,
+			wantCommit: errs.ErrUploadIncomplete,
+		},
+		{
+			name:       "chunk_checksum_mismatch",
+			upload:     &Upload{Name: "new", Type: "binary", Size: 12},
+			chunks:     [][]byte{value},
+			offsets:    []int64{0},
+			checksums:  [][]byte{chunkSum[:]},
+			wantChunk:  errs.ErrChecksumMismatch,
+			wantCommit: errs.ErrUploadIncomplete,
+		},
+		{
+			name:       "value_checksum_mismatch",
+			upload:     &Upload{Name: "new", Type: "binary", Size: 12, Checksum: hex.EncodeToString(chunkSum[:])},
+			chunks:     [][]byte{value},
+			offsets:    []int64{0},
+			checksums:  [][]byte{nil},
+			wantCommit: errs.ErrChecksumMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUploadsRepository()
+			repo.saved["stored"] = &Data{Name: "stored", Type: "binary", Version: 1}
+			s := NewDataService(ctx, repo)
+
+			err := s.InitUpload(ctx, tt.upload)
+			if err != nil || tt.upload.ID == "" {
+				t.Fatalf("DataService.InitUpload() error = %v, id %q", err, tt.upload.ID)
+			}
+
+			var chunkErr error
+			for i, chunk := range tt.chunks {
+				_, err = s.WriteChunk(ctx, tt.upload.ID, tt.offsets[i], chunk, tt.checksums[i])
+				if err != nil {
+					chunkErr = err
+				}
+			}
+			if !errors.Is(chunkErr, tt.wantChunk) {
+				t.Errorf("DataService.WriteChunk() error = %v, want %v", chunkErr, tt.wantChunk)
+			}
+
+			d, err := s.CommitUpload(ctx, tt.upload.ID, tt.version, "laptop")
+			if !errors.Is(err, tt.wantCommit) {
+				t.Fatalf("DataService.CommitUpload() error = %v, want %v", err, tt.wantCommit)
+			}
+			if tt.wantCommit != nil {
+				return
+			}
+			if d.Version != tt.wantVersion || !bytes.Equal(d.Data, value) || d.Device != "laptop" {
+				t.Errorf("DataService.CommitUpload() = %v, want version %v", d, tt.wantVersion)
+			}
+			if _, ok := repo.uploads[tt.upload.ID]; ok {
+				t.Errorf("DataService.CommitUpload() upload %s is not deleted", tt.upload.ID)
+			}
+		})
+	}
+}
+
+func Test_isValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  string
+		want bool
+	}{
+		{
+			name: "ok",
+			arg:  "work servers",
+			want: true,
+		},
+		{
+			name: "empty",
+			arg:  "",
+			want: false,
+		},
+		{
+			name: "slash",
+			arg:  "work/servers",
+			want: false,
+		},
+		{
+			name: "parent",
+			arg:  "..",
+			want: false,
+		},
+		{
+			name: "too_long",
+			arg:  strings.Repeat("a", MaxNameLength+1),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidName(tt.arg); got != tt.want {
+				t.Errorf("isValidName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    *Query
+		wantErr error
+	}{
+		{
+			name: "all_terms",
+			arg:  "Git* hub type:Credentials",
+			want: &Query{
+				Names:    []string{"hub"},
+				Prefixes: []string{"git"},
+				Types:    []string{"credentials"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "empty",
+			arg:     "  ",
+			want:    nil,
+			wantErr: errs.ErrQueryIncorrect,
+		},
+		{
+			name:    "too_many_terms",
+			arg:     strings.Repeat("a ", MaxQueryTerms+1),
+			want:    nil,
+			wantErr: errs.ErrQueryIncorrect,
+		},
+		{
+			name:    "incorrect_type",
+			arg:     "type:photo",
+			want:    nil,
+			wantErr: errs.ErrDataTypeIncorrect,
+		},
+		{
+			name:    "metadata_term",
+			arg:     "git* url=github.com",
+			want:    nil,
+			wantErr: errs.ErrQueryIncorrect,
+		},
+		{
+			name:    "empty_prefix",
+			arg:     "*",
+			want:    nil,
+			wantErr: errs.ErrQueryIncorrect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// foldersRepository is a repository stub that returns stored folders.
+type foldersRepository struct {
+	Repository
+	folders []*Folder
+}
+
+func (r *foldersRepository) GetFolders(ctx context.Context) ([]*Folder, error) {
+	return r.folders, nil
+}
+
+func (r *foldersRepository) UpdateFolder(ctx context.Context, f *Folder) error {
+	return nil
+}
+
+func TestDataService_EditFolder(t *testing.T) {
+	ctx := context.Background()
+	s := NewDataService(ctx, &foldersRepository{folders: []*Folder{
+		{ID: 1, Name: "work"},
+		{ID: 2, Name: "servers", ParentID: 1},
+		{ID: 3, Name: "home"},
+	}})
+
+	tests := []struct {
+		name    string
+		folder  *Folder
+		wantErr error
+	}{
+		{
+			name:    "rename",
+			folder:  &Folder{ID: 1, Name: "job"},
+			wantErr: nil,
+		},
+		{
+			name:    "move",
+			folder:  &Folder{ID: 2, Name: "servers", ParentID: 3},
+			wantErr: nil,
+		},
+		{
+			name:    "into_itself",
+			folder:  &Folder{ID: 1, Name: "work", ParentID: 1},
+			wantErr: errs.ErrFolderIncorrect,
+		},
+		{
+			name:    "into_subfolder",
+			folder:  &Folder{ID: 1, Name: "work", ParentID: 2},
+			wantErr: errs.ErrFolderIncorrect,
+		},
+		{
+			name:    "unknown_parent",
+			folder:  &Folder{ID: 1, Name: "work", ParentID: 4},
+			wantErr: errs.ErrFolderNotFound,
+		},
+		{
+			name:    "unknown_folder",
+			folder:  &Folder{ID: 4, Name: "other"},
+			wantErr: errs.ErrFolderNotFound,
+		},
+		{
+			name:    "incorrect_name",
+			folder:  &Folder{ID: 1, Name: "a/b"},
+			wantErr: errs.ErrFolderIncorrect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.EditFolder(ctx, tt.folder)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DataService.EditFolder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
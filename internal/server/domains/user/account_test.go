@@ -0,0 +1,207 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// accountRepository is a repository stub with one user and the user's sessions.
+type accountRepository struct {
+	Repository
+	user     *User
+	sessions map[string]bool
+}
+
+func (r *accountRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	if r.user == nil || id != r.user.ID {
+		return nil, errs.ErrUserNotFound
+	}
+	stored := *r.user
+	return &stored, nil
+}
+
+func (r *accountRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	r.user.Password = password
+	return nil
+}
+
+func (r *accountRepository) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	if r.user.KeyCheck != nil {
+		return errs.ErrKeyCheckExists
+	}
+	r.user.KeyCheck = keyCheck
+	return nil
+}
+
+func (r *accountRepository) DeleteUser(ctx context.Context, id int) error {
+	r.user = nil
+	r.sessions = nil
+	return nil
+}
+
+func (r *accountRepository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	for id := range r.sessions {
+		if id != keepID {
+			r.sessions[id] = false
+		}
+	}
+	return nil
+}
+
+func (r *accountRepository) GetTOTP(ctx context.Context, userID int) (*TOTP, error) {
+	return nil, errs.ErrTOTPNotEnrolled
+}
+
+func newAccountRepository(t *testing.T, password string) *accountRepository {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password failed %v", err)
+	}
+	return &accountRepository{
+		user:     &User{ID: 1, Login: "user", Password: string(hashed)},
+		sessions: map[string]bool{"current": true, "other": true},
+	}
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		change       *PasswordChange
+		wantErr      error
+		wantPassword string
+		wantOther    bool
+	}{
+		{
+			name:         "ok",
+			change:       &PasswordChange{OldPassword: "old", NewPassword: "new"},
+			wantErr:      nil,
+			wantPassword: "new",
+			wantOther:    false,
+		},
+		{
+			name:         "wrong_password",
+			change:       &PasswordChange{OldPassword: "wrong", NewPassword: "new"},
+			wantErr:      errs.ErrPasswordNotMatch,
+			wantPassword: "old",
+			wantOther:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newAccountRepository(t, "old")
+			s := NewUserService(ctx, repo)
+
+			err := s.ChangePassword(ctx, 1, "current", tt.change)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.ChangePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(repo.user.Password), []byte(tt.wantPassword)) != nil {
+				t.Errorf("UserService.ChangePassword() password is not %q", tt.wantPassword)
+			}
+			if !repo.sessions["current"] {
+				t.Errorf("UserService.ChangePassword() current session is revoked")
+			}
+			if repo.sessions["other"] != tt.wantOther {
+				t.Errorf("UserService.ChangePassword() other session active = %v, want %v",
+					repo.sessions["other"], tt.wantOther)
+			}
+		})
+	}
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		user        *User
+		wantErr     error
+		wantDeleted bool
+	}{
+		{
+			name:        "ok",
+			user:        &User{Password: "secret"},
+			wantErr:     nil,
+			wantDeleted: true,
+		},
+		{
+			name:        "wrong_password",
+			user:        &User{Password: "wrong"},
+			wantErr:     errs.ErrPasswordNotMatch,
+			wantDeleted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newAccountRepository(t, "secret")
+			s := NewUserService(ctx, repo)
+
+			err := s.DeleteUser(ctx, 1, tt.user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (repo.user == nil) != tt.wantDeleted {
+				t.Errorf("UserService.DeleteUser() deleted = %v, want %v", repo.user == nil, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestUserService_SetKeyCheck(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		stored       []byte
+		keyCheck     []byte
+		wantErr      error
+		wantKeyCheck string
+	}{
+		{
+			name:         "ok",
+			stored:       nil,
+			keyCheck:     []byte("check"),
+			wantErr:      nil,
+			wantKeyCheck: "check",
+		},
+		{
+			name:         "empty",
+			stored:       nil,
+			keyCheck:     nil,
+			wantErr:      errs.ErrKeyCheckInvalid,
+			wantKeyCheck: "",
+		},
+		{
+			name:         "already_set",
+			stored:       []byte("stored"),
+			keyCheck:     []byte("check"),
+			wantErr:      errs.ErrKeyCheckExists,
+			wantKeyCheck: "stored",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newAccountRepository(t, "secret")
+			repo.user.KeyCheck = tt.stored
+			s := NewUserService(ctx, repo)
+
+			err := s.SetKeyCheck(ctx, 1, tt.keyCheck)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.SetKeyCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := s.GetSalt(ctx, 1)
+			if err != nil {
+				t.Fatalf("UserService.GetSalt() error = %v", err)
+			}
+			if string(got.KeyCheck) != tt.wantKeyCheck {
+				t.Errorf("UserService.GetSalt() key check = %q, want %q", got.KeyCheck, tt.wantKeyCheck)
+			}
+		})
+	}
+}
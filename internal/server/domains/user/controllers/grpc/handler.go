@@ -0,0 +1,178 @@
+// Package grpc contains object of user gRPC handler,
+// functions for registering the user handler in gRPC server
+// and user handlers.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
+	pb "github.com/pavlegich/gophkeeper/internal/common/proto"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserHandler contains objects for work
+// with user gRPC handlers.
+type UserHandler struct {
+	pb.UnimplementedUsersServer
+	Config  *config.ServerConfig
+	Service user.Service
+}
+
+// Activate registers user handler in gRPC server.
+func Activate(ctx context.Context, s *grpc.Server, cfg *config.ServerConfig, repo user.Repository) error {
+	policy, err := user.NewPolicy(cfg.PassLength, cfg.PassClasses, cfg.PassEntropy, cfg.LoginPattern)
+	if err != nil {
+		return fmt.Errorf("Activate: create policy failed %w", err)
+	}
+	us := user.NewUserService(ctx, repo, user.WithPolicy(policy))
+	pb.RegisterUsersServer(s, newHandler(ctx, cfg, us))
+	return nil
+}
+
+// newHandler initializes gRPC handler for user.
+func newHandler(ctx context.Context, cfg *config.ServerConfig, s user.Service) *UserHandler {
+	return &UserHandler{
+		Config:  cfg,
+		Service: s,
+	}
+}
+
+// Register registers new user and creates the session.
+func (h *UserHandler) Register(ctx context.Context, req *pb.Credentials) (*pb.Session, error) {
+	currentUser, err := h.Service.Register(ctx, &user.User{
+		Login:    req.GetLogin(),
+		Password: req.GetPassword(),
+	})
+	if err != nil {
+		logger.Log.Error("Register: user register failed",
+			zap.Error(err))
+		var policyErr *user.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		case errors.Is(err, errs.ErrLoginBusy):
+			return nil, status.Error(codes.AlreadyExists, errs.ErrLoginBusy.Error())
+		default:
+			return nil, status.Error(codes.Internal, "register user failed")
+		}
+	}
+
+	return h.createSession(ctx, currentUser.ID)
+}
+
+// Login handles user login with received credentials and creates the session.
+func (h *UserHandler) Login(ctx context.Context, req *pb.Credentials) (*pb.Session, error) {
+	currentUser, err := h.Service.Login(ctx, &user.User{
+		Login:    req.GetLogin(),
+		Password: req.GetPassword(),
+		Code:     req.GetCode(),
+	})
+	if err != nil {
+		logger.Log.Error("Login: user login failed",
+			zap.Error(err))
+		switch {
+		case errors.Is(err, errs.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, errs.ErrUserNotFound.Error())
+		case errors.Is(err, errs.ErrPasswordNotMatch), errors.Is(err, errs.ErrCodeInvalid):
+			return nil, status.Error(codes.Unauthenticated, "login or password is wrong")
+		case errors.Is(err, errs.ErrCodeRequired):
+			// The second step of login with the one-time code is required
+			return nil, status.Error(codes.FailedPrecondition, errs.ErrCodeRequired.Error())
+		default:
+			return nil, status.Error(codes.Internal, "login failed")
+		}
+	}
+
+	return h.createSession(ctx, currentUser.ID)
+}
+
+// Refresh replaces the refresh token with the new one
+// and issues new access token of the same session.
+func (h *UserHandler) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.Session, error) {
+	sess, refresh, err := h.Service.RefreshSession(ctx, req.GetRefreshToken(), h.Config.RefreshExp)
+	if err != nil {
+		logger.Log.Error("Refresh: refresh session failed",
+			zap.Error(err))
+		if errors.Is(err, errs.ErrSessionNotFound) || errors.Is(err, errs.ErrSessionRevoked) {
+			return nil, status.Error(codes.Unauthenticated, errs.ErrSessionRevoked.Error())
+		}
+		return nil, status.Error(codes.Internal, "refresh session failed")
+	}
+
+	return h.newSession(ctx, sess, refresh)
+}
+
+// Logout revokes the session of the request,
+// so its tokens are not accepted anymore.
+func (h *UserHandler) Logout(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Logout: get user id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get user failed")
+	}
+	sessionID, err := utils.GetSessionIDFromContext(ctx)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("Logout: get session id from context failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "get session failed")
+	}
+
+	err = h.Service.RevokeSession(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, errs.ErrSessionNotFound) {
+		logger.Log.With(zap.String("user_id", idString)).Error("Logout: revoke session failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "revoke session failed")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// createSession creates the session of the user with information
+// about the client from the request metadata.
+func (h *UserHandler) createSession(ctx context.Context, userID int) (*pb.Session, error) {
+	sess, refresh, err := h.Service.CreateSession(ctx, &user.Session{
+		UserID:    userID,
+		Device:    utils.GetDeviceFromMetadata(ctx),
+		IP:        utils.GetIPFromPeer(ctx),
+		UserAgent: utils.GetUserAgentFromMetadata(ctx),
+	}, h.Config.RefreshExp)
+	if err != nil {
+		logger.Log.Error("createSession: create session failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "create session failed")
+	}
+
+	return h.newSession(ctx, sess, refresh)
+}
+
+// newSession creates the access token of the session and returns
+// the session with the access and refresh tokens.
+func (h *UserHandler) newSession(ctx context.Context, sess *user.Session, refresh string) (*pb.Session, error) {
+	token, err := h.Config.Token.Create(ctx, sess.UserID, sess.ID)
+	if err != nil {
+		logger.Log.Error("newSession: create token failed",
+			zap.Error(err))
+		return nil, status.Error(codes.Internal, "create token failed")
+	}
+
+	return &pb.Session{
+		AccessToken:  token,
+		RefreshToken: refresh,
+		ExpiresAt:    timestamppb.New(sess.ExpiresAt),
+	}, nil
+}
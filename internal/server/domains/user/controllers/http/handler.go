@@ -6,20 +6,27 @@ package http
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
 	"github.com/pavlegich/gophkeeper/internal/common/infra/logger"
 	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
-	repo "github.com/pavlegich/gophkeeper/internal/server/domains/user/repository"
 	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"github.com/pavlegich/gophkeeper/internal/server/utils"
 	"go.uber.org/zap"
 )
 
+// Names of the cookies with access and refresh tokens.
+const (
+	authCookie    = "auth"
+	refreshCookie = "refresh"
+)
+
 // UserHandler contains objects for work
 // with user handlers.
 type UserHandler struct {
@@ -28,9 +35,14 @@ type UserHandler struct {
 }
 
 // Activate activates handler for user.
-func Activate(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, db *sql.DB) {
-	s := user.NewUserService(ctx, repo.NewUserRepository(ctx, db))
+func Activate(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, repo user.Repository) error {
+	policy, err := user.NewPolicy(cfg.PassLength, cfg.PassClasses, cfg.PassEntropy, cfg.LoginPattern)
+	if err != nil {
+		return fmt.Errorf("Activate: create policy failed %w", err)
+	}
+	s := user.NewUserService(ctx, repo, user.WithPolicy(policy))
 	newHandler(ctx, r, cfg, s)
+	return nil
 }
 
 // newHandler initializes handler for user.
@@ -42,6 +54,16 @@ func newHandler(ctx context.Context, r *chi.Mux, cfg *config.ServerConfig, s use
 	r.Post("/api/user/register", h.HandleRegister)
 	r.Post("/api/user/login", h.HandleLogin)
 	r.Post("/api/user/logout", h.HandleLogout)
+	r.Post("/api/user/token/refresh", h.HandleRefresh)
+	r.Post("/api/user/password", h.HandlePassword)
+	r.Delete("/api/user", h.HandleUnregister)
+	r.Get("/api/user/sessions", h.HandleSessions)
+	r.Delete("/api/user/sessions/{sessionID}", h.HandleSessionRevoke)
+	r.Post("/api/user/2fa/enroll", h.HandleTOTPEnroll)
+	r.Post("/api/user/2fa/confirm", h.HandleTOTPConfirm)
+	r.Delete("/api/user/2fa", h.HandleTOTPDisable)
+	r.Get("/api/user/salt", h.HandleSalt)
+	r.Put("/api/user/salt", h.HandleKeyCheck)
 }
 
 // HandleRegister registers new user.
@@ -70,7 +92,10 @@ func (h *UserHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	currentUser, err := h.Service.Register(ctx, &req)
 	if err != nil {
-		if errors.Is(err, errs.ErrLoginBusy) {
+		var policyErr *user.PolicyError
+		if errors.As(err, &policyErr) {
+			writeViolations(w, policyErr)
+		} else if errors.Is(err, errs.ErrLoginBusy) {
 			w.WriteHeader(http.StatusConflict)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -80,23 +105,21 @@ func (h *UserHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.Config.Token.Create(ctx, currentUser.ID)
+	sess, refresh, err := h.Service.CreateSession(ctx, newSession(r, currentUser.ID), h.Config.RefreshExp)
 	if err != nil {
-		logger.Log.Error("HandleRegister: create token failed",
+		logger.Log.Error("HandleRegister: create session failed",
 			zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	cookie := &http.Cookie{
-		Name:  "auth",
-		Value: token,
-		Path:  "/api/user/",
-		// Secure: true,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
+	err = h.setSessionCookies(ctx, w, sess, refresh)
+	if err != nil {
+		logger.Log.Error("HandleRegister: set session cookies failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	http.SetCookie(w, cookie)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -128,8 +151,11 @@ func (h *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, errs.ErrUserNotFound) {
 			w.WriteHeader(http.StatusNoContent)
-		} else if errors.Is(err, errs.ErrPasswordNotMatch) {
+		} else if errors.Is(err, errs.ErrPasswordNotMatch) || errors.Is(err, errs.ErrCodeInvalid) {
 			w.WriteHeader(http.StatusUnauthorized)
+		} else if errors.Is(err, errs.ErrCodeRequired) {
+			// The second step of login with the one-time code is required
+			w.WriteHeader(http.StatusAccepted)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
@@ -138,34 +164,544 @@ func (h *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.Config.Token.Create(ctx, currentUser.ID)
+	sess, refresh, err := h.Service.CreateSession(ctx, newSession(r, currentUser.ID), h.Config.RefreshExp)
 	if err != nil {
-		logger.Log.Error("HandleLogin: create token failed",
+		logger.Log.Error("HandleLogin: create session failed",
 			zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	cookie := &http.Cookie{
-		Name:  "auth",
-		Value: token,
-		Path:  "/api/user/",
-		// Secure:   true,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
+	err = h.setSessionCookies(ctx, w, sess, refresh)
+	if err != nil {
+		logger.Log.Error("HandleLogin: set session cookies failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	http.SetCookie(w, cookie)
 	w.WriteHeader(http.StatusOK)
 }
 
-// HandleLogout log user out from the service.
+// HandleLogout log user out from the service and revokes the session,
+// so its tokens are not accepted anymore.
 func (h *UserHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		logger.Log.Error("HandleLogout: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	sessionID, err := utils.GetSessionIDFromContext(ctx)
+	if err != nil {
+		logger.Log.Error("HandleLogout: get session id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Service.RevokeSession(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, errs.ErrSessionNotFound) {
+		logger.Log.Error("HandleLogout: revoke session failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.clearSessionCookies(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandlePassword changes the user's password, if the current password
+// from the request body is correct. All the other sessions of the user
+// are revoked, so other devices have to login with the new password.
+func (h *UserHandler) HandlePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandlePassword: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	sessionID, err := utils.GetSessionIDFromContext(ctx)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandlePassword: get session id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req user.PasswordChange
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.NewPassword == "" {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandlePassword: request unmarshal failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	err = h.Service.ChangePassword(ctx, userID, sessionID, &req)
+	if err != nil {
+		var policyErr *user.PolicyError
+		if errors.As(err, &policyErr) {
+			writeViolations(w, policyErr)
+		} else if errors.Is(err, errs.ErrPasswordNotMatch) {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandlePassword: change password failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUnregister deletes the user with all the user's data, if the password
+// and the one-time code from the request body are correct.
+func (h *UserHandler) HandleUnregister(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUnregister: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req user.User
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUnregister: request unmarshal failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	err = h.Service.DeleteUser(ctx, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrPasswordNotMatch) || errors.Is(err, errs.ErrCodeInvalid):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, errs.ErrCodeRequired):
+			// The one-time code is required for deleting the user
+			w.WriteHeader(http.StatusAccepted)
+		case errors.Is(err, errs.ErrUserNotFound):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleUnregister: delete user failed",
+			zap.Error(err))
+		return
+	}
+
+	h.clearSessionCookies(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleRefresh replaces the refresh token from the cookie with the new one
+// and issues new access token of the same session.
+func (h *UserHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie(refreshCookie)
+	if err != nil {
+		logger.Log.Error("HandleRefresh: get refresh cookie failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	sess, refresh, err := h.Service.RefreshSession(ctx, cookie.Value, h.Config.RefreshExp)
+	if err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) || errors.Is(err, errs.ErrSessionRevoked) {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.Error("HandleRefresh: refresh session failed",
+			zap.Error(err))
+		return
+	}
+
+	err = h.setSessionCookies(ctx, w, sess, refresh)
+	if err != nil {
+		logger.Log.Error("HandleRefresh: set session cookies failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleSalt writes the user's salt for deriving the client-side
+// encryption key and the key check value for verifying the derived key
+// into response body in JSON format.
+func (h *UserHandler) HandleSalt(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		logger.Log.Error("HandleSalt: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	storedUser, err := h.Service.GetSalt(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.Error("HandleSalt: get salt failed",
+			zap.Error(err))
+		return
+	}
+
+	resp, err := json.Marshal(map[string][]byte{
+		"salt":      storedUser.Salt,
+		"key_check": storedUser.KeyCheck,
+	})
+	if err != nil {
+		logger.Log.Error("HandleSalt: marshal salt failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleKeyCheck saves the key check value from the request body, which is
+// created by the client with the key derived from the master password.
+// The value is set only once, so the conflict is returned, if it is already set.
+func (h *UserHandler) HandleKeyCheck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleKeyCheck: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		KeyCheck []byte `json:"key_check"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleKeyCheck: request unmarshal failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	err = h.Service.SetKeyCheck(ctx, userID, req.KeyCheck)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrKeyCheckInvalid):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, errs.ErrKeyCheckExists):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, errs.ErrUserNotFound):
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleKeyCheck: set key check failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleSessions writes information about the active sessions of the user
+// into response body in JSON format, the session of the request is marked as current.
+func (h *UserHandler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSessions: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	sessionID, _ := utils.GetSessionIDFromContext(ctx)
+
+	sessions, err := h.Service.ListSessions(ctx, userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSessions: get sessions failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, sess := range sessions {
+		sess.Current = sess.ID == sessionID
+	}
+
+	resp, err := json.Marshal(sessions)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSessions: marshal sessions failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleSessionRevoke revokes the requested session of the user,
+// e.g. the session of the lost device.
+func (h *UserHandler) HandleSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionID := chi.URLParam(r, "sessionID")
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSessionRevoke: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.Service.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, errs.ErrSessionNotFound) {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleSessionRevoke: revoke session failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleTOTPEnroll generates new secret for two-factor authentication and writes
+// its otpauth URI into response body in JSON format.
+func (h *UserHandler) HandleTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPEnroll: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, err := h.Service.EnrollTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrTOTPEnabled) {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPEnroll: enroll two-factor authentication failed",
+			zap.Error(err))
+		return
+	}
+
+	resp, err := json.Marshal(enrollment)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPEnroll: marshal enrollment failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleTOTPConfirm enables two-factor authentication, if the one-time code
+// from the request body matches the enrolled secret, and writes recovery codes
+// into response body in JSON format.
+func (h *UserHandler) HandleTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPConfirm: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req user.User
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPConfirm: request unmarshal failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	codes, err := h.Service.ConfirmTOTP(ctx, userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrCodeInvalid):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, errs.ErrTOTPNotEnrolled), errors.Is(err, errs.ErrTOTPEnabled):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPConfirm: confirm two-factor authentication failed",
+			zap.Error(err))
+		return
+	}
+
+	resp, err := json.Marshal(map[string][]string{"recovery_codes": codes})
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPConfirm: marshal recovery codes failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// HandleTOTPDisable disables two-factor authentication, if the one-time code
+// or the recovery code from the request body is correct.
+func (h *UserHandler) HandleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := utils.GetUserIDFromContext(ctx)
+	idString := strconv.Itoa(userID)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPDisable: get user id from context failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var req user.User
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPDisable: request unmarshal failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	err = h.Service.DisableTOTP(ctx, userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrCodeInvalid):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, errs.ErrTOTPNotEnrolled):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		logger.Log.With(zap.String("user_id", idString)).Error("HandleTOTPDisable: disable two-factor authentication failed",
+			zap.Error(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// newSession returns the session of the user with information about
+// the client from the request.
+func newSession(r *http.Request, userID int) *user.Session {
+	return &user.Session{
+		UserID:    userID,
+		Device:    utils.GetDeviceFromRequest(r),
+		IP:        utils.GetIPFromRequest(r),
+		UserAgent: utils.GetUserAgentFromRequest(r),
+	}
+}
+
+// writeViolations writes the violated rules of the policy
+// into the response body with 400 Bad Request status.
+func writeViolations(w http.ResponseWriter, policyErr *user.PolicyError) {
+	resp, err := json.Marshal(policyErr)
+	if err != nil {
+		logger.Log.Error("writeViolations: marshal violations failed",
+			zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(resp)
+}
+
+// clearSessionCookies removes the cookies with access and refresh tokens.
+func (h *UserHandler) clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{authCookie, refreshCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/api/user",
+			Secure:   h.Config.TLSEnabled(),
+			HttpOnly: true,
+			MaxAge:   -1,
+		})
+	}
+}
+
+// setSessionCookies creates the access token of the session and sets cookies
+// with the access and refresh tokens.
+func (h *UserHandler) setSessionCookies(ctx context.Context, w http.ResponseWriter, sess *user.Session, refresh string) error {
+	token, err := h.Config.Token.Create(ctx, sess.UserID, sess.ID)
+	if err != nil {
+		return fmt.Errorf("setSessionCookies: create token failed %w", err)
+	}
+
 	http.SetCookie(w, &http.Cookie{
-		Name: "auth",
-		Path: "/api/user/",
-		// Secure:   true,
+		Name:     authCookie,
+		Value:    token,
+		Path:     "/api/user",
+		Secure:   h.Config.TLSEnabled(),
 		HttpOnly: true,
-		MaxAge:   -1,
+		SameSite: http.SameSiteLaxMode,
 	})
-	w.WriteHeader(http.StatusOK)
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookie,
+		Value:    refresh,
+		Path:     "/api/user",
+		Expires:  sess.ExpiresAt,
+		Secure:   h.Config.TLSEnabled(),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
 }
@@ -2,13 +2,56 @@
 // for user registration, login and authorization.
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // User contains information about user.
 type User struct {
 	ID       int    `db:"id" json:"id"`
 	Login    string `db:"login" json:"login"`
 	Password string `db:"password" json:"password"`
+	Salt     []byte `db:"salt" json:"-"`
+	KeyCheck []byte `db:"key_check" json:"-"`
+	Code     string `db:"-" json:"code,omitempty"`
+}
+
+// PasswordChange contains the current and the new passwords of the user.
+type PasswordChange struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// TOTP contains the user's secret for two-factor authentication
+// and the time step of the last accepted one-time code.
+type TOTP struct {
+	UserID   int    `db:"user_id" json:"-"`
+	Secret   []byte `db:"secret" json:"-"`
+	Enabled  bool   `db:"enabled" json:"enabled"`
+	LastStep int64  `db:"last_step" json:"-"`
+}
+
+// Enrollment contains the secret for setting up the authenticator application.
+type Enrollment struct {
+	URI    string `json:"uri"`
+	Secret string `json:"secret"`
+}
+
+// Session contains information about the user's login session and its client.
+// Only the hash of the session refresh token is stored.
+type Session struct {
+	ID          string     `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"-"`
+	RefreshHash []byte     `db:"refresh_hash" json:"-"`
+	Device      string     `db:"device" json:"device"`
+	IP          string     `db:"ip" json:"ip"`
+	UserAgent   string     `db:"user_agent" json:"user_agent"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt  time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"-"`
+	Current     bool       `db:"-" json:"current"`
 }
 
 // Service describes methods related with user
@@ -16,11 +59,41 @@ type User struct {
 type Service interface {
 	Register(ctx context.Context, user *User) (*User, error)
 	Login(ctx context.Context, user *User) (*User, error)
+	GetSalt(ctx context.Context, id int) (*User, error)
+	SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error
+	ChangePassword(ctx context.Context, userID int, sessionID string, change *PasswordChange) error
+	DeleteUser(ctx context.Context, userID int, u *User) error
+	CreateSession(ctx context.Context, sess *Session, exp time.Duration) (*Session, string, error)
+	ListSessions(ctx context.Context, userID int) ([]*Session, error)
+	RefreshSession(ctx context.Context, refresh string, exp time.Duration) (*Session, string, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	CheckSession(ctx context.Context, sessionID string) error
+	EnrollTOTP(ctx context.Context, userID int) (*Enrollment, error)
+	ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID int, code string) error
 }
 
 // Repository describes methods related with user
 // for communication between services and database.
 type Repository interface {
 	GetUserByLogin(ctx context.Context, login string) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error)
 	CreateUser(ctx context.Context, user *User) (*User, error)
+	UpdatePassword(ctx context.Context, id int, password string) error
+	SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error
+	DeleteUser(ctx context.Context, id int) error
+	CreateSession(ctx context.Context, sess *Session) error
+	GetSessionByID(ctx context.Context, id string) (*Session, error)
+	GetSessionByRefresh(ctx context.Context, refreshHash []byte) (*Session, error)
+	GetSessions(ctx context.Context, userID int) ([]*Session, error)
+	TouchSession(ctx context.Context, id string, lastUsedAt time.Time) error
+	RotateSession(ctx context.Context, sess *Session, oldHash []byte) error
+	RevokeSession(ctx context.Context, userID int, id string) error
+	RevokeOtherSessions(ctx context.Context, userID int, keepID string) error
+	GetTOTP(ctx context.Context, userID int) (*TOTP, error)
+	SetTOTP(ctx context.Context, t *TOTP) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	DeleteTOTP(ctx context.Context, userID int) error
+	SetRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID int, hash []byte) error
 }
@@ -0,0 +1,209 @@
+package user
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+)
+
+const (
+	// MaxLoginLength is the maximum length of the login stored in the database.
+	MaxLoginLength = 64
+	// MaxPasswordBytes is the maximum length of the password accepted by bcrypt.
+	MaxPasswordBytes = 72
+)
+
+// Rules of the registration policy.
+const (
+	RuleLoginLength     = "login_length"
+	RuleLoginFormat     = "login_format"
+	RulePasswordLength  = "password_length"
+	RulePasswordClasses = "password_classes"
+	RulePasswordEntropy = "password_entropy"
+	RulePasswordLogin   = "password_login"
+)
+
+// Violation contains the violated rule of the policy and its explanation.
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError contains the violated rules of the policy.
+type PolicyError struct {
+	Violations []*Violation `json:"violations"`
+}
+
+// Error returns the explanations of the violated rules.
+func (e *PolicyError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Message)
+	}
+	return errs.ErrPolicyViolation.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+// Is reports that the error is ErrPolicyViolation.
+func (e *PolicyError) Is(target error) bool {
+	return target == errs.ErrPolicyViolation
+}
+
+// Policy contains the rules for logins and passwords of the users.
+// The password must contain at least MinClasses of lowercase letters,
+// uppercase letters, digits and other symbols, and its estimated
+// entropy in bits must be at least MinEntropy.
+type Policy struct {
+	MinLength  int
+	MinClasses int
+	MinEntropy float64
+	Login      *regexp.Regexp
+}
+
+// NewPolicy creates and returns new policy, the login must match the pattern.
+func NewPolicy(minLength int, minClasses int, minEntropy float64, loginPattern string) (*Policy, error) {
+	login, err := regexp.Compile(loginPattern)
+	if err != nil {
+		return nil, fmt.Errorf("NewPolicy: compile login pattern failed %w", err)
+	}
+	return &Policy{
+		MinLength:  minLength,
+		MinClasses: minClasses,
+		MinEntropy: minEntropy,
+		Login:      login,
+	}, nil
+}
+
+// Check checks the login and the password of the new user,
+// PolicyError is returned if any rules are violated.
+func (p *Policy) Check(login string, password string) error {
+	violations := p.checkLogin(login)
+	violations = append(violations, p.checkPassword(login, password)...)
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// CheckPassword checks the new password of the existing user.
+func (p *Policy) CheckPassword(login string, password string) error {
+	violations := p.checkPassword(login, password)
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// checkLogin returns the violated rules for the login.
+func (p *Policy) checkLogin(login string) []*Violation {
+	violations := make([]*Violation, 0)
+	length := utf8.RuneCountInString(login)
+	if length == 0 || length > MaxLoginLength {
+		violations = append(violations, &Violation{
+			Field:   "login",
+			Rule:    RuleLoginLength,
+			Message: fmt.Sprintf("login must contain from 1 to %d characters", MaxLoginLength),
+		})
+	}
+	if p.Login != nil && !p.Login.MatchString(login) {
+		violations = append(violations, &Violation{
+			Field:   "login",
+			Rule:    RuleLoginFormat,
+			Message: fmt.Sprintf("login must match %s", p.Login),
+		})
+	}
+	return violations
+}
+
+// checkPassword returns the violated rules for the password.
+func (p *Policy) checkPassword(login string, password string) []*Violation {
+	violations := make([]*Violation, 0)
+	if utf8.RuneCountInString(password) < p.MinLength || len(password) > MaxPasswordBytes {
+		violations = append(violations, &Violation{
+			Field:   "password",
+			Rule:    RulePasswordLength,
+			Message: fmt.Sprintf("password must contain from %d characters to %d bytes", p.MinLength, MaxPasswordBytes),
+		})
+	}
+	if classes, _ := charClasses(password); classes < p.MinClasses {
+		violations = append(violations, &Violation{
+			Field:   "password",
+			Rule:    RulePasswordClasses,
+			Message: fmt.Sprintf("password must contain at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses),
+		})
+	}
+	if Entropy(password) < p.MinEntropy {
+		violations = append(violations, &Violation{
+			Field:   "password",
+			Rule:    RulePasswordEntropy,
+			Message: "password is too easy to guess, use longer password with less repeated characters",
+		})
+	}
+	if login != "" && strings.Contains(strings.ToLower(password), strings.ToLower(login)) {
+		violations = append(violations, &Violation{
+			Field:   "password",
+			Rule:    RulePasswordLogin,
+			Message: "password must not contain the login",
+		})
+	}
+	return violations
+}
+
+// Entropy returns the estimated entropy of the password in bits. Every character adds
+// the entropy of the alphabet made of the character classes used in the password,
+// repeated characters add half of it.
+func Entropy(password string) float64 {
+	_, alphabet := charClasses(password)
+	if alphabet == 0 {
+		return 0
+	}
+
+	length := 0.0
+	seen := make(map[rune]bool)
+	for _, r := range password {
+		if seen[r] {
+			length += 0.5
+			continue
+		}
+		seen[r] = true
+		length++
+	}
+	return length * math.Log2(float64(alphabet))
+}
+
+// charClasses returns the number of character classes used in the password
+// and the size of the alphabet made of these classes.
+func charClasses(password string) (int, int) {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	classes, alphabet := 0, 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			classes++
+			alphabet += c.size
+		}
+	}
+	return classes, alphabet
+}
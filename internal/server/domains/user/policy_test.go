@@ -0,0 +1,85 @@
+package user
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	p, err := NewPolicy(8, 2, 40, `^[A-Za-z0-9][A-Za-z0-9._@-]{2,63}$`)
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		login     string
+		password  string
+		wantRules []string
+	}{
+		{
+			name:      "ok",
+			login:     "gopher",
+			password:  "Correct-Horse7",
+			wantRules: nil,
+		},
+		{
+			name:      "empty",
+			login:     "",
+			password:  "",
+			wantRules: []string{RuleLoginLength, RuleLoginFormat, RulePasswordLength, RulePasswordClasses, RulePasswordEntropy},
+		},
+		{
+			name:      "long_login",
+			login:     strings.Repeat("a", MaxLoginLength+1),
+			password:  "Correct-Horse7",
+			wantRules: []string{RuleLoginLength, RuleLoginFormat},
+		},
+		{
+			name:      "one_class",
+			login:     "gopher",
+			password:  "qwertyuiop",
+			wantRules: []string{RulePasswordClasses},
+		},
+		{
+			name:      "repeated",
+			login:     "gopher",
+			password:  "aaaaaaaaaaaa1",
+			wantRules: []string{RulePasswordEntropy},
+		},
+		{
+			name:      "contains_login",
+			login:     "gopher",
+			password:  "my-Gopher-2024",
+			wantRules: []string{RulePasswordLogin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.login, tt.password)
+			if tt.wantRules == nil {
+				if err != nil {
+					t.Errorf("Policy.Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, errs.ErrPolicyViolation) {
+				t.Fatalf("Policy.Check() error = %v, want %v", err, errs.ErrPolicyViolation)
+			}
+			var policyErr *PolicyError
+			errors.As(err, &policyErr)
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("Policy.Check() rules = %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+)
+
+// recoveryCode contains the hash of the recovery code and the time of its use.
+type recoveryCode struct {
+	hash   []byte
+	usedAt *time.Time
+}
+
+// MemoryRepository contains the users, their sessions and secrets kept in memory,
+// they are lost when the server is stopped.
+type MemoryRepository struct {
+	mu       sync.Mutex
+	lastID   int
+	users    map[int]*user.User
+	sessions map[string]*user.Session
+	totp     map[int]*user.TOTP
+	codes    map[int][]*recoveryCode
+	cascade  []func(userID int)
+}
+
+// NewMemoryUserRepository returns new repository object in memory. The cascade
+// functions are called after the user is deleted, so they delete the user's objects
+// from the other repositories in memory like the database cascade does.
+func NewMemoryUserRepository(ctx context.Context, cascade ...func(userID int)) *MemoryRepository {
+	return &MemoryRepository{
+		users:    make(map[int]*user.User),
+		sessions: make(map[string]*user.Session),
+		totp:     make(map[int]*user.TOTP),
+		codes:    make(map[int][]*recoveryCode),
+		cascade:  cascade,
+	}
+}
+
+// GetUserByLogin gets by login from the storage and returns user object.
+func (r *MemoryRepository) GetUserByLogin(ctx context.Context, login string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Login == login {
+			return copyUser(u), nil
+		}
+	}
+	return nil, fmt.Errorf("GetUserByLogin: %w", errs.ErrUserNotFound)
+}
+
+// GetUserByID gets user by id from the storage and returns user object.
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id int) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("GetUserByID: %w", errs.ErrUserNotFound)
+	}
+	return copyUser(u), nil
+}
+
+// CreateUser saves new user data into the storage and returns user object.
+func (r *MemoryRepository) CreateUser(ctx context.Context, u *user.User) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.users {
+		if stored.Login == u.Login {
+			return nil, fmt.Errorf("CreateUser: %w", errs.ErrLoginBusy)
+		}
+	}
+
+	r.lastID++
+	stored := &user.User{
+		ID:       r.lastID,
+		Login:    u.Login,
+		Password: u.Password,
+		Salt:     bytes.Clone(u.Salt),
+	}
+	r.users[stored.ID] = stored
+	return copyUser(stored), nil
+}
+
+// UpdatePassword replaces the hash of the user's password in the storage.
+func (r *MemoryRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return fmt.Errorf("UpdatePassword: %w", errs.ErrUserNotFound)
+	}
+	u.Password = password
+	return nil
+}
+
+// SetKeyCheck saves the key check value of the user into the storage,
+// the value is set only once, so it isn't replaced by the wrong one.
+func (r *MemoryRepository) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return fmt.Errorf("SetKeyCheck: %w", errs.ErrUserNotFound)
+	}
+	if u.KeyCheck != nil {
+		return fmt.Errorf("SetKeyCheck: %w", errs.ErrKeyCheckExists)
+	}
+	u.KeyCheck = bytes.Clone(keyCheck)
+	return nil
+}
+
+// DeleteUser deletes the user with the sessions and secrets from the storage
+// and calls the cascade functions for deleting the user's data.
+func (r *MemoryRepository) DeleteUser(ctx context.Context, id int) error {
+	r.mu.Lock()
+	if _, ok := r.users[id]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("DeleteUser: %w", errs.ErrUserNotFound)
+	}
+	delete(r.users, id)
+	for sid, sess := range r.sessions {
+		if sess.UserID == id {
+			delete(r.sessions, sid)
+		}
+	}
+	delete(r.totp, id)
+	delete(r.codes, id)
+	r.mu.Unlock()
+
+	for _, fn := range r.cascade {
+		fn(id)
+	}
+	return nil
+}
+
+// CreateSession saves new session into the storage.
+func (r *MemoryRepository) CreateSession(ctx context.Context, sess *user.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[sess.UserID]; !ok {
+		return fmt.Errorf("CreateSession: %w", errs.ErrUserNotFound)
+	}
+	for _, stored := range r.sessions {
+		if stored.ID == sess.ID || bytes.Equal(stored.RefreshHash, sess.RefreshHash) {
+			return fmt.Errorf("CreateSession: session already exists")
+		}
+	}
+	r.sessions[sess.ID] = copySession(sess)
+	return nil
+}
+
+// GetSessionByID gets session by id from the storage.
+func (r *MemoryRepository) GetSessionByID(ctx context.Context, id string) (*user.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sess, ok := r.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("GetSessionByID: %w", errs.ErrSessionNotFound)
+	}
+	return copySession(sess), nil
+}
+
+// GetSessionByRefresh gets session by the hash of its refresh token from the storage.
+func (r *MemoryRepository) GetSessionByRefresh(ctx context.Context, refreshHash []byte) (*user.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sess := range r.sessions {
+		if bytes.Equal(sess.RefreshHash, refreshHash) {
+			return copySession(sess), nil
+		}
+	}
+	return nil, fmt.Errorf("GetSessionByRefresh: %w", errs.ErrSessionNotFound)
+}
+
+// RotateSession replaces the refresh token hash and the times of the session,
+// if the session still has the old refresh token hash and is not revoked.
+func (r *MemoryRepository) RotateSession(ctx context.Context, sess *user.Session, oldHash []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.sessions[sess.ID]
+	if !ok || stored.RevokedAt != nil || !bytes.Equal(stored.RefreshHash, oldHash) {
+		return fmt.Errorf("RotateSession: %w", errs.ErrSessionNotFound)
+	}
+	stored.RefreshHash = bytes.Clone(sess.RefreshHash)
+	stored.LastUsedAt = sess.LastUsedAt
+	stored.ExpiresAt = sess.ExpiresAt
+	return nil
+}
+
+// RevokeSession marks the session of the user as revoked.
+func (r *MemoryRepository) RevokeSession(ctx context.Context, userID int, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sess, ok := r.sessions[id]
+	if !ok || sess.UserID != userID || sess.RevokedAt != nil {
+		return fmt.Errorf("RevokeSession: %w", errs.ErrSessionNotFound)
+	}
+	now := time.Now()
+	sess.RevokedAt = &now
+	return nil
+}
+
+// RevokeOtherSessions marks all the sessions of the user except the kept one as revoked.
+func (r *MemoryRepository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, sess := range r.sessions {
+		if sess.UserID == userID && sess.ID != keepID && sess.RevokedAt == nil {
+			sess.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// GetSessions gets all the not revoked sessions of the user from the storage.
+func (r *MemoryRepository) GetSessions(ctx context.Context, userID int) ([]*user.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions := make([]*user.Session, 0)
+	for _, sess := range r.sessions {
+		if sess.UserID == userID && sess.RevokedAt == nil {
+			sessions = append(sessions, copySession(sess))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// TouchSession sets the time of the last use of the session.
+func (r *MemoryRepository) TouchSession(ctx context.Context, id string, lastUsedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sess, ok := r.sessions[id]; ok {
+		sess.LastUsedAt = lastUsedAt
+	}
+	return nil
+}
+
+// GetTOTP gets the user's secret for two-factor authentication from the storage.
+func (r *MemoryRepository) GetTOTP(ctx context.Context, userID int) (*user.TOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.totp[userID]
+	if !ok {
+		return nil, fmt.Errorf("GetTOTP: %w", errs.ErrTOTPNotEnrolled)
+	}
+	stored := *t
+	stored.Secret = bytes.Clone(t.Secret)
+	return &stored, nil
+}
+
+// SetTOTP saves the user's secret for two-factor authentication into the storage.
+func (r *MemoryRepository) SetTOTP(ctx context.Context, t *user.TOTP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[t.UserID]; !ok {
+		return fmt.Errorf("SetTOTP: %w", errs.ErrUserNotFound)
+	}
+	stored := *t
+	stored.Secret = bytes.Clone(t.Secret)
+	r.totp[t.UserID] = &stored
+	return nil
+}
+
+// UseTOTPStep saves the time step of the accepted one-time code, so the codes
+// of this and previous steps are not accepted anymore.
+func (r *MemoryRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.totp[userID]
+	if !ok || t.LastStep >= step {
+		return fmt.Errorf("UseTOTPStep: %w", errs.ErrCodeInvalid)
+	}
+	t.LastStep = step
+	return nil
+}
+
+// DeleteTOTP deletes the user's secret and recovery codes from the storage.
+func (r *MemoryRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.totp, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+// SetRecoveryCodes replaces the user's recovery codes in the storage.
+func (r *MemoryRepository) SetRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make([]*recoveryCode, 0, len(hashes))
+	for _, h := range hashes {
+		codes = append(codes, &recoveryCode{hash: bytes.Clone(h)})
+	}
+	r.codes[userID] = codes
+	return nil
+}
+
+// UseRecoveryCode marks the user's recovery code as used.
+func (r *MemoryRepository) UseRecoveryCode(ctx context.Context, userID int, hash []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.codes[userID] {
+		if c.usedAt == nil && bytes.Equal(c.hash, hash) {
+			now := time.Now()
+			c.usedAt = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("UseRecoveryCode: %w", errs.ErrCodeInvalid)
+}
+
+// copyUser returns the copy of the stored user, so the stored user
+// is not changed by the caller.
+func copyUser(u *user.User) *user.User {
+	c := *u
+	c.Salt = bytes.Clone(u.Salt)
+	c.KeyCheck = bytes.Clone(u.KeyCheck)
+	return &c
+}
+
+// copySession returns the copy of the session.
+func copySession(sess *user.Session) *user.Session {
+	c := *sess
+	c.RefreshHash = bytes.Clone(sess.RefreshHash)
+	if sess.RevokedAt != nil {
+		revokedAt := *sess.RevokedAt
+		c.RevokedAt = &revokedAt
+	}
+	return &c
+}
@@ -7,31 +7,48 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
 	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
 	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 )
 
 // Repository contains storage objects.
 type Repository struct {
-	db *sql.DB
+	db          *sql.DB
+	deleteBlobs func(ctx context.Context, keys []string) error
 }
 
-// NewUserRepository returns new repository object.
-func NewUserRepository(ctx context.Context, db *sql.DB) *Repository {
-	return &Repository{
+// Option describes the function for setting repository options.
+type Option func(r *Repository)
+
+// WithBlobCleanup sets the function, which deletes the blobs with the keys from
+// the blob storage, if they are not used anymore. It is called with the keys
+// of the user's binary values after the user is deleted.
+func WithBlobCleanup(deleteBlobs func(ctx context.Context, keys []string) error) Option {
+	return func(r *Repository) {
+		r.deleteBlobs = deleteBlobs
+	}
+}
+
+// NewUserRepository returns new repository object in PostgreSQL or SQLite database.
+func NewUserRepository(ctx context.Context, db *sql.DB, opts ...Option) *Repository {
+	r := &Repository{
 		db: db,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // GetUserByLogin gets by login from the storage and returns user object.
 func (r *Repository) GetUserByLogin(ctx context.Context, login string) (*user.User, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id, login, password FROM users WHERE login = $1`, login)
+	row := r.db.QueryRowContext(ctx, `SELECT id, login, password, salt, key_check FROM users WHERE login = $1`, login)
 
 	var storedUser user.User
-	err := row.Scan(&storedUser.ID, &storedUser.Login, &storedUser.Password)
+	err := row.Scan(&storedUser.ID, &storedUser.Login, &storedUser.Password, &storedUser.Salt, &storedUser.KeyCheck)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("GetUserByLogin: scan row failed %w", errs.ErrUserNotFound)
 	}
@@ -47,16 +64,36 @@ func (r *Repository) GetUserByLogin(ctx context.Context, login string) (*user.Us
 	return &storedUser, nil
 }
 
+// GetUserByID gets user by id from the storage and returns user object.
+func (r *Repository) GetUserByID(ctx context.Context, id int) (*user.User, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, login, password, salt, key_check FROM users WHERE id = $1`, id)
+
+	var storedUser user.User
+	err := row.Scan(&storedUser.ID, &storedUser.Login, &storedUser.Password, &storedUser.Salt, &storedUser.KeyCheck)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("GetUserByID: scan row failed %w", errs.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetUserByID: scan row failed %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetUserByID: row.Err %w", err)
+	}
+
+	return &storedUser, nil
+}
+
 // CreateUser saves new user data into the storage and returns user object.
 func (r *Repository) CreateUser(ctx context.Context, u *user.User) (*user.User, error) {
-	row := r.db.QueryRowContext(ctx, `INSERT INTO users (login, password) VALUES ($1, $2) 
-	RETURNING id, login, password`, u.Login, u.Password)
+	row := r.db.QueryRowContext(ctx, `INSERT INTO users (login, password, salt) VALUES ($1, $2, $3) 
+	RETURNING id, login, password, salt`, u.Login, u.Password, u.Salt)
 
 	var storedUser user.User
-	err := row.Scan(&storedUser.ID, &storedUser.Login, &storedUser.Password)
+	err := row.Scan(&storedUser.ID, &storedUser.Login, &storedUser.Password, &storedUser.Salt)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		if database.IsUniqueViolation(err) {
 			return nil, fmt.Errorf("CreateUser: %w", errs.ErrLoginBusy)
 		}
 		return nil, fmt.Errorf("CreateUser: insert into table failed %w", err)
@@ -69,3 +106,381 @@ func (r *Repository) CreateUser(ctx context.Context, u *user.User) (*user.User,
 
 	return &storedUser, nil
 }
+
+// UpdatePassword replaces the hash of the user's password in the storage.
+func (r *Repository) UpdatePassword(ctx context.Context, id int, password string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET password = $1 WHERE id = $2`, password, id)
+	if err != nil {
+		return fmt.Errorf("UpdatePassword: update user failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("UpdatePassword: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("UpdatePassword: %w", errs.ErrUserNotFound)
+	}
+	return nil
+}
+
+// SetKeyCheck saves the key check value of the user into the storage,
+// the value is set only once, so it isn't replaced by the wrong one.
+func (r *Repository) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET key_check = $1 WHERE id = $2 AND key_check IS NULL`,
+		keyCheck, id)
+	if err != nil {
+		return fmt.Errorf("SetKeyCheck: update user failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("SetKeyCheck: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		_, err = r.GetUserByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("SetKeyCheck: %w", err)
+		}
+		return fmt.Errorf("SetKeyCheck: %w", errs.ErrKeyCheckExists)
+	}
+	return nil
+}
+
+// DeleteUser deletes the user from the storage, the user's data,
+// sessions and secrets are deleted by the database cascade. The blobs
+// of the user's binary values are deleted after the user is deleted.
+func (r *Repository) DeleteUser(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteUser: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	var blobKeys []string
+	if r.deleteBlobs != nil {
+		blobKeys, err = getUserBlobKeys(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("DeleteUser: get blob keys failed %w", err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("DeleteUser: delete user failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteUser: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("DeleteUser: %w", errs.ErrUserNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DeleteUser: commit transaction failed %w", err)
+	}
+
+	// The user is already deleted, the blob which failed to be deleted is just left in the storage
+	if len(blobKeys) > 0 {
+		_ = r.deleteBlobs(ctx, blobKeys)
+	}
+
+	return nil
+}
+
+// getUserBlobKeys returns the keys of the blobs used by the user's data and its versions.
+func getUserBlobKeys(ctx context.Context, tx *sql.Tx, userID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT blob_key FROM data WHERE user_id = $1 AND blob_key IS NOT NULL 
+	UNION SELECT v.blob_key FROM data_versions v JOIN data d ON d.id = v.data_id 
+	WHERE d.user_id = $1 AND v.blob_key IS NOT NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("getUserBlobKeys: query rows failed %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, fmt.Errorf("getUserBlobKeys: scan row failed %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("getUserBlobKeys: rows.Err %w", err)
+	}
+
+	return keys, nil
+}
+
+// CreateSession saves new session into the storage.
+func (r *Repository) CreateSession(ctx context.Context, sess *user.Session) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO sessions (id, user_id, refresh_hash, device, ip, user_agent, 
+	created_at, last_used_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		sess.ID, sess.UserID, sess.RefreshHash, sess.Device, sess.IP, sess.UserAgent,
+		sess.CreatedAt, sess.LastUsedAt, sess.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("CreateSession: insert into table failed %w", err)
+	}
+	return nil
+}
+
+// GetSessionByID gets session by id from the storage.
+func (r *Repository) GetSessionByID(ctx context.Context, id string) (*user.Session, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, refresh_hash, device, ip, user_agent, 
+	created_at, last_used_at, expires_at, revoked_at FROM sessions WHERE id = $1`, id)
+
+	sess, err := scanSession(row)
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionByID: %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionByID: row.Err %w", err)
+	}
+	return sess, nil
+}
+
+// GetSessionByRefresh gets session by the hash of its refresh token from the storage.
+func (r *Repository) GetSessionByRefresh(ctx context.Context, refreshHash []byte) (*user.Session, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, refresh_hash, device, ip, user_agent, 
+	created_at, last_used_at, expires_at, revoked_at FROM sessions WHERE refresh_hash = $1`, refreshHash)
+
+	sess, err := scanSession(row)
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionByRefresh: %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetSessionByRefresh: row.Err %w", err)
+	}
+	return sess, nil
+}
+
+// RotateSession replaces the refresh token hash and the times of the session,
+// if the session still has the old refresh token hash and is not revoked.
+func (r *Repository) RotateSession(ctx context.Context, sess *user.Session, oldHash []byte) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE sessions SET refresh_hash = $1, last_used_at = $2, expires_at = $3 
+	WHERE id = $4 AND refresh_hash = $5 AND revoked_at IS NULL`,
+		sess.RefreshHash, sess.LastUsedAt, sess.ExpiresAt, sess.ID, oldHash)
+	if err != nil {
+		return fmt.Errorf("RotateSession: update session failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RotateSession: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("RotateSession: %w", errs.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// RevokeSession marks the session of the user as revoked.
+func (r *Repository) RevokeSession(ctx context.Context, userID int, id string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP 
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return fmt.Errorf("RevokeSession: update session failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RevokeSession: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("RevokeSession: %w", errs.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// RevokeOtherSessions marks all the sessions of the user except the kept one as revoked.
+func (r *Repository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP 
+	WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`, userID, keepID)
+	if err != nil {
+		return fmt.Errorf("RevokeOtherSessions: update sessions failed %w", err)
+	}
+	return nil
+}
+
+// GetSessions gets all the not revoked sessions of the user from the storage.
+func (r *Repository) GetSessions(ctx context.Context, userID int) ([]*user.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, refresh_hash, device, ip, user_agent, 
+	created_at, last_used_at, expires_at, revoked_at FROM sessions 
+	WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("GetSessions: read rows from table failed %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*user.Session, 0)
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("GetSessions: %w", err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetSessions: rows.Err %w", err)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession sets the time of the last use of the session.
+func (r *Repository) TouchSession(ctx context.Context, id string, lastUsedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE sessions SET last_used_at = $1 WHERE id = $2`, lastUsedAt, id)
+	if err != nil {
+		return fmt.Errorf("TouchSession: update session failed %w", err)
+	}
+	return nil
+}
+
+// rowScanner describes the row of the query result.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSession scans the row into the session object.
+func scanSession(row rowScanner) (*user.Session, error) {
+	var sess user.Session
+	var revokedAt sql.NullTime
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.RefreshHash, &sess.Device, &sess.IP, &sess.UserAgent,
+		&sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("scanSession: scan row failed %w", errs.ErrSessionNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanSession: scan row failed %w", err)
+	}
+	if revokedAt.Valid {
+		sess.RevokedAt = &revokedAt.Time
+	}
+	return &sess, nil
+}
+
+// GetTOTP gets the user's secret for two-factor authentication from the storage.
+func (r *Repository) GetTOTP(ctx context.Context, userID int) (*user.TOTP, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT user_id, secret, enabled, last_step FROM users_totp 
+	WHERE user_id = $1`, userID)
+
+	var t user.TOTP
+	err := row.Scan(&t.UserID, &t.Secret, &t.Enabled, &t.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("GetTOTP: scan row failed %w", errs.ErrTOTPNotEnrolled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetTOTP: scan row failed %w", err)
+	}
+
+	err = row.Err()
+	if err != nil {
+		return nil, fmt.Errorf("GetTOTP: row.Err %w", err)
+	}
+
+	return &t, nil
+}
+
+// SetTOTP saves the user's secret for two-factor authentication into the storage.
+func (r *Repository) SetTOTP(ctx context.Context, t *user.TOTP) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users_totp (user_id, secret, enabled, last_step) 
+	VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE 
+	SET secret = EXCLUDED.secret, enabled = EXCLUDED.enabled, last_step = EXCLUDED.last_step`,
+		t.UserID, t.Secret, t.Enabled, t.LastStep)
+	if err != nil {
+		return fmt.Errorf("SetTOTP: insert into table failed %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep saves the time step of the accepted one-time code, so the codes
+// of this and previous steps are not accepted anymore.
+func (r *Repository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users_totp SET last_step = $1 
+	WHERE user_id = $2 AND last_step < $1`, step, userID)
+	if err != nil {
+		return fmt.Errorf("UseTOTPStep: update table failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("UseTOTPStep: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("UseTOTPStep: %w", errs.ErrCodeInvalid)
+	}
+	return nil
+}
+
+// DeleteTOTP deletes the user's secret and recovery codes from the storage.
+func (r *Repository) DeleteTOTP(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteTOTP: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteTOTP: delete recovery codes failed %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM users_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteTOTP: delete secret failed %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("DeleteTOTP: commit transaction failed %w", err)
+	}
+	return nil
+}
+
+// SetRecoveryCodes replaces the user's recovery codes in the storage.
+func (r *Repository) SetRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SetRecoveryCodes: begin transaction failed %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("SetRecoveryCodes: delete recovery codes failed %w", err)
+	}
+	for _, h := range hashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h)
+		if err != nil {
+			return fmt.Errorf("SetRecoveryCodes: insert into table failed %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("SetRecoveryCodes: commit transaction failed %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the user's recovery code as used.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int, hash []byte) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP 
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("UseRecoveryCode: update table failed %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("UseRecoveryCode: get affected rows failed %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("UseRecoveryCode: %w", errs.ErrCodeInvalid)
+	}
+	return nil
+}
@@ -5,8 +5,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 )
 
 func TestNewUserRepository(t *testing.T) {
@@ -39,3 +45,47 @@ func TestNewUserRepository(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.InitSQLite(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("InitSQLite() error = %v", err)
+	}
+	defer db.Close()
+
+	var deleted []string
+	r := NewUserRepository(ctx, db, WithBlobCleanup(func(ctx context.Context, keys []string) error {
+		deleted = append(deleted, keys...)
+		return nil
+	}))
+
+	_, err = db.ExecContext(ctx, `INSERT INTO users (id, login, password, salt) VALUES (1, 'alice', 'hash', 'salt')`)
+	if err != nil {
+		t.Fatalf("insert user error = %v", err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO data (id, user_id, name, data_type, blob_key) 
+	VALUES (1, 1, 'file', 'binary', 'current')`)
+	if err != nil {
+		t.Fatalf("insert data error = %v", err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO data_versions (data_id, version, blob_key) 
+	VALUES (1, 1, 'previous'), (1, 2, 'current')`)
+	if err != nil {
+		t.Fatalf("insert data versions error = %v", err)
+	}
+
+	err = r.DeleteUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("Repository.DeleteUser() error = %v", err)
+	}
+	sort.Strings(deleted)
+	if want := []string{"current", "previous"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Repository.DeleteUser() deleted blobs = %v, want %v", deleted, want)
+	}
+
+	err = r.DeleteUser(ctx, 1)
+	if !errors.Is(err, errs.ErrUserNotFound) {
+		t.Errorf("Repository.DeleteUser() repeated error = %v, want %v", err, errs.ErrUserNotFound)
+	}
+}
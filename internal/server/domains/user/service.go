@@ -2,31 +2,100 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/pavlegich/gophkeeper/internal/common/infra/encryption"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/totp"
 	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// SessionTouchPeriod is the accuracy of the session last use time.
+const SessionTouchPeriod = time.Minute
+
+// TOTPIssuer is the issuer name shown in authenticator applications.
+const TOTPIssuer = "GophKeeper"
+
+// RecoveryCodesCount is the number of recovery codes issued
+// on enabling two-factor authentication.
+const RecoveryCodesCount = 10
+
+// Clock describes the source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock returns the system time.
+type realClock struct{}
+
+// Now returns the current system time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
 // UserService contatins objects for user service.
 type UserService struct {
-	repo Repository
+	repo   Repository
+	clock  Clock
+	policy *Policy
+}
+
+// Option describes the function for setting user service options.
+type Option func(s *UserService)
+
+// WithClock sets the source of the current time, e.g. for tests.
+func WithClock(c Clock) Option {
+	return func(s *UserService) {
+		s.clock = c
+	}
+}
+
+// WithPolicy sets the policy for logins and passwords of the users,
+// logins and passwords are not checked without the policy.
+func WithPolicy(p *Policy) Option {
+	return func(s *UserService) {
+		s.policy = p
+	}
 }
 
 // NewUserService returns new user service.
-func NewUserService(ctx context.Context, repo Repository) *UserService {
-	return &UserService{
-		repo: repo,
+func NewUserService(ctx context.Context, repo Repository, opts ...Option) *UserService {
+	s := &UserService{
+		repo:  repo,
+		clock: realClock{},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Register validates, stores and returns new user.
 func (s *UserService) Register(ctx context.Context, user *User) (*User, error) {
+	if s.policy != nil {
+		err := s.policy.Check(user.Login, user.Password)
+		if err != nil {
+			return nil, fmt.Errorf("Register: %w", err)
+		}
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("Register: hash generate failed %w", err)
 	}
 	user.Password = string(hashedPassword)
+	user.Salt, err = encryption.GenerateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("Register: generate salt failed %w", err)
+	}
 	user, err = s.repo.CreateUser(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("Register: save user failed %w", err)
@@ -35,14 +104,391 @@ func (s *UserService) Register(ctx context.Context, user *User) (*User, error) {
 }
 
 // Login validates the obtained user credentials and returns stored user.
+// If two-factor authentication is enabled, the one-time code or
+// the recovery code is also required.
 func (s *UserService) Login(ctx context.Context, user *User) (*User, error) {
 	storedUser, err := s.repo.GetUserByLogin(ctx, user.Login)
 	if err != nil {
 		return nil, err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password))
+	err = s.authenticate(ctx, storedUser, user)
+	if err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+
+	return storedUser, nil
+}
+
+// ChangePassword checks the current password of the user, replaces it with the new one
+// and revokes all the sessions of the user except the current one.
+func (s *UserService) ChangePassword(ctx context.Context, userID int, sessionID string, change *PasswordChange) error {
+	storedUser, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: get user failed %w", err)
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(change.OldPassword))
+	if err != nil {
+		return fmt.Errorf("ChangePassword: %w", errs.ErrPasswordNotMatch)
+	}
+	if s.policy != nil {
+		err = s.policy.CheckPassword(storedUser.Login, change.NewPassword)
+		if err != nil {
+			return fmt.Errorf("ChangePassword: %w", err)
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(change.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: hash generate failed %w", err)
+	}
+	err = s.repo.UpdatePassword(ctx, userID, string(hashedPassword))
+	if err != nil {
+		return fmt.Errorf("ChangePassword: update password failed %w", err)
+	}
+
+	err = s.repo.RevokeOtherSessions(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("ChangePassword: revoke sessions failed %w", err)
+	}
+	return nil
+}
+
+// DeleteUser authenticates the user again with the password and, if two-factor
+// authentication is enabled, with the one-time code, and deletes the user
+// with all the user's data and sessions.
+func (s *UserService) DeleteUser(ctx context.Context, userID int, user *User) error {
+	storedUser, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteUser: get user failed %w", err)
+	}
+	err = s.authenticate(ctx, storedUser, user)
+	if err != nil {
+		return fmt.Errorf("DeleteUser: %w", err)
+	}
+
+	err = s.repo.DeleteUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteUser: delete user failed %w", err)
+	}
+	return nil
+}
+
+// authenticate checks the password of the stored user and, if two-factor
+// authentication is enabled, the one-time code or the recovery code.
+func (s *UserService) authenticate(ctx context.Context, storedUser *User, user *User) error {
+	err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password))
+	if err != nil {
+		return errs.ErrPasswordNotMatch
+	}
+
+	t, err := s.repo.GetTOTP(ctx, storedUser.ID)
+	if errors.Is(err, errs.ErrTOTPNotEnrolled) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("authenticate: get two-factor secret failed %w", err)
+	}
+	if !t.Enabled {
+		return nil
+	}
+	if user.Code == "" {
+		return fmt.Errorf("authenticate: %w", errs.ErrCodeRequired)
+	}
+	err = s.verifyCode(ctx, t, user.Code, true)
+	if err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	return nil
+}
+
+// GetSalt returns the user with the salt for deriving the encryption key
+// on the client side and the key check value for verifying the derived key.
+func (s *UserService) GetSalt(ctx context.Context, id int) (*User, error) {
+	storedUser, err := s.repo.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, errs.ErrPasswordNotMatch
+		return nil, fmt.Errorf("GetSalt: get user failed %w", err)
 	}
 	return storedUser, nil
 }
+
+// SetKeyCheck saves the key check value of the user, the value is created
+// by the client with the key derived from the master password. The value
+// is set only once, if the user has no key check value yet.
+func (s *UserService) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	if len(keyCheck) == 0 {
+		return fmt.Errorf("SetKeyCheck: %w", errs.ErrKeyCheckInvalid)
+	}
+	err := s.repo.SetKeyCheck(ctx, id, keyCheck)
+	if err != nil {
+		return fmt.Errorf("SetKeyCheck: set key check failed %w", err)
+	}
+	return nil
+}
+
+// CreateSession starts new login session of the user with the client
+// information from the sess and returns the session with the refresh token,
+// which is valid for the exp period.
+func (s *UserService) CreateSession(ctx context.Context, sess *Session, exp time.Duration) (*Session, string, error) {
+	id, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("CreateSession: generate session id failed %w", err)
+	}
+	refresh, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("CreateSession: generate refresh token failed %w", err)
+	}
+
+	now := s.clock.Now()
+	sess = &Session{
+		ID:          id,
+		UserID:      sess.UserID,
+		RefreshHash: refreshHash(refresh),
+		Device:      sess.Device,
+		IP:          sess.IP,
+		UserAgent:   sess.UserAgent,
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(exp),
+	}
+	err = s.repo.CreateSession(ctx, sess)
+	if err != nil {
+		return nil, "", fmt.Errorf("CreateSession: save session failed %w", err)
+	}
+
+	return sess, refresh, nil
+}
+
+// RefreshSession finds the active session by the refresh token, replaces
+// the refresh token with the new one and prolongs the session for the exp period.
+// Every refresh token can be used only once.
+func (s *UserService) RefreshSession(ctx context.Context, refresh string, exp time.Duration) (*Session, string, error) {
+	sess, err := s.repo.GetSessionByRefresh(ctx, refreshHash(refresh))
+	if err != nil {
+		return nil, "", fmt.Errorf("RefreshSession: get session failed %w", err)
+	}
+	if !sess.active(s.clock.Now()) {
+		return nil, "", fmt.Errorf("RefreshSession: %w", errs.ErrSessionRevoked)
+	}
+
+	newRefresh, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("RefreshSession: generate refresh token failed %w", err)
+	}
+
+	oldHash := sess.RefreshHash
+	now := s.clock.Now()
+	sess.RefreshHash = refreshHash(newRefresh)
+	sess.LastUsedAt = now
+	sess.ExpiresAt = now.Add(exp)
+	err = s.repo.RotateSession(ctx, sess, oldHash)
+	if err != nil {
+		return nil, "", fmt.Errorf("RefreshSession: rotate refresh token failed %w", err)
+	}
+
+	return sess, newRefresh, nil
+}
+
+// RevokeSession revokes the session of the user, tokens of the session
+// are not accepted after that.
+func (s *UserService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	err := s.repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("RevokeSession: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns active sessions of the user ordered by the last use.
+func (s *UserService) ListSessions(ctx context.Context, userID int) ([]*Session, error) {
+	sessions, err := s.repo.GetSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ListSessions: get sessions failed %w", err)
+	}
+
+	now := s.clock.Now()
+	active := make([]*Session, 0, len(sessions))
+	for _, sess := range sessions {
+		if sess.active(now) {
+			active = append(active, sess)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].LastUsedAt.After(active[j].LastUsedAt)
+	})
+
+	return active, nil
+}
+
+// CheckSession checks that the session is neither revoked nor expired
+// and updates the time of its last use.
+func (s *UserService) CheckSession(ctx context.Context, sessionID string) error {
+	sess, err := s.repo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("CheckSession: get session failed %w", err)
+	}
+	now := s.clock.Now()
+	if !sess.active(now) {
+		return fmt.Errorf("CheckSession: %w", errs.ErrSessionRevoked)
+	}
+
+	// The time of the last use is not updated on every request
+	if now.Sub(sess.LastUsedAt) > SessionTouchPeriod {
+		err = s.repo.TouchSession(ctx, sessionID, now)
+		if err != nil {
+			return fmt.Errorf("CheckSession: update last use failed %w", err)
+		}
+	}
+	return nil
+}
+
+// EnrollTOTP generates new secret for two-factor authentication of the user.
+// Two-factor authentication is enabled after confirming the secret with
+// the one-time code from the authenticator application.
+func (s *UserService) EnrollTOTP(ctx context.Context, userID int) (*Enrollment, error) {
+	storedUser, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("EnrollTOTP: get user failed %w", err)
+	}
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, errs.ErrTOTPNotEnrolled) {
+		return nil, fmt.Errorf("EnrollTOTP: get two-factor secret failed %w", err)
+	}
+	if t != nil && t.Enabled {
+		return nil, fmt.Errorf("EnrollTOTP: %w", errs.ErrTOTPEnabled)
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, fmt.Errorf("EnrollTOTP: generate secret failed %w", err)
+	}
+	err = s.repo.SetTOTP(ctx, &TOTP{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("EnrollTOTP: save secret failed %w", err)
+	}
+
+	return &Enrollment{
+		URI:    totp.URI(TOTPIssuer, storedUser.Login, secret),
+		Secret: totp.EncodeSecret(secret),
+	}, nil
+}
+
+// ConfirmTOTP checks the one-time code for the enrolled secret, enables
+// two-factor authentication and returns new recovery codes of the user.
+func (s *UserService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP: get two-factor secret failed %w", err)
+	}
+	if t.Enabled {
+		return nil, fmt.Errorf("ConfirmTOTP: %w", errs.ErrTOTPEnabled)
+	}
+	err = s.verifyCode(ctx, t, code, false)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP: %w", err)
+	}
+
+	codes := make([]string, 0, RecoveryCodesCount)
+	hashes := make([][]byte, 0, RecoveryCodesCount)
+	for i := 0; i < RecoveryCodesCount; i++ {
+		c, err := randomString(5, recoveryEncoding)
+		if err != nil {
+			return nil, fmt.Errorf("ConfirmTOTP: generate recovery code failed %w", err)
+		}
+		codes = append(codes, c[:4]+"-"+c[4:])
+		hashes = append(hashes, codeHash(c))
+	}
+	err = s.repo.SetRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP: save recovery codes failed %w", err)
+	}
+
+	t.Enabled = true
+	err = s.repo.SetTOTP(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("ConfirmTOTP: enable two-factor authentication failed %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP checks the one-time code or the recovery code and disables
+// two-factor authentication of the user.
+func (s *UserService) DisableTOTP(ctx context.Context, userID int, code string) error {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP: get two-factor secret failed %w", err)
+	}
+	err = s.verifyCode(ctx, t, code, t.Enabled)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP: %w", err)
+	}
+	err = s.repo.DeleteTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("DisableTOTP: delete two-factor secret failed %w", err)
+	}
+	return nil
+}
+
+// verifyCode checks the one-time code, every code can be used only once.
+// If recovery is true, the unused recovery code is also accepted.
+func (s *UserService) verifyCode(ctx context.Context, t *TOTP, code string, recovery bool) error {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Verify(t.Secret, code, s.clock.Now(), 1)
+		if !ok || step <= t.LastStep {
+			return fmt.Errorf("verifyCode: %w", errs.ErrCodeInvalid)
+		}
+		err := s.repo.UseTOTPStep(ctx, t.UserID, step)
+		if err != nil {
+			return fmt.Errorf("verifyCode: %w", err)
+		}
+		t.LastStep = step
+		return nil
+	}
+
+	if !recovery {
+		return fmt.Errorf("verifyCode: %w", errs.ErrCodeInvalid)
+	}
+	err := s.repo.UseRecoveryCode(ctx, t.UserID, codeHash(code))
+	if err != nil {
+		return fmt.Errorf("verifyCode: %w", err)
+	}
+	return nil
+}
+
+// active checks whether the session is neither revoked nor expired at the moment.
+func (sess *Session) active(now time.Time) bool {
+	return sess.RevokedAt == nil && now.Before(sess.ExpiresAt)
+}
+
+// refreshHash returns the hash of the refresh token for storing.
+func refreshHash(refresh string) []byte {
+	sum := sha256.Sum256([]byte(refresh))
+	return sum[:]
+}
+
+// codeHash returns the hash of the recovery code for storing.
+func codeHash(code string) []byte {
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
+
+// recoveryEncoding encodes random bytes of the recovery code, e.g. 'k3q7ma2x'.
+func recoveryEncoding(b []byte) string {
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+// randomString returns n random bytes encoded with the encode function.
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("randomString: %w", err)
+	}
+	return encode(b), nil
+}
@@ -24,7 +24,8 @@ func TestNewUserService(t *testing.T) {
 				repo: nil,
 			},
 			want: &UserService{
-				repo: nil,
+				repo:  nil,
+				clock: realClock{},
 			},
 		},
 	}
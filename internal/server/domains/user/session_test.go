@@ -0,0 +1,133 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+)
+
+// sessionsRepository is a repository stub that keeps sessions in memory.
+type sessionsRepository struct {
+	Repository
+	sessions map[string]*Session
+}
+
+func (r *sessionsRepository) CreateSession(ctx context.Context, sess *Session) error {
+	stored := *sess
+	r.sessions[sess.ID] = &stored
+	return nil
+}
+
+func (r *sessionsRepository) GetSessionByID(ctx context.Context, id string) (*Session, error) {
+	sess, ok := r.sessions[id]
+	if !ok {
+		return nil, errs.ErrSessionNotFound
+	}
+	stored := *sess
+	return &stored, nil
+}
+
+func (r *sessionsRepository) GetSessionByRefresh(ctx context.Context, refreshHash []byte) (*Session, error) {
+	for _, sess := range r.sessions {
+		if bytes.Equal(sess.RefreshHash, refreshHash) {
+			stored := *sess
+			return &stored, nil
+		}
+	}
+	return nil, errs.ErrSessionNotFound
+}
+
+func (r *sessionsRepository) GetSessions(ctx context.Context, userID int) ([]*Session, error) {
+	sessions := make([]*Session, 0)
+	for _, sess := range r.sessions {
+		if sess.UserID == userID && sess.RevokedAt == nil {
+			stored := *sess
+			sessions = append(sessions, &stored)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *sessionsRepository) TouchSession(ctx context.Context, id string, lastUsedAt time.Time) error {
+	r.sessions[id].LastUsedAt = lastUsedAt
+	return nil
+}
+
+func (r *sessionsRepository) RotateSession(ctx context.Context, sess *Session, oldHash []byte) error {
+	stored, ok := r.sessions[sess.ID]
+	if !ok || !bytes.Equal(stored.RefreshHash, oldHash) || stored.RevokedAt != nil {
+		return errs.ErrSessionNotFound
+	}
+	updated := *sess
+	r.sessions[sess.ID] = &updated
+	return nil
+}
+
+func (r *sessionsRepository) RevokeSession(ctx context.Context, userID int, id string) error {
+	stored, ok := r.sessions[id]
+	if !ok || stored.UserID != userID || stored.RevokedAt != nil {
+		return errs.ErrSessionNotFound
+	}
+	now := time.Now()
+	stored.RevokedAt = &now
+	return nil
+}
+
+func TestUserService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	s := NewUserService(ctx, &sessionsRepository{sessions: make(map[string]*Session)})
+
+	sess, refresh, err := s.CreateSession(ctx, &Session{UserID: 1, Device: "laptop"}, time.Hour)
+	if err != nil {
+		t.Fatalf("UserService.CreateSession() error = %v", err)
+	}
+	if err := s.CheckSession(ctx, sess.ID); err != nil {
+		t.Errorf("UserService.CheckSession() error = %v", err)
+	}
+
+	refreshed, newRefresh, err := s.RefreshSession(ctx, refresh, time.Hour)
+	if err != nil {
+		t.Fatalf("UserService.RefreshSession() error = %v", err)
+	}
+	if refreshed.ID != sess.ID || newRefresh == refresh {
+		t.Errorf("UserService.RefreshSession() = %v %v, want the same session with new refresh token",
+			refreshed.ID, newRefresh)
+	}
+	if _, _, err := s.RefreshSession(ctx, refresh, time.Hour); !errors.Is(err, errs.ErrSessionNotFound) {
+		t.Errorf("UserService.RefreshSession() used token error = %v, want %v", err, errs.ErrSessionNotFound)
+	}
+
+	sessions, err := s.ListSessions(ctx, 1)
+	if err != nil || len(sessions) != 1 || sessions[0].Device != "laptop" {
+		t.Errorf("UserService.ListSessions() = %v, error = %v, want one session of laptop", sessions, err)
+	}
+
+	if err := s.RevokeSession(ctx, 2, sess.ID); !errors.Is(err, errs.ErrSessionNotFound) {
+		t.Errorf("UserService.RevokeSession() another user error = %v, want %v", err, errs.ErrSessionNotFound)
+	}
+	if err := s.RevokeSession(ctx, 1, sess.ID); err != nil {
+		t.Fatalf("UserService.RevokeSession() error = %v", err)
+	}
+	if err := s.CheckSession(ctx, sess.ID); !errors.Is(err, errs.ErrSessionRevoked) {
+		t.Errorf("UserService.CheckSession() revoked error = %v, want %v", err, errs.ErrSessionRevoked)
+	}
+	if _, _, err := s.RefreshSession(ctx, newRefresh, time.Hour); !errors.Is(err, errs.ErrSessionRevoked) {
+		t.Errorf("UserService.RefreshSession() revoked error = %v, want %v", err, errs.ErrSessionRevoked)
+	}
+
+	expired, _, err := s.CreateSession(ctx, &Session{UserID: 1, Device: "phone"}, -time.Minute)
+	if err != nil {
+		t.Fatalf("UserService.CreateSession() error = %v", err)
+	}
+	if err := s.CheckSession(ctx, expired.ID); !errors.Is(err, errs.ErrSessionRevoked) {
+		t.Errorf("UserService.CheckSession() expired error = %v, want %v", err, errs.ErrSessionRevoked)
+	}
+	sessions, err = s.ListSessions(ctx, 1)
+	if err != nil || len(sessions) != 0 {
+		t.Errorf("UserService.ListSessions() = %v, error = %v, want no active sessions", sessions, err)
+	}
+}
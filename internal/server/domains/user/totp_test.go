@@ -0,0 +1,162 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/totp"
+	errs "github.com/pavlegich/gophkeeper/internal/server/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeClock is a clock which time is set by the test.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// totpRepository is a repository stub that keeps one user
+// and the user's two-factor secret in memory.
+type totpRepository struct {
+	Repository
+	user     *User
+	totp     *TOTP
+	recovery map[string]bool
+}
+
+func (r *totpRepository) GetUserByLogin(ctx context.Context, login string) (*User, error) {
+	if login != r.user.Login {
+		return nil, errs.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func (r *totpRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	if id != r.user.ID {
+		return nil, errs.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func (r *totpRepository) GetTOTP(ctx context.Context, userID int) (*TOTP, error) {
+	if r.totp == nil {
+		return nil, errs.ErrTOTPNotEnrolled
+	}
+	t := *r.totp
+	return &t, nil
+}
+
+func (r *totpRepository) SetTOTP(ctx context.Context, t *TOTP) error {
+	stored := *t
+	r.totp = &stored
+	return nil
+}
+
+func (r *totpRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	if step <= r.totp.LastStep {
+		return errs.ErrCodeInvalid
+	}
+	r.totp.LastStep = step
+	return nil
+}
+
+func (r *totpRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	r.totp = nil
+	r.recovery = nil
+	return nil
+}
+
+func (r *totpRepository) SetRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error {
+	r.recovery = make(map[string]bool)
+	for _, h := range hashes {
+		r.recovery[string(h)] = false
+	}
+	return nil
+}
+
+func (r *totpRepository) UseRecoveryCode(ctx context.Context, userID int, hash []byte) error {
+	used, ok := r.recovery[string(hash)]
+	if !ok || used {
+		return errs.ErrCodeInvalid
+	}
+	r.recovery[string(hash)] = true
+	return nil
+}
+
+func TestUserService_TOTP(t *testing.T) {
+	ctx := context.Background()
+	password, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password failed %v", err)
+	}
+	repo := &totpRepository{user: &User{ID: 1, Login: "user", Password: string(password)}}
+	clock := &fakeClock{now: time.Date(2024, time.February, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewUserService(ctx, repo, WithClock(clock))
+
+	login := func(code string) error {
+		_, err := s.Login(ctx, &User{Login: "user", Password: "password", Code: code})
+		return err
+	}
+
+	// Enrollment
+	enrollment, err := s.EnrollTOTP(ctx, 1)
+	if err != nil {
+		t.Fatalf("UserService.EnrollTOTP() error = %v", err)
+	}
+	if !bytes.Contains([]byte(enrollment.URI), []byte("secret="+enrollment.Secret)) {
+		t.Errorf("UserService.EnrollTOTP() uri = %v, want secret %v", enrollment.URI, enrollment.Secret)
+	}
+	if err := login(""); err != nil {
+		t.Errorf("UserService.Login() before confirmation error = %v", err)
+	}
+	if _, err := s.ConfirmTOTP(ctx, 1, "000000"); !errors.Is(err, errs.ErrCodeInvalid) {
+		t.Errorf("UserService.ConfirmTOTP() wrong code error = %v, want %v", err, errs.ErrCodeInvalid)
+	}
+	secret := repo.totp.Secret
+	codes, err := s.ConfirmTOTP(ctx, 1, totp.Code(secret, totp.Step(clock.now)))
+	if err != nil {
+		t.Fatalf("UserService.ConfirmTOTP() error = %v", err)
+	}
+	if len(codes) != RecoveryCodesCount {
+		t.Errorf("UserService.ConfirmTOTP() = %d recovery codes, want %d", len(codes), RecoveryCodesCount)
+	}
+	if _, err := s.EnrollTOTP(ctx, 1); !errors.Is(err, errs.ErrTOTPEnabled) {
+		t.Errorf("UserService.EnrollTOTP() enabled error = %v, want %v", err, errs.ErrTOTPEnabled)
+	}
+
+	// Second step of login
+	if err := login(""); !errors.Is(err, errs.ErrCodeRequired) {
+		t.Errorf("UserService.Login() without code error = %v, want %v", err, errs.ErrCodeRequired)
+	}
+	if err := login(totp.Code(secret, totp.Step(clock.now))); !errors.Is(err, errs.ErrCodeInvalid) {
+		t.Errorf("UserService.Login() used code error = %v, want %v", err, errs.ErrCodeInvalid)
+	}
+	clock.now = clock.now.Add(totp.Period)
+	if err := login(totp.Code(secret, totp.Step(clock.now))); err != nil {
+		t.Errorf("UserService.Login() next code error = %v", err)
+	}
+	clock.now = clock.now.Add(10 * totp.Period)
+	if err := login(totp.Code(secret, totp.Step(clock.now)-5)); !errors.Is(err, errs.ErrCodeInvalid) {
+		t.Errorf("UserService.Login() old code error = %v, want %v", err, errs.ErrCodeInvalid)
+	}
+	if err := login(codes[0]); err != nil {
+		t.Errorf("UserService.Login() recovery code error = %v", err)
+	}
+	if err := login(codes[0]); !errors.Is(err, errs.ErrCodeInvalid) {
+		t.Errorf("UserService.Login() used recovery code error = %v, want %v", err, errs.ErrCodeInvalid)
+	}
+
+	// Disabling
+	if err := s.DisableTOTP(ctx, 1, codes[1]); err != nil {
+		t.Fatalf("UserService.DisableTOTP() error = %v", err)
+	}
+	if err := login(""); err != nil {
+		t.Errorf("UserService.Login() after disabling error = %v", err)
+	}
+}
@@ -6,4 +6,15 @@ var (
 	ErrDataNotFound      = errors.New("data not found for this user")
 	ErrDataAlreadyUpload = errors.New("data already uploaded by this user")
 	ErrDataTypeIncorrect = errors.New("incorrect data type")
+	ErrDataVersionDiffer = errors.New("data version differs from the expected one")
+	ErrDataTooLarge      = errors.New("data is too large")
+	ErrUploadNotFound    = errors.New("upload not found for this user")
+	ErrUploadOffset      = errors.New("chunk offset differs from the uploaded size")
+	ErrUploadIncomplete  = errors.New("upload is not complete")
+	ErrChecksumMismatch  = errors.New("checksum of data differs from the expected one")
+	ErrFolderNotFound    = errors.New("folder not found for this user")
+	ErrFolderNameBusy    = errors.New("folder with this name already exists")
+	ErrFolderIncorrect   = errors.New("incorrect folder name or parent")
+	ErrTagIncorrect      = errors.New("incorrect tag")
+	ErrQueryIncorrect    = errors.New("incorrect search query")
 )
@@ -8,4 +8,13 @@ var (
 	ErrUserNotFound     = errors.New("user not found")
 	ErrPasswordNotMatch = errors.New("passwords do not match")
 	ErrUserUnauthorized = errors.New("user unauthorized")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrSessionRevoked   = errors.New("session is revoked or expired")
+	ErrCodeRequired     = errors.New("one-time code is required")
+	ErrCodeInvalid      = errors.New("one-time code is invalid")
+	ErrTOTPEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled  = errors.New("two-factor authentication is not enrolled")
+	ErrPolicyViolation  = errors.New("login or password violates the policy")
+	ErrKeyCheckExists   = errors.New("key check value is already set")
+	ErrKeyCheckInvalid  = errors.New("key check value is empty")
 )
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"google.golang.org/grpc"
+)
+
+// GRPCServer contains gRPC server attributes.
+type GRPCServer struct {
+	server *grpc.Server
+	config *config.ServerConfig
+}
+
+// NewGRPCServer returns new gRPC server object.
+func NewGRPCServer(ctx context.Context, gs *grpc.Server, cfg *config.ServerConfig) *GRPCServer {
+	return &GRPCServer{
+		server: gs,
+		config: cfg,
+	}
+}
+
+// GetAddress returns gRPC server's address.
+func (s *GRPCServer) GetAddress(ctx context.Context) string {
+	return s.config.GRPCAddress
+}
+
+// Serve starts listening the network by the gRPC server.
+func (s *GRPCServer) Serve(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.config.GRPCAddress)
+	if err != nil {
+		return fmt.Errorf("Serve: listen address failed %w", err)
+	}
+	return s.server.Serve(lis)
+}
+
+// Shutdown gracefully stops the gRPC server waiting for the active requests,
+// the remaining requests are cancelled when the context is done.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -35,6 +35,50 @@ func (m *MockDataService) EXPECT() *MockDataServiceMockRecorder {
 	return m.recorder
 }
 
+// AbortUpload mocks base method.
+func (m *MockDataService) AbortUpload(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortUpload", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortUpload indicates an expected call of AbortUpload.
+func (mr *MockDataServiceMockRecorder) AbortUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortUpload", reflect.TypeOf((*MockDataService)(nil).AbortUpload), ctx, id)
+}
+
+// Changes mocks base method.
+func (m *MockDataService) Changes(ctx context.Context, since int64, limit int) (*data.ChangeFeed, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", ctx, since, limit)
+	ret0, _ := ret[0].(*data.ChangeFeed)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockDataServiceMockRecorder) Changes(ctx, since, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockDataService)(nil).Changes), ctx, since, limit)
+}
+
+// CommitUpload mocks base method.
+func (m *MockDataService) CommitUpload(ctx context.Context, id string, version int, device string) (*data.Data, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitUpload", ctx, id, version, device)
+	ret0, _ := ret[0].(*data.Data)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitUpload indicates an expected call of CommitUpload.
+func (mr *MockDataServiceMockRecorder) CommitUpload(ctx, id, version, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitUpload", reflect.TypeOf((*MockDataService)(nil).CommitUpload), ctx, id, version, device)
+}
+
 // Create mocks base method.
 func (m *MockDataService) Create(ctx context.Context, data *data.Data) error {
 	m.ctrl.T.Helper()
@@ -49,32 +93,190 @@ func (mr *MockDataServiceMockRecorder) Create(ctx, data interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDataService)(nil).Create), ctx, data)
 }
 
+// CreateFolder mocks base method.
+func (m *MockDataService) CreateFolder(ctx context.Context, folder *data.Folder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFolder", ctx, folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFolder indicates an expected call of CreateFolder.
+func (mr *MockDataServiceMockRecorder) CreateFolder(ctx, folder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFolder", reflect.TypeOf((*MockDataService)(nil).CreateFolder), ctx, folder)
+}
+
 // Delete mocks base method.
-func (m *MockDataService) Delete(ctx context.Context, dType, name string) error {
+func (m *MockDataService) Delete(ctx context.Context, dType, name string, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, dType, name)
+	ret := m.ctrl.Call(m, "Delete", ctx, dType, name, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockDataServiceMockRecorder) Delete(ctx, dType, name interface{}) *gomock.Call {
+func (mr *MockDataServiceMockRecorder) Delete(ctx, dType, name, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDataService)(nil).Delete), ctx, dType, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDataService)(nil).Delete), ctx, dType, name, version)
+}
+
+// DeleteFolder mocks base method.
+func (m *MockDataService) DeleteFolder(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFolder", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFolder indicates an expected call of DeleteFolder.
+func (mr *MockDataServiceMockRecorder) DeleteFolder(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFolder", reflect.TypeOf((*MockDataService)(nil).DeleteFolder), ctx, id)
 }
 
 // Edit mocks base method.
-func (m *MockDataService) Edit(ctx context.Context, data *data.Data) error {
+func (m *MockDataService) Edit(ctx context.Context, data *data.Data, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Edit", ctx, data)
+	ret := m.ctrl.Call(m, "Edit", ctx, data, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Edit indicates an expected call of Edit.
-func (mr *MockDataServiceMockRecorder) Edit(ctx, data interface{}) *gomock.Call {
+func (mr *MockDataServiceMockRecorder) Edit(ctx, data, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockDataService)(nil).Edit), ctx, data, version)
+}
+
+// EditFolder mocks base method.
+func (m *MockDataService) EditFolder(ctx context.Context, folder *data.Folder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditFolder", ctx, folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EditFolder indicates an expected call of EditFolder.
+func (mr *MockDataServiceMockRecorder) EditFolder(ctx, folder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditFolder", reflect.TypeOf((*MockDataService)(nil).EditFolder), ctx, folder)
+}
+
+// Folders mocks base method.
+func (m *MockDataService) Folders(ctx context.Context) ([]*data.Folder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Folders", ctx)
+	ret0, _ := ret[0].([]*data.Folder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Folders indicates an expected call of Folders.
+func (mr *MockDataServiceMockRecorder) Folders(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Folders", reflect.TypeOf((*MockDataService)(nil).Folders), ctx)
+}
+
+// GetUpload mocks base method.
+func (m *MockDataService) GetUpload(ctx context.Context, id string) (*data.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", ctx, id)
+	ret0, _ := ret[0].(*data.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockDataServiceMockRecorder) GetUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockDataService)(nil).GetUpload), ctx, id)
+}
+
+// InitUpload mocks base method.
+func (m *MockDataService) InitUpload(ctx context.Context, upload *data.Upload) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitUpload", ctx, upload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitUpload indicates an expected call of InitUpload.
+func (mr *MockDataServiceMockRecorder) InitUpload(ctx, upload interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockDataService)(nil).Edit), ctx, data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitUpload", reflect.TypeOf((*MockDataService)(nil).InitUpload), ctx, upload)
+}
+
+// List mocks base method.
+func (m *MockDataService) List(ctx context.Context, filter data.Filter) ([]*data.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].([]*data.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDataServiceMockRecorder) List(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDataService)(nil).List), ctx, filter)
+}
+
+// Move mocks base method.
+func (m *MockDataService) Move(ctx context.Context, dType, name string, folderID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", ctx, dType, name, folderID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockDataServiceMockRecorder) Move(ctx, dType, name, folderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockDataService)(nil).Move), ctx, dType, name, folderID)
+}
+
+// Restore mocks base method.
+func (m *MockDataService) Restore(ctx context.Context, data *data.Data, version, expected int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, data, version, expected)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockDataServiceMockRecorder) Restore(ctx, data, version, expected interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockDataService)(nil).Restore), ctx, data, version, expected)
+}
+
+// Search mocks base method.
+func (m *MockDataService) Search(ctx context.Context, q string) ([]*data.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, q)
+	ret0, _ := ret[0].([]*data.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockDataServiceMockRecorder) Search(ctx, q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockDataService)(nil).Search), ctx, q)
+}
+
+// Tag mocks base method.
+func (m *MockDataService) Tag(ctx context.Context, dType, name, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tag", ctx, dType, name, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Tag indicates an expected call of Tag.
+func (mr *MockDataServiceMockRecorder) Tag(ctx, dType, name, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tag", reflect.TypeOf((*MockDataService)(nil).Tag), ctx, dType, name, tag)
 }
 
 // Unload mocks base method.
@@ -92,6 +294,65 @@ func (mr *MockDataServiceMockRecorder) Unload(ctx, dType, name interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unload", reflect.TypeOf((*MockDataService)(nil).Unload), ctx, dType, name)
 }
 
+// UnloadVersion mocks base method.
+func (m *MockDataService) UnloadVersion(ctx context.Context, dType, name string, version int) (*data.Data, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnloadVersion", ctx, dType, name, version)
+	ret0, _ := ret[0].(*data.Data)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnloadVersion indicates an expected call of UnloadVersion.
+func (mr *MockDataServiceMockRecorder) UnloadVersion(ctx, dType, name, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnloadVersion", reflect.TypeOf((*MockDataService)(nil).UnloadVersion), ctx, dType, name, version)
+}
+
+// Untag mocks base method.
+func (m *MockDataService) Untag(ctx context.Context, dType, name, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Untag", ctx, dType, name, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Untag indicates an expected call of Untag.
+func (mr *MockDataServiceMockRecorder) Untag(ctx, dType, name, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Untag", reflect.TypeOf((*MockDataService)(nil).Untag), ctx, dType, name, tag)
+}
+
+// Versions mocks base method.
+func (m *MockDataService) Versions(ctx context.Context, dType, name string) ([]*data.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Versions", ctx, dType, name)
+	ret0, _ := ret[0].([]*data.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Versions indicates an expected call of Versions.
+func (mr *MockDataServiceMockRecorder) Versions(ctx, dType, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Versions", reflect.TypeOf((*MockDataService)(nil).Versions), ctx, dType, name)
+}
+
+// WriteChunk mocks base method.
+func (m *MockDataService) WriteChunk(ctx context.Context, id string, offset int64, chunk, checksum []byte) (*data.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteChunk", ctx, id, offset, chunk, checksum)
+	ret0, _ := ret[0].(*data.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteChunk indicates an expected call of WriteChunk.
+func (mr *MockDataServiceMockRecorder) WriteChunk(ctx, id, offset, chunk, checksum interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteChunk", reflect.TypeOf((*MockDataService)(nil).WriteChunk), ctx, id, offset, chunk, checksum)
+}
+
 // MockDataRepository is a mock of Repository interface.
 type MockDataRepository struct {
 	ctrl     *gomock.Controller
@@ -115,6 +376,35 @@ func (m *MockDataRepository) EXPECT() *MockDataRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddDataTag mocks base method.
+func (m *MockDataRepository) AddDataTag(ctx context.Context, dType, name, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDataTag", ctx, dType, name, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDataTag indicates an expected call of AddDataTag.
+func (mr *MockDataRepositoryMockRecorder) AddDataTag(ctx, dType, name, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDataTag", reflect.TypeOf((*MockDataRepository)(nil).AddDataTag), ctx, dType, name, tag)
+}
+
+// AppendUploadChunk mocks base method.
+func (m *MockDataRepository) AppendUploadChunk(ctx context.Context, id string, offset int64, chunk []byte) (*data.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendUploadChunk", ctx, id, offset, chunk)
+	ret0, _ := ret[0].(*data.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AppendUploadChunk indicates an expected call of AppendUploadChunk.
+func (mr *MockDataRepositoryMockRecorder) AppendUploadChunk(ctx, id, offset, chunk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendUploadChunk", reflect.TypeOf((*MockDataRepository)(nil).AppendUploadChunk), ctx, id, offset, chunk)
+}
+
 // CreateData mocks base method.
 func (m *MockDataRepository) CreateData(ctx context.Context, data *data.Data) error {
 	m.ctrl.T.Helper()
@@ -129,18 +419,89 @@ func (mr *MockDataRepositoryMockRecorder) CreateData(ctx, data interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateData", reflect.TypeOf((*MockDataRepository)(nil).CreateData), ctx, data)
 }
 
+// CreateFolder mocks base method.
+func (m *MockDataRepository) CreateFolder(ctx context.Context, folder *data.Folder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFolder", ctx, folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFolder indicates an expected call of CreateFolder.
+func (mr *MockDataRepositoryMockRecorder) CreateFolder(ctx, folder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFolder", reflect.TypeOf((*MockDataRepository)(nil).CreateFolder), ctx, folder)
+}
+
+// CreateUpload mocks base method.
+func (m *MockDataRepository) CreateUpload(ctx context.Context, upload *data.Upload) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUpload", ctx, upload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUpload indicates an expected call of CreateUpload.
+func (mr *MockDataRepositoryMockRecorder) CreateUpload(ctx, upload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUpload", reflect.TypeOf((*MockDataRepository)(nil).CreateUpload), ctx, upload)
+}
+
 // DeleteDataByName mocks base method.
-func (m *MockDataRepository) DeleteDataByName(ctx context.Context, dType, name string) error {
+func (m *MockDataRepository) DeleteDataByName(ctx context.Context, dType, name string, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteDataByName", ctx, dType, name)
+	ret := m.ctrl.Call(m, "DeleteDataByName", ctx, dType, name, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteDataByName indicates an expected call of DeleteDataByName.
-func (mr *MockDataRepositoryMockRecorder) DeleteDataByName(ctx, dType, name interface{}) *gomock.Call {
+func (mr *MockDataRepositoryMockRecorder) DeleteDataByName(ctx, dType, name, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataByName", reflect.TypeOf((*MockDataRepository)(nil).DeleteDataByName), ctx, dType, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataByName", reflect.TypeOf((*MockDataRepository)(nil).DeleteDataByName), ctx, dType, name, version)
+}
+
+// DeleteFolder mocks base method.
+func (m *MockDataRepository) DeleteFolder(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFolder", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFolder indicates an expected call of DeleteFolder.
+func (mr *MockDataRepositoryMockRecorder) DeleteFolder(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFolder", reflect.TypeOf((*MockDataRepository)(nil).DeleteFolder), ctx, id)
+}
+
+// DeleteUpload mocks base method.
+func (m *MockDataRepository) DeleteUpload(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUpload", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUpload indicates an expected call of DeleteUpload.
+func (mr *MockDataRepositoryMockRecorder) DeleteUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUpload", reflect.TypeOf((*MockDataRepository)(nil).DeleteUpload), ctx, id)
+}
+
+// GetChanges mocks base method.
+func (m *MockDataRepository) GetChanges(ctx context.Context, since int64, limit int) ([]*data.Change, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChanges", ctx, since, limit)
+	ret0, _ := ret[0].([]*data.Change)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChanges indicates an expected call of GetChanges.
+func (mr *MockDataRepositoryMockRecorder) GetChanges(ctx, since, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChanges", reflect.TypeOf((*MockDataRepository)(nil).GetChanges), ctx, since, limit)
 }
 
 // GetDataByName mocks base method.
@@ -158,16 +519,177 @@ func (mr *MockDataRepositoryMockRecorder) GetDataByName(ctx, dType, name interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataByName", reflect.TypeOf((*MockDataRepository)(nil).GetDataByName), ctx, dType, name)
 }
 
+// GetDataList mocks base method.
+func (m *MockDataRepository) GetDataList(ctx context.Context, filter data.Filter) ([]*data.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataList", ctx, filter)
+	ret0, _ := ret[0].([]*data.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataList indicates an expected call of GetDataList.
+func (mr *MockDataRepositoryMockRecorder) GetDataList(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataList", reflect.TypeOf((*MockDataRepository)(nil).GetDataList), ctx, filter)
+}
+
+// GetDataVersion mocks base method.
+func (m *MockDataRepository) GetDataVersion(ctx context.Context, dType, name string, version int) (*data.Data, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataVersion", ctx, dType, name, version)
+	ret0, _ := ret[0].(*data.Data)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataVersion indicates an expected call of GetDataVersion.
+func (mr *MockDataRepositoryMockRecorder) GetDataVersion(ctx, dType, name, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataVersion", reflect.TypeOf((*MockDataRepository)(nil).GetDataVersion), ctx, dType, name, version)
+}
+
+// GetDataVersions mocks base method.
+func (m *MockDataRepository) GetDataVersions(ctx context.Context, dType, name string) ([]*data.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataVersions", ctx, dType, name)
+	ret0, _ := ret[0].([]*data.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataVersions indicates an expected call of GetDataVersions.
+func (mr *MockDataRepositoryMockRecorder) GetDataVersions(ctx, dType, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataVersions", reflect.TypeOf((*MockDataRepository)(nil).GetDataVersions), ctx, dType, name)
+}
+
+// GetFolders mocks base method.
+func (m *MockDataRepository) GetFolders(ctx context.Context) ([]*data.Folder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFolders", ctx)
+	ret0, _ := ret[0].([]*data.Folder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFolders indicates an expected call of GetFolders.
+func (mr *MockDataRepositoryMockRecorder) GetFolders(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFolders", reflect.TypeOf((*MockDataRepository)(nil).GetFolders), ctx)
+}
+
+// GetUpload mocks base method.
+func (m *MockDataRepository) GetUpload(ctx context.Context, id string) (*data.Upload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUpload", ctx, id)
+	ret0, _ := ret[0].(*data.Upload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUpload indicates an expected call of GetUpload.
+func (mr *MockDataRepositoryMockRecorder) GetUpload(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpload", reflect.TypeOf((*MockDataRepository)(nil).GetUpload), ctx, id)
+}
+
+// GetUploadContent mocks base method.
+func (m *MockDataRepository) GetUploadContent(ctx context.Context, upload *data.Upload) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUploadContent", ctx, upload)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUploadContent indicates an expected call of GetUploadContent.
+func (mr *MockDataRepositoryMockRecorder) GetUploadContent(ctx, upload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadContent", reflect.TypeOf((*MockDataRepository)(nil).GetUploadContent), ctx, upload)
+}
+
+// RemoveDataTag mocks base method.
+func (m *MockDataRepository) RemoveDataTag(ctx context.Context, dType, name, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveDataTag", ctx, dType, name, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveDataTag indicates an expected call of RemoveDataTag.
+func (mr *MockDataRepositoryMockRecorder) RemoveDataTag(ctx, dType, name, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDataTag", reflect.TypeOf((*MockDataRepository)(nil).RemoveDataTag), ctx, dType, name, tag)
+}
+
+// RestoreDataVersion mocks base method.
+func (m *MockDataRepository) RestoreDataVersion(ctx context.Context, data *data.Data, version, expected int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreDataVersion", ctx, data, version, expected)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreDataVersion indicates an expected call of RestoreDataVersion.
+func (mr *MockDataRepositoryMockRecorder) RestoreDataVersion(ctx, data, version, expected interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreDataVersion", reflect.TypeOf((*MockDataRepository)(nil).RestoreDataVersion), ctx, data, version, expected)
+}
+
+// SearchData mocks base method.
+func (m *MockDataRepository) SearchData(ctx context.Context, query *data.Query) ([]*data.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchData", ctx, query)
+	ret0, _ := ret[0].([]*data.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchData indicates an expected call of SearchData.
+func (mr *MockDataRepositoryMockRecorder) SearchData(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchData", reflect.TypeOf((*MockDataRepository)(nil).SearchData), ctx, query)
+}
+
+// SetDataFolder mocks base method.
+func (m *MockDataRepository) SetDataFolder(ctx context.Context, dType, name string, folderID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDataFolder", ctx, dType, name, folderID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDataFolder indicates an expected call of SetDataFolder.
+func (mr *MockDataRepositoryMockRecorder) SetDataFolder(ctx, dType, name, folderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDataFolder", reflect.TypeOf((*MockDataRepository)(nil).SetDataFolder), ctx, dType, name, folderID)
+}
+
 // UpdateData mocks base method.
-func (m *MockDataRepository) UpdateData(ctx context.Context, data *data.Data) error {
+func (m *MockDataRepository) UpdateData(ctx context.Context, data *data.Data, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateData", ctx, data)
+	ret := m.ctrl.Call(m, "UpdateData", ctx, data, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateData indicates an expected call of UpdateData.
-func (mr *MockDataRepositoryMockRecorder) UpdateData(ctx, data interface{}) *gomock.Call {
+func (mr *MockDataRepositoryMockRecorder) UpdateData(ctx, data, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateData", reflect.TypeOf((*MockDataRepository)(nil).UpdateData), ctx, data, version)
+}
+
+// UpdateFolder mocks base method.
+func (m *MockDataRepository) UpdateFolder(ctx context.Context, folder *data.Folder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFolder", ctx, folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFolder indicates an expected call of UpdateFolder.
+func (mr *MockDataRepositoryMockRecorder) UpdateFolder(ctx, folder interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateData", reflect.TypeOf((*MockDataRepository)(nil).UpdateData), ctx, data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFolder", reflect.TypeOf((*MockDataRepository)(nil).UpdateFolder), ctx, folder)
 }
@@ -6,6 +6,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	user "github.com/pavlegich/gophkeeper/internal/server/domains/user"
@@ -34,6 +35,138 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockUserService) ChangePassword(ctx context.Context, userID int, sessionID string, change *user.PasswordChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userID, sessionID, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockUserServiceMockRecorder) ChangePassword(ctx, userID, sessionID, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockUserService)(nil).ChangePassword), ctx, userID, sessionID, change)
+}
+
+// CheckSession mocks base method.
+func (m *MockUserService) CheckSession(ctx context.Context, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSession", ctx, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSession indicates an expected call of CheckSession.
+func (mr *MockUserServiceMockRecorder) CheckSession(ctx, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockUserService)(nil).CheckSession), ctx, sessionID)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockUserService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, userID, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockUserServiceMockRecorder) ConfirmTOTP(ctx, userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockUserService)(nil).ConfirmTOTP), ctx, userID, code)
+}
+
+// CreateSession mocks base method.
+func (m *MockUserService) CreateSession(ctx context.Context, sess *user.Session, exp time.Duration) (*user.Session, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, sess, exp)
+	ret0, _ := ret[0].(*user.Session)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockUserServiceMockRecorder) CreateSession(ctx, sess, exp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUserService)(nil).CreateSession), ctx, sess, exp)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserService) DeleteUser(ctx context.Context, userID int, u *user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userID, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserServiceMockRecorder) DeleteUser(ctx, userID, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserService)(nil).DeleteUser), ctx, userID, u)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockUserService) DisableTOTP(ctx context.Context, userID int, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockUserServiceMockRecorder) DisableTOTP(ctx, userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockUserService)(nil).DisableTOTP), ctx, userID, code)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockUserService) EnrollTOTP(ctx context.Context, userID int) (*user.Enrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, userID)
+	ret0, _ := ret[0].(*user.Enrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockUserServiceMockRecorder) EnrollTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockUserService)(nil).EnrollTOTP), ctx, userID)
+}
+
+// GetSalt mocks base method.
+func (m *MockUserService) GetSalt(ctx context.Context, id int) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSalt", ctx, id)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSalt indicates an expected call of GetSalt.
+func (mr *MockUserServiceMockRecorder) GetSalt(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSalt", reflect.TypeOf((*MockUserService)(nil).GetSalt), ctx, id)
+}
+
+// ListSessions mocks base method.
+func (m *MockUserService) ListSessions(ctx context.Context, userID int) ([]*user.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]*user.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockUserServiceMockRecorder) ListSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockUserService)(nil).ListSessions), ctx, userID)
+}
+
 // Login mocks base method.
 func (m *MockUserService) Login(ctx context.Context, u *user.User) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +182,22 @@ func (mr *MockUserServiceMockRecorder) Login(ctx, user interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserService)(nil).Login), ctx, user)
 }
 
+// RefreshSession mocks base method.
+func (m *MockUserService) RefreshSession(ctx context.Context, refresh string, exp time.Duration) (*user.Session, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshSession", ctx, refresh, exp)
+	ret0, _ := ret[0].(*user.Session)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RefreshSession indicates an expected call of RefreshSession.
+func (mr *MockUserServiceMockRecorder) RefreshSession(ctx, refresh, exp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshSession", reflect.TypeOf((*MockUserService)(nil).RefreshSession), ctx, refresh, exp)
+}
+
 // Register mocks base method.
 func (m *MockUserService) Register(ctx context.Context, u *user.User) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +213,34 @@ func (mr *MockUserServiceMockRecorder) Register(ctx, user interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUserService)(nil).Register), ctx, user)
 }
 
+// RevokeSession mocks base method.
+func (m *MockUserService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUserServiceMockRecorder) RevokeSession(ctx, userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUserService)(nil).RevokeSession), ctx, userID, sessionID)
+}
+
+// SetKeyCheck mocks base method.
+func (m *MockUserService) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetKeyCheck", ctx, id, keyCheck)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetKeyCheck indicates an expected call of SetKeyCheck.
+func (mr *MockUserServiceMockRecorder) SetKeyCheck(ctx, id, keyCheck interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetKeyCheck", reflect.TypeOf((*MockUserService)(nil).SetKeyCheck), ctx, id, keyCheck)
+}
+
 // MockUserRepository is a mock of Repository interface.
 type MockUserRepository struct {
 	ctrl     *gomock.Controller
@@ -87,6 +264,20 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CreateSession mocks base method.
+func (m *MockUserRepository) CreateSession(ctx context.Context, sess *user.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, sess)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockUserRepositoryMockRecorder) CreateSession(ctx, sess interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUserRepository)(nil).CreateSession), ctx, sess)
+}
+
 // CreateUser mocks base method.
 func (m *MockUserRepository) CreateUser(ctx context.Context, u *user.User) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -102,6 +293,109 @@ func (mr *MockUserRepositoryMockRecorder) CreateUser(ctx, user interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), ctx, user)
 }
 
+// DeleteTOTP mocks base method.
+func (m *MockUserRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockUserRepositoryMockRecorder) DeleteTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockUserRepository)(nil).DeleteTOTP), ctx, userID)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserRepository) DeleteUser(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserRepositoryMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepository)(nil).DeleteUser), ctx, id)
+}
+
+// GetSessionByID mocks base method.
+func (m *MockUserRepository) GetSessionByID(ctx context.Context, id string) (*user.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByID", ctx, id)
+	ret0, _ := ret[0].(*user.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByID indicates an expected call of GetSessionByID.
+func (mr *MockUserRepositoryMockRecorder) GetSessionByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByID", reflect.TypeOf((*MockUserRepository)(nil).GetSessionByID), ctx, id)
+}
+
+// GetSessionByRefresh mocks base method.
+func (m *MockUserRepository) GetSessionByRefresh(ctx context.Context, refreshHash []byte) (*user.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByRefresh", ctx, refreshHash)
+	ret0, _ := ret[0].(*user.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByRefresh indicates an expected call of GetSessionByRefresh.
+func (mr *MockUserRepositoryMockRecorder) GetSessionByRefresh(ctx, refreshHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByRefresh", reflect.TypeOf((*MockUserRepository)(nil).GetSessionByRefresh), ctx, refreshHash)
+}
+
+// GetSessions mocks base method.
+func (m *MockUserRepository) GetSessions(ctx context.Context, userID int) ([]*user.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]*user.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockUserRepositoryMockRecorder) GetSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockUserRepository)(nil).GetSessions), ctx, userID)
+}
+
+// GetTOTP mocks base method.
+func (m *MockUserRepository) GetTOTP(ctx context.Context, userID int) (*user.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", ctx, userID)
+	ret0, _ := ret[0].(*user.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockUserRepositoryMockRecorder) GetTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockUserRepository)(nil).GetTOTP), ctx, userID)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id int) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryMockRecorder) GetUserByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepository)(nil).GetUserByID), ctx, id)
+}
+
 // GetUserByLogin mocks base method.
 func (m *MockUserRepository) GetUserByLogin(ctx context.Context, login string) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -116,3 +410,143 @@ func (mr *MockUserRepositoryMockRecorder) GetUserByLogin(ctx, login interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockUserRepository)(nil).GetUserByLogin), ctx, login)
 }
+
+// RevokeOtherSessions mocks base method.
+func (m *MockUserRepository) RevokeOtherSessions(ctx context.Context, userID int, keepID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, userID, keepID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockUserRepositoryMockRecorder) RevokeOtherSessions(ctx, userID, keepID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockUserRepository)(nil).RevokeOtherSessions), ctx, userID, keepID)
+}
+
+// RevokeSession mocks base method.
+func (m *MockUserRepository) RevokeSession(ctx context.Context, userID int, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUserRepositoryMockRecorder) RevokeSession(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUserRepository)(nil).RevokeSession), ctx, userID, id)
+}
+
+// RotateSession mocks base method.
+func (m *MockUserRepository) RotateSession(ctx context.Context, sess *user.Session, oldHash []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, sess, oldHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockUserRepositoryMockRecorder) RotateSession(ctx, sess, oldHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockUserRepository)(nil).RotateSession), ctx, sess, oldHash)
+}
+
+// SetKeyCheck mocks base method.
+func (m *MockUserRepository) SetKeyCheck(ctx context.Context, id int, keyCheck []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetKeyCheck", ctx, id, keyCheck)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetKeyCheck indicates an expected call of SetKeyCheck.
+func (mr *MockUserRepositoryMockRecorder) SetKeyCheck(ctx, id, keyCheck interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetKeyCheck", reflect.TypeOf((*MockUserRepository)(nil).SetKeyCheck), ctx, id, keyCheck)
+}
+
+// SetRecoveryCodes mocks base method.
+func (m *MockUserRepository) SetRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRecoveryCodes", ctx, userID, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRecoveryCodes indicates an expected call of SetRecoveryCodes.
+func (mr *MockUserRepositoryMockRecorder) SetRecoveryCodes(ctx, userID, hashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRecoveryCodes", reflect.TypeOf((*MockUserRepository)(nil).SetRecoveryCodes), ctx, userID, hashes)
+}
+
+// SetTOTP mocks base method.
+func (m *MockUserRepository) SetTOTP(ctx context.Context, t *user.TOTP) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTP", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTP indicates an expected call of SetTOTP.
+func (mr *MockUserRepositoryMockRecorder) SetTOTP(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTP", reflect.TypeOf((*MockUserRepository)(nil).SetTOTP), ctx, t)
+}
+
+// TouchSession mocks base method.
+func (m *MockUserRepository) TouchSession(ctx context.Context, id string, lastUsedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", ctx, id, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MockUserRepositoryMockRecorder) TouchSession(ctx, id, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession", reflect.TypeOf((*MockUserRepository)(nil).TouchSession), ctx, id, lastUsedAt)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepositoryMockRecorder) UpdatePassword(ctx, id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepository)(nil).UpdatePassword), ctx, id, password)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, userID int, hash []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserRepositoryMockRecorder) UseRecoveryCode(ctx, userID, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserRepository)(nil).UseRecoveryCode), ctx, userID, hash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockUserRepositoryMockRecorder) UseTOTPStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockUserRepository)(nil).UseTOTPStep), ctx, userID, step)
+}
@@ -3,6 +3,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -20,9 +21,15 @@ func NewServer(ctx context.Context, mh *chi.Mux, cfg *config.ServerConfig) (*Ser
 	r := chi.NewRouter()
 	r.Mount("/", mh)
 
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("NewServer: %w", err)
+	}
+
 	srv := &http.Server{
-		Addr:    cfg.Address,
-		Handler: r,
+		Addr:      cfg.Address,
+		Handler:   r,
+		TLSConfig: tlsCfg,
 	}
 
 	return &Server{
@@ -36,8 +43,13 @@ func (s *Server) GetAddress(ctx context.Context) string {
 	return s.config.Address
 }
 
-// Serve starts listening the network by the server.
+// Serve starts listening the network by the server,
+// only TLS connections are accepted if TLS is enabled.
 func (s *Server) Serve(ctx context.Context) error {
+	if s.server.TLSConfig != nil {
+		// Certificates are already loaded into TLS configuration
+		return s.server.ListenAndServeTLS("", "")
+	}
 	return s.server.ListenAndServe()
 }
 
@@ -0,0 +1,83 @@
+// Package storage contains the storage of users and data object
+// selected by the server configuration.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pavlegich/gophkeeper/internal/common/infra/blob"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/config"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/database"
+	"github.com/pavlegich/gophkeeper/internal/common/infra/hash"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
+	dataRepo "github.com/pavlegich/gophkeeper/internal/server/domains/data/repository"
+	"github.com/pavlegich/gophkeeper/internal/server/domains/user"
+	userRepo "github.com/pavlegich/gophkeeper/internal/server/domains/user/repository"
+)
+
+// Storage contains the repositories of users and data objects
+// and the storage of keys for signing tokens.
+type Storage struct {
+	Users user.Repository
+	Data  data.Repository
+	Keys  hash.KeyStorage
+	db    *sql.DB
+}
+
+// NewStorage initializes the storage selected by the configuration: PostgreSQL
+// database, embedded SQLite database or memory, which is lost when the server
+// is stopped and is used for trying the server and for tests.
+func NewStorage(ctx context.Context, cfg *config.ServerConfig) (*Storage, error) {
+	var db *sql.DB
+	var err error
+	switch cfg.Storage {
+	case "memory":
+		return NewMemoryStorage(ctx), nil
+	case "sqlite":
+		db, err = database.InitSQLite(ctx, cfg.SQLitePath)
+	default:
+		db, err = database.Init(ctx, cfg.DSN)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("NewStorage: database initialization failed %w", err)
+	}
+
+	store, err := blob.NewStore(ctx, cfg)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("NewStorage: create blob store failed %w", err)
+	}
+
+	dataRepository := dataRepo.NewDataRepository(ctx, db, dataRepo.WithBlobStore(store, cfg.BlobThreshold))
+	return &Storage{
+		Users: userRepo.NewUserRepository(ctx, db, userRepo.WithBlobCleanup(dataRepository.DeleteBlobs)),
+		Data:  dataRepository,
+		Keys:  hash.NewDBKeyStorage(db),
+		db:    db,
+	}, nil
+}
+
+// NewMemoryStorage returns new storage in memory,
+// the user's data is deleted together with the user.
+func NewMemoryStorage(ctx context.Context) *Storage {
+	dataMemory := dataRepo.NewMemoryDataRepository(ctx)
+	return &Storage{
+		Users: userRepo.NewMemoryUserRepository(ctx, dataMemory.DeleteUserData),
+		Data:  dataMemory,
+		Keys:  hash.NewMemoryKeyStorage(),
+	}
+}
+
+// Close closes the database of the storage.
+func (s *Storage) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	if err != nil {
+		return fmt.Errorf("Close: close database failed %w", err)
+	}
+	return nil
+}
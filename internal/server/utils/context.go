@@ -12,6 +12,7 @@ const (
 	// List of const variables contains variables for
 	// put values into and get values from the context.
 	ContextIDKey contextKey = iota
+	ContextSessionKey
 )
 
 // GetUserIDFromContext finds and returns user id from the context.
@@ -26,3 +27,12 @@ func GetUserIDFromContext(ctx context.Context) (int, error) {
 	}
 	return userID, nil
 }
+
+// GetSessionIDFromContext finds and returns session id from the context.
+func GetSessionIDFromContext(ctx context.Context) (string, error) {
+	sessionID, ok := ctx.Value(ContextSessionKey).(string)
+	if !ok || sessionID == "" {
+		return "", fmt.Errorf("GetSessionIDFromContext: get context value failed")
+	}
+	return sessionID, nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// AuthMetadata is the gRPC metadata key with the access token.
+const AuthMetadata = "authorization"
+
+// getMetadataValue returns the first value of the incoming gRPC metadata
+// by the key, cut to the limit.
+func getMetadataValue(ctx context.Context, key string, limit int) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	value := strings.TrimSpace(values[0])
+	if len(value) > limit {
+		value = value[:limit]
+	}
+	return value
+}
+
+// GetTokenFromMetadata returns the access token from the "authorization"
+// metadata of gRPC request in format "Bearer <token>".
+func GetTokenFromMetadata(ctx context.Context) string {
+	value := getMetadataValue(ctx, AuthMetadata, 4096)
+	scheme, token, found := strings.Cut(value, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// GetDeviceFromMetadata returns the name of the client device
+// from the metadata of gRPC request.
+func GetDeviceFromMetadata(ctx context.Context) string {
+	return getMetadataValue(ctx, DeviceHeader, 128)
+}
+
+// GetUserAgentFromMetadata returns the user agent of the client
+// from the metadata of gRPC request.
+func GetUserAgentFromMetadata(ctx context.Context) string {
+	return getMetadataValue(ctx, "user-agent", 256)
+}
+
+// GetIPFromPeer returns the IP address of the client of gRPC request.
+func GetIPFromPeer(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
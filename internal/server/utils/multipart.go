@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -13,8 +14,20 @@ import (
 	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
 )
 
+// MultipartOverhead is the size of the request body reserved for metadata
+// and multipart headers in addition to the maximum size of data value.
+const MultipartOverhead = 1 << 20
+
+// maxSizeHint is the maximum size of the buffer allocated in advance
+// for the declared content length, which is not trusted above it.
+const maxSizeHint = 64 << 20
+
 // GetMultipartDataFromRequest reads multipart fields from the request and returns
-// the data object with the obtained multipart data.
+// the data object with the obtained multipart data, the file name and the content
+// type of the value part are kept as declared by the client. The value part sent
+// after metadata is not read, it is streamed from the request body as the content
+// of data object, so the storage reads it directly from the request. The value
+// sent before metadata by the clients of previous versions is read into memory.
 func GetMultipartDataFromRequest(ctx context.Context, r *http.Request, d *data.Data) (*data.Data, error) {
 	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
@@ -25,7 +38,7 @@ func GetMultipartDataFromRequest(ctx context.Context, r *http.Request, d *data.D
 
 	if strings.HasPrefix(mediaType, "multipart/") {
 		multipartReader := multipart.NewReader(r.Body, params["boundary"])
-		defer r.Body.Close()
+		metadataRead := false
 
 		for {
 			field, err := multipartReader.NextPart()
@@ -35,22 +48,40 @@ func GetMultipartDataFromRequest(ctx context.Context, r *http.Request, d *data.D
 				}
 				return nil, fmt.Errorf("GetMultipartDataFromRequest: get next multi part failed %w", err)
 			}
-			defer field.Close()
 
 			switch field.FormName() {
 			case "data", "file":
-				multiparted.Data, err = io.ReadAll(field)
+				multiparted.FileName = field.FileName()
+				multiparted.ContentType = field.Header.Get("Content-Type")
+				if metadataRead {
+					// The value is the last part, the rest of the body is not read
+					multiparted.Content = field
+					return multiparted, nil
+				}
+				multiparted.Data, err = readPart(field, r.ContentLength)
 				if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 					return nil, fmt.Errorf("GetMultipartDataFromRequest: couldn't read data from the field data %w", err)
 				}
 			case "metadata":
-				multiparted.Metadata, err = io.ReadAll(field)
+				multiparted.Metadata, err = readPart(field, 0)
 				if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 					return nil, fmt.Errorf("GetMultipartDataFromRequest: couldn't read data from the field metadata %w", err)
 				}
+				metadataRead = true
 			}
 		}
 	}
 
 	return multiparted, nil
 }
+
+// readPart reads the multipart field. If the expected size is known, the buffer
+// is allocated once, so the large value is not copied while the buffer grows.
+func readPart(field *multipart.Part, sizeHint int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if sizeHint > 0 {
+		buf.Grow(int(min(sizeHint, maxSizeHint)))
+	}
+	_, err := buf.ReadFrom(field)
+	return buf.Bytes(), err
+}
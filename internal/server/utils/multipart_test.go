@@ -3,24 +3,43 @@ package utils
 import (
 	"bytes"
 	"context"
+	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"reflect"
 	"testing"
 
 	"github.com/pavlegich/gophkeeper/internal/server/domains/data"
 )
 
-func createRequest(d *data.Data) *http.Request {
+func createRequest(d *data.Data, metadataFirst bool) *http.Request {
 	var buf bytes.Buffer
 	mw := multipart.NewWriter(&buf)
 	defer mw.Close()
 
-	dataPart, _ := mw.CreateFormField("data")
+	if metadataFirst {
+		metaPart, _ := mw.CreateFormField("metadata")
+		metaPart.Write(d.Metadata)
+	}
+
+	var dataPart io.Writer
+	if d.FileName != "" {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data",
+			map[string]string{"name": "file", "filename": d.FileName}))
+		h.Set("Content-Type", d.ContentType)
+		dataPart, _ = mw.CreatePart(h)
+	} else {
+		dataPart, _ = mw.CreateFormField("data")
+	}
 	dataPart.Write(d.Data)
 
-	metaPart, _ := mw.CreateFormField("metadata")
-	metaPart.Write(d.Metadata)
+	if !metadataFirst {
+		metaPart, _ := mw.CreateFormField("metadata")
+		metaPart.Write(d.Metadata)
+	}
 
 	r, _ := http.NewRequest("", "", &buf)
 	r.Header.Set("Content-Type", mw.FormDataContentType())
@@ -31,14 +50,16 @@ func createRequest(d *data.Data) *http.Request {
 func TestGetMultipartDataFromRequest(t *testing.T) {
 	ctx := context.Background()
 	type args struct {
-		ctx context.Context
-		d   *data.Data
+		ctx           context.Context
+		d             *data.Data
+		metadataFirst bool
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    *data.Data
-		wantErr bool
+		name        string
+		args        args
+		want        *data.Data
+		wantContent []byte
+		wantErr     bool
 	}{
 		{
 			name: "ok",
@@ -55,15 +76,73 @@ func TestGetMultipartDataFromRequest(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "file",
+			args: args{
+				ctx: ctx,
+				d: &data.Data{
+					Data:     []byte(`content`),
+					Metadata: []byte(`{"meta": "meta"}`),
+					File: data.File{
+						FileName:    "report.pdf",
+						ContentType: "application/pdf",
+					},
+				},
+			},
+			want: &data.Data{
+				Data:     []byte(`content`),
+				Metadata: []byte(`{"meta": "meta"}`),
+				File: data.File{
+					FileName:    "report.pdf",
+					ContentType: "application/pdf",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "streamed_file",
+			args: args{
+				ctx: ctx,
+				d: &data.Data{
+					Data:     []byte(`content`),
+					Metadata: []byte(`{"meta": "meta"}`),
+					File: data.File{
+						FileName:    "report.pdf",
+						ContentType: "application/pdf",
+					},
+				},
+				metadataFirst: true,
+			},
+			want: &data.Data{
+				Metadata: []byte(`{"meta": "meta"}`),
+				File: data.File{
+					FileName:    "report.pdf",
+					ContentType: "application/pdf",
+				},
+			},
+			wantContent: []byte(`content`),
+			wantErr:     false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := createRequest(tt.args.d)
-			got, err := GetMultipartDataFromRequest(tt.args.ctx, req, tt.args.d)
+			req := createRequest(tt.args.d, tt.args.metadataFirst)
+			got, err := GetMultipartDataFromRequest(tt.args.ctx, req, &data.Data{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetMultipartDataFromRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantContent != nil {
+				if got.Content == nil {
+					t.Fatalf("GetMultipartDataFromRequest() content is not streamed")
+				}
+				content, err := io.ReadAll(got.Content)
+				if err != nil || !bytes.Equal(content, tt.wantContent) {
+					t.Errorf("GetMultipartDataFromRequest() content = %s, error = %v, want %s",
+						content, err, tt.wantContent)
+				}
+				got.Content = nil
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("GetMultipartDataFromRequest() = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DeviceHeader is the request header with the name of the client device.
+const DeviceHeader = "X-Device"
+
+// MetadataHeader is the response header with the metadata
+// of binary data object encoded in base64.
+const MetadataHeader = "X-Metadata"
+
+// Headers of chunked upload requests with the offset of the chunk
+// and its checksum in the form "sha256 <base64 digest>".
+const (
+	UploadOffsetHeader   = "Upload-Offset"
+	UploadChecksumHeader = "Upload-Checksum"
+)
+
+// ETag returns the entity tag for the version of data object.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// GetVersionFromRequest returns the version of data object expected by the client
+// from the If-Match request header. Zero is returned if any version is acceptable.
+func GetVersionFromRequest(r *http.Request) (int, error) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, fmt.Errorf("GetVersionFromRequest: incorrect entity tag %s", tag)
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("GetVersionFromRequest: incorrect version in entity tag %s", tag)
+	}
+	return version, nil
+}
+
+// GetOffsetFromRequest returns the offset of the uploaded chunk from the request header.
+func GetOffsetFromRequest(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get(UploadOffsetHeader))
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("GetOffsetFromRequest: incorrect offset %s", value)
+	}
+	return offset, nil
+}
+
+// GetChecksumFromRequest returns SHA-256 digest of the uploaded chunk
+// from the request header, nil is returned if the checksum is not specified.
+func GetChecksumFromRequest(r *http.Request) ([]byte, error) {
+	value := strings.TrimSpace(r.Header.Get(UploadChecksumHeader))
+	if value == "" {
+		return nil, nil
+	}
+	algorithm, digest, _ := strings.Cut(value, " ")
+	if !strings.EqualFold(algorithm, "sha256") {
+		return nil, fmt.Errorf("GetChecksumFromRequest: unsupported algorithm %s", algorithm)
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digest))
+	if err != nil || len(sum) != 32 {
+		return nil, fmt.Errorf("GetChecksumFromRequest: incorrect digest %s", digest)
+	}
+	return sum, nil
+}
+
+// GetDeviceFromRequest returns the name of the client device
+// from the request header.
+func GetDeviceFromRequest(r *http.Request) string {
+	device := strings.TrimSpace(r.Header.Get(DeviceHeader))
+	if len(device) > 128 {
+		device = device[:128]
+	}
+	return device
+}
+
+// GetIPFromRequest returns the IP address of the client from the request.
+func GetIPFromRequest(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// GetUserAgentFromRequest returns the user agent of the client from the request.
+func GetUserAgentFromRequest(r *http.Request) string {
+	agent := strings.TrimSpace(r.UserAgent())
+	if len(agent) > 256 {
+		agent = agent[:256]
+	}
+	return agent
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetDeviceFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		device string
+		want   string
+	}{
+		{
+			name:   "ok",
+			device: "laptop",
+			want:   "laptop",
+		},
+		{
+			name:   "no_header",
+			device: "",
+			want:   "",
+		},
+		{
+			name:   "too_long",
+			device: strings.Repeat("a", 200),
+			want:   strings.Repeat("a", 128),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if tt.device != "" {
+				r.Header.Set(DeviceHeader, tt.device)
+			}
+			if got := GetDeviceFromRequest(r); got != tt.want {
+				t.Errorf("GetDeviceFromRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVersionFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "ok",
+			ifMatch: `"3"`,
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "weak_tag",
+			ifMatch: `W/"5"`,
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name:    "no_header",
+			ifMatch: "",
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "any_version",
+			ifMatch: "*",
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "unquoted",
+			ifMatch: "3",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "not_number",
+			ifMatch: `"abc"`,
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodPut, "/", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			got, err := GetVersionFromRequest(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetVersionFromRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetVersionFromRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIPFromRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{
+			name:       "ipv4",
+			remoteAddr: "192.0.2.1:51234",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "ipv6",
+			remoteAddr: "[2001:db8::1]:51234",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "without_port",
+			remoteAddr: "192.0.2.1",
+			want:       "192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if got := GetIPFromRequest(r); got != tt.want {
+				t.Errorf("GetIPFromRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetChecksumFromRequest(t *testing.T) {
+	sum := sha256.Sum256([]byte("chunk"))
+	digest := base64.StdEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		checksum string
+		want     []byte
+		wantErr  bool
+	}{
+		{
+			name:     "ok",
+			checksum: "sha256 " + digest,
+			want:     sum[:],
+			wantErr:  false,
+		},
+		{
+			name:     "no_header",
+			checksum: "",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "unsupported_algorithm",
+			checksum: "md5 " + digest,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "short_digest",
+			checksum: "sha256 " + base64.StdEncoding.EncodeToString(sum[:16]),
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodPut, "/", nil)
+			if tt.checksum != "" {
+				r.Header.Set(UploadChecksumHeader, tt.checksum)
+			}
+			got, err := GetChecksumFromRequest(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetChecksumFromRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("GetChecksumFromRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}